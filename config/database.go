@@ -35,11 +35,116 @@ func ConnectDatabase() {
 		log.Fatalf("Failed to connect to the database: %v", err)
 	}
 
+	// Duplicate setting keys would block the unique index on settings.key
+	if err := dedupeSettingKeys(DB); err != nil {
+		log.Fatalf("Failed to deduplicate settings: %v", err)
+	}
+
 	// AutoMigrate will create the table if it does not exist
-	err = DB.AutoMigrate(&models.User{})
+	err = DB.AutoMigrate(&models.User{}, &models.RoleHasRuleCondition{}, &models.Setting{}, &models.SettingHistory{}, &models.EmailOutbox{}, &models.NotificationTemplate{}, &models.Notification{}, &models.DeviceToken{}, &models.PushDelivery{}, &models.DocumentDistribution{}, &models.DocumentAcknowledgement{}, &models.DocumentImportJob{}, &models.DocumentImportRow{}, &models.DocumentChangeRequest{}, &models.DocumentChangeRequestAttachment{}, &models.DocumentRetentionPolicy{}, &models.HealthRiskRuleSet{}, &models.HealthMeasurementIssue{}, &models.HealthPerson{}, &models.HealthAccessLog{})
 	if err != nil {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
+
+	// Bodies of sent emails were once kept; they may hold password reset links, so clear them
+	if err := DB.Model(&models.EmailOutbox{}).Where("status = ? AND body <> ''", models.EmailStatusSent).
+		Update("body", "").Error; err != nil {
+		log.Fatalf("Failed to clear sent email bodies: %v", err)
+	}
+
+	// forms is managed outside AutoMigrate; only add the column used for submission notifications
+	if DB.Migrator().HasTable(&models.Form{}) && !DB.Migrator().HasColumn(&models.Form{}, "CreatedBy") {
+		if err := DB.Migrator().AddColumn(&models.Form{}, "CreatedBy"); err != nil {
+			log.Fatalf("Failed to migrate forms: %v", err)
+		}
+	}
+
+	// document_control is managed outside AutoMigrate as well; add the review, search, check-out and trash columns when missing
+	if DB.Migrator().HasTable(&models.DocumentControl{}) {
+		for _, field := range []string{"ReviewIntervalMonths", "NextReviewDate", "LastReviewedAt", "ExpiryDate", "ReviewOverdue", "ReviewReminderSentAt", "ExpiryReminderSentAt", "ExpiredNotifiedAt", "SearchVector",
+			"CheckedOutBy", "CheckedOutAt", "CheckoutExpiresAt", "LockVersion", "DeletedBy", "PurgeAfter"} {
+			if !DB.Migrator().HasColumn(&models.DocumentControl{}, field) {
+				if err := DB.Migrator().AddColumn(&models.DocumentControl{}, field); err != nil {
+					log.Fatalf("Failed to migrate document_control: %v", err)
+				}
+			}
+		}
+		for _, field := range []string{"NextReviewDate", "ExpiryDate", "PurgeAfter"} {
+			if !DB.Migrator().HasIndex(&models.DocumentControl{}, field) {
+				if err := DB.Migrator().CreateIndex(&models.DocumentControl{}, field); err != nil {
+					log.Fatalf("Failed to index document_control: %v", err)
+				}
+			}
+		}
+		if err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_document_control_search_vector ON document_control USING GIN (search_vector)").Error; err != nil {
+			log.Fatalf("Failed to index document_control: %v", err)
+		}
+	}
+
+	// health_data is managed outside AutoMigrate; record which risk engine and rule version assessed each entry and
+	// the person it belongs to
+	if DB.Migrator().HasTable(&models.Health{}) {
+		for _, field := range []string{"RiskEngine", "RiskRuleVersion", "HealthPersonID"} {
+			if !DB.Migrator().HasColumn(&models.Health{}, field) {
+				if err := DB.Migrator().AddColumn(&models.Health{}, field); err != nil {
+					log.Fatalf("Failed to migrate health_data: %v", err)
+				}
+			}
+		}
+		if !DB.Migrator().HasIndex(&models.Health{}, "HealthPersonID") {
+			if err := DB.Migrator().CreateIndex(&models.Health{}, "HealthPersonID"); err != nil {
+				log.Fatalf("Failed to index health_data: %v", err)
+			}
+		}
+	}
+
+	// document_version keeps the extracted text, the inspected details and the archival of each upload
+	if DB.Migrator().HasTable(&models.DocumentVersion{}) {
+		for _, field := range []string{"ContentText", "Checksum", "MimeType", "FileSize", "PageCount", "PdfTitle", "IsEncrypted", "DocumentChangeRequestID", "ArchivedAt", "RetainUntil"} {
+			if !DB.Migrator().HasColumn(&models.DocumentVersion{}, field) {
+				if err := DB.Migrator().AddColumn(&models.DocumentVersion{}, field); err != nil {
+					log.Fatalf("Failed to migrate document_version: %v", err)
+				}
+			}
+		}
+		for _, field := range []string{"Checksum", "DocumentChangeRequestID", "RetainUntil"} {
+			if !DB.Migrator().HasIndex(&models.DocumentVersion{}, field) {
+				if err := DB.Migrator().CreateIndex(&models.DocumentVersion{}, field); err != nil {
+					log.Fatalf("Failed to index document_version: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// duplicateSettingsSQL selects every settings row but the most recently updated one of each key
+const duplicateSettingsSQL = `FROM settings AS a WHERE EXISTS (SELECT 1 FROM settings AS b
+	WHERE b.key = a.key AND (a.updated_at < b.updated_at OR (a.updated_at = b.updated_at AND a.id < b.id)))`
+
+// dedupeSettingKeys is the one-time migration that lets settings.key become unique. It only runs while the unique
+// index does not exist yet: the rows it discards are copied to setting_history as deletions first, so no value is lost.
+func dedupeSettingKeys(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&models.Setting{}) || db.Migrator().HasIndex(&models.Setting{}, "Key") {
+		return nil
+	}
+	if err := db.AutoMigrate(&models.SettingHistory{}); err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		moved := tx.Exec(`INSERT INTO setting_history (setting_id, setting_uuid, key, value, action, changed_by, changed_at)
+			SELECT a.id, a.uuid, a.key, a.value, ?, 0, CURRENT_TIMESTAMP `+duplicateSettingsSQL, models.SettingHistoryActionDelete)
+		if moved.Error != nil {
+			return fmt.Errorf("failed to copy duplicate settings to setting_history: %w", moved.Error)
+		}
+		if err := tx.Exec("DELETE " + duplicateSettingsSQL).Error; err != nil {
+			return fmt.Errorf("failed to delete duplicate settings: %w", err)
+		}
+		if moved.RowsAffected > 0 {
+			log.Printf("Moved %d duplicate setting(s) to setting_history", moved.RowsAffected)
+		}
+		return nil
+	})
 }
 
 // Helper function to get environment variables with default fallback
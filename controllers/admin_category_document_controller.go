@@ -40,7 +40,7 @@ func (c *CategoryDocumentController) GetCategoryDocuments(ctx *fiber.Ctx) error
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the user has access to the "category-document" resource using the "read" action
-	hasAccess, err := enforcer.Enforce(username, "category-document", "read", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "category-document", "read", "none", "none", "none")
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"statusCode": fiber.StatusInternalServerError,
@@ -103,7 +103,7 @@ func (c *CategoryDocumentController) CreateCategoryDocument(ctx *fiber.Ctx) erro
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the user has access to create a "category-document"
-	hasAccess, err := enforcer.Enforce(username, "category-document", "create", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "category-document", "create", "none", "none", "none")
 	if err != nil {
 		log.Printf("Casbin enforcement error: %v", err)
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -187,7 +187,7 @@ func (c *CategoryDocumentController) GetCategoryDocumentByUUID(ctx *fiber.Ctx) e
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the user has access to read a "category-document"
-	hasAccess, err := enforcer.Enforce(username, "category-document", "read", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "category-document", "read", "none", "none", "none")
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"statusCode": fiber.StatusInternalServerError,
@@ -245,7 +245,7 @@ func (c *CategoryDocumentController) UpdateCategoryDocument(ctx *fiber.Ctx) erro
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the user has access to update a "category-document"
-	hasAccess, err := enforcer.Enforce(username, "category-document", "update", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "category-document", "update", "none", "none", "none")
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"statusCode": fiber.StatusInternalServerError,
@@ -320,7 +320,7 @@ func (c *CategoryDocumentController) DeleteCategoryDocument(ctx *fiber.Ctx) erro
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the user has access to delete a "category-document"
-	hasAccess, err := enforcer.Enforce(username, "category-document", "delete", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "category-document", "delete", "none", "none", "none")
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"statusCode": fiber.StatusInternalServerError,
@@ -365,7 +365,7 @@ func (c *CategoryDocumentController) GetRolesAndActions(ctx *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the user has access to delete a "category-document"
-	hasAccess, err := enforcer.Enforce(username, "category-document", "read", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "category-document", "read", "none", "none", "none")
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"statusCode": fiber.StatusInternalServerError,
@@ -0,0 +1,385 @@
+package controllers
+
+import (
+	"backend-school/helpers"
+	"backend-school/models"
+	"backend-school/services"
+	"errors"
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// DocumentChangeRequestDecision is the body of an approval or rejection
+type DocumentChangeRequestDecision struct {
+	Comment string `json:"comment"`
+}
+
+type DocumentChangeRequestController struct {
+	Service         *services.DocumentChangeRequestService
+	DocumentService *services.DocumentControlService
+}
+
+func NewDocumentChangeRequestController() *DocumentChangeRequestController {
+	documentService := NewDocumentControlController().Service
+	return &DocumentChangeRequestController{
+		Service:         services.NewDocumentChangeRequestService(documentService),
+		DocumentService: documentService,
+	}
+}
+
+// changeRequestErrorResponse maps change request service errors to HTTP responses
+func changeRequestErrorResponse(ctx *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrChangeRequestNotFound), errors.Is(err, services.ErrChangeRequestAttachmentNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, services.ErrInvalidChangeRequest), errors.Is(err, services.ErrInvalidDocumentFile):
+		status = fiber.StatusBadRequest
+	case errors.Is(err, services.ErrChangeRequestState), errors.Is(err, services.ErrDuplicateDocumentVersion):
+		status = fiber.StatusConflict
+	case errors.Is(err, services.ErrChangeRequestSelfReview), errors.Is(err, services.ErrChangeRequestNotRequester):
+		status = fiber.StatusForbidden
+	case errors.Is(err, services.ErrDocumentCheckedOut):
+		status = fiber.StatusLocked
+	}
+	return ctx.Status(status).JSON(fiber.Map{
+		"statusCode": status,
+		"message":    err.Error(),
+		"data":       nil,
+	})
+}
+
+// checkChangeRequestAccess checks a "document-change-request" action. ownerID, when set, is evaluated by
+// "owner" conditions. When access is not granted the error response has been written.
+func checkChangeRequestAccess(ctx *fiber.Ctx, action string, ownerID *int) (bool, error) {
+	requesterUsername := ctx.Locals("username").(string)
+
+	// Get Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	attrs := helpers.RequestAttributesFromCtx(ctx)
+	if ownerID != nil {
+		attrs.OwnerID = *ownerID
+	}
+	hasAccess, err := helpers.EnforceWithAttributes(enforcer, attrs, requesterUsername, "document-change-request", action, "none", "none", "none")
+	if err != nil {
+		log.Printf("Error checking Casbin permissions: %v", err)
+		return false, ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access permissions.",
+		})
+	}
+
+	// If the requester doesn't have access, return a forbidden status
+	if !hasAccess {
+		return false, ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have permission to access this resource.",
+		})
+	}
+	return true, nil
+}
+
+// findChangeRequest loads the change request named in the route; when it returns none the error response has been written
+func (c *DocumentChangeRequestController) findChangeRequest(ctx *fiber.Ctx) (*services.DocumentChangeRequestDetail, error) {
+	uuidParam := ctx.Params("uuid")
+	if _, err := uuid.Parse(uuidParam); err != nil {
+		return nil, ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid UUID format",
+			"data":       nil,
+		})
+	}
+
+	request, err := c.Service.GetChangeRequest(uuidParam)
+	if err != nil {
+		return nil, changeRequestErrorResponse(ctx, err)
+	}
+	return request, nil
+}
+
+// GetChangeRequests lists change requests (?document_uuid=, ?status=, ?mine=true).
+func (c *DocumentChangeRequestController) GetChangeRequests(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(int)
+
+	if allowed, err := checkChangeRequestAccess(ctx, "read", nil); !allowed {
+		return err
+	}
+
+	currentPage, err := strconv.Atoi(ctx.Query("currentPage", "1"))
+	if err != nil || currentPage < 1 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid currentPage",
+			"data":       nil,
+		})
+	}
+
+	pageSize, err := strconv.Atoi(ctx.Query("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid pageSize",
+			"data":       nil,
+		})
+	}
+
+	filter := services.DocumentChangeRequestFilter{Status: ctx.Query("status", "")}
+	switch filter.Status {
+	case "", models.DocumentChangeRequestSubmitted, models.DocumentChangeRequestInReview, models.DocumentChangeRequestApproved,
+		models.DocumentChangeRequestRejected, models.DocumentChangeRequestCancelled, models.DocumentChangeRequestImplemented:
+	default:
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid status",
+			"data":       nil,
+		})
+	}
+
+	if documentUUID := ctx.Query("document_uuid", ""); documentUUID != "" {
+		documentControl, err := c.DocumentService.GetDocumentControlByUUID(documentUUID)
+		if err != nil {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"statusCode": fiber.StatusNotFound,
+				"message":    "Document control not found",
+			})
+		}
+		filter.DocumentControlID = documentControl.ID
+	}
+	if mine, _ := strconv.ParseBool(ctx.Query("mine", "false")); mine {
+		filter.RequestedBy = userID
+	}
+
+	result, err := c.Service.GetChangeRequestsPaginated(filter, pageSize, currentPage)
+	if err != nil {
+		return changeRequestErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Change requests fetched successfully",
+		"data":       result,
+	})
+}
+
+// CreateChangeRequest submits a change request for a document. It takes a multipart form with document_uuid,
+// reason, proposed_changes and any number of "attachments" files.
+func (c *DocumentChangeRequestController) CreateChangeRequest(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(int)
+
+	documentControl, err := c.DocumentService.GetDocumentControlByUUID(ctx.FormValue("document_uuid"))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"statusCode": fiber.StatusNotFound,
+			"message":    "Document control not found",
+		})
+	}
+
+	// Check access with the document owner so "owner" conditions can be evaluated
+	if allowed, err := checkChangeRequestAccess(ctx, "create", documentControl.CreatedBy); !allowed {
+		return err
+	}
+
+	form, err := ctx.MultipartForm()
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid form data",
+			"data":       nil,
+		})
+	}
+
+	request, err := c.Service.CreateChangeRequest(documentControl, ctx.FormValue("reason"), ctx.FormValue("proposed_changes"), form.File["attachments"], userID)
+	if err != nil {
+		return changeRequestErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"statusCode": fiber.StatusCreated,
+		"message":    "Change request submitted successfully",
+		"data":       request,
+	})
+}
+
+// GetChangeRequest returns a change request with its attachments.
+func (c *DocumentChangeRequestController) GetChangeRequest(ctx *fiber.Ctx) error {
+	request, err := c.findChangeRequest(ctx)
+	if request == nil {
+		return err
+	}
+
+	// The requester is the owner of a change request
+	if allowed, err := checkChangeRequestAccess(ctx, "read", &request.RequestedBy); !allowed {
+		return err
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Change request fetched successfully",
+		"data":       request,
+	})
+}
+
+// StartReview takes a submitted change request into review.
+func (c *DocumentChangeRequestController) StartReview(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(int)
+
+	request, err := c.findChangeRequest(ctx)
+	if request == nil {
+		return err
+	}
+
+	if allowed, err := checkChangeRequestAccess(ctx, "review", nil); !allowed {
+		return err
+	}
+
+	request, err = c.Service.StartReview(request, userID)
+	if err != nil {
+		return changeRequestErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Change request is in review",
+		"data":       request,
+	})
+}
+
+// ApproveChangeRequest approves a change request in review, which allows it to be implemented.
+func (c *DocumentChangeRequestController) ApproveChangeRequest(ctx *fiber.Ctx) error {
+	return c.decide(ctx, true)
+}
+
+// RejectChangeRequest rejects a change request in review; a comment is required.
+func (c *DocumentChangeRequestController) RejectChangeRequest(ctx *fiber.Ctx) error {
+	return c.decide(ctx, false)
+}
+
+func (c *DocumentChangeRequestController) decide(ctx *fiber.Ctx, approve bool) error {
+	userID := ctx.Locals("user_id").(int)
+
+	request, err := c.findChangeRequest(ctx)
+	if request == nil {
+		return err
+	}
+
+	if allowed, err := checkChangeRequestAccess(ctx, "review", nil); !allowed {
+		return err
+	}
+
+	var req DocumentChangeRequestDecision
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&req); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"statusCode": fiber.StatusBadRequest,
+				"message":    "Invalid request body",
+				"data":       nil,
+			})
+		}
+	}
+
+	request, err = c.Service.Decide(request, approve, req.Comment, userID)
+	if err != nil {
+		return changeRequestErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Change request " + request.Status,
+		"data":       request,
+	})
+}
+
+// CancelChangeRequest lets the requester withdraw a change request that has not been decided.
+func (c *DocumentChangeRequestController) CancelChangeRequest(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(int)
+
+	request, err := c.findChangeRequest(ctx)
+	if request == nil {
+		return err
+	}
+
+	request, err = c.Service.Cancel(request, userID)
+	if err != nil {
+		return changeRequestErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Change request cancelled",
+		"data":       request,
+	})
+}
+
+// ImplementChangeRequest uploads the new revision of an approved change request. It takes a multipart form with
+// the file, an optional note and an optional status_document_id for the document and the new version.
+func (c *DocumentChangeRequestController) ImplementChangeRequest(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(int)
+
+	request, err := c.findChangeRequest(ctx)
+	if request == nil {
+		return err
+	}
+
+	if allowed, err := checkChangeRequestAccess(ctx, "implement", nil); !allowed {
+		return err
+	}
+
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "File upload failed",
+			"data":       nil,
+		})
+	}
+
+	statusID := 0
+	if value := ctx.FormValue("status_document_id"); value != "" {
+		statusID, err = strconv.Atoi(value)
+		if err != nil || statusID < 1 {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"statusCode": fiber.StatusBadRequest,
+				"message":    "Invalid status_document_id",
+				"data":       nil,
+			})
+		}
+	}
+
+	request, err = c.Service.Implement(request, fileHeader, ctx.FormValue("note"), statusID, userID)
+	if err != nil {
+		return changeRequestErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"statusCode": fiber.StatusCreated,
+		"message":    "Change request implemented as a new revision",
+		"data":       request,
+	})
+}
+
+// DownloadAttachment sends an attachment of a change request.
+func (c *DocumentChangeRequestController) DownloadAttachment(ctx *fiber.Ctx) error {
+	request, err := c.findChangeRequest(ctx)
+	if request == nil {
+		return err
+	}
+
+	// The requester is the owner of a change request
+	if allowed, err := checkChangeRequestAccess(ctx, "read", &request.RequestedBy); !allowed {
+		return err
+	}
+
+	download, err := c.Service.DownloadAttachment(request, ctx.Params("attachmentUuid"))
+	if err != nil {
+		return changeRequestErrorResponse(ctx, err)
+	}
+
+	ctx.Set(fiber.HeaderCacheControl, "private, no-store")
+	ctx.Attachment(download.FileName)
+	ctx.Set(fiber.HeaderContentType, download.ContentType)
+	return ctx.Send(download.Content)
+}
@@ -5,18 +5,21 @@ import (
 	"backend-school/helpers"
 	"backend-school/models"
 	"backend-school/services"
+	"bufio"
 	"errors"
 	"log"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
 )
 
 type DocumentControlController struct {
-	Service *services.DocumentControlService
+	Service       *services.DocumentControlService
+	ReviewService *services.DocumentReviewService
 }
 
 // NewDocumentControlController initializes and returns a new DocumentControlController
@@ -27,13 +30,16 @@ func NewDocumentControlController() *DocumentControlController {
 
 	documentControlService := services.NewDocumentControlService(minioClient, bucketName, minioService)
 
-	return &DocumentControlController{Service: documentControlService}
+	return &DocumentControlController{
+		Service:       documentControlService,
+		ReviewService: services.NewDocumentReviewService(),
+	}
 }
 
 func (c *DocumentControlController) CreateDocumentControl(ctx *fiber.Ctx) error {
 	// Get the username of the requester from the context (set by JWT middleware)
 	requesterUsername := ctx.Locals("username").(string)
-	userID := ctx.Locals("user_id").(string)
+	userID := ctx.Locals("user_id").(int)
 
 	var req services.DocumentControlPayload
 	err := ctx.BodyParser(&req)
@@ -46,18 +52,13 @@ func (c *DocumentControlController) CreateDocumentControl(ctx *fiber.Ctx) error
 	}
 
 	//add user_id
-	id, err := strconv.Atoi(userID)
-	if err != nil {
-		// handle the error, e.g., log it or return an error response
-		log.Println("Error converting userID:", err)
-	}
-	req.CreatedBy = id
+	req.CreatedBy = userID
 
 	// Get Casbin enforcer
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the requester has access to view the specific user's details
-	hasAccess, err := enforcer.Enforce(requesterUsername, "document", "create", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, requesterUsername, "document", "create", "none", "none", "none")
 	if err != nil {
 		log.Printf("Error checking Casbin permissions: %v", err)
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -87,6 +88,14 @@ func (c *DocumentControlController) CreateDocumentControl(ctx *fiber.Ctx) error
 	// Call service to create DocumentControl and save the initial document version
 	documentControl, documentVersion, err := c.Service.AddDocumentControlWithVersion(&req, fileHeader)
 	if err != nil {
+		// A rejected file is the client's fault
+		if errors.Is(err, services.ErrInvalidDocumentFile) {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"statusCode": fiber.StatusBadRequest,
+				"message":    err.Error(),
+				"data":       nil,
+			})
+		}
 		// Capture the detailed error from the service and return it to the client
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"statusCode": fiber.StatusInternalServerError,
@@ -105,14 +114,37 @@ func (c *DocumentControlController) CreateDocumentControl(ctx *fiber.Ctx) error
 	})
 }
 
-// GetDocumentControls retrieves a paginated list of document controls with optional search filter
+// ListDocumentControls lists documents of any category. Filters: category_id, type_id, status_id, created_by,
+// search, and the YYYY-MM-DD ranges publish_from/publish_to, created_from/created_to and updated_from/updated_to.
+// sort takes a comma-separated list of fields, "-" for descending (e.g. sort=-publish_date,document_number).
+// Pages are numbered with currentPage, or followed with cursor: pass an empty cursor for the first page and the
+// returned next_cursor for the next one. Only documents the requester may read are listed.
+func (c *DocumentControlController) ListDocumentControls(ctx *fiber.Ctx) error {
+	return c.listDocumentControls(ctx, 0)
+}
+
+// GetDocumentInternalControls is ListDocumentControls limited to the internal register
 func (c *DocumentControlController) GetDocumentInternalControls(ctx *fiber.Ctx) error {
+	return c.listDocumentControls(ctx, 1)
+}
+
+// GetDocumentExternalControls is ListDocumentControls limited to the external register
+func (c *DocumentControlController) GetDocumentExternalControls(ctx *fiber.Ctx) error {
+	return c.listDocumentControls(ctx, 2)
+}
+
+// listDocumentControls serves the document listings; a categoryID other than 0 replaces the category_id filter
+func (c *DocumentControlController) listDocumentControls(ctx *fiber.Ctx, categoryID int) error {
+	scope, err := documentReadScope(ctx)
+	if scope == nil {
+		return err
+	}
+
 	pageStr := ctx.Query("currentPage", "1")
 	pageSizeStr := ctx.Query("pageSize", "10")
-	search := ctx.Query("search", "")
 
 	currentPage, err := strconv.Atoi(pageStr)
-	if err != nil {
+	if err != nil || currentPage < 1 {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"statusCode": fiber.StatusBadRequest,
 			"message":    "Invalid currentPage",
@@ -120,54 +152,47 @@ func (c *DocumentControlController) GetDocumentInternalControls(ctx *fiber.Ctx)
 	}
 
 	pageSize, err := strconv.Atoi(pageSizeStr)
-	if err != nil {
+	if err != nil || pageSize < 1 {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"statusCode": fiber.StatusBadRequest,
 			"message":    "Invalid pageSize",
 		})
 	}
 
-	// Call service to get paginated document controls
-	result, err := c.Service.GetDocumentControlsInternalPaginated(currentPage, pageSize, search)
-	if err != nil {
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"statusCode": fiber.StatusInternalServerError,
-			"message":    "Failed to fetch document controls",
-		})
-	}
-
-	return ctx.JSON(fiber.Map{
-		"statusCode": fiber.StatusOK,
-		"message":    "Success",
-		"data":       result,
-	})
-}
-
-// GetDocumentControls retrieves a paginated list of document controls with optional search filter
-func (c *DocumentControlController) GetDocumentExternalControls(ctx *fiber.Ctx) error {
-	pageStr := ctx.Query("currentPage", "1")
-	pageSizeStr := ctx.Query("pageSize", "10")
-	search := ctx.Query("search", "")
-
-	currentPage, err := strconv.Atoi(pageStr)
+	filter, err := parseDocumentListFilter(ctx)
 	if err != nil {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"statusCode": fiber.StatusBadRequest,
-			"message":    "Invalid currentPage",
+			"message":    err.Error(),
 		})
 	}
+	if categoryID != 0 {
+		filter.CategoryID = categoryID
+	}
+	filter.Scope = scope
 
-	pageSize, err := strconv.Atoi(pageSizeStr)
-	if err != nil {
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"statusCode": fiber.StatusBadRequest,
-			"message":    "Invalid pageSize",
-		})
+	params := services.DocumentListParams{
+		DocumentListFilter: filter,
+		Sort:               ctx.Query("sort", ""),
+		CurrentPage:        currentPage,
+		PageSize:           pageSize,
+		Cursor:             ctx.Query("cursor", ""),
 	}
 
-	// Call service to get paginated document controls
-	result, err := c.Service.GetDocumentControlsExternalPaginated(currentPage, pageSize, search)
+	// Call service to get the requested page of document controls
+	var result interface{}
+	if ctx.Context().QueryArgs().Has("cursor") {
+		result, err = c.Service.ListDocumentControlsByCursor(params)
+	} else {
+		result, err = c.Service.ListDocumentControls(params)
+	}
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidDocumentSort) || errors.Is(err, services.ErrInvalidDocumentCursor) {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"statusCode": fiber.StatusBadRequest,
+				"message":    err.Error(),
+			})
+		}
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"statusCode": fiber.StatusInternalServerError,
 			"message":    "Failed to fetch document controls",
@@ -181,6 +206,46 @@ func (c *DocumentControlController) GetDocumentExternalControls(ctx *fiber.Ctx)
 	})
 }
 
+// parseDocumentListFilter reads the listing filters from the query string; the error is the message for a 400
+func parseDocumentListFilter(ctx *fiber.Ctx) (services.DocumentListFilter, error) {
+	filter := services.DocumentListFilter{Search: ctx.Query("search", "")}
+
+	// Parse the numeric filters
+	for name, target := range map[string]*int{
+		"category_id": &filter.CategoryID,
+		"type_id":     &filter.TypeID,
+		"status_id":   &filter.StatusID,
+		"created_by":  &filter.CreatedBy,
+	} {
+		if value := ctx.Query(name, ""); value != "" {
+			id, err := strconv.Atoi(value)
+			if err != nil || id < 1 {
+				return filter, errors.New("Invalid " + name)
+			}
+			*target = id
+		}
+	}
+
+	// Parse the date ranges
+	for name, target := range map[string]**time.Time{
+		"publish_from": &filter.PublishFrom,
+		"publish_to":   &filter.PublishTo,
+		"created_from": &filter.CreatedFrom,
+		"created_to":   &filter.CreatedTo,
+		"updated_from": &filter.UpdatedFrom,
+		"updated_to":   &filter.UpdatedTo,
+	} {
+		if value := ctx.Query(name, ""); value != "" {
+			date, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				return filter, errors.New("Invalid " + name + ": expected format is YYYY-MM-DD")
+			}
+			*target = &date
+		}
+	}
+	return filter, nil
+}
+
 // GetDocumentControlByUUID retrieves a single document control by its UUID
 func (c *DocumentControlController) GetDocumentControlByUUID(ctx *fiber.Ctx) error {
 	uuidStr := ctx.Params("uuid")
@@ -261,7 +326,11 @@ func (c *DocumentControlController) GetDocumentControlByUUID(ctx *fiber.Ctx) err
 			enforcer := helpers.GetCasbinEnforcer()
 
 			// Check if the requester has access to view the specific details
-			hasAccess, err := enforcer.Enforce(Username, "document", strings.ToLower(statusDocument.Name), documentCategory.Prefix, documentType.Prefix, "none")
+			attrs := helpers.RequestAttributesFromCtx(ctx)
+			if documentControl.CreatedBy != nil {
+				attrs.OwnerID = *documentControl.CreatedBy
+			}
+			hasAccess, err := helpers.EnforceWithAttributes(enforcer, attrs, Username, "document", strings.ToLower(statusDocument.Name), documentCategory.Prefix, documentType.Prefix, "none")
 			if err != nil {
 				log.Printf("Error checking Casbin permissions: %v", err)
 				return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -280,6 +349,8 @@ func (c *DocumentControlController) GetDocumentControlByUUID(ctx *fiber.Ctx) err
 		}
 	}
 
+	// Sent back in If-Match, the ETag rejects an update when the document changed in between
+	ctx.Set(fiber.HeaderETag, documentETag(documentControl.LockVersion))
 	return ctx.JSON(fiber.Map{
 		"statusCode": fiber.StatusOK,
 		"message":    "Success",
@@ -287,12 +358,48 @@ func (c *DocumentControlController) GetDocumentControlByUUID(ctx *fiber.Ctx) err
 	})
 }
 
-// DeleteDocumentControl deletes a document control by its UUID
+// DeleteDocumentControl moves a document to the trash, from where it can be restored until it is purged
 func (c *DocumentControlController) DeleteDocumentControl(ctx *fiber.Ctx) error {
 	uuidStr := ctx.Params("uuid")
+	requesterUsername := ctx.Locals("username").(string)
+
+	// Load the document to know who owns it
+	existing, err := c.Service.GetDocumentControlByUUID(uuidStr)
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"statusCode": fiber.StatusNotFound,
+			"message":    "Document control not found",
+		})
+	}
+
+	// Get Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	// Check access with the document owner so "owner" conditions can be evaluated
+	attrs := helpers.RequestAttributesFromCtx(ctx)
+	if existing.CreatedBy != nil {
+		attrs.OwnerID = *existing.CreatedBy
+	}
+	hasAccess, err := helpers.EnforceWithAttributes(enforcer, attrs, requesterUsername, "document", "delete", "none", "none", "none")
+	if err != nil {
+		log.Printf("Error checking Casbin permissions: %v", err)
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access permissions.",
+		})
+	}
+
+	// If the requester doesn't have access, return a forbidden status
+	if !hasAccess {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have permission to access this resource.",
+		})
+	}
 
-	// Call service to delete the document control and its related version file
-	if err := c.Service.DeleteDocumentControl(uuidStr); err != nil {
+	// Move the document to the trash; its files are removed by the purge once its retention has passed
+	if err := c.Service.DeleteDocumentControl(uuidStr, ctx.Locals("user_id").(int)); err != nil {
+		log.Printf("Error deleting document %s: %v", uuidStr, err)
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"statusCode": fiber.StatusInternalServerError,
 			"message":    "Could not delete document control",
@@ -305,9 +412,244 @@ func (c *DocumentControlController) DeleteDocumentControl(ctx *fiber.Ctx) error
 	})
 }
 
-// UpdateDocumentControl updates a document control by UUID
+// GetTrashedDocumentControls lists the documents in the trash with the listing filters; sort defaults to -deleted_at
+func (c *DocumentControlController) GetTrashedDocumentControls(ctx *fiber.Ctx) error {
+	requesterUsername := ctx.Locals("username").(string)
+
+	// Get Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	// Check if the requester may see the trash
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, requesterUsername, "document-trash", "read", "none", "none", "none")
+	if err != nil {
+		log.Printf("Error checking Casbin permissions: %v", err)
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access permissions.",
+		})
+	}
+
+	// If the requester doesn't have access, return a forbidden status
+	if !hasAccess {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have permission to access this resource.",
+		})
+	}
+
+	currentPage, err := strconv.Atoi(ctx.Query("currentPage", "1"))
+	if err != nil || currentPage < 1 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid currentPage",
+		})
+	}
+
+	pageSize, err := strconv.Atoi(ctx.Query("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid pageSize",
+		})
+	}
+
+	filter, err := parseDocumentListFilter(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    err.Error(),
+		})
+	}
+
+	result, err := c.Service.ListTrashedDocumentControls(services.DocumentListParams{
+		DocumentListFilter: filter,
+		Sort:               ctx.Query("sort", ""),
+		CurrentPage:        currentPage,
+		PageSize:           pageSize,
+	})
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidDocumentSort) {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"statusCode": fiber.StatusBadRequest,
+				"message":    err.Error(),
+			})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to fetch the trash",
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Success",
+		"data":       result,
+	})
+}
+
+// RestoreDocumentControl takes a document out of the trash
+func (c *DocumentControlController) RestoreDocumentControl(ctx *fiber.Ctx) error {
+	uuidStr := ctx.Params("uuid")
+	requesterUsername := ctx.Locals("username").(string)
+
+	documentControl, err := c.Service.GetTrashedDocumentControlByUUID(uuidStr)
+	if err != nil {
+		if errors.Is(err, services.ErrDocumentNotInTrash) {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"statusCode": fiber.StatusNotFound,
+				"message":    err.Error(),
+			})
+		}
+		log.Printf("Error fetching trashed document %s: %v", uuidStr, err)
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to fetch document control",
+		})
+	}
+
+	// Get Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	// Check access with the document owner so "owner" conditions can be evaluated
+	attrs := helpers.RequestAttributesFromCtx(ctx)
+	if documentControl.CreatedBy != nil {
+		attrs.OwnerID = *documentControl.CreatedBy
+	}
+	hasAccess, err := helpers.EnforceWithAttributes(enforcer, attrs, requesterUsername, "document-trash", "restore", "none", "none", "none")
+	if err != nil {
+		log.Printf("Error checking Casbin permissions: %v", err)
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access permissions.",
+		})
+	}
+
+	// If the requester doesn't have access, return a forbidden status
+	if !hasAccess {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have permission to access this resource.",
+		})
+	}
+
+	documentControl, err = c.Service.RestoreDocumentControl(documentControl)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		message := "Failed to restore document control"
+		switch {
+		case errors.Is(err, services.ErrDocumentNotInTrash):
+			status, message = fiber.StatusNotFound, err.Error()
+		case errors.Is(err, services.ErrDocumentNumberInUse):
+			status, message = fiber.StatusConflict, err.Error()
+		default:
+			log.Printf("Error restoring document %s: %v", uuidStr, err)
+		}
+		return ctx.Status(status).JSON(fiber.Map{
+			"statusCode": status,
+			"message":    message,
+		})
+	}
+
+	ctx.Set(fiber.HeaderETag, documentETag(documentControl.LockVersion))
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Document control restored successfully",
+		"data":       documentControl,
+	})
+}
+
+// RunDocumentRetention archives superseded versions and purges expired files and documents now instead of
+// waiting for the scheduler
+func (c *DocumentControlController) RunDocumentRetention(ctx *fiber.Ctx) error {
+	username := ctx.Locals("username").(string)
+
+	// Get Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	// Check if the requester may run the retention
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "document-retention", "run", "none", "none", "none")
+	if err != nil {
+		log.Printf("Error checking Casbin permissions: %v", err)
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access permissions.",
+		})
+	}
+
+	// If the requester doesn't have access, return a forbidden status
+	if !hasAccess {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have permission to access this resource.",
+		})
+	}
+
+	result, err := c.Service.ProcessDocumentRetention(time.Now())
+	if err != nil {
+		log.Printf("Error running document retention: %v", err)
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Document retention failed",
+			"data":       result,
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Document retention completed",
+		"data":       result,
+	})
+}
+
+// UpdateDocumentControl updates a document and, with a file, adds a new version. The If-Match header must carry the
+// document's ETag, which rejects the update when the document changed since it was read.
 func (c *DocumentControlController) UpdateDocumentControl(ctx *fiber.Ctx) error {
+	return c.updateDocumentControl(ctx, false)
+}
+
+// CheckInDocumentControl is an update with a required file that releases the requester's check-out.
+func (c *DocumentControlController) CheckInDocumentControl(ctx *fiber.Ctx) error {
+	return c.updateDocumentControl(ctx, true)
+}
+
+func (c *DocumentControlController) updateDocumentControl(ctx *fiber.Ctx, checkIn bool) error {
 	uuidStr := ctx.Params("uuid")
+	requesterUsername := ctx.Locals("username").(string)
+	userID := ctx.Locals("user_id").(int)
+
+	// Load the document to know who owns it
+	existing, err := c.Service.GetDocumentControlByUUID(uuidStr)
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"statusCode": fiber.StatusNotFound,
+			"message":    "Document control not found",
+		})
+	}
+
+	// Get Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	// Check access with the document owner so "owner" conditions can be evaluated
+	attrs := helpers.RequestAttributesFromCtx(ctx)
+	if existing.CreatedBy != nil {
+		attrs.OwnerID = *existing.CreatedBy
+	}
+	hasAccess, err := helpers.EnforceWithAttributes(enforcer, attrs, requesterUsername, "document", "update", "none", "none", "none")
+	if err != nil {
+		log.Printf("Error checking Casbin permissions: %v", err)
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access permissions.",
+		})
+	}
+
+	// If the requester doesn't have access, return a forbidden status
+	if !hasAccess {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have permission to access this resource.",
+		})
+	}
 
 	// Parse the request body
 	var req services.DocumentControlPayload
@@ -327,18 +669,712 @@ func (c *DocumentControlController) UpdateDocumentControl(ctx *fiber.Ctx) error
 		})
 	}
 
-	// Call the service to update the document control
-	documentControl, err := c.Service.UpdateDocumentControl(uuidStr, &req, fileHeader)
-	if err != nil {
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"statusCode": fiber.StatusInternalServerError,
-			"message":    "Could not update document control",
+	lockVersion, err := ifMatchLockVersion(ctx)
+	if errors.Is(err, errIfMatchRequired) {
+		return ctx.Status(fiber.StatusPreconditionRequired).JSON(fiber.Map{
+			"statusCode": fiber.StatusPreconditionRequired,
+			"message":    err.Error(),
+		})
+	}
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid If-Match header",
+		})
+	}
+
+	// Call the service to update the document control
+	documentControl, err := c.Service.UpdateDocumentControl(uuidStr, &req, fileHeader, services.DocumentUpdateOptions{
+		UserID:      userID,
+		LockVersion: lockVersion,
+		CheckIn:     checkIn,
+	})
+	if err != nil {
+		status := 0
+		switch {
+		case errors.Is(err, services.ErrInvalidDocumentFile):
+			status = fiber.StatusBadRequest
+		case errors.Is(err, services.ErrDuplicateDocumentVersion), errors.Is(err, services.ErrChangeRequestRequired),
+			errors.Is(err, services.ErrDocumentNotCheckedOut):
+			status = fiber.StatusConflict
+		case errors.Is(err, services.ErrDocumentCheckedOut):
+			status = fiber.StatusLocked
+		case errors.Is(err, services.ErrStaleDocument):
+			status = fiber.StatusPreconditionFailed
+		}
+		if status != 0 {
+			return ctx.Status(status).JSON(fiber.Map{
+				"statusCode": status,
+				"message":    err.Error(),
+				"data":       nil,
+			})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Could not update document control",
 		})
 	}
 
+	ctx.Set(fiber.HeaderETag, documentETag(documentControl.LockVersion))
 	return ctx.JSON(fiber.Map{
 		"statusCode": fiber.StatusOK,
 		"message":    "Document control updated successfully",
 		"data":       documentControl,
 	})
 }
+
+// documentETag is the ETag of a document's lock_version
+func documentETag(lockVersion int) string {
+	return `"` + strconv.Itoa(lockVersion) + `"`
+}
+
+// errIfMatchRequired is returned for an update without the ETag of the document it was made on
+var errIfMatchRequired = errors.New("If-Match header with the document's ETag is required")
+
+// ifMatchLockVersion reads the lock_version from an If-Match header. "*" would match any version, so it is refused
+// like a missing header.
+func ifMatchLockVersion(ctx *fiber.Ctx) (int, error) {
+	value := strings.TrimSpace(ctx.Get(fiber.HeaderIfMatch))
+	if value == "" || value == "*" {
+		return 0, errIfMatchRequired
+	}
+	value = strings.Trim(strings.TrimPrefix(value, "W/"), `"`)
+	lockVersion, err := strconv.Atoi(value)
+	if err != nil || lockVersion < 1 {
+		return 0, errors.New("invalid If-Match header")
+	}
+	return lockVersion, nil
+}
+
+// maxDueForReviewPageSize caps the pageSize of the documents due for review
+const maxDueForReviewPageSize = 100
+
+// GetDocumentsDueForReview lists documents whose review or expiry is due within `days` (default: the reminder period).
+// Only documents the requester may read are listed.
+func (c *DocumentControlController) GetDocumentsDueForReview(ctx *fiber.Ctx) error {
+	scope, err := documentReadScope(ctx)
+	if scope == nil {
+		return err
+	}
+
+	userID := ctx.Locals("user_id").(int)
+	pageStr := ctx.Query("currentPage", "1")
+	pageSizeStr := ctx.Query("pageSize", "10")
+	daysStr := ctx.Query("days", "")
+	overdueOnly := ctx.QueryBool("overdue", false)
+	mine := ctx.QueryBool("mine", false)
+
+	currentPage, err := strconv.Atoi(pageStr)
+	if err != nil || currentPage < 1 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid currentPage",
+		})
+	}
+
+	pageSize, err := strconv.Atoi(pageSizeStr)
+	if err != nil || pageSize < 1 || pageSize > maxDueForReviewPageSize {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid pageSize",
+		})
+	}
+
+	days := -1
+	if daysStr != "" {
+		days, err = strconv.Atoi(daysStr)
+		if err != nil || days < 0 {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"statusCode": fiber.StatusBadRequest,
+				"message":    "Invalid days",
+			})
+		}
+	}
+
+	createdBy := 0
+	if mine {
+		createdBy = userID
+	}
+
+	result, err := c.ReviewService.GetDueForReviewPaginated(currentPage, pageSize, days, overdueOnly, createdBy, scope)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to fetch documents due for review",
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Success",
+		"data":       result,
+	})
+}
+
+// MarkDocumentReviewed records a completed review and schedules the next one
+func (c *DocumentControlController) MarkDocumentReviewed(ctx *fiber.Ctx) error {
+	uuidStr := ctx.Params("uuid")
+	requesterUsername := ctx.Locals("username").(string)
+
+	// Load the document to know who owns it
+	existing, err := c.Service.GetDocumentControlByUUID(uuidStr)
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"statusCode": fiber.StatusNotFound,
+			"message":    "Document control not found",
+		})
+	}
+
+	// Get Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	// Reviewing a document is an update; check it with the owner so "owner" conditions can be evaluated
+	attrs := helpers.RequestAttributesFromCtx(ctx)
+	if existing.CreatedBy != nil {
+		attrs.OwnerID = *existing.CreatedBy
+	}
+	hasAccess, err := helpers.EnforceWithAttributes(enforcer, attrs, requesterUsername, "document", "update", "none", "none", "none")
+	if err != nil {
+		log.Printf("Error checking Casbin permissions: %v", err)
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access permissions.",
+		})
+	}
+
+	// If the requester doesn't have access, return a forbidden status
+	if !hasAccess {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have permission to access this resource.",
+		})
+	}
+
+	// The next review date is optional; without it the review interval is used
+	var req struct {
+		NextReviewDate string `json:"next_review_date"`
+	}
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&req); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"statusCode": fiber.StatusBadRequest,
+				"message":    "Invalid request payload",
+			})
+		}
+	}
+
+	documentControl, err := c.ReviewService.MarkReviewed(uuidStr, req.NextReviewDate, ctx.Locals("user_id").(int))
+	if errors.Is(err, services.ErrDocumentCheckedOut) {
+		return checkoutErrorResponse(ctx, err)
+	}
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Document review recorded successfully",
+		"data":       documentControl,
+	})
+}
+
+// SearchDocumentControls runs a ranked full-text search with optional category, type, status and publish date filters.
+// Only documents the requester may read are searched.
+func (c *DocumentControlController) SearchDocumentControls(ctx *fiber.Ctx) error {
+	scope, err := documentReadScope(ctx)
+	if scope == nil {
+		return err
+	}
+
+	pageStr := ctx.Query("currentPage", "1")
+	pageSizeStr := ctx.Query("pageSize", "10")
+
+	currentPage, err := strconv.Atoi(pageStr)
+	if err != nil || currentPage < 1 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid currentPage",
+		})
+	}
+
+	pageSize, err := strconv.Atoi(pageSizeStr)
+	if err != nil || pageSize < 1 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid pageSize",
+		})
+	}
+
+	params := services.DocumentSearchParams{
+		Query:       ctx.Query("q", ""),
+		Scope:       scope,
+		CurrentPage: currentPage,
+		PageSize:    pageSize,
+	}
+
+	// Parse the numeric filters
+	for name, target := range map[string]*int{
+		"category_id": &params.CategoryID,
+		"type_id":     &params.TypeID,
+		"status_id":   &params.StatusID,
+	} {
+		if value := ctx.Query(name, ""); value != "" {
+			id, err := strconv.Atoi(value)
+			if err != nil || id < 1 {
+				return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"statusCode": fiber.StatusBadRequest,
+					"message":    "Invalid " + name,
+				})
+			}
+			*target = id
+		}
+	}
+
+	// Parse the publish date range
+	for name, target := range map[string]**time.Time{
+		"publish_from": &params.PublishFrom,
+		"publish_to":   &params.PublishTo,
+	} {
+		if value := ctx.Query(name, ""); value != "" {
+			date, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"statusCode": fiber.StatusBadRequest,
+					"message":    "Invalid " + name + ": expected format is YYYY-MM-DD",
+				})
+			}
+			*target = &date
+		}
+	}
+
+	result, err := c.Service.SearchDocumentControls(params)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to search document controls",
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Success",
+		"data":       result,
+	})
+}
+
+// DownloadDocumentVersion sends a version of a document (?version_uuid=, default latest).
+// PDFs are stamped with the document number, revision, downloader and the copy status; the stored file is not changed.
+func (c *DocumentControlController) DownloadDocumentVersion(ctx *fiber.Ctx) error {
+	uuidStr := ctx.Params("uuid")
+	Username := ctx.Locals("username").(string)
+
+	documentControl, err := c.Service.GetDocumentControlByUUID(uuidStr)
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"statusCode": fiber.StatusNotFound,
+			"message":    "Document control not found",
+		})
+	}
+
+	// Owners may always download; anyone else needs the same access as for viewing the document
+	if ok, err := checkDocumentReadAccess(ctx, documentControl); !ok {
+		return err
+	}
+
+	download, err := c.Service.DownloadDocumentVersion(documentControl, ctx.Query("version_uuid", ""), Username)
+	if err != nil {
+		if errors.Is(err, services.ErrDocumentVersionNotFound) {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"statusCode": fiber.StatusNotFound,
+				"message":    err.Error(),
+			})
+		}
+		log.Printf("Error downloading document %s: %v", uuidStr, err)
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to download document",
+		})
+	}
+
+	// Stamped copies carry the downloader's name and must not be shared through caches
+	ctx.Set(fiber.HeaderCacheControl, "private, no-store")
+	ctx.Attachment(download.FileName)
+	ctx.Set(fiber.HeaderContentType, download.ContentType)
+	return ctx.Send(download.Content)
+}
+
+// ExportDocumentRegister streams the master list of documents as XLSX or PDF (?format=xlsx|pdf).
+// It takes the filters of ListDocumentControls; ?register=internal|external is a shorthand for category_id.
+// Like the listing, it only holds documents the requester may read.
+func (c *DocumentControlController) ExportDocumentRegister(ctx *fiber.Ctx) error {
+	requesterUsername := ctx.Locals("username").(string)
+	format := strings.ToLower(ctx.Query("format", services.DocumentRegisterFormatXLSX))
+
+	// Get Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	// Check if the requester may export the document register
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, requesterUsername, "document-register", "export", "none", "none", "none")
+	if err != nil {
+		log.Printf("Error checking Casbin permissions: %v", err)
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access permissions.",
+		})
+	}
+
+	// If the requester doesn't have access, return a forbidden status
+	if !hasAccess {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have permission to access this resource.",
+		})
+	}
+
+	scope, err := documentReadScope(ctx)
+	if scope == nil {
+		return err
+	}
+
+	var contentType string
+	switch format {
+	case services.DocumentRegisterFormatXLSX:
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case services.DocumentRegisterFormatPDF:
+		contentType = "application/pdf"
+	default:
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid format: expected xlsx or pdf",
+		})
+	}
+
+	filter, err := parseDocumentListFilter(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    err.Error(),
+		})
+	}
+	if register := strings.ToLower(ctx.Query("register", "")); register != "" {
+		id, ok := services.DocumentRegisterCategories[register]
+		if !ok {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"statusCode": fiber.StatusBadRequest,
+				"message":    "Invalid register: expected internal or external",
+			})
+		}
+		filter.CategoryID = id
+	}
+	filter.Scope = scope
+
+	fileName := "document-register-" + time.Now().Format("2006-01-02") + "." + format
+	ctx.Attachment(fileName)
+	ctx.Set(fiber.HeaderContentType, contentType)
+	ctx.Set(fiber.HeaderCacheControl, "private, no-store")
+
+	// The body is produced while it is sent, so a failure part-way can only be logged
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := c.Service.ExportDocumentRegister(format, filter, w); err != nil {
+			log.Printf("Error exporting document register: %v", err)
+		}
+		w.Flush()
+	})
+	return nil
+}
+
+// checkDocumentAccess checks a "document" action with the document owner, so "owner" conditions can be evaluated.
+// When access is not granted the error response has been written.
+func checkDocumentAccess(ctx *fiber.Ctx, documentControl *models.DocumentControl, action string) (bool, error) {
+	requesterUsername := ctx.Locals("username").(string)
+
+	// Get Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	attrs := helpers.RequestAttributesFromCtx(ctx)
+	if documentControl.CreatedBy != nil {
+		attrs.OwnerID = *documentControl.CreatedBy
+	}
+	hasAccess, err := helpers.EnforceWithAttributes(enforcer, attrs, requesterUsername, "document", action, "none", "none", "none")
+	if err != nil {
+		log.Printf("Error checking Casbin permissions: %v", err)
+		return false, ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access permissions.",
+		})
+	}
+
+	// If the requester doesn't have access, return a forbidden status
+	if !hasAccess {
+		return false, ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have permission to access this resource.",
+		})
+	}
+	return true, nil
+}
+
+// checkDocumentReadAccess lets the owner through and checks anyone else against the document's status, category
+// and type, as for viewing it. On false the response has been written.
+func checkDocumentReadAccess(ctx *fiber.Ctx, documentControl *models.DocumentControl) (bool, error) {
+	requesterUsername := ctx.Locals("username").(string)
+	userID := ctx.Locals("user_id").(int)
+	if documentControl.CreatedBy != nil && *documentControl.CreatedBy == userID {
+		return true, nil
+	}
+
+	var documentCategory models.CategoryDocument
+	var documentType models.DocumentType
+	var statusDocument models.StatusDocument
+	config.DB.Where("id = ?", documentControl.DocumentCategoryID).First(&documentCategory)
+	config.DB.Where("id = ?", documentControl.DocumentTypeID).First(&documentType)
+	config.DB.Where("id = ?", documentControl.StatusDocumentID).First(&statusDocument)
+
+	// Get Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	attrs := helpers.RequestAttributesFromCtx(ctx)
+	if documentControl.CreatedBy != nil {
+		attrs.OwnerID = *documentControl.CreatedBy
+	}
+	hasAccess, err := helpers.EnforceWithAttributes(enforcer, attrs, requesterUsername, "document", strings.ToLower(statusDocument.Name), documentCategory.Prefix, documentType.Prefix, "none")
+	if err != nil {
+		log.Printf("Error checking Casbin permissions: %v", err)
+		return false, ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access permissions.",
+		})
+	}
+
+	// If the requester doesn't have access, return a forbidden status
+	if !hasAccess {
+		return false, ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have permission to access this resource.",
+		})
+	}
+	return true, nil
+}
+
+// documentReadScope requires the "document" "read" permission and collects the statuses, categories and types the
+// requester may read documents of, each checked as checkDocumentReadAccess checks a document they did not create.
+// On nil the response has been written.
+func documentReadScope(ctx *fiber.Ctx) (*services.DocumentReadScope, error) {
+	requesterUsername := ctx.Locals("username").(string)
+
+	// Get Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	attrs := helpers.RequestAttributesFromCtx(ctx)
+	hasAccess, err := helpers.EnforceWithAttributes(enforcer, attrs, requesterUsername, "document", "read", "none", "none", "none")
+	if err != nil {
+		log.Printf("Error checking Casbin permissions: %v", err)
+		return nil, ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access permissions.",
+		})
+	}
+
+	// If the requester doesn't have access, return a forbidden status
+	if !hasAccess {
+		return nil, ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have permission to access this resource.",
+		})
+	}
+
+	permissions, err := enforcer.GetImplicitPermissionsForUser(requesterUsername)
+	if err != nil {
+		log.Printf("Error listing Casbin permissions: %v", err)
+		return nil, ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access permissions.",
+		})
+	}
+
+	scope := &services.DocumentReadScope{UserID: attrs.UserID}
+	seen := map[services.DocumentReadGrant]bool{}
+	for _, permission := range permissions {
+		// A permission is sub, obj, act, cat, type, docid; document policies name a category and a type
+		if len(permission) < 5 || permission[1] != "document" || permission[3] == "none" || permission[4] == "none" {
+			continue
+		}
+		grant := services.DocumentReadGrant{Status: permission[2], CategoryPrefix: permission[3], TypePrefix: permission[4]}
+		if seen[grant] {
+			continue
+		}
+		seen[grant] = true
+
+		// The policy may carry conditions, which are evaluated for this request
+		allowed, err := helpers.EnforceWithAttributes(enforcer, attrs, requesterUsername, "document", grant.Status, grant.CategoryPrefix, grant.TypePrefix, "none")
+		if err != nil {
+			log.Printf("Error checking Casbin permissions: %v", err)
+			return nil, ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"statusCode": fiber.StatusInternalServerError,
+				"message":    "Failed to check access permissions.",
+			})
+		}
+		if allowed {
+			scope.Grants = append(scope.Grants, grant)
+		}
+	}
+	return scope, nil
+}
+
+// CompareDocumentVersions compares two versions of a document (?from=&to= version UUIDs). Without to the latest
+// version is compared, without from the version before it. PDF text is diffed line by line next to the metadata.
+func (c *DocumentControlController) CompareDocumentVersions(ctx *fiber.Ctx) error {
+	uuidStr := ctx.Params("uuid")
+
+	documentControl, err := c.Service.GetDocumentControlByUUID(uuidStr)
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"statusCode": fiber.StatusNotFound,
+			"message":    "Document control not found",
+		})
+	}
+
+	if ok, err := checkDocumentReadAccess(ctx, documentControl); !ok {
+		return err
+	}
+
+	comparison, err := c.Service.CompareDocumentVersions(documentControl, ctx.Query("from", ""), ctx.Query("to", ""))
+	if err != nil {
+		if errors.Is(err, services.ErrDocumentVersionNotFound) {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"statusCode": fiber.StatusNotFound,
+				"message":    err.Error(),
+			})
+		}
+		log.Printf("Error comparing versions of document %s: %v", uuidStr, err)
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to compare document versions",
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Document versions compared successfully",
+		"data":       comparison,
+	})
+}
+
+// checkoutErrorResponse maps check-out service errors to HTTP responses
+func checkoutErrorResponse(ctx *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrDocumentCheckedOut):
+		status = fiber.StatusLocked
+	case errors.Is(err, services.ErrDocumentNotCheckedOut):
+		status = fiber.StatusConflict
+	}
+	return ctx.Status(status).JSON(fiber.Map{
+		"statusCode": status,
+		"message":    err.Error(),
+		"data":       nil,
+	})
+}
+
+// CheckOutDocument locks a document to the requester so nobody else can upload a version until it is checked in,
+// released or the check-out expires. Checking out again extends the lock.
+func (c *DocumentControlController) CheckOutDocument(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(int)
+
+	documentControl, err := c.Service.GetDocumentControlByUUID(ctx.Params("uuid"))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"statusCode": fiber.StatusNotFound,
+			"message":    "Document control not found",
+		})
+	}
+
+	if allowed, err := checkDocumentAccess(ctx, documentControl, "update"); !allowed {
+		return err
+	}
+
+	documentControl, err = c.Service.CheckOutDocument(documentControl, userID)
+	if err != nil {
+		return checkoutErrorResponse(ctx, err)
+	}
+
+	ctx.Set(fiber.HeaderETag, documentETag(documentControl.LockVersion))
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Document checked out successfully",
+		"data":       documentControl,
+	})
+}
+
+// CancelCheckOut releases the requester's check-out without uploading a new version.
+func (c *DocumentControlController) CancelCheckOut(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(int)
+
+	documentControl, err := c.Service.GetDocumentControlByUUID(ctx.Params("uuid"))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"statusCode": fiber.StatusNotFound,
+			"message":    "Document control not found",
+		})
+	}
+
+	documentControl, err = c.Service.CancelCheckOut(documentControl, userID)
+	if err != nil {
+		return checkoutErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Check-out released successfully",
+		"data":       documentControl,
+	})
+}
+
+// ForceUnlockDocument releases the check-out of a document whoever holds it.
+func (c *DocumentControlController) ForceUnlockDocument(ctx *fiber.Ctx) error {
+	username := ctx.Locals("username").(string)
+	userID := ctx.Locals("user_id").(int)
+
+	// Get Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	// Check if the requester may release other users' check-outs
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "document-checkout", "unlock", "none", "none", "none")
+	if err != nil {
+		log.Printf("Error checking Casbin permissions: %v", err)
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access permissions.",
+		})
+	}
+
+	// If the requester doesn't have access, return a forbidden status
+	if !hasAccess {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have permission to access this resource.",
+		})
+	}
+
+	documentControl, err := c.Service.GetDocumentControlByUUID(ctx.Params("uuid"))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"statusCode": fiber.StatusNotFound,
+			"message":    "Document control not found",
+		})
+	}
+
+	documentControl, err = c.Service.ForceUnlockDocument(documentControl, userID)
+	if err != nil {
+		return checkoutErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Document unlocked successfully",
+		"data":       documentControl,
+	})
+}
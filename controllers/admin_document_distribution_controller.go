@@ -0,0 +1,295 @@
+package controllers
+
+import (
+	"backend-school/helpers"
+	"backend-school/services"
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// DocumentDistributionRequest adds either a role or a user to a distribution list
+type DocumentDistributionRequest struct {
+	RoleGuardName string `json:"role_guard_name"`
+	UserUUID      string `json:"user_uuid"`
+}
+
+type DocumentDistributionController struct {
+	Service         *services.DocumentDistributionService
+	DocumentService *services.DocumentControlService
+}
+
+func NewDocumentDistributionController() *DocumentDistributionController {
+	return &DocumentDistributionController{
+		Service:         services.NewDocumentDistributionService(),
+		DocumentService: NewDocumentControlController().Service,
+	}
+}
+
+// distributionErrorResponse maps distribution service errors to HTTP responses
+func distributionErrorResponse(ctx *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrDistributionEntryExists):
+		status = fiber.StatusConflict
+	case errors.Is(err, services.ErrDistributionNotFound), errors.Is(err, services.ErrDocumentVersionNotFound),
+		errors.Is(err, services.ErrAcknowledgementNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, services.ErrInvalidDistributionTarget), err.Error() == "role not found", err.Error() == "user not found":
+		status = fiber.StatusBadRequest
+	}
+	return ctx.Status(status).JSON(fiber.Map{
+		"statusCode": status,
+		"message":    err.Error(),
+		"data":       nil,
+	})
+}
+
+// GetDistribution lists the distribution entries of a document.
+func (c *DocumentDistributionController) GetDistribution(ctx *fiber.Ctx) error {
+	uuidStr := ctx.Params("uuid")
+	requesterUsername := ctx.Locals("username").(string)
+
+	documentControl, err := c.DocumentService.GetDocumentControlByUUID(uuidStr)
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"statusCode": fiber.StatusNotFound,
+			"message":    "Document control not found",
+		})
+	}
+
+	// Get Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	// Check access with the document owner so "owner" conditions can be evaluated
+	attrs := helpers.RequestAttributesFromCtx(ctx)
+	if documentControl.CreatedBy != nil {
+		attrs.OwnerID = *documentControl.CreatedBy
+	}
+	hasAccess, err := helpers.EnforceWithAttributes(enforcer, attrs, requesterUsername, "document-distribution", "read", "none", "none", "none")
+	if err != nil {
+		log.Printf("Error checking Casbin permissions: %v", err)
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access permissions.",
+		})
+	}
+
+	// If the requester doesn't have access, return a forbidden status
+	if !hasAccess {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have permission to access this resource.",
+		})
+	}
+
+	entries, err := c.Service.GetDistribution(documentControl.ID)
+	if err != nil {
+		return distributionErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Distribution list fetched successfully",
+		"data":       entries,
+	})
+}
+
+// AddDistribution adds a role or a user to the distribution list of a document.
+func (c *DocumentDistributionController) AddDistribution(ctx *fiber.Ctx) error {
+	uuidStr := ctx.Params("uuid")
+	requesterUsername := ctx.Locals("username").(string)
+	userID := ctx.Locals("user_id").(int)
+
+	documentControl, err := c.DocumentService.GetDocumentControlByUUID(uuidStr)
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"statusCode": fiber.StatusNotFound,
+			"message":    "Document control not found",
+		})
+	}
+
+	// Get Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	// Check access with the document owner so "owner" conditions can be evaluated
+	attrs := helpers.RequestAttributesFromCtx(ctx)
+	if documentControl.CreatedBy != nil {
+		attrs.OwnerID = *documentControl.CreatedBy
+	}
+	hasAccess, err := helpers.EnforceWithAttributes(enforcer, attrs, requesterUsername, "document-distribution", "create", "none", "none", "none")
+	if err != nil {
+		log.Printf("Error checking Casbin permissions: %v", err)
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access permissions.",
+		})
+	}
+
+	// If the requester doesn't have access, return a forbidden status
+	if !hasAccess {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have permission to access this resource.",
+		})
+	}
+
+	var req DocumentDistributionRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid request payload",
+			"data":       nil,
+		})
+	}
+
+	entry, assigned, err := c.Service.AddDistribution(documentControl, req.RoleGuardName, req.UserUUID, userID)
+	if err != nil {
+		return distributionErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"statusCode": fiber.StatusCreated,
+		"message":    "Distribution entry added successfully",
+		"data": fiber.Map{
+			"distribution":              entry,
+			"acknowledgements_assigned": assigned,
+		},
+	})
+}
+
+// DeleteDistribution removes an entry from a document's distribution list.
+func (c *DocumentDistributionController) DeleteDistribution(ctx *fiber.Ctx) error {
+	uuidStr := ctx.Params("uuid")
+	entryUUID := ctx.Params("entryUuid")
+	requesterUsername := ctx.Locals("username").(string)
+
+	documentControl, err := c.DocumentService.GetDocumentControlByUUID(uuidStr)
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"statusCode": fiber.StatusNotFound,
+			"message":    "Document control not found",
+		})
+	}
+
+	// Get Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	// Check access with the document owner so "owner" conditions can be evaluated
+	attrs := helpers.RequestAttributesFromCtx(ctx)
+	if documentControl.CreatedBy != nil {
+		attrs.OwnerID = *documentControl.CreatedBy
+	}
+	hasAccess, err := helpers.EnforceWithAttributes(enforcer, attrs, requesterUsername, "document-distribution", "delete", "none", "none", "none")
+	if err != nil {
+		log.Printf("Error checking Casbin permissions: %v", err)
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access permissions.",
+		})
+	}
+
+	// If the requester doesn't have access, return a forbidden status
+	if !hasAccess {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have permission to access this resource.",
+		})
+	}
+
+	if err := c.Service.DeleteDistribution(documentControl.ID, entryUUID); err != nil {
+		return distributionErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Distribution entry deleted successfully",
+		"data":       nil,
+	})
+}
+
+// GetDocumentComplianceReport reports who has acknowledged a document version (?version_uuid=, default latest).
+func (c *DocumentDistributionController) GetDocumentComplianceReport(ctx *fiber.Ctx) error {
+	username := ctx.Locals("username").(string)
+
+	// Get the Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	// Check if the user has access to the "document-acknowledgement" resource using the "read" action
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "document-acknowledgement", "read", "none", "none", "none")
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access.",
+		})
+	}
+
+	if !hasAccess {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have access to this resource",
+		})
+	}
+
+	documentControl, err := c.DocumentService.GetDocumentControlByUUID(ctx.Params("uuid"))
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"statusCode": fiber.StatusNotFound,
+			"message":    "Document control not found",
+		})
+	}
+
+	report, err := c.Service.GetDocumentComplianceReport(documentControl, ctx.Query("version_uuid", ""))
+	if err != nil {
+		return distributionErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Compliance report fetched successfully",
+		"data":       report,
+	})
+}
+
+// GetUserComplianceReport reports which documents a user has and has not acknowledged.
+func (c *DocumentDistributionController) GetUserComplianceReport(ctx *fiber.Ctx) error {
+	username := ctx.Locals("username").(string)
+
+	// Get the Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	// Check if the user has access to the "document-acknowledgement" resource using the "read" action
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "document-acknowledgement", "read", "none", "none", "none")
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access.",
+		})
+	}
+
+	if !hasAccess {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have access to this resource",
+		})
+	}
+
+	report, err := c.Service.GetUserComplianceReport(ctx.Params("uuid"))
+	if err != nil {
+		if err.Error() == "user not found" {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"statusCode": fiber.StatusNotFound,
+				"message":    err.Error(),
+				"data":       nil,
+			})
+		}
+		return distributionErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Compliance report fetched successfully",
+		"data":       report,
+	})
+}
@@ -0,0 +1,277 @@
+package controllers
+
+import (
+	"backend-school/helpers"
+	"backend-school/models"
+	"backend-school/services"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type DocumentImportController struct {
+	Service *services.DocumentImportService
+}
+
+func NewDocumentImportController() *DocumentImportController {
+	return &DocumentImportController{
+		Service: services.NewDocumentImportService(NewDocumentControlController().Service),
+	}
+}
+
+// checkDocumentImportAccess requires the "document" "create" permission, since an import creates documents.
+// When access is not granted the error response has been written.
+func checkDocumentImportAccess(ctx *fiber.Ctx) (bool, error) {
+	requesterUsername := ctx.Locals("username").(string)
+
+	// Get Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, requesterUsername, "document", "create", "none", "none", "none")
+	if err != nil {
+		log.Printf("Error checking Casbin permissions: %v", err)
+		return false, ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access permissions.",
+		})
+	}
+
+	// If the requester doesn't have access, return a forbidden status
+	if !hasAccess {
+		return false, ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have permission to access this resource.",
+		})
+	}
+	return true, nil
+}
+
+// documentImportStatusAccess checks the status of an import row as a "document" "<status>" "<category>" "<type>"
+// permission of the requester. The rows are imported after the request, so its attributes are copied now.
+func documentImportStatusAccess(ctx *fiber.Ctx) services.DocumentStatusAccess {
+	requesterUsername := ctx.Locals("username").(string)
+	attrs := helpers.RequestAttributesFromCtx(ctx)
+	// The IP may point into the request buffer, which is reused once the request is done
+	attrs.ClientIP = strings.Clone(attrs.ClientIP)
+
+	return func(status, categoryPrefix, typePrefix string) (bool, error) {
+		return helpers.EnforceWithAttributes(helpers.GetCasbinEnforcer(), attrs, requesterUsername, "document", status, categoryPrefix, typePrefix, "none")
+	}
+}
+
+// StartImport accepts a ZIP archive with a manifest and imports its documents in the background.
+// With dry_run=true the rows are only validated. A row with a status other than the initial one fails unless the
+// requester may set that status for its category and type.
+func (c *DocumentImportController) StartImport(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(int)
+
+	if allowed, err := checkDocumentImportAccess(ctx); !allowed {
+		return err
+	}
+
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "File upload failed",
+			"data":       nil,
+		})
+	}
+	if !strings.EqualFold(filepath.Ext(fileHeader.Filename), ".zip") || fileHeader.Size > services.MaxDocumentImportSize {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "A ZIP archive of at most 200 MB is required",
+			"data":       nil,
+		})
+	}
+
+	dryRun, _ := strconv.ParseBool(ctx.FormValue("dry_run", "false"))
+
+	// Keep the archive on disk until the background job is done with it
+	archive, err := os.CreateTemp("", "document-import-*.zip")
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to store the archive",
+			"data":       nil,
+		})
+	}
+	archive.Close()
+	if err := ctx.SaveFile(fileHeader, archive.Name()); err != nil {
+		os.Remove(archive.Name())
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to store the archive",
+			"data":       nil,
+		})
+	}
+
+	job, err := c.Service.StartImport(archive.Name(), fileHeader.Filename, dryRun, userID, documentImportStatusAccess(ctx))
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, services.ErrInvalidDocumentImport) {
+			status = fiber.StatusBadRequest
+		}
+		return ctx.Status(status).JSON(fiber.Map{
+			"statusCode": status,
+			"message":    err.Error(),
+			"data":       nil,
+		})
+	}
+
+	return ctx.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"statusCode": fiber.StatusAccepted,
+		"message":    "Import queued",
+		"data":       job,
+	})
+}
+
+// GetImportJobs lists the current user's import jobs.
+func (c *DocumentImportController) GetImportJobs(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(int)
+
+	if allowed, err := checkDocumentImportAccess(ctx); !allowed {
+		return err
+	}
+
+	currentPage, err := strconv.Atoi(ctx.Query("currentPage", "1"))
+	if err != nil || currentPage < 1 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid currentPage",
+			"data":       nil,
+		})
+	}
+
+	pageSize, err := strconv.Atoi(ctx.Query("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid pageSize",
+			"data":       nil,
+		})
+	}
+
+	result, err := c.Service.GetImportJobsPaginated(userID, pageSize, currentPage)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    err.Error(),
+			"data":       nil,
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Import jobs fetched successfully",
+		"data":       result,
+	})
+}
+
+// GetImportJob returns the status and progress of an import job, for polling.
+func (c *DocumentImportController) GetImportJob(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(int)
+
+	if allowed, err := checkDocumentImportAccess(ctx); !allowed {
+		return err
+	}
+
+	job, err := c.findJob(ctx, userID)
+	if job == nil {
+		return err
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Import job fetched successfully",
+		"data":       job,
+	})
+}
+
+// GetImportRows returns the per-row report of an import job (?status=valid|created|failed).
+func (c *DocumentImportController) GetImportRows(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(int)
+
+	if allowed, err := checkDocumentImportAccess(ctx); !allowed {
+		return err
+	}
+
+	job, err := c.findJob(ctx, userID)
+	if job == nil {
+		return err
+	}
+
+	currentPage, err := strconv.Atoi(ctx.Query("currentPage", "1"))
+	if err != nil || currentPage < 1 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid currentPage",
+			"data":       nil,
+		})
+	}
+
+	pageSize, err := strconv.Atoi(ctx.Query("pageSize", "50"))
+	if err != nil || pageSize < 1 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid pageSize",
+			"data":       nil,
+		})
+	}
+
+	status := ctx.Query("status", "")
+	if status != "" && status != models.DocumentImportRowValid && status != models.DocumentImportRowCreated && status != models.DocumentImportRowFailed {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid status",
+			"data":       nil,
+		})
+	}
+
+	result, err := c.Service.GetImportRowsPaginated(job, pageSize, currentPage, status)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    err.Error(),
+			"data":       nil,
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Import report fetched successfully",
+		"data":       result,
+	})
+}
+
+// findJob loads the job named in the route; when it returns no job the error response has been written
+func (c *DocumentImportController) findJob(ctx *fiber.Ctx, userID int) (*models.DocumentImportJob, error) {
+	uuidParam := ctx.Params("uuid")
+	if _, err := uuid.Parse(uuidParam); err != nil {
+		return nil, ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid UUID format",
+			"data":       nil,
+		})
+	}
+
+	job, err := c.Service.GetImportJob(uuidParam, userID)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, services.ErrDocumentImportNotFound) {
+			status = fiber.StatusNotFound
+		}
+		return nil, ctx.Status(status).JSON(fiber.Map{
+			"statusCode": status,
+			"message":    err.Error(),
+			"data":       nil,
+		})
+	}
+	return job, nil
+}
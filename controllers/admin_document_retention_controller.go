@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"backend-school/helpers"
+	"backend-school/services"
+	"errors"
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type DocumentRetentionController struct {
+	Service *services.DocumentRetentionService
+}
+
+func NewDocumentRetentionController() *DocumentRetentionController {
+	return &DocumentRetentionController{
+		Service: services.NewDocumentRetentionService(),
+	}
+}
+
+// retentionPolicyErrorResponse maps retention policy service errors to HTTP responses
+func retentionPolicyErrorResponse(ctx *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrRetentionPolicyNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, services.ErrInvalidRetentionPolicy):
+		status = fiber.StatusBadRequest
+	case errors.Is(err, services.ErrRetentionPolicyExists):
+		status = fiber.StatusConflict
+	}
+	return ctx.Status(status).JSON(fiber.Map{
+		"statusCode": status,
+		"message":    err.Error(),
+		"data":       nil,
+	})
+}
+
+// checkRetentionPolicyAccess checks a "document-retention-policy" action. When access is not granted the error
+// response has been written.
+func checkRetentionPolicyAccess(ctx *fiber.Ctx, action string) (bool, error) {
+	requesterUsername := ctx.Locals("username").(string)
+
+	// Get Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, requesterUsername, "document-retention-policy", action, "none", "none", "none")
+	if err != nil {
+		log.Printf("Error checking Casbin permissions: %v", err)
+		return false, ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access permissions.",
+		})
+	}
+
+	// If the requester doesn't have access, return a forbidden status
+	if !hasAccess {
+		return false, ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have permission to access this resource.",
+		})
+	}
+	return true, nil
+}
+
+// retentionPolicyUUID reads the policy UUID of the route; when it returns false the error response has been written
+func retentionPolicyUUID(ctx *fiber.Ctx) (string, bool, error) {
+	uuidParam := ctx.Params("uuid")
+	if _, err := uuid.Parse(uuidParam); err != nil {
+		return "", false, ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid UUID format",
+			"data":       nil,
+		})
+	}
+	return uuidParam, true, nil
+}
+
+// parseRetentionPolicyPayload reads the body of a create or update; when it returns nil the error response has been written
+func parseRetentionPolicyPayload(ctx *fiber.Ctx) (*services.DocumentRetentionPolicyPayload, error) {
+	payload := new(services.DocumentRetentionPolicyPayload)
+	if err := ctx.BodyParser(payload); err != nil {
+		return nil, ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid or empty input",
+			"data":       nil,
+		})
+	}
+	return payload, nil
+}
+
+// GetRetentionPolicies lists the retention policies.
+func (c *DocumentRetentionController) GetRetentionPolicies(ctx *fiber.Ctx) error {
+	if allowed, err := checkRetentionPolicyAccess(ctx, "read"); !allowed {
+		return err
+	}
+
+	currentPage, err := strconv.Atoi(ctx.Query("currentPage", "1"))
+	if err != nil || currentPage < 1 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid currentPage",
+			"data":       nil,
+		})
+	}
+
+	pageSize, err := strconv.Atoi(ctx.Query("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid pageSize",
+			"data":       nil,
+		})
+	}
+
+	result, err := c.Service.GetPoliciesPaginated(pageSize, currentPage)
+	if err != nil {
+		return retentionPolicyErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Retention policies fetched successfully",
+		"data":       result,
+	})
+}
+
+// GetRetentionPolicyByUUID retrieves a retention policy by UUID.
+func (c *DocumentRetentionController) GetRetentionPolicyByUUID(ctx *fiber.Ctx) error {
+	if allowed, err := checkRetentionPolicyAccess(ctx, "read"); !allowed {
+		return err
+	}
+	uuidParam, ok, err := retentionPolicyUUID(ctx)
+	if !ok {
+		return err
+	}
+
+	policy, err := c.Service.GetPolicyByUUID(uuidParam)
+	if err != nil {
+		return retentionPolicyErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Retention policy fetched successfully",
+		"data":       policy,
+	})
+}
+
+// CreateRetentionPolicy adds a retention policy for a category, a type or both.
+func (c *DocumentRetentionController) CreateRetentionPolicy(ctx *fiber.Ctx) error {
+	if allowed, err := checkRetentionPolicyAccess(ctx, "create"); !allowed {
+		return err
+	}
+	payload, err := parseRetentionPolicyPayload(ctx)
+	if payload == nil {
+		return err
+	}
+
+	policy, err := c.Service.CreatePolicy(payload, ctx.Locals("user_id").(int))
+	if err != nil {
+		return retentionPolicyErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"statusCode": fiber.StatusCreated,
+		"message":    "Retention policy created successfully",
+		"data":       policy,
+	})
+}
+
+// UpdateRetentionPolicy changes a retention policy.
+func (c *DocumentRetentionController) UpdateRetentionPolicy(ctx *fiber.Ctx) error {
+	if allowed, err := checkRetentionPolicyAccess(ctx, "update"); !allowed {
+		return err
+	}
+	uuidParam, ok, err := retentionPolicyUUID(ctx)
+	if !ok {
+		return err
+	}
+	payload, err := parseRetentionPolicyPayload(ctx)
+	if payload == nil {
+		return err
+	}
+
+	policy, err := c.Service.UpdatePolicy(uuidParam, payload, ctx.Locals("user_id").(int))
+	if err != nil {
+		return retentionPolicyErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Retention policy updated successfully",
+		"data":       policy,
+	})
+}
+
+// DeleteRetentionPolicy removes a retention policy.
+func (c *DocumentRetentionController) DeleteRetentionPolicy(ctx *fiber.Ctx) error {
+	if allowed, err := checkRetentionPolicyAccess(ctx, "delete"); !allowed {
+		return err
+	}
+	uuidParam, ok, err := retentionPolicyUUID(ctx)
+	if !ok {
+		return err
+	}
+
+	if err := c.Service.DeletePolicy(uuidParam); err != nil {
+		return retentionPolicyErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Retention policy deleted successfully",
+		"data":       nil,
+	})
+}
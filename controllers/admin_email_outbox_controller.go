@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"backend-school/helpers"
+	"backend-school/models"
+	"backend-school/services"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type EmailOutboxController struct {
+	Service *services.EmailService
+}
+
+func NewEmailOutboxController() *EmailOutboxController {
+	return &EmailOutboxController{
+		Service: emailService,
+	}
+}
+
+// GetEmailOutbox retrieves a paginated list of queued emails with their delivery status.
+func (c *EmailOutboxController) GetEmailOutbox(ctx *fiber.Ctx) error {
+	username := ctx.Locals("username").(string)
+
+	// Get the Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	// Check if the user has access to the "email-outbox" resource using the "read" action
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "email-outbox", "read", "none", "none", "none")
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access.",
+		})
+	}
+
+	if !hasAccess {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have access to this resource",
+		})
+	}
+
+	// Parse pagination and filter query parameters
+	pageStr := ctx.Query("currentPage", "1")
+	pageSizeStr := ctx.Query("pageSize", "10")
+	status := ctx.Query("status", "")
+
+	currentPage, err := strconv.Atoi(pageStr)
+	if err != nil || currentPage < 1 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid currentPage",
+			"data":       nil,
+		})
+	}
+
+	pageSize, err := strconv.Atoi(pageSizeStr)
+	if err != nil || pageSize < 1 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid pageSize",
+			"data":       nil,
+		})
+	}
+
+	switch status {
+	case "", models.EmailStatusPending, models.EmailStatusSending, models.EmailStatusSent, models.EmailStatusDead:
+	default:
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid status",
+			"data":       nil,
+		})
+	}
+
+	result, err := c.Service.GetEmailOutboxPaginated(pageSize, currentPage, status)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    err.Error(),
+			"data":       nil,
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Email outbox fetched successfully",
+		"data":       result,
+	})
+}
+
+// RetryEmail re-queues a dead-lettered email.
+func (c *EmailOutboxController) RetryEmail(ctx *fiber.Ctx) error {
+	username := ctx.Locals("username").(string)
+
+	// Get the Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	// Check if the user has access to the "email-outbox" resource using the "update" action
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "email-outbox", "update", "none", "none", "none")
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access.",
+		})
+	}
+
+	if !hasAccess {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have access to this resource",
+		})
+	}
+
+	uuidParam := ctx.Params("uuid")
+	if _, err := uuid.Parse(uuidParam); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid UUID format",
+			"data":       nil,
+		})
+	}
+
+	message, err := c.Service.RetryEmail(uuidParam)
+	if err != nil {
+		status := fiber.StatusBadRequest
+		if err.Error() == "email not found" {
+			status = fiber.StatusNotFound
+		}
+		return ctx.Status(status).JSON(fiber.Map{
+			"statusCode": status,
+			"message":    err.Error(),
+			"data":       nil,
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Email queued for retry",
+		"data":       message,
+	})
+}
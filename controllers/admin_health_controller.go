@@ -2,9 +2,13 @@ package controllers
 
 import (
 	"backend-school/helpers"
+	"backend-school/models"
 	"backend-school/services"
+	"errors"
+	"fmt"
 	"log"
 	"strconv"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -32,29 +36,110 @@ type HealthRequest struct {
 	Profesi      string `json:"profesi"`
 }
 
-// GetHealths retrieves a paginated list of health data.
-func (c *HealthController) GetHealths(ctx *fiber.Ctx) error {
-	username := ctx.Locals("username").(string)
+// healthErrorResponse maps health service errors to HTTP responses
+func healthErrorResponse(ctx *fiber.Ctx, err error) error {
+	var validationErr *services.HealthValidationError
+	if errors.As(err, &validationErr) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"status":     "error",
+			"message":    "Validation failed",
+			"errors":     validationErr.Errors,
+		})
+	}
+
+	status := fiber.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrHealthNotFound), errors.Is(err, services.ErrHealthNotInTrash):
+		status = fiber.StatusNotFound
+	case errors.Is(err, services.ErrInvalidHealthImport), errors.Is(err, services.ErrInvalidHealthData):
+		status = fiber.StatusBadRequest
+	}
+	if status == fiber.StatusInternalServerError {
+		log.Printf("Health data error: %v", err)
+	}
+	return ctx.Status(status).JSON(fiber.Map{
+		"statusCode": status,
+		"message":    err.Error(),
+		"data":       nil,
+	})
+}
 
-	// Get the Casbin enforcer
+// checkHealthAccess checks a "health" action. When access is not granted the error response has been written.
+func checkHealthAccess(ctx *fiber.Ctx, action string) (bool, error) {
+	requesterUsername := ctx.Locals("username").(string)
+
+	// Get Casbin enforcer
 	enforcer := helpers.GetCasbinEnforcer()
 
-	// Check if the user has access to the "document-type" resource with "read" action
-	hasAccess, err := enforcer.Enforce(username, "document-type", "read", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, requesterUsername, "health", action, "none", "none", "none")
 	if err != nil {
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		log.Printf("Error checking Casbin permissions: %v", err)
+		return false, ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"statusCode": fiber.StatusInternalServerError,
-			"message":    "Failed to check access.",
+			"message":    "Failed to check access permissions.",
 		})
 	}
 
+	// If the requester doesn't have access, return a forbidden status
 	if !hasAccess {
-		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+		return false, ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"statusCode": fiber.StatusForbidden,
-			"message":    "Forbidden: You don't have access to this resource",
+			"message":    "Forbidden: You don't have permission to access this resource.",
+		})
+	}
+	return true, nil
+}
+
+// healthAccessor describes the requester for the access log. Without the "health" "read-clinical" permission the
+// clinical fields of every record read are masked. When it returns false the error response has been written.
+func healthAccessor(ctx *fiber.Ctx) (services.HealthAccessor, bool, error) {
+	username := ctx.Locals("username").(string)
+	accessor := services.HealthAccessor{
+		UserID:   ctx.Locals("user_id").(int),
+		Username: username,
+		IP:       ctx.IP(),
+	}
+
+	clinical, err := helpers.EnforceRequest(ctx, helpers.GetCasbinEnforcer(), username, "health", "read-clinical", "none", "none", "none")
+	if err != nil {
+		log.Printf("Error checking Casbin permissions: %v", err)
+		return accessor, false, ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access permissions.",
 		})
 	}
+	accessor.Clinical = clinical
+	return accessor, true, nil
+}
+
+// maskedHealthFields lists the fields masked for the accessor, nil when every field is shown
+func maskedHealthFields(accessor services.HealthAccessor) []string {
+	if accessor.Clinical {
+		return nil
+	}
+	return services.HealthMaskedFields
+}
+
+// GetHealths retrieves a paginated list of health data.
+func (c *HealthController) GetHealths(ctx *fiber.Ctx) error {
+	if allowed, err := checkHealthAccess(ctx, "read"); !allowed {
+		return err
+	}
 
+	return c.respondHealthList(ctx, false)
+}
+
+// GetTrashedHealths lists deleted health data with the same search, filters and sort as GetHealths.
+func (c *HealthController) GetTrashedHealths(ctx *fiber.Ctx) error {
+	if allowed, err := checkHealthAccess(ctx, "read"); !allowed {
+		return err
+	}
+	return c.respondHealthList(ctx, true)
+}
+
+// respondHealthList parses the pagination, search, filter and sort parameters of a health data listing
+func (c *HealthController) respondHealthList(ctx *fiber.Ctx, trashed bool) error {
 	// Parse pagination and search query parameters
 	pageStr := ctx.Query("currentPage", "1")
 	pageSizeStr := ctx.Query("pageSize", "10")
@@ -62,13 +147,13 @@ func (c *HealthController) GetHealths(ctx *fiber.Ctx) error {
 	showAll := ctx.Query("showAll", "")
 
 	currentPage, err := strconv.Atoi(pageStr)
-	// if err != nil || currentPage < 1 {
-	// 	return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-	// 		"statusCode": fiber.StatusBadRequest,
-	// 		"message":    "Invalid currentPage",
-	// 		"data":       nil,
-	// 	})
-	// }
+	if err != nil || currentPage < 1 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid currentPage",
+			"data":       nil,
+		})
+	}
 
 	pageSize, err := strconv.Atoi(pageSizeStr)
 	if err != nil || pageSize < 1 {
@@ -79,8 +164,60 @@ func (c *HealthController) GetHealths(ctx *fiber.Ctx) error {
 		})
 	}
 
+	// Numeric filters are given as <field>_min and <field>_max, e.g. systol_min=140
+	filter := services.HealthListFilter{
+		Risk:         ctx.Query("risk"),
+		JenisKelamin: ctx.Query("jenis_kelamin"),
+		Profesi:      ctx.Query("profesi"),
+		Sort:         ctx.Query("sort"),
+		Ranges:       map[string]services.HealthMeasurementRange{},
+		Trashed:      trashed,
+	}
+	for field := range services.HealthMeasurementUnits() {
+		var measurementRange services.HealthMeasurementRange
+		for _, bound := range []struct {
+			param string
+			value **float64
+		}{{field + "_min", &measurementRange.Min}, {field + "_max", &measurementRange.Max}} {
+			raw := ctx.Query(bound.param)
+			if raw == "" {
+				continue
+			}
+			number, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"statusCode": fiber.StatusBadRequest,
+					"message":    "Invalid " + bound.param,
+					"data":       nil,
+				})
+			}
+			*bound.value = &number
+		}
+		if measurementRange.Min != nil || measurementRange.Max != nil {
+			filter.Ranges[field] = measurementRange
+		}
+	}
+
+	accessor, ok, err := healthAccessor(ctx)
+	if !ok {
+		return err
+	}
+	if fields := filter.ClinicalFields(); !accessor.Clinical && len(fields) > 0 {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: filtering or sorting on " + strings.Join(fields, ", ") + " requires clinical access.",
+		})
+	}
+
 	// Call service to get paginated health data
-	result, err := c.Service.GetHealthsPaginated(currentPage, pageSize, search, showAll)
+	result, err := c.Service.GetHealthsPaginated(currentPage, pageSize, search, showAll, filter)
+	if errors.Is(err, services.ErrInvalidHealthSort) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    err.Error(),
+			"data":       nil,
+		})
+	}
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"statusCode": fiber.StatusInternalServerError,
@@ -89,34 +226,21 @@ func (c *HealthController) GetHealths(ctx *fiber.Ctx) error {
 		})
 	}
 
+	if err := accessor.Read(models.HealthAccessActionList, result["data"].([]models.Health)); err != nil {
+		return healthErrorResponse(ctx, err)
+	}
+
 	return ctx.JSON(fiber.Map{
-		"statusCode": fiber.StatusOK,
-		"message":    "Success",
-		"data":       result,
+		"statusCode":    fiber.StatusOK,
+		"message":       "Success",
+		"data":          result,
+		"masked_fields": maskedHealthFields(accessor),
 	})
 }
 
 func (c *HealthController) CreateHealth(ctx *fiber.Ctx) error {
-	username := ctx.Locals("username").(string)
-
-	// Get the Casbin enforcer
-	enforcer := helpers.GetCasbinEnforcer()
-
-	// Check if the user has access to create a "document-type"
-	hasAccess, err := enforcer.Enforce(username, "document-type", "create", "none", "none", "none")
-	if err != nil {
-		log.Printf("Casbin enforcement error: %v", err)
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"statusCode": fiber.StatusInternalServerError,
-			"message":    "Failed to check access.",
-		})
-	}
-
-	if !hasAccess {
-		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"statusCode": fiber.StatusForbidden,
-			"message":    "Forbidden: You don't have access to this resource",
-		})
+	if allowed, err := checkHealthAccess(ctx, "create"); !allowed {
+		return err
 	}
 
 	// Parse body from JSON into HealthRequest struct
@@ -140,50 +264,57 @@ func (c *HealthController) CreateHealth(ctx *fiber.Ctx) error {
 		Diastol:      ctx.FormValue("diastol"),
 		HeartRate:    ctx.FormValue("heart_rate"),
 		Profesi:      ctx.FormValue("profesi"),
+		PersonUUID:   ctx.FormValue("person_uuid"),
 	}
-	log.Printf("Payload: %v", Health)
 	// Call service to create the document type
-	createdHealth, err := c.Service.AddHealth(&Health)
+	accessor, ok, err := healthAccessor(ctx)
+	if !ok {
+		return err
+	}
+
+	createdHealth, err := c.Service.AddHealth(&Health, ctx.Locals("username").(string))
+	var validationErr *services.HealthValidationError
+	if errors.As(err, &validationErr) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"status":     "error",
+			"message":    "Validation failed",
+			"errors":     validationErr.Errors,
+		})
+	}
 	if err != nil {
-		log.Printf("Error creating document type: %v", err)
+		log.Printf("Error creating health data: %v", err)
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"statusCode": fiber.StatusInternalServerError,
-			"message":    "Could not create document type",
+			"message":    "Could not create health data",
 			"data":       nil,
 		})
 	}
 
+	// The assessed risk is returned, so the new record is read like any other
+	records := []models.Health{*createdHealth}
+	if err := accessor.Read(models.HealthAccessActionCreate, records); err != nil {
+		return healthErrorResponse(ctx, err)
+	}
+
 	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"statusCode": fiber.StatusCreated,
 		"message":    "Health Data created successfully",
 		"data": fiber.Map{
-			"name": createdHealth.Nama,
-			"uuid": createdHealth.UUID,
+			"name":              records[0].Nama,
+			"uuid":              records[0].UUID,
+			"risk":              records[0].Risk,
+			"risk_engine":       records[0].RiskEngine,
+			"risk_rule_version": records[0].RiskRuleVersion,
 		},
+		"masked_fields": maskedHealthFields(accessor),
 	})
 }
 
 // GetHealthByUUID retrieves a Health Data by its UUID.
 func (c *HealthController) GetHealthByUUID(ctx *fiber.Ctx) error {
-	username := ctx.Locals("username").(string)
-
-	// Get the Casbin enforcer
-	enforcer := helpers.GetCasbinEnforcer()
-
-	// Check if the user has access to read a "document-type"
-	hasAccess, err := enforcer.Enforce(username, "document-type", "read", "none", "none", "none")
-	if err != nil {
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"statusCode": fiber.StatusInternalServerError,
-			"message":    "Failed to check access.",
-		})
-	}
-
-	if !hasAccess {
-		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"statusCode": fiber.StatusForbidden,
-			"message":    "Forbidden: You don't have access to this resource",
-		})
+	if allowed, err := checkHealthAccess(ctx, "read"); !allowed {
+		return err
 	}
 
 	uuidStr := ctx.Params("uuid")
@@ -204,49 +335,183 @@ func (c *HealthController) GetHealthByUUID(ctx *fiber.Ctx) error {
 		})
 	}
 
+	accessor, ok, err := healthAccessor(ctx)
+	if !ok {
+		return err
+	}
+
 	Health, err := c.Service.GetHealthByUUID(uuid.String())
-	if err != nil {
+	if errors.Is(err, services.ErrHealthNotFound) {
 		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"statusCode": fiber.StatusNotFound,
 			"message":    "Health Data not found",
 			"data":       nil,
 		})
 	}
+	if err != nil {
+		log.Printf("Error fetching health data %s: %v", uuid, err)
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to fetch health data",
+			"data":       nil,
+		})
+	}
+
+	records := []models.Health{*Health}
+	if err := accessor.Read(models.HealthAccessActionView, records); err != nil {
+		return healthErrorResponse(ctx, err)
+	}
 
 	return ctx.JSON(fiber.Map{
-		"statusCode": fiber.StatusOK,
-		"message":    "Success",
-		"data":       Health,
+		"statusCode":    fiber.StatusOK,
+		"message":       "Success",
+		"data":          records[0],
+		"masked_fields": maskedHealthFields(accessor),
 	})
 }
 
-// DeleteHealth deletes a Health Data by its UUID.
-func (c *HealthController) DeleteHealth(ctx *fiber.Ctx) error {
-	username := ctx.Locals("username").(string)
+// UpdateHealth replaces the details and measurements of a health check. The risk, BMI and recommendations are
+// generated again.
+func (c *HealthController) UpdateHealth(ctx *fiber.Ctx) error {
+	if allowed, err := checkHealthAccess(ctx, "update"); !allowed {
+		return err
+	}
 
-	// Get the Casbin enforcer
-	enforcer := helpers.GetCasbinEnforcer()
+	payload := new(services.HealthPayload)
+	if err := ctx.BodyParser(payload); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid request payload",
+			"data":       nil,
+		})
+	}
+
+	accessor, ok, err := healthAccessor(ctx)
+	if !ok {
+		return err
+	}
+
+	health, err := c.Service.UpdateHealth(ctx.Params("uuid"), payload, ctx.Locals("username").(string))
+	if err != nil {
+		return healthErrorResponse(ctx, err)
+	}
+
+	// The reassessed record is returned, so it is read like any other
+	records := []models.Health{*health}
+	if err := accessor.Read(models.HealthAccessActionUpdate, records); err != nil {
+		return healthErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode":    fiber.StatusOK,
+		"message":       "Health Data updated successfully",
+		"data":          records[0],
+		"masked_fields": maskedHealthFields(accessor),
+	})
+}
+
+// RestoreHealth takes deleted health data out of the trash.
+func (c *HealthController) RestoreHealth(ctx *fiber.Ctx) error {
+	if allowed, err := checkHealthAccess(ctx, "restore"); !allowed {
+		return err
+	}
+
+	accessor, ok, err := healthAccessor(ctx)
+	if !ok {
+		return err
+	}
+
+	health, err := c.Service.RestoreHealth(ctx.Params("uuid"), ctx.Locals("username").(string))
+	if err != nil {
+		return healthErrorResponse(ctx, err)
+	}
+
+	// The restored record is returned, so it is read like any other
+	records := []models.Health{*health}
+	if err := accessor.Read(models.HealthAccessActionView, records); err != nil {
+		return healthErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode":    fiber.StatusOK,
+		"message":       "Health Data restored successfully",
+		"data":          records[0],
+		"masked_fields": maskedHealthFields(accessor),
+	})
+}
+
+// ImportHealthScreening records the results of a screening day from a CSV or XLSX file and reports every row.
+// With dry_run=true the rows are only validated and assessed.
+func (c *HealthController) ImportHealthScreening(ctx *fiber.Ctx) error {
+	if allowed, err := checkHealthAccess(ctx, "create"); !allowed {
+		return err
+	}
+
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "File upload failed",
+			"data":       nil,
+		})
+	}
+	if fileHeader.Size > services.MaxHealthImportSize {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "A CSV or XLSX file of at most 10 MB is required",
+			"data":       nil,
+		})
+	}
 
-	// Check if the user has access to delete a "document-type"
-	hasAccess, err := enforcer.Enforce(username, "document-type", "delete", "none", "none", "none")
+	dryRun, _ := strconv.ParseBool(ctx.FormValue("dry_run", "false"))
+
+	file, err := fileHeader.Open()
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"statusCode": fiber.StatusInternalServerError,
-			"message":    "Failed to check access.",
+			"message":    "Failed to read the file",
+			"data":       nil,
 		})
 	}
+	defer file.Close()
 
-	if !hasAccess {
-		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"statusCode": fiber.StatusForbidden,
-			"message":    "Forbidden: You don't have access to this resource",
-		})
+	accessor, ok, err := healthAccessor(ctx)
+	if !ok {
+		return err
+	}
+
+	result, err := c.Service.ImportScreening(file, fileHeader.Filename, dryRun, ctx.Locals("username").(string))
+	if err != nil {
+		return healthErrorResponse(ctx, err)
+	}
+	if !accessor.Clinical {
+		for i := range result.Rows {
+			result.Rows[i].Bmi, result.Rows[i].Risk = nil, ""
+		}
+	}
+
+	message := fmt.Sprintf("%d of %d row(s) imported, %d failed", result.Succeeded, result.TotalRows, result.Failed)
+	if dryRun {
+		message = fmt.Sprintf("Dry run: %d of %d row(s) can be imported, %d have errors", result.Succeeded, result.TotalRows, result.Failed)
+	}
+	return ctx.JSON(fiber.Map{
+		"statusCode":    fiber.StatusOK,
+		"message":       message,
+		"data":          result,
+		"masked_fields": maskedHealthFields(accessor),
+	})
+}
+
+// DeleteHealth deletes a Health Data by its UUID.
+func (c *HealthController) DeleteHealth(ctx *fiber.Ctx) error {
+	if allowed, err := checkHealthAccess(ctx, "delete"); !allowed {
+		return err
 	}
 
 	uuidStr := ctx.Params("uuid")
 
-	if err := c.Service.DeleteHealth(uuidStr); err != nil {
-		if err.Error() == "Health Data not found" {
+	if err := c.Service.DeleteHealth(uuidStr, ctx.Locals("username").(string)); err != nil {
+		if errors.Is(err, services.ErrHealthNotFound) {
 			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"statusCode": fiber.StatusNotFound,
 				"message":    "Health Data not found",
@@ -254,7 +519,7 @@ func (c *HealthController) DeleteHealth(ctx *fiber.Ctx) error {
 		}
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"statusCode": fiber.StatusInternalServerError,
-			"message":    "Could not delete document type",
+			"message":    "Could not delete health data",
 		})
 	}
 
@@ -263,3 +528,120 @@ func (c *HealthController) DeleteHealth(ctx *fiber.Ctx) error {
 		"message":    "Health Data deleted successfully",
 	})
 }
+
+// GetHealthMeasurementIssues lists the former text measurements that could not be converted to numbers.
+func (c *HealthController) GetHealthMeasurementIssues(ctx *fiber.Ctx) error {
+	// The issues hold the original measurements, which are clinical data
+	if allowed, err := checkHealthAccess(ctx, "read-clinical"); !allowed {
+		return err
+	}
+
+	currentPage, err := strconv.Atoi(ctx.Query("currentPage", "1"))
+	if err != nil || currentPage < 1 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid currentPage",
+			"data":       nil,
+		})
+	}
+
+	pageSize, err := strconv.Atoi(ctx.Query("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid pageSize",
+			"data":       nil,
+		})
+	}
+
+	result, err := c.Service.GetMeasurementIssuesPaginated(currentPage, pageSize, ctx.Query("field"))
+	if err != nil {
+		log.Printf("Error fetching health measurement issues: %v", err)
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to fetch health measurement issues",
+			"data":       nil,
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Health measurement issues fetched successfully",
+		"data":       result,
+	})
+}
+
+// GetHealthDashboard aggregates health checks by profession, gender and risk level. By default each person counts
+// once with their latest check; latest=false counts every check. The risk breakdowns are clinical data.
+func (c *HealthController) GetHealthDashboard(ctx *fiber.Ctx) error {
+	if allowed, err := checkHealthAccess(ctx, "read-clinical"); !allowed {
+		return err
+	}
+
+	latestOnly, err := strconv.ParseBool(ctx.Query("latest", "true"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid latest",
+			"data":       nil,
+		})
+	}
+
+	dashboard, err := c.Service.GetDashboard(latestOnly)
+	if err != nil {
+		log.Printf("Error aggregating health data: %v", err)
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to aggregate health data",
+			"data":       nil,
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Health dashboard fetched successfully",
+		"data":       dashboard,
+	})
+}
+
+// GetHealthAccessLog lists who read, exported or erased health data, newest first. It can be narrowed to a record
+// (health_uuid), a person (person_uuid), a username or an action.
+func (c *HealthController) GetHealthAccessLog(ctx *fiber.Ctx) error {
+	if allowed, err := checkHealthAccess(ctx, "audit"); !allowed {
+		return err
+	}
+
+	currentPage, err := strconv.Atoi(ctx.Query("currentPage", "1"))
+	if err != nil || currentPage < 1 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid currentPage",
+			"data":       nil,
+		})
+	}
+
+	pageSize, err := strconv.Atoi(ctx.Query("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid pageSize",
+			"data":       nil,
+		})
+	}
+
+	result, err := c.Service.GetAccessLogPaginated(currentPage, pageSize, services.HealthAccessLogFilter{
+		HealthUUID: ctx.Query("health_uuid"),
+		PersonUUID: ctx.Query("person_uuid"),
+		Username:   ctx.Query("username"),
+		Action:     ctx.Query("action"),
+	})
+	if err != nil {
+		return healthErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Health access log fetched successfully",
+		"data":       result,
+	})
+}
@@ -0,0 +1,515 @@
+package controllers
+
+import (
+	"backend-school/helpers"
+	"backend-school/models"
+	"backend-school/services"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type HealthPersonController struct {
+	Service *services.HealthPersonService
+}
+
+func NewHealthPersonController() *HealthPersonController {
+	return &HealthPersonController{
+		Service: services.NewHealthPersonService(),
+	}
+}
+
+// healthPersonErrorResponse maps person service errors to HTTP responses
+func healthPersonErrorResponse(ctx *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrHealthPersonNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, services.ErrInvalidHealthPerson), errors.Is(err, services.ErrHealthErasureReason):
+		status = fiber.StatusBadRequest
+	case errors.Is(err, services.ErrHealthPersonExists):
+		status = fiber.StatusConflict
+	case errors.Is(err, services.ErrHealthPersonLinkForbidden):
+		status = fiber.StatusForbidden
+	}
+	if status == fiber.StatusInternalServerError {
+		log.Printf("Health person error: %v", err)
+	}
+	return ctx.Status(status).JSON(fiber.Map{
+		"statusCode": status,
+		"message":    err.Error(),
+		"data":       nil,
+	})
+}
+
+// checkHealthPersonAccess checks a "health-person" action. When access is not granted the error response has been
+// written.
+func checkHealthPersonAccess(ctx *fiber.Ctx, action string) (bool, error) {
+	requesterUsername := ctx.Locals("username").(string)
+
+	// Get Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, requesterUsername, "health-person", action, "none", "none", "none")
+	if err != nil {
+		log.Printf("Error checking Casbin permissions: %v", err)
+		return false, ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access permissions.",
+		})
+	}
+
+	// If the requester doesn't have access, return a forbidden status
+	if !hasAccess {
+		return false, ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have permission to access this resource.",
+		})
+	}
+	return true, nil
+}
+
+// healthPersonUUID reads the person UUID of the route; when it returns false the error response has been written
+func healthPersonUUID(ctx *fiber.Ctx) (string, bool, error) {
+	uuidParam := ctx.Params("uuid")
+	if _, err := uuid.Parse(uuidParam); err != nil {
+		return "", false, ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid UUID format",
+			"data":       nil,
+		})
+	}
+	return uuidParam, true, nil
+}
+
+// parseHealthPersonPayload reads the body of a create or update; when it returns nil the error response has been written
+func parseHealthPersonPayload(ctx *fiber.Ctx) (*services.HealthPersonPayload, error) {
+	payload := new(services.HealthPersonPayload)
+	if err := ctx.BodyParser(payload); err != nil {
+		return nil, ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid or empty input",
+			"data":       nil,
+		})
+	}
+	return payload, nil
+}
+
+// healthPeriod reads the optional from and to dates (YYYY-MM-DD, both inclusive) of a timeline or trend; when it
+// returns false the error response has been written
+func healthPeriod(ctx *fiber.Ctx) (*time.Time, *time.Time, bool, error) {
+	var period [2]*time.Time
+	for i, param := range []string{"from", "to"} {
+		value := ctx.Query(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			return nil, nil, false, ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"statusCode": fiber.StatusBadRequest,
+				"message":    "Invalid " + param + ", expected YYYY-MM-DD",
+				"data":       nil,
+			})
+		}
+		if param == "to" {
+			parsed = parsed.Add(24*time.Hour - time.Nanosecond)
+		}
+		period[i] = &parsed
+	}
+	return period[0], period[1], true, nil
+}
+
+// GetHealthPersons lists the people health checks are recorded for.
+func (c *HealthPersonController) GetHealthPersons(ctx *fiber.Ctx) error {
+	if allowed, err := checkHealthPersonAccess(ctx, "read"); !allowed {
+		return err
+	}
+
+	currentPage, err := strconv.Atoi(ctx.Query("currentPage", "1"))
+	if err != nil || currentPage < 1 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid currentPage",
+			"data":       nil,
+		})
+	}
+
+	pageSize, err := strconv.Atoi(ctx.Query("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid pageSize",
+			"data":       nil,
+		})
+	}
+
+	result, err := c.Service.GetPersonsPaginated(pageSize, currentPage, ctx.Query("search"))
+	if err != nil {
+		return healthPersonErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Persons fetched successfully",
+		"data":       result,
+	})
+}
+
+// GetHealthPersonByUUID retrieves a person with a summary of their health checks.
+func (c *HealthPersonController) GetHealthPersonByUUID(ctx *fiber.Ctx) error {
+	if allowed, err := checkHealthPersonAccess(ctx, "read"); !allowed {
+		return err
+	}
+	uuidParam, ok, err := healthPersonUUID(ctx)
+	if !ok {
+		return err
+	}
+
+	person, err := c.Service.GetPersonByUUID(uuidParam)
+	if err != nil {
+		return healthPersonErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Person fetched successfully",
+		"data":       person,
+	})
+}
+
+// CreateHealthPerson adds a person.
+func (c *HealthPersonController) CreateHealthPerson(ctx *fiber.Ctx) error {
+	if allowed, err := checkHealthPersonAccess(ctx, "create"); !allowed {
+		return err
+	}
+	payload, err := parseHealthPersonPayload(ctx)
+	if payload == nil {
+		return err
+	}
+
+	accessor, ok, err := healthAccessor(ctx)
+	if !ok {
+		return err
+	}
+
+	person, err := c.Service.CreatePerson(payload, ctx.Locals("user_id").(int), accessor.Clinical)
+	if err != nil {
+		return healthPersonErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"statusCode": fiber.StatusCreated,
+		"message":    "Person created successfully",
+		"data":       person,
+	})
+}
+
+// UpdateHealthPerson changes a person.
+func (c *HealthPersonController) UpdateHealthPerson(ctx *fiber.Ctx) error {
+	if allowed, err := checkHealthPersonAccess(ctx, "update"); !allowed {
+		return err
+	}
+	uuidParam, ok, err := healthPersonUUID(ctx)
+	if !ok {
+		return err
+	}
+	payload, err := parseHealthPersonPayload(ctx)
+	if payload == nil {
+		return err
+	}
+
+	accessor, ok, err := healthAccessor(ctx)
+	if !ok {
+		return err
+	}
+
+	person, err := c.Service.UpdatePerson(uuidParam, payload, ctx.Locals("user_id").(int), accessor.Clinical)
+	if err != nil {
+		return healthPersonErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Person updated successfully",
+		"data":       person,
+	})
+}
+
+// DeleteHealthPerson removes a person; their health checks are kept.
+func (c *HealthPersonController) DeleteHealthPerson(ctx *fiber.Ctx) error {
+	if allowed, err := checkHealthPersonAccess(ctx, "delete"); !allowed {
+		return err
+	}
+	uuidParam, ok, err := healthPersonUUID(ctx)
+	if !ok {
+		return err
+	}
+
+	if err := c.Service.DeletePerson(uuidParam); err != nil {
+		return healthPersonErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Person deleted successfully",
+		"data":       nil,
+	})
+}
+
+// LinkHealthRecords attaches existing health checks to a person.
+func (c *HealthPersonController) LinkHealthRecords(ctx *fiber.Ctx) error {
+	if allowed, err := checkHealthPersonAccess(ctx, "update"); !allowed {
+		return err
+	}
+	uuidParam, ok, err := healthPersonUUID(ctx)
+	if !ok {
+		return err
+	}
+
+	var body struct {
+		HealthUUIDs []string `json:"health_uuids"`
+	}
+	if err := ctx.BodyParser(&body); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid or empty input",
+			"data":       nil,
+		})
+	}
+
+	accessor, ok, err := healthAccessor(ctx)
+	if !ok {
+		return err
+	}
+
+	linked, err := c.Service.LinkHealthRecords(uuidParam, body.HealthUUIDs, accessor.Clinical)
+	if err != nil {
+		return healthPersonErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Health data linked successfully",
+		"data":       fiber.Map{"linked": linked},
+	})
+}
+
+// personForHealthHistory resolves the person of a timeline or trend route after checking the "health" action the
+// route needs; when it returns nil the error response has been written
+func (c *HealthPersonController) personForHealthHistory(ctx *fiber.Ctx, healthAction string) (*models.HealthPerson, error) {
+	if allowed, err := checkHealthPersonAccess(ctx, "read"); !allowed {
+		return nil, err
+	}
+	if allowed, err := checkHealthAccess(ctx, healthAction); !allowed {
+		return nil, err
+	}
+	uuidParam, ok, err := healthPersonUUID(ctx)
+	if !ok {
+		return nil, err
+	}
+	person, err := c.Service.GetPersonByUUID(uuidParam)
+	if err != nil {
+		return nil, healthPersonErrorResponse(ctx, err)
+	}
+	return &person.HealthPerson, nil
+}
+
+// GetHealthPersonTimeline lists the health checks of a person, oldest first. Without clinical access the
+// measurements are masked.
+func (c *HealthPersonController) GetHealthPersonTimeline(ctx *fiber.Ctx) error {
+	person, err := c.personForHealthHistory(ctx, "read")
+	if person == nil {
+		return err
+	}
+	accessor, ok, err := healthAccessor(ctx)
+	if !ok {
+		return err
+	}
+	return c.respondTimeline(ctx, person, accessor)
+}
+
+// GetHealthPersonTrends summarises the measurements of a person over time and reports notable changes. Trends are
+// clinical data, so clinical access is required.
+func (c *HealthPersonController) GetHealthPersonTrends(ctx *fiber.Ctx) error {
+	person, err := c.personForHealthHistory(ctx, "read-clinical")
+	if person == nil {
+		return err
+	}
+	accessor, ok, err := healthAccessor(ctx)
+	if !ok {
+		return err
+	}
+	return c.respondTrends(ctx, person, accessor)
+}
+
+// myHealthPerson resolves the person linked to the requester; when it returns nil the error response has been written
+func myHealthPerson(ctx *fiber.Ctx) (*models.HealthPerson, error) {
+	person, err := services.FindHealthPersonByUserID(ctx.Locals("user_id").(int))
+	if err != nil {
+		return nil, healthPersonErrorResponse(ctx, err)
+	}
+	return person, nil
+}
+
+// unmaskedHealthAccessor describes a requester who sees every field: a user reading their own data, or the
+// export of a person's data
+func unmaskedHealthAccessor(ctx *fiber.Ctx) services.HealthAccessor {
+	return services.HealthAccessor{
+		UserID:   ctx.Locals("user_id").(int),
+		Username: ctx.Locals("username").(string),
+		IP:       ctx.IP(),
+		Clinical: true,
+	}
+}
+
+// myHealthAccessor describes a user reading their own data. They see every field only when their login was linked
+// to the person by someone with clinical access; a link made before that was required is treated like any other
+// reader.
+func myHealthAccessor(ctx *fiber.Ctx, person *models.HealthPerson) (services.HealthAccessor, bool, error) {
+	if person.UserLinkedBy != nil {
+		return unmaskedHealthAccessor(ctx), true, nil
+	}
+	return healthAccessor(ctx)
+}
+
+// GetMyHealthTimeline lists the requester's own health checks. No permission is needed to read one's own data.
+func (c *HealthPersonController) GetMyHealthTimeline(ctx *fiber.Ctx) error {
+	person, err := myHealthPerson(ctx)
+	if person == nil {
+		return err
+	}
+	accessor, ok, err := myHealthAccessor(ctx, person)
+	if !ok {
+		return err
+	}
+	return c.respondTimeline(ctx, person, accessor)
+}
+
+// GetMyHealthTrends summarises the requester's own measurements over time. Trends are clinical data, so they are
+// refused when the requester would only see their checks masked.
+func (c *HealthPersonController) GetMyHealthTrends(ctx *fiber.Ctx) error {
+	person, err := myHealthPerson(ctx)
+	if person == nil {
+		return err
+	}
+	accessor, ok, err := myHealthAccessor(ctx, person)
+	if !ok {
+		return err
+	}
+	if !accessor.Clinical {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have permission to access this resource.",
+		})
+	}
+	return c.respondTrends(ctx, person, accessor)
+}
+
+func (c *HealthPersonController) respondTimeline(ctx *fiber.Ctx, person *models.HealthPerson, accessor services.HealthAccessor) error {
+	from, to, ok, err := healthPeriod(ctx)
+	if !ok {
+		return err
+	}
+
+	timeline, err := c.Service.GetTimeline(person, from, to)
+	if err != nil {
+		return healthPersonErrorResponse(ctx, err)
+	}
+	if err := accessor.Read(models.HealthAccessActionTimeline, timeline.Records); err != nil {
+		return healthPersonErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode":    fiber.StatusOK,
+		"message":       "Health timeline fetched successfully",
+		"data":          timeline,
+		"masked_fields": maskedHealthFields(accessor),
+	})
+}
+
+func (c *HealthPersonController) respondTrends(ctx *fiber.Ctx, person *models.HealthPerson, accessor services.HealthAccessor) error {
+	from, to, ok, err := healthPeriod(ctx)
+	if !ok {
+		return err
+	}
+
+	trends, err := c.Service.GetTrends(person, from, to)
+	if err != nil {
+		return healthPersonErrorResponse(ctx, err)
+	}
+	if err := accessor.ReadTrends(trends); err != nil {
+		return healthPersonErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Health trends fetched successfully",
+		"data":       trends,
+	})
+}
+
+// ExportHealthPerson downloads everything held about a person's health as a JSON file, for a data-protection
+// access request.
+func (c *HealthPersonController) ExportHealthPerson(ctx *fiber.Ctx) error {
+	if allowed, err := checkHealthPersonAccess(ctx, "export"); !allowed {
+		return err
+	}
+	uuidParam, ok, err := healthPersonUUID(ctx)
+	if !ok {
+		return err
+	}
+
+	export, err := c.Service.ExportPerson(uuidParam, unmaskedHealthAccessor(ctx))
+	if err != nil {
+		return healthPersonErrorResponse(ctx, err)
+	}
+
+	content, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return healthPersonErrorResponse(ctx, err)
+	}
+	ctx.Attachment("health-person-" + export.Person.UUID.String() + ".json")
+	ctx.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSONCharsetUTF8)
+	ctx.Set(fiber.HeaderCacheControl, "private, no-store")
+	return ctx.Send(content)
+}
+
+// EraseHealthPerson permanently deletes a person and their health data for a data-protection erasure request.
+// The body must give the reason, which is kept in the access log.
+func (c *HealthPersonController) EraseHealthPerson(ctx *fiber.Ctx) error {
+	if allowed, err := checkHealthPersonAccess(ctx, "erase"); !allowed {
+		return err
+	}
+	uuidParam, ok, err := healthPersonUUID(ctx)
+	if !ok {
+		return err
+	}
+
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := ctx.BodyParser(&body); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid or empty input",
+			"data":       nil,
+		})
+	}
+
+	result, err := c.Service.ErasePerson(uuidParam, body.Reason, unmaskedHealthAccessor(ctx))
+	if err != nil {
+		return healthPersonErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Person and health data erased successfully",
+		"data":       result,
+	})
+}
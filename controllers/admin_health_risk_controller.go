@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"backend-school/helpers"
+	"backend-school/services"
+	"context"
+	"errors"
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type HealthRiskController struct {
+	Service *services.HealthRiskService
+}
+
+func NewHealthRiskController() *HealthRiskController {
+	return &HealthRiskController{
+		Service: services.NewHealthRiskService(),
+	}
+}
+
+// healthRiskErrorResponse maps health risk service errors to HTTP responses
+func healthRiskErrorResponse(ctx *fiber.Ctx, err error) error {
+	var validationErr *services.HealthValidationError
+	if errors.As(err, &validationErr) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"status":     "error",
+			"message":    "Validation failed",
+			"errors":     validationErr.Errors,
+		})
+	}
+
+	status := fiber.StatusInternalServerError
+	if errors.Is(err, services.ErrInvalidHealthRiskRules) {
+		status = fiber.StatusBadRequest
+	}
+	return ctx.Status(status).JSON(fiber.Map{
+		"statusCode": status,
+		"message":    err.Error(),
+		"data":       nil,
+	})
+}
+
+// checkHealthRiskRuleAccess checks a "health-risk-rule" action. When access is not granted the error response has
+// been written.
+func checkHealthRiskRuleAccess(ctx *fiber.Ctx, action string) (bool, error) {
+	requesterUsername := ctx.Locals("username").(string)
+
+	// Get Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, requesterUsername, "health-risk-rule", action, "none", "none", "none")
+	if err != nil {
+		log.Printf("Error checking Casbin permissions: %v", err)
+		return false, ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access permissions.",
+		})
+	}
+
+	// If the requester doesn't have access, return a forbidden status
+	if !hasAccess {
+		return false, ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have permission to access this resource.",
+		})
+	}
+	return true, nil
+}
+
+// GetHealthRiskRules returns the active thresholds of the local risk engine.
+func (c *HealthRiskController) GetHealthRiskRules(ctx *fiber.Ctx) error {
+	if allowed, err := checkHealthRiskRuleAccess(ctx, "read"); !allowed {
+		return err
+	}
+
+	ruleSet, err := c.Service.GetActiveRules()
+	if err != nil {
+		return healthRiskErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Health risk rules fetched successfully",
+		"data":       ruleSet,
+	})
+}
+
+// GetHealthRiskRuleHistory lists the saved rule set versions.
+func (c *HealthRiskController) GetHealthRiskRuleHistory(ctx *fiber.Ctx) error {
+	if allowed, err := checkHealthRiskRuleAccess(ctx, "read"); !allowed {
+		return err
+	}
+
+	currentPage, err := strconv.Atoi(ctx.Query("currentPage", "1"))
+	if err != nil || currentPage < 1 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid currentPage",
+			"data":       nil,
+		})
+	}
+
+	pageSize, err := strconv.Atoi(ctx.Query("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid pageSize",
+			"data":       nil,
+		})
+	}
+
+	result, err := c.Service.GetRuleHistory(pageSize, currentPage)
+	if err != nil {
+		return healthRiskErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Health risk rule history fetched successfully",
+		"data":       result,
+	})
+}
+
+// UpdateHealthRiskRules saves new thresholds as the next rule set version.
+func (c *HealthRiskController) UpdateHealthRiskRules(ctx *fiber.Ctx) error {
+	if allowed, err := checkHealthRiskRuleAccess(ctx, "update"); !allowed {
+		return err
+	}
+
+	payload := new(services.HealthRiskRulesPayload)
+	if err := ctx.BodyParser(payload); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid or empty input",
+			"data":       nil,
+		})
+	}
+
+	ruleSet, err := c.Service.SaveRules(payload, ctx.Locals("user_id").(int))
+	if err != nil {
+		return healthRiskErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"statusCode": fiber.StatusCreated,
+		"message":    "Health risk rules saved successfully",
+		"data":       ruleSet,
+	})
+}
+
+// EvaluateHealthRisk assesses a set of measurements without storing them, the same way new health data is assessed.
+func (c *HealthRiskController) EvaluateHealthRisk(ctx *fiber.Ctx) error {
+	if allowed, err := checkHealthRiskRuleAccess(ctx, "read"); !allowed {
+		return err
+	}
+
+	payload := new(services.HealthPayload)
+	if err := ctx.BodyParser(payload); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid or empty input",
+			"data":       nil,
+		})
+	}
+
+	assessment, err := c.Service.Evaluate(context.Background(), payload)
+	if err != nil {
+		return healthRiskErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Health risk assessed successfully",
+		"data":       assessment,
+	})
+}
@@ -0,0 +1,381 @@
+package controllers
+
+import (
+	"backend-school/helpers"
+	"backend-school/models"
+	"backend-school/services"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type NotificationTemplateController struct {
+	Service *services.NotificationService
+}
+
+func NewNotificationTemplateController() *NotificationTemplateController {
+	return &NotificationTemplateController{
+		Service: services.NewNotificationService(emailService, settingService),
+	}
+}
+
+// notificationTemplateError maps NotificationService errors to responses
+func notificationTemplateError(ctx *fiber.Ctx, err error) error {
+	status := fiber.StatusBadRequest
+	if errors.Is(err, services.ErrNotificationTemplateNotFound) {
+		status = fiber.StatusNotFound
+	}
+	return ctx.Status(status).JSON(fiber.Map{
+		"statusCode": status,
+		"message":    err.Error(),
+		"data":       nil,
+	})
+}
+
+// GetNotificationTemplates retrieves a paginated list of notification templates.
+func (c *NotificationTemplateController) GetNotificationTemplates(ctx *fiber.Ctx) error {
+	username := ctx.Locals("username").(string)
+
+	// Get the Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	// Check if the user has access to the "notification-template" resource using the "read" action
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "notification-template", "read", "none", "none", "none")
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access.",
+		})
+	}
+
+	if !hasAccess {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have access to this resource",
+		})
+	}
+
+	pageStr := ctx.Query("currentPage", "1")
+	pageSizeStr := ctx.Query("pageSize", "10")
+	event := ctx.Query("event", "")
+
+	currentPage, err := strconv.Atoi(pageStr)
+	if err != nil || currentPage < 1 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid currentPage",
+			"data":       nil,
+		})
+	}
+
+	pageSize, err := strconv.Atoi(pageSizeStr)
+	if err != nil || pageSize < 1 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid pageSize",
+			"data":       nil,
+		})
+	}
+
+	result, err := c.Service.GetTemplatesPaginated(pageSize, currentPage, event)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    err.Error(),
+			"data":       nil,
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Notification templates fetched successfully",
+		"data":       result,
+	})
+}
+
+// GetNotificationTemplateByUUID retrieves a notification template by UUID.
+func (c *NotificationTemplateController) GetNotificationTemplateByUUID(ctx *fiber.Ctx) error {
+	username := ctx.Locals("username").(string)
+
+	// Get the Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	// Check if the user has access to the "notification-template" resource using the "read" action
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "notification-template", "read", "none", "none", "none")
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access.",
+		})
+	}
+
+	if !hasAccess {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have access to this resource",
+		})
+	}
+
+	uuidParam := ctx.Params("uuid")
+	if _, err := uuid.Parse(uuidParam); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid UUID format",
+			"data":       nil,
+		})
+	}
+
+	template, err := c.Service.GetTemplateByUUID(uuidParam)
+	if err != nil {
+		return notificationTemplateError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Notification template fetched successfully",
+		"data":       template,
+	})
+}
+
+// CreateNotificationTemplate adds a template for an event and locale.
+func (c *NotificationTemplateController) CreateNotificationTemplate(ctx *fiber.Ctx) error {
+	username := ctx.Locals("username").(string)
+
+	// Get the Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	// Check if the user has access to the "notification-template" resource using the "create" action
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "notification-template", "create", "none", "none", "none")
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access.",
+		})
+	}
+
+	if !hasAccess {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have access to this resource",
+		})
+	}
+
+	// A template is active unless the body says otherwise
+	template := &models.NotificationTemplate{IsActive: true}
+	if err := ctx.BodyParser(template); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid or empty input",
+			"data":       nil,
+		})
+	}
+
+	if template.Event == "" || template.Subject == "" || template.Body == "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "event, subject and body are required",
+			"data":       nil,
+		})
+	}
+
+	userID, _ := ctx.Locals("user_id").(int)
+	template.CreatedBy = userID
+	template.UpdatedBy = userID
+
+	if err := c.Service.CreateTemplate(template); err != nil {
+		return notificationTemplateError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"statusCode": fiber.StatusCreated,
+		"message":    "Notification template created successfully",
+		"data":       template,
+	})
+}
+
+// UpdateNotificationTemplate edits the subject, body or active flag of a template.
+func (c *NotificationTemplateController) UpdateNotificationTemplate(ctx *fiber.Ctx) error {
+	username := ctx.Locals("username").(string)
+
+	// Get the Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	// Check if the user has access to the "notification-template" resource using the "update" action
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "notification-template", "update", "none", "none", "none")
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access.",
+		})
+	}
+
+	if !hasAccess {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have access to this resource",
+		})
+	}
+
+	uuidParam := ctx.Params("uuid")
+	if _, err := uuid.Parse(uuidParam); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid UUID format",
+			"data":       nil,
+		})
+	}
+
+	var payload services.NotificationTemplatePayload
+	if err := ctx.BodyParser(&payload); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid or empty input",
+			"data":       nil,
+		})
+	}
+
+	userID, _ := ctx.Locals("user_id").(int)
+	template, err := c.Service.UpdateTemplate(uuidParam, payload, userID)
+	if err != nil {
+		return notificationTemplateError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Notification template updated successfully",
+		"data":       template,
+	})
+}
+
+// DeleteNotificationTemplate removes a template.
+func (c *NotificationTemplateController) DeleteNotificationTemplate(ctx *fiber.Ctx) error {
+	username := ctx.Locals("username").(string)
+
+	// Get the Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	// Check if the user has access to the "notification-template" resource using the "delete" action
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "notification-template", "delete", "none", "none", "none")
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access.",
+		})
+	}
+
+	if !hasAccess {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have access to this resource",
+		})
+	}
+
+	uuidParam := ctx.Params("uuid")
+	if _, err := uuid.Parse(uuidParam); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid UUID format",
+			"data":       nil,
+		})
+	}
+
+	if err := c.Service.DeleteTemplate(uuidParam); err != nil {
+		return notificationTemplateError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Notification template deleted successfully",
+		"data":       nil,
+	})
+}
+
+// PreviewNotificationTemplate renders a stored template or an unsaved draft with sample or supplied data.
+func (c *NotificationTemplateController) PreviewNotificationTemplate(ctx *fiber.Ctx) error {
+	username := ctx.Locals("username").(string)
+
+	// Get the Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	// Check if the user has access to the "notification-template" resource using the "read" action
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "notification-template", "read", "none", "none", "none")
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access.",
+		})
+	}
+
+	if !hasAccess {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have access to this resource",
+		})
+	}
+
+	var req services.NotificationPreviewRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid or empty input",
+			"data":       nil,
+		})
+	}
+
+	rendered, err := c.Service.Preview(req)
+	if err != nil {
+		return notificationTemplateError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Notification preview rendered successfully",
+		"data":       rendered,
+	})
+}
+
+// TestSendNotificationTemplate renders a template and queues it for delivery to a test recipient.
+func (c *NotificationTemplateController) TestSendNotificationTemplate(ctx *fiber.Ctx) error {
+	username := ctx.Locals("username").(string)
+
+	// Get the Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	// Check if the user has access to the "notification-template" resource using the "update" action
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "notification-template", "update", "none", "none", "none")
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access.",
+		})
+	}
+
+	if !hasAccess {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have access to this resource",
+		})
+	}
+
+	var req services.NotificationPreviewRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid or empty input",
+			"data":       nil,
+		})
+	}
+
+	message, err := c.Service.TestSend(req)
+	if err != nil {
+		return notificationTemplateError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Test notification queued",
+		"data":       message,
+	})
+}
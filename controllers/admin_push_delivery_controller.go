@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"backend-school/helpers"
+	"backend-school/models"
+	"backend-school/services"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type PushDeliveryController struct {
+	Service *services.PushService
+}
+
+func NewPushDeliveryController() *PushDeliveryController {
+	return &PushDeliveryController{
+		Service: services.NewPushService(),
+	}
+}
+
+// GetPushDeliveries retrieves a paginated list of recorded push deliveries.
+func (c *PushDeliveryController) GetPushDeliveries(ctx *fiber.Ctx) error {
+	username := ctx.Locals("username").(string)
+
+	// Get the Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	// Check if the user has access to the "push-delivery" resource using the "read" action
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "push-delivery", "read", "none", "none", "none")
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access.",
+		})
+	}
+
+	if !hasAccess {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have access to this resource",
+		})
+	}
+
+	// Parse pagination and filter query parameters
+	pageStr := ctx.Query("currentPage", "1")
+	pageSizeStr := ctx.Query("pageSize", "10")
+	status := ctx.Query("status", "")
+
+	currentPage, err := strconv.Atoi(pageStr)
+	if err != nil || currentPage < 1 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid currentPage",
+			"data":       nil,
+		})
+	}
+
+	pageSize, err := strconv.Atoi(pageSizeStr)
+	if err != nil || pageSize < 1 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid pageSize",
+			"data":       nil,
+		})
+	}
+
+	switch status {
+	case "", models.PushStatusSent, models.PushStatusFailed, models.PushStatusInvalidToken:
+	default:
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid status",
+			"data":       nil,
+		})
+	}
+
+	result, err := c.Service.GetDeliveriesPaginated(pageSize, currentPage, status)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    err.Error(),
+			"data":       nil,
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Push deliveries fetched successfully",
+		"data":       result,
+	})
+}
@@ -20,7 +20,7 @@ func GetAllRolesHandler(c *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the requester has access to view the specific user's details
-	hasAccess, err := enforcer.Enforce(requesterUsername, "roles", "read", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(c, enforcer, requesterUsername, "roles", "read", "none", "none", "none")
 	if err != nil {
 		log.Printf("Error checking Casbin permissions: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -62,7 +62,7 @@ func GetPaginatedRolesHandler(ctx *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the user has access to the "roles" resource using the "GET" action
-	hasAccess, err := enforcer.Enforce(username, "roles", "read", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "roles", "read", "none", "none", "none")
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"statusCode": fiber.StatusInternalServerError,
@@ -135,7 +135,7 @@ func GetRoleByUUIDHandler(ctx *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the user has access to the "roles" resource using the "manage" action
-	hasAccess, err := enforcer.Enforce(username, "all-content", "manage", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "all-content", "manage", "none", "none", "none")
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"statusCode": fiber.StatusInternalServerError,
@@ -234,7 +234,7 @@ func CreateRoleHandler(ctx *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the user has access to the "roles" resource using the "GET" action
-	hasAccess, err := enforcer.Enforce(username, "roles", "create", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "roles", "create", "none", "none", "none")
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"statusCode": fiber.StatusInternalServerError,
@@ -303,7 +303,7 @@ func UpdateRoleByUUIDHandler(ctx *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the requester has access to view the specific user's details
-	hasAccess, err := enforcer.Enforce(requesterUsername, "roles", "update", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, requesterUsername, "roles", "update", "none", "none", "none")
 	if err != nil {
 		log.Printf("Error checking Casbin permissions: %v", err)
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -383,7 +383,7 @@ func DeleteRoleByUUIDHandler(ctx *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the requester has access to view the specific user's details
-	hasAccess, err := enforcer.Enforce(requesterUsername, "roles", "delete", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, requesterUsername, "roles", "delete", "none", "none", "none")
 	if err != nil {
 		log.Printf("Error checking Casbin permissions: %v", err)
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -439,7 +439,7 @@ func AddCasbinRuleHandlerBulk(ctx *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the requester has access to view the specific user's details
-	hasAccess, err := enforcer.Enforce(requesterUsername, "all-content", "manage", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, requesterUsername, "all-content", "manage", "none", "none", "none")
 	if err != nil {
 		log.Printf("Error checking Casbin permissions: %v", err)
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"backend-school/helpers"
+	"backend-school/models"
 	"backend-school/services"
 	"log"
 	"strconv"
@@ -67,10 +68,10 @@ func CreateRoleHasRuleHandler(ctx *fiber.Ctx) error {
 	}
 
 	// Validate required fields
-	if request.RoleGuardName == "" || request.RoleGuardName == "" || len(request.Rules) == 0 {
+	if request.RoleGuardName == "" || len(request.Rules) == 0 {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"statusCode": fiber.StatusBadRequest,
-			"message":    "RoleGuardName, RoleName, and Rules are required",
+			"message":    "RoleGuardName and Rules are required",
 		})
 	}
 
@@ -101,7 +102,7 @@ func GetRoleHasRulesListHandler(ctx *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the requester has access to view the specific user's details
-	hasAccess, err := enforcer.Enforce(requesterUsername, "rules", "read", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, requesterUsername, "rules", "read", "none", "none", "none")
 	if err != nil {
 		log.Printf("Error checking Casbin permissions: %v", err)
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -161,7 +162,7 @@ func GetPaginatedRoleHasRulesHandler(ctx *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the requester has access to view the specific user's details
-	hasAccess, err := enforcer.Enforce(requesterUsername, "rules", "read", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, requesterUsername, "rules", "read", "none", "none", "none")
 	if err != nil {
 		log.Printf("Error checking Casbin permissions: %v", err)
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -224,7 +225,7 @@ func UpdateRoleHasRuleByUUIDHandler(ctx *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the requester has access to view the specific user's details
-	hasAccess, err := enforcer.Enforce(requesterUsername, "rules", "update", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, requesterUsername, "rules", "update", "none", "none", "none")
 	if err != nil {
 		log.Printf("Error checking Casbin permissions: %v", err)
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -304,7 +305,7 @@ func DeleteRoleHasRuleByUUIDHandler(ctx *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the requester has access to view the specific user's details
-	hasAccess, err := enforcer.Enforce(requesterUsername, "rules", "delete", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, requesterUsername, "rules", "delete", "none", "none", "none")
 	if err != nil {
 		log.Printf("Error checking Casbin permissions: %v", err)
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -351,6 +352,75 @@ func DeleteRoleHasRuleByUUIDHandler(ctx *fiber.Ctx) error {
 	})
 }
 
+// UpdateRoleHasRuleConditionsHandler replaces the ABAC conditions (owner, ip_range, time_window) of a role_has_rule
+func UpdateRoleHasRuleConditionsHandler(ctx *fiber.Ctx) error {
+	requesterUsername := ctx.Locals("username").(string)
+
+	// Get Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	// Check if the requester has access to update rules
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, requesterUsername, "rules", "update", "none", "none", "none")
+	if err != nil {
+		log.Printf("Error checking Casbin permissions: %v", err)
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access permissions.",
+		})
+	}
+
+	// If the requester doesn't have access, return a forbidden status
+	if !hasAccess {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have permission to access this resource.",
+		})
+	}
+	// Get the UUID from the URL parameters
+	uuid := ctx.Params("uuid")
+
+	// Define a struct to hold the request body; an empty list removes every condition
+	type UpdateConditionsRequest struct {
+		Conditions []models.RoleHasRuleCondition `json:"conditions"`
+	}
+
+	// Parse the request body
+	var request UpdateConditionsRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid request payload",
+		})
+	}
+
+	// Call the service to replace the conditions
+	roleHasRule, err := services.NewPolicyService().SetRuleConditions(uuid, request.Conditions)
+	if err != nil {
+		if err.Error() == "invalid UUID format" {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"statusCode": fiber.StatusBadRequest,
+				"message":    "Invalid UUID format",
+			})
+		}
+		if err.Error() == "role_has_rule not found" {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"statusCode": fiber.StatusNotFound,
+				"message":    "RoleHasRule not found",
+			})
+		}
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "RoleHasRule conditions updated successfully",
+		"data":       roleHasRule,
+	})
+}
+
 // AddCasbinRuleHandler handles adding a new rule to Casbin
 func AddCasbinRuleHandler(ctx *fiber.Ctx) error {
 	requesterUsername := ctx.Locals("username").(string)
@@ -359,7 +429,7 @@ func AddCasbinRuleHandler(ctx *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the requester has access to view the specific user's details
-	hasAccess, err := enforcer.Enforce(requesterUsername, "rules", "create", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, requesterUsername, "rules", "create", "none", "none", "none")
 	if err != nil {
 		log.Printf("Error checking Casbin permissions: %v", err)
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -432,7 +502,7 @@ func DeleteCasbinRuleHandler(ctx *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the requester has access to view the specific user's details
-	hasAccess, err := enforcer.Enforce(requesterUsername, "rules", "delete", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, requesterUsername, "rules", "delete", "none", "none", "none")
 	if err != nil {
 		log.Printf("Error checking Casbin permissions: %v", err)
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -506,7 +576,7 @@ func GetUniqueRulePoliciesHandler(ctx *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the requester has access to view rule policies
-	hasAccess, err := enforcer.Enforce(requesterUsername, "rules", "read", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, requesterUsername, "rules", "read", "none", "none", "none")
 	if err != nil {
 		log.Printf("Error checking Casbin permissions: %v", err)
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -581,3 +651,85 @@ func CreateRoleHasRuleForAdminHandler(ctx *fiber.Ctx) error {
 		"data":       rule,
 	})
 }
+
+// GetPolicyDriftHandler reports the differences between role_has_rule and casbin_rule
+func GetPolicyDriftHandler(ctx *fiber.Ctx) error {
+	requesterUsername := ctx.Locals("username").(string)
+
+	// Get Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	// Check if the requester has access to read rules
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, requesterUsername, "rules", "read", "none", "none", "none")
+	if err != nil {
+		log.Printf("Error checking Casbin permissions: %v", err)
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access permissions.",
+		})
+	}
+
+	// If the requester doesn't have access, return a forbidden status
+	if !hasAccess {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have permission to access this resource.",
+		})
+	}
+
+	diff, err := services.NewPolicyService().DiffPolicies()
+	if err != nil {
+		log.Printf("Error comparing role_has_rule and casbin_rule: %v", err)
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to compare rule policies",
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Rule policy differences fetched successfully",
+		"data":       diff,
+	})
+}
+
+// ReconcilePoliciesHandler repairs drift between role_has_rule and casbin_rule on demand
+func ReconcilePoliciesHandler(ctx *fiber.Ctx) error {
+	requesterUsername := ctx.Locals("username").(string)
+
+	// Get Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	// Check if the requester has access to update rules
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, requesterUsername, "rules", "update", "none", "none", "none")
+	if err != nil {
+		log.Printf("Error checking Casbin permissions: %v", err)
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access permissions.",
+		})
+	}
+
+	// If the requester doesn't have access, return a forbidden status
+	if !hasAccess {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have permission to access this resource.",
+		})
+	}
+
+	diff, err := services.NewPolicyService().ReconcilePolicies()
+	if err != nil {
+		log.Printf("Error reconciling rule policies: %v", err)
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to reconcile rule policies",
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Rule policies reconciled successfully",
+		"data":       diff,
+	})
+}
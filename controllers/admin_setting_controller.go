@@ -4,8 +4,8 @@ import (
 	"backend-school/helpers"
 	"backend-school/models"
 	"backend-school/services"
+	"errors"
 	"fmt"
-	"log"
 	"strconv"
 
 	"github.com/go-playground/validator/v10"
@@ -24,22 +24,67 @@ var customErrorMessagesAdminSetting = map[string]string{
 }
 
 type AdminSettingController struct {
-	AdminSettingsService *services.AdminSettingsService
+	SettingsService *services.SettingsService
 }
 
-// NewAdminSettingController initializes the controller and handles any errors from NewAdminSettingsService
+// NewAdminSettingController initializes the controller with the shared settings service
 func NewAdminSettingController() *AdminSettingController {
-	settingService, err := services.NewAdminSettingsService()
-	if err != nil {
-		log.Fatalf("Failed to initialize setting service: %v", err) // Log and stop execution if there's an error
+	return &AdminSettingController{SettingsService: settingService}
+}
+
+// settingErrorResponse maps settings service errors to HTTP responses
+func settingErrorResponse(ctx *fiber.Ctx, err error) error {
+	// Values that don't match the setting type are client errors
+	var settingErr *services.SettingValidationError
+	if errors.As(err, &settingErr) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"status":     "error",
+			"message":    "Validation failed",
+			"errors":     settingErr.Errors,
+		})
+	}
+
+	status := fiber.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrSettingNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, services.ErrSettingKeyExists):
+		status = fiber.StatusConflict
 	}
-	return &AdminSettingController{AdminSettingsService: settingService}
+	return ctx.Status(status).JSON(fiber.Map{
+		"statusCode": status,
+		"status":     "error",
+		"message":    err.Error(),
+	})
 }
 
-// GetAdminSetting handles fetching a setting by slug
+// GetAdminSetting handles fetching a setting by key
 func (c *AdminSettingController) GetAdminSetting(ctx *fiber.Ctx) error {
-	slug := ctx.Params("slug")
-	setting, err := c.AdminSettingsService.GetAdminSettingBySlug(slug)
+	// Get the username from the context (set by the JWT middleware)
+	username := ctx.Locals("username").(string)
+
+	// Get the Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	// Check if the user has access to read settings
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "settings", "read", "none", "none", "none")
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access.",
+		})
+	}
+
+	if !hasAccess {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have access to this resource",
+		})
+	}
+
+	key := ctx.Params("key")
+	setting, err := c.SettingsService.GetSettingByKey(key)
 	if err != nil {
 		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{ // Use ctx.Status
 			"statusCode": fiber.StatusNotFound,
@@ -63,7 +108,7 @@ func (c *AdminSettingController) GetAdminSettingUUID(ctx *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the user has access to the "/admin" resource using the "GET" action
-	hasAccess, err := enforcer.Enforce(username, "settings", "read", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "settings", "read", "none", "none", "none")
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{ // Use ctx.Status
 			"statusCode": fiber.StatusInternalServerError,
@@ -90,7 +135,7 @@ func (c *AdminSettingController) GetAdminSettingUUID(ctx *fiber.Ctx) error {
 	}
 
 	// Memanggil service untuk mendapatkan data publikasi
-	setting, err := c.AdminSettingsService.GetAdminSettingByUUID(uuidParam)
+	setting, err := c.SettingsService.GetSettingByUUID(uuidParam)
 	if err != nil {
 		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"statusCode": fiber.StatusNotFound,
@@ -117,7 +162,7 @@ func (c *AdminSettingController) GetAdminSettingsPaginated(ctx *fiber.Ctx) error
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the user has access to the "/admin" resource using the "GET" action
-	hasAccess, err := enforcer.Enforce(username, "settings", "read", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "settings", "read", "none", "none", "none")
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{ // Use ctx.Status
 			"statusCode": fiber.StatusInternalServerError,
@@ -136,6 +181,7 @@ func (c *AdminSettingController) GetAdminSettingsPaginated(ctx *fiber.Ctx) error
 	pageStr := ctx.Query("currentPage", ctx.Query("page", "1"))
 	sortBy := ctx.Query("sortBy", "id")
 	sortDescStr := ctx.Query("sortDesc", "false")
+	namespace := ctx.Query("namespace", "")
 
 	perPage, err := strconv.Atoi(perPageStr)
 	if err != nil {
@@ -164,7 +210,7 @@ func (c *AdminSettingController) GetAdminSettingsPaginated(ctx *fiber.Ctx) error
 		})
 	}
 
-	settings, err := c.AdminSettingsService.GetAdminSettingsPaginated(perPage, page, sortBy, sortDesc)
+	settings, err := c.SettingsService.GetSettingsPaginated(perPage, page, sortBy, sortDesc, namespace)
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{ // Use ctx.Status
 			"statusCode": fiber.StatusInternalServerError,
@@ -180,7 +226,30 @@ func (c *AdminSettingController) GetAdminSettingsPaginated(ctx *fiber.Ctx) error
 	})
 }
 
+// CreateSetting handles creating a setting
 func (c *AdminSettingController) CreateSetting(ctx *fiber.Ctx) error {
+	// Get the username from the context (set by the JWT middleware)
+	username := ctx.Locals("username").(string)
+
+	// Get the Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	// Check if the user has access to create settings
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "settings", "create", "none", "none", "none")
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access.",
+		})
+	}
+
+	if !hasAccess {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have access to this resource",
+		})
+	}
+
 	// Parse request body into the AdminSetting model
 	setting := new(models.Setting)
 	if err := ctx.BodyParser(setting); err != nil {
@@ -236,19 +305,13 @@ func (c *AdminSettingController) CreateSetting(ctx *fiber.Ctx) error {
 		})
 	}
 
-	// Handle file upload (if present)
-	img, err := ctx.FormFile("image_url")
-	if err != nil && err != fiber.ErrUnprocessableEntity {
-		img = nil
-	}
+	// Record who created the setting
+	userID, _ := ctx.Locals("user_id").(int)
+	setting.CreatedBy = userID
+	setting.UpdatedBy = userID
 
-	// Create the setting with the uploaded image
-	if err := c.AdminSettingsService.CreateSetting(setting, img); err != nil {
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"statusCode": fiber.StatusInternalServerError,
-			"status":     "error",
-			"message":    err.Error(),
-		})
+	if err := c.SettingsService.CreateSetting(setting); err != nil {
+		return settingErrorResponse(ctx, err)
 	}
 
 	// Return a success response when the setting is created
@@ -268,7 +331,7 @@ func (c *AdminSettingController) UpdateSetting(ctx *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the user has access to update
-	hasAccess, err := enforcer.Enforce(username, "settings", "update", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "settings", "update", "none", "none", "none")
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"statusCode": fiber.StatusInternalServerError,
@@ -339,19 +402,13 @@ func (c *AdminSettingController) UpdateSetting(ctx *fiber.Ctx) error {
 		})
 	}
 
-	// Handle file upload (if present)
-	img, err := ctx.FormFile("img")
-	if err != nil && err != fiber.ErrUnprocessableEntity {
-		img = nil
-	}
+	// Record who changed the setting
+	userID, _ := ctx.Locals("user_id").(int)
+	updatedAdminSetting.UpdatedBy = userID
 
-	// Call the service layer to update the setting with the provided data and image
-	if err := c.AdminSettingsService.UpdateSetting(uuid, updatedAdminSetting, img); err != nil {
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"statusCode": fiber.StatusInternalServerError,
-			"status":     "error",
-			"message":    err.Error(),
-		})
+	// Call the service layer to update the setting and keep its previous value in history
+	if err := c.SettingsService.UpdateSetting(uuid, updatedAdminSetting); err != nil {
+		return settingErrorResponse(ctx, err)
 	}
 
 	// Return a success response when the setting is updated
@@ -363,7 +420,7 @@ func (c *AdminSettingController) UpdateSetting(ctx *fiber.Ctx) error {
 	})
 }
 
-// DeleteAdminSetting handles deleting a setting by slug
+// DeleteSetting handles deleting a setting by UUID
 func (c *AdminSettingController) DeleteSetting(ctx *fiber.Ctx) error {
 	// Get the username from the context (set by the JWT middleware)
 	username := ctx.Locals("username").(string) // Use ctx.Locals instead of c.Locals
@@ -372,7 +429,7 @@ func (c *AdminSettingController) DeleteSetting(ctx *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the user has access to the "/admin" resource using the "GET" action
-	hasAccess, err := enforcer.Enforce(username, "settings", "delete", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "settings", "delete", "none", "none", "none")
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{ // Use ctx.Status
 			"statusCode": fiber.StatusInternalServerError,
@@ -389,12 +446,10 @@ func (c *AdminSettingController) DeleteSetting(ctx *fiber.Ctx) error {
 
 	uuid := ctx.Params("uuid")
 
-	if err := c.AdminSettingsService.DeleteSetting(uuid); err != nil {
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{ // Use ctx.Status
-			"statusCode": fiber.StatusInternalServerError,
-			"message":    "Failed to delete setting",
-			"data":       nil,
-		})
+	userID, _ := ctx.Locals("user_id").(int)
+
+	if err := c.SettingsService.DeleteSetting(uuid, userID); err != nil {
+		return settingErrorResponse(ctx, err)
 	}
 
 	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{ // Use ctx.Status
@@ -403,3 +458,52 @@ func (c *AdminSettingController) DeleteSetting(ctx *fiber.Ctx) error {
 		"data":       nil,
 	})
 }
+
+// GetSettingHistory handles listing the previous values of a setting
+func (c *AdminSettingController) GetSettingHistory(ctx *fiber.Ctx) error {
+	// Get the username from the context (set by the JWT middleware)
+	username := ctx.Locals("username").(string)
+
+	// Get the Casbin enforcer
+	enforcer := helpers.GetCasbinEnforcer()
+
+	// Check if the user has access to read settings
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "settings", "read", "none", "none", "none")
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Failed to check access.",
+		})
+	}
+
+	if !hasAccess {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"statusCode": fiber.StatusForbidden,
+			"message":    "Forbidden: You don't have access to this resource",
+		})
+	}
+
+	uuidParam := ctx.Params("uuid")
+	if _, err := uuid.Parse(uuidParam); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid UUID format",
+			"data":       nil,
+		})
+	}
+
+	history, err := c.SettingsService.GetSettingHistory(uuidParam)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    err.Error(),
+			"data":       nil,
+		})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Setting history fetched successfully",
+		"data":       history,
+	})
+}
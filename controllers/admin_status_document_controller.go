@@ -29,7 +29,7 @@ func (c *StatusDocumentController) GetStatusDocuments(ctx *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the user has access to the "status-document" resource using the "read" action
-	hasAccess, err := enforcer.Enforce(username, "status-document", "read", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "status-document", "read", "none", "none", "none")
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"statusCode": fiber.StatusInternalServerError,
@@ -92,7 +92,7 @@ func (c *StatusDocumentController) CreateStatusDocument(ctx *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the user has access to create a "status-document"
-	hasAccess, err := enforcer.Enforce(username, "status-document", "create", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "status-document", "create", "none", "none", "none")
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"statusCode": fiber.StatusInternalServerError,
@@ -142,7 +142,7 @@ func (c *StatusDocumentController) GetStatusDocumentByUUID(ctx *fiber.Ctx) error
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the user has access to read a "status-document"
-	hasAccess, err := enforcer.Enforce(username, "status-document", "read", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "status-document", "read", "none", "none", "none")
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"statusCode": fiber.StatusInternalServerError,
@@ -201,7 +201,7 @@ func (c *StatusDocumentController) UpdateStatusDocument(ctx *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the user has access to update a "status-document"
-	hasAccess, err := enforcer.Enforce(username, "status-document", "update", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "status-document", "update", "none", "none", "none")
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"statusCode": fiber.StatusInternalServerError,
@@ -254,7 +254,7 @@ func (c *StatusDocumentController) DeleteStatusDocument(ctx *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the user has access to delete a "status-document"
-	hasAccess, err := enforcer.Enforce(username, "status-document", "delete", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "status-document", "delete", "none", "none", "none")
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"statusCode": fiber.StatusInternalServerError,
@@ -41,7 +41,7 @@ func (c *DocumentTypeController) GetDocumentTypes(ctx *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the user has access to the "document-type" resource with "read" action
-	hasAccess, err := enforcer.Enforce(username, "document-type", "read", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "document-type", "read", "none", "none", "none")
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"statusCode": fiber.StatusInternalServerError,
@@ -104,7 +104,7 @@ func (c *DocumentTypeController) CreateDocumentType(ctx *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the user has access to create a "document-type"
-	hasAccess, err := enforcer.Enforce(username, "document-type", "create", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "document-type", "create", "none", "none", "none")
 	if err != nil {
 		log.Printf("Casbin enforcement error: %v", err)
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -185,7 +185,7 @@ func (c *DocumentTypeController) GetDocumentTypeByUUID(ctx *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the user has access to read a "document-type"
-	hasAccess, err := enforcer.Enforce(username, "document-type", "read", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "document-type", "read", "none", "none", "none")
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"statusCode": fiber.StatusInternalServerError,
@@ -241,7 +241,7 @@ func (c *DocumentTypeController) UpdateDocumentType(ctx *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the user has access to update a "category-document"
-	hasAccess, err := enforcer.Enforce(username, "document-type", "update", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "document-type", "update", "none", "none", "none")
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"statusCode": fiber.StatusInternalServerError,
@@ -317,7 +317,7 @@ func (c *DocumentTypeController) DeleteDocumentType(ctx *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the user has access to delete a "document-type"
-	hasAccess, err := enforcer.Enforce(username, "document-type", "delete", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "document-type", "delete", "none", "none", "none")
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"statusCode": fiber.StatusInternalServerError,
@@ -211,7 +211,7 @@ func ForgotPasswordHandler(c *fiber.Ctx) error {
 	}
 
 	// Call the ForgotPassword service
-	if err := services.ForgotPassword(req.Email); err != nil {
+	if err := services.ForgotPassword(req.Email, req.Locale); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"statusCode": fiber.StatusInternalServerError,
 			"message":    err.Error(),
@@ -259,7 +259,7 @@ func GetAllUsersPaginated(ctx *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the user has access to the "users" resource using the "GET" action
-	hasAccess, err := enforcer.Enforce(username, "users", "read", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(ctx, enforcer, username, "users", "read", "none", "none", "none")
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"statusCode": fiber.StatusInternalServerError,
@@ -336,7 +336,7 @@ func GetUserDetailByUUID(c *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the requester has access to view the specific user's details
-	hasAccess, err := enforcer.Enforce(requesterUsername, "users", "read", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(c, enforcer, requesterUsername, "users", "read", "none", "none", "none")
 	if err != nil {
 		log.Printf("Error checking Casbin permissions: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -406,7 +406,7 @@ func AddUserRoleByUUIDHandler(c *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the requester has access to view the specific user's details
-	hasAccess, err := enforcer.Enforce(requesterUsername, "users", "create", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(c, enforcer, requesterUsername, "users", "create", "none", "none", "none")
 	if err != nil {
 		log.Printf("Error checking Casbin permissions: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -477,7 +477,7 @@ func DeleteUserRoleByUUIDHandler(c *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the requester has access to view the specific user's details
-	hasAccess, err := enforcer.Enforce(requesterUsername, "users", "delete", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(c, enforcer, requesterUsername, "users", "delete", "none", "none", "none")
 	if err != nil {
 		log.Printf("Error checking Casbin permissions: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -541,7 +541,7 @@ func CreateUserByAdminHandler(c *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the requester has access to view the specific user's details
-	hasAccess, err := enforcer.Enforce(requesterUsername, "users", "create", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(c, enforcer, requesterUsername, "users", "create", "none", "none", "none")
 	if err != nil {
 		log.Printf("Error checking Casbin permissions: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -724,7 +724,7 @@ func UpdateUserByAdminHandler(c *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the requester has access to view the specific user's details
-	hasAccess, err := enforcer.Enforce(requesterUsername, "users", "update", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(c, enforcer, requesterUsername, "users", "update", "none", "none", "none")
 	if err != nil {
 		log.Printf("Error checking Casbin permissions: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -797,7 +797,7 @@ func DeleteUserByAdminHandler(c *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the requester has access to view the specific user's details
-	hasAccess, err := enforcer.Enforce(requesterUsername, "users", "delete", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(c, enforcer, requesterUsername, "users", "delete", "none", "none", "none")
 	if err != nil {
 		log.Printf("Error checking Casbin permissions: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -840,7 +840,7 @@ func ActivateUserHandler(c *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the requester has access to view the specific user's details
-	hasAccess, err := enforcer.Enforce(requesterUsername, "users", "update", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(c, enforcer, requesterUsername, "users", "update", "none", "none", "none")
 	if err != nil {
 		log.Printf("Error checking Casbin permissions: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -883,7 +883,7 @@ func DeactivateUserHandler(c *fiber.Ctx) error {
 	enforcer := helpers.GetCasbinEnforcer()
 
 	// Check if the requester has access to view the specific user's details
-	hasAccess, err := enforcer.Enforce(requesterUsername, "users", "update", "none", "none", "none")
+	hasAccess, err := helpers.EnforceRequest(c, enforcer, requesterUsername, "users", "update", "none", "none", "none")
 	if err != nil {
 		log.Printf("Error checking Casbin permissions: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
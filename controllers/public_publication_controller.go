@@ -412,7 +412,6 @@ func (c *PublicationController) UpdatePublication(ctx *fiber.Ctx) error {
 			"message":    "Invalid or empty input",
 		})
 	}
-	fmt.Printf("updatedPublication", updatedPublication)
 
 	// Validate the input data
 	if err := validate.Struct(updatedPublication); err != nil {
@@ -423,8 +422,6 @@ func (c *PublicationController) UpdatePublication(ctx *fiber.Ctx) error {
 		for _, err := range validationErrors {
 			field := err.Field() // Field that failed validation
 			tag := err.Tag()     // The validation rule (e.g., 'required', 'min', 'numeric')
-			// Debugging output
-			fmt.Printf("Validation failed for field: %s with rule: %s\n", field, tag)
 
 			// Construct error key using field and tag, e.g., "Title.required"
 			errorKey := fmt.Sprintf("%s.%s", field, tag)
@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"backend-school/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// DeviceTokenRequest is the payload for registering or removing a push token
+type DeviceTokenRequest struct {
+	Token    string `json:"token"`
+	Platform string `json:"platform"`
+}
+
+type UserDeviceController struct {
+	Service *services.PushService
+}
+
+func NewUserDeviceController() *UserDeviceController {
+	return &UserDeviceController{
+		Service: services.NewPushService(),
+	}
+}
+
+// GetDevices lists the current user's registered push devices.
+func (c *UserDeviceController) GetDevices(ctx *fiber.Ctx) error {
+	userID, _ := ctx.Locals("user_id").(int)
+
+	devices, err := c.Service.GetDevices(userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    err.Error(),
+			"data":       nil,
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Devices fetched successfully",
+		"data":       devices,
+	})
+}
+
+// RegisterDevice registers a push token for the current user.
+func (c *UserDeviceController) RegisterDevice(ctx *fiber.Ctx) error {
+	userID, _ := ctx.Locals("user_id").(int)
+
+	var req DeviceTokenRequest
+	if err := ctx.BodyParser(&req); err != nil || req.Token == "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "token is required",
+			"data":       nil,
+		})
+	}
+
+	switch req.Platform {
+	case "", "android", "ios", "web":
+	default:
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "platform must be one of the following: android ios web",
+			"data":       nil,
+		})
+	}
+
+	device, err := c.Service.RegisterDevice(userID, req.Token, req.Platform)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    err.Error(),
+			"data":       nil,
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Device registered successfully",
+		"data":       device,
+	})
+}
+
+// UnregisterDevice removes a push token of the current user, e.g. on logout.
+func (c *UserDeviceController) UnregisterDevice(ctx *fiber.Ctx) error {
+	userID, _ := ctx.Locals("user_id").(int)
+
+	var req DeviceTokenRequest
+	if err := ctx.BodyParser(&req); err != nil || req.Token == "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "token is required",
+			"data":       nil,
+		})
+	}
+
+	if err := c.Service.UnregisterDevice(userID, req.Token); err != nil {
+		status := fiber.StatusInternalServerError
+		if err.Error() == "device not found" {
+			status = fiber.StatusNotFound
+		}
+		return ctx.Status(status).JSON(fiber.Map{
+			"statusCode": status,
+			"message":    err.Error(),
+			"data":       nil,
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Device unregistered successfully",
+		"data":       nil,
+	})
+}
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"backend-school/services"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type UserDocumentAcknowledgementController struct {
+	Service *services.DocumentDistributionService
+}
+
+func NewUserDocumentAcknowledgementController() *UserDocumentAcknowledgementController {
+	return &UserDocumentAcknowledgementController{
+		Service: services.NewDocumentDistributionService(),
+	}
+}
+
+// GetMyAcknowledgements lists the documents the current user has been asked to read.
+func (c *UserDocumentAcknowledgementController) GetMyAcknowledgements(ctx *fiber.Ctx) error {
+	userID, _ := ctx.Locals("user_id").(int)
+
+	pageStr := ctx.Query("currentPage", "1")
+	pageSizeStr := ctx.Query("pageSize", "10")
+	pendingOnly := ctx.Query("pending", "false") == "true"
+
+	currentPage, err := strconv.Atoi(pageStr)
+	if err != nil || currentPage < 1 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid currentPage",
+			"data":       nil,
+		})
+	}
+
+	pageSize, err := strconv.Atoi(pageSizeStr)
+	if err != nil || pageSize < 1 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid pageSize",
+			"data":       nil,
+		})
+	}
+
+	result, err := c.Service.GetMyAcknowledgementsPaginated(userID, pageSize, currentPage, pendingOnly)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    err.Error(),
+			"data":       nil,
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Acknowledgements fetched successfully",
+		"data":       result,
+	})
+}
+
+// AcknowledgeDocument confirms that the current user has read the document version of a task.
+func (c *UserDocumentAcknowledgementController) AcknowledgeDocument(ctx *fiber.Ctx) error {
+	userID, _ := ctx.Locals("user_id").(int)
+
+	uuidParam := ctx.Params("uuid")
+	if _, err := uuid.Parse(uuidParam); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid UUID format",
+			"data":       nil,
+		})
+	}
+
+	acknowledgement, err := c.Service.Acknowledge(userID, uuidParam)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, services.ErrAcknowledgementNotFound) {
+			status = fiber.StatusNotFound
+		}
+		return ctx.Status(status).JSON(fiber.Map{
+			"statusCode": status,
+			"message":    err.Error(),
+			"data":       nil,
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Document acknowledged successfully",
+		"data":       acknowledgement,
+	})
+}
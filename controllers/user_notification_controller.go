@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"backend-school/services"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// notificationKeepAliveInterval keeps idle event streams open through proxies
+const notificationKeepAliveInterval = 25 * time.Second
+
+type UserNotificationController struct {
+	Service *services.InAppNotificationService
+}
+
+func NewUserNotificationController() *UserNotificationController {
+	return &UserNotificationController{
+		Service: services.NewInAppNotificationService(),
+	}
+}
+
+// GetNotifications lists the current user's notifications.
+func (c *UserNotificationController) GetNotifications(ctx *fiber.Ctx) error {
+	userID, _ := ctx.Locals("user_id").(int)
+
+	pageStr := ctx.Query("currentPage", "1")
+	pageSizeStr := ctx.Query("pageSize", "10")
+	unreadOnly := ctx.Query("unread", "false") == "true"
+
+	currentPage, err := strconv.Atoi(pageStr)
+	if err != nil || currentPage < 1 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid currentPage",
+			"data":       nil,
+		})
+	}
+
+	pageSize, err := strconv.Atoi(pageSizeStr)
+	if err != nil || pageSize < 1 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid pageSize",
+			"data":       nil,
+		})
+	}
+
+	result, err := c.Service.GetNotificationsPaginated(userID, pageSize, currentPage, unreadOnly)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    err.Error(),
+			"data":       nil,
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Notifications fetched successfully",
+		"data":       result,
+	})
+}
+
+// MarkNotificationRead marks one notification of the current user as read.
+func (c *UserNotificationController) MarkNotificationRead(ctx *fiber.Ctx) error {
+	userID, _ := ctx.Locals("user_id").(int)
+
+	uuidParam := ctx.Params("uuid")
+	if _, err := uuid.Parse(uuidParam); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"statusCode": fiber.StatusBadRequest,
+			"message":    "Invalid UUID format",
+			"data":       nil,
+		})
+	}
+
+	notification, err := c.Service.MarkRead(userID, uuidParam)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, services.ErrNotificationNotFound) {
+			status = fiber.StatusNotFound
+		}
+		return ctx.Status(status).JSON(fiber.Map{
+			"statusCode": status,
+			"message":    err.Error(),
+			"data":       nil,
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Notification marked as read",
+		"data":       notification,
+	})
+}
+
+// MarkAllNotificationsRead marks every unread notification of the current user as read.
+func (c *UserNotificationController) MarkAllNotificationsRead(ctx *fiber.Ctx) error {
+	userID, _ := ctx.Locals("user_id").(int)
+
+	updated, err := c.Service.MarkAllRead(userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    err.Error(),
+			"data":       nil,
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Notifications marked as read",
+		"data":       fiber.Map{"updated": updated},
+	})
+}
+
+// IssueNotificationStreamToken returns a stream token for opening the notification stream with EventSource, which
+// cannot send an Authorization header: GET /api/notifications/stream?stream_token=<token>. The token expires after
+// a minute, so a reconnect needs a new one.
+func (c *UserNotificationController) IssueNotificationStreamToken(ctx *fiber.Ctx) error {
+	username, _ := ctx.Locals("username").(string)
+
+	token, expiresAt, err := services.IssueStreamToken(username)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    err.Error(),
+			"data":       nil,
+		})
+	}
+
+	// The token grants access, so it must not be cached
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+	return ctx.JSON(fiber.Map{
+		"statusCode": fiber.StatusOK,
+		"message":    "Stream token issued",
+		"data":       fiber.Map{"stream_token": token, "expires_at": expiresAt},
+	})
+}
+
+// StreamNotifications pushes the current user's new notifications as Server-Sent Events.
+func (c *UserNotificationController) StreamNotifications(ctx *fiber.Ctx) error {
+	userID, _ := ctx.Locals("user_id").(int)
+
+	ctx.Set("Content-Type", "text/event-stream")
+	ctx.Set("Cache-Control", "no-cache")
+	ctx.Set("Connection", "keep-alive")
+	ctx.Set("X-Accel-Buffering", "no")
+
+	notifications, unsubscribe := services.SubscribeNotifications(userID)
+
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer unsubscribe()
+
+		keepAlive := time.NewTicker(notificationKeepAliveInterval)
+		defer keepAlive.Stop()
+
+		fmt.Fprint(w, "event: ready\ndata: {}\n\n")
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		for {
+			select {
+			case notification, ok := <-notifications:
+				if !ok {
+					return
+				}
+				payload, err := json.Marshal(notification)
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(w, "id: %s\nevent: notification\ndata: %s\n\n", notification.UUID, payload)
+			case <-keepAlive.C:
+				fmt.Fprint(w, ": keep-alive\n\n")
+			}
+
+			// Flush fails once the client has disconnected
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+
+	return nil
+}
@@ -8,7 +8,8 @@ type LoginRequest struct {
 }
 
 type ForgotPasswordRequest struct {
-	Email string `json:"email" validate:"required,email"`
+	Email  string `json:"email" validate:"required,email"`
+	Locale string `json:"locale"`
 }
 
 type ResetPasswordRequest struct {
@@ -1,12 +1,12 @@
 package helpers
 
 import (
-	"log"
-	"sync"
 	"backend-school/config"
+	"backend-school/models"
 	"github.com/casbin/casbin/v2"
 	"github.com/casbin/gorm-adapter/v3"
-  "backend-school/models" 
+	"log"
+	"sync"
 )
 
 var enforcerInstance *casbin.Enforcer
@@ -27,12 +27,20 @@ func GetCasbinEnforcer() *casbin.Enforcer {
 			log.Fatalf("Failed to load Casbin enforcer: %v", err)
 		}
 
+		// Register the ABAC condition function used by the matchers
+		RegisterPolicyFunctions(enforcer)
+
 		// Load policies from DB
 		err = enforcer.LoadPolicy()
 		if err != nil {
 			log.Fatalf("Failed to load Casbin policies: %v", err)
 		}
 
+		// Load the conditions attached to those policies
+		if err := LoadPolicyConditions(); err != nil {
+			log.Fatalf("%v", err)
+		}
+
 		enforcerInstance = enforcer
 	})
 
@@ -0,0 +1,290 @@
+package helpers
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/font"
+)
+
+// PDF table layout, in points, on a landscape A4 page
+const (
+	pdfTablePageWidth  = 842.0
+	pdfTablePageHeight = 595.0
+	pdfTableMargin     = 30.0
+	pdfTableFontSize   = 8
+	pdfTableLeading    = 10.0
+	pdfTablePadding    = 3.0
+	pdfTableMaxLines   = 6 // longer cell text is cut off with an ellipsis
+)
+
+// PDFTableColumn is a column of a PDF table; widths are relative and scaled to the page
+type PDFTableColumn struct {
+	Title string
+	Width float64
+}
+
+// PDFTableWriter streams a table into a PDF one row at a time. Each page is written out as soon as it is full,
+// so memory use does not grow with the number of rows.
+type PDFTableWriter struct {
+	out      *bufio.Writer
+	written  int64
+	offsets  map[int]int64
+	nextID   int
+	pageIDs  []int
+	title    string
+	subtitle string
+	columns  []PDFTableColumn
+	widths   []float64
+	page     bytes.Buffer
+	pageOpen bool
+	y        float64
+	err      error
+}
+
+// PDF object numbers reserved up front; pages get the numbers after these
+const (
+	pdfCatalogID = iota + 1
+	pdfPagesID
+	pdfFontID
+	pdfBoldFontID
+	pdfFirstFreeID
+)
+
+// NewPDFTableWriter starts a PDF whose pages show title, subtitle and the column headers above the rows
+func NewPDFTableWriter(w io.Writer, title, subtitle string, columns []PDFTableColumn) *PDFTableWriter {
+	t := &PDFTableWriter{
+		out:      bufio.NewWriter(w),
+		offsets:  map[int]int64{},
+		nextID:   pdfFirstFreeID,
+		title:    title,
+		subtitle: subtitle,
+		columns:  columns,
+	}
+
+	// Scale the relative widths to the printable width
+	var total float64
+	for _, column := range columns {
+		total += column.Width
+	}
+	for _, column := range columns {
+		t.widths = append(t.widths, column.Width/total*(pdfTablePageWidth-2*pdfTableMargin))
+	}
+
+	t.write("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	t.object(pdfFontID, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	t.object(pdfBoldFontID, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	return t
+}
+
+func (t *PDFTableWriter) write(s string) {
+	if t.err != nil {
+		return
+	}
+	n, err := t.out.WriteString(s)
+	t.written += int64(n)
+	t.err = err
+}
+
+// object writes an indirect object and records its offset for the cross-reference table
+func (t *PDFTableWriter) object(id int, body string) {
+	t.offsets[id] = t.written
+	t.write(fmt.Sprintf("%d 0 obj\n%s\nendobj\n", id, body))
+}
+
+// pdfText converts text to WinAnsi for the standard fonts; characters outside Latin-1 become "?"
+func pdfText(text string) string {
+	var b strings.Builder
+	for _, r := range strings.Join(strings.Fields(text), " ") {
+		if r > 0xff || (r >= 0x80 && r < 0xa0) {
+			r = '?'
+		}
+		b.WriteByte(byte(r))
+	}
+	return b.String()
+}
+
+// pdfString escapes WinAnsi text for a PDF string literal
+func pdfString(text string) string {
+	return "(" + strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(text) + ")"
+}
+
+// wrapPDFText breaks WinAnsi text into lines no wider than width, cutting words that do not fit on a line
+func wrapPDFText(text, fontName string, width float64) []string {
+	fits := func(s string) bool { return font.TextWidth(s, fontName, pdfTableFontSize) <= width }
+
+	var lines []string
+	line := ""
+	for _, word := range strings.Split(text, " ") {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if fits(candidate) {
+			line = candidate
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+		for !fits(word) && len(word) > 1 {
+			cut := len(word) - 1
+			for cut > 1 && !fits(word[:cut]) {
+				cut--
+			}
+			lines = append(lines, word[:cut])
+			word = word[cut:]
+		}
+		line = word
+	}
+	if line != "" || len(lines) == 0 {
+		lines = append(lines, line)
+	}
+
+	if len(lines) > pdfTableMaxLines {
+		lines = lines[:pdfTableMaxLines]
+		last := lines[pdfTableMaxLines-1]
+		for last != "" && !fits(last+"...") {
+			last = last[:len(last)-1]
+		}
+		lines[pdfTableMaxLines-1] = last + "..."
+	}
+	return lines
+}
+
+// textAt draws one line of text with its baseline at x, y
+func (t *PDFTableWriter) textAt(fontRef string, size int, x, y float64, text string) {
+	fmt.Fprintf(&t.page, "BT /%s %d Tf %.2f %.2f Td %s Tj ET\n", fontRef, size, x, y, pdfString(text))
+}
+
+// row draws a table row at the current position and moves below it
+func (t *PDFTableWriter) row(cells [][]string, fontRef string, shaded bool) {
+	lineCount := 1
+	for _, lines := range cells {
+		if len(lines) > lineCount {
+			lineCount = len(lines)
+		}
+	}
+	height := float64(lineCount)*pdfTableLeading + 2*pdfTablePadding
+
+	if shaded {
+		fmt.Fprintf(&t.page, "0.9 g %.2f %.2f %.2f %.2f re f 0 g\n", pdfTableMargin, t.y-height, pdfTablePageWidth-2*pdfTableMargin, height)
+	}
+
+	x := pdfTableMargin
+	for i, lines := range cells {
+		for j, line := range lines {
+			t.textAt(fontRef, pdfTableFontSize, x+pdfTablePadding, t.y-pdfTablePadding-float64(j+1)*pdfTableLeading+2, line)
+		}
+		x += t.widths[i]
+	}
+	fmt.Fprintf(&t.page, "0.75 G %.2f %.2f m %.2f %.2f l S 0 G\n", pdfTableMargin, t.y-height, pdfTablePageWidth-pdfTableMargin, t.y-height)
+	t.y -= height
+}
+
+// wrapCells lays out the values of a row in their columns
+func (t *PDFTableWriter) wrapCells(values []string, fontName string) [][]string {
+	cells := make([][]string, len(t.columns))
+	for i := range t.columns {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		cells[i] = wrapPDFText(pdfText(value), fontName, t.widths[i]-2*pdfTablePadding)
+	}
+	return cells
+}
+
+// startPage begins a page with the title and the column headers
+func (t *PDFTableWriter) startPage() {
+	t.page.Reset()
+	t.pageOpen = true
+	t.y = pdfTablePageHeight - pdfTableMargin
+
+	t.textAt("F2", 14, pdfTableMargin, t.y-14, pdfText(t.title))
+	t.textAt("F1", pdfTableFontSize, pdfTableMargin, t.y-28, pdfText(t.subtitle))
+	t.y -= 38
+
+	titles := make([]string, len(t.columns))
+	for i, column := range t.columns {
+		titles[i] = column.Title
+	}
+	t.row(t.wrapCells(titles, "Helvetica-Bold"), "F2", true)
+}
+
+// finishPage writes the current page with its page number
+func (t *PDFTableWriter) finishPage() {
+	if !t.pageOpen {
+		return
+	}
+	t.pageOpen = false
+	t.textAt("F1", pdfTableFontSize, pdfTableMargin, pdfTableMargin/2, fmt.Sprintf("Page %d", len(t.pageIDs)+1))
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write(t.page.Bytes())
+	zw.Close()
+
+	contentID, pageID := t.nextID, t.nextID+1
+	t.nextID += 2
+	t.offsets[contentID] = t.written
+	t.write(fmt.Sprintf("%d 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", contentID, compressed.Len()))
+	t.write(compressed.String())
+	t.write("\nendstream\nendobj\n")
+	t.object(pageID, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.0f %.0f] /Contents %d 0 R "+
+		"/Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> >>",
+		pdfPagesID, pdfTablePageWidth, pdfTablePageHeight, contentID, pdfFontID, pdfBoldFontID))
+	t.pageIDs = append(t.pageIDs, pageID)
+}
+
+// WriteRow adds a row, starting a new page when it does not fit on the current one
+func (t *PDFTableWriter) WriteRow(values []string) error {
+	cells := t.wrapCells(values, "Helvetica")
+	lineCount := 1
+	for _, lines := range cells {
+		if len(lines) > lineCount {
+			lineCount = len(lines)
+		}
+	}
+	height := float64(lineCount)*pdfTableLeading + 2*pdfTablePadding
+
+	if !t.pageOpen {
+		t.startPage()
+	} else if t.y-height < pdfTableMargin {
+		t.finishPage()
+		t.startPage()
+	}
+	t.row(cells, "F1", false)
+	return t.err
+}
+
+// Close writes the last page, the page tree and the trailer
+func (t *PDFTableWriter) Close() error {
+	if !t.pageOpen && len(t.pageIDs) == 0 {
+		t.startPage()
+	}
+	t.finishPage()
+
+	kids := make([]string, len(t.pageIDs))
+	for i, id := range t.pageIDs {
+		kids[i] = fmt.Sprintf("%d 0 R", id)
+	}
+	t.object(pdfPagesID, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(t.pageIDs)))
+	t.object(pdfCatalogID, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pdfPagesID))
+
+	xref := t.written
+	t.write(fmt.Sprintf("xref\n0 %d\n0000000000 65535 f \n", t.nextID))
+	for id := 1; id < t.nextID; id++ {
+		t.write(fmt.Sprintf("%010d 00000 n \n", t.offsets[id]))
+	}
+	t.write(fmt.Sprintf("trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", t.nextID, pdfCatalogID, xref))
+
+	if t.err != nil {
+		return t.err
+	}
+	return t.out.Flush()
+}
@@ -0,0 +1,240 @@
+package helpers
+
+import (
+	"backend-school/config"
+	"backend-school/models"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequestAttributes holds the request attributes that ABAC conditions are evaluated against
+type RequestAttributes struct {
+	UserID   int
+	ClientIP string
+	Time     time.Time
+	OwnerID  int // owner of the resource being accessed, 0 when not applicable
+}
+
+// attributeEnforceContext selects the r2/m2 definitions of the Casbin model, which carry RequestAttributes
+var attributeEnforceContext = casbin.EnforceContext{RType: "r2", PType: "p", EType: "e", MType: "m2"}
+
+var (
+	conditionMu    sync.RWMutex
+	conditionCache = map[string][]models.RoleHasRuleCondition{}
+)
+
+func conditionKey(sub, obj, act, cat, typ string) string {
+	if cat == "" {
+		cat = "none"
+	}
+	if typ == "" {
+		typ = "none"
+	}
+	return fmt.Sprintf("%s|%s|%s|%s|%s", sub, obj, act, cat, typ)
+}
+
+// LoadPolicyConditions refreshes the in-memory copy of role_has_rule_condition used by the Casbin matcher
+func LoadPolicyConditions() error {
+	var rules []models.RoleHasRule
+	if err := config.DB.Preload("Conditions").
+		Where("id IN (?)", config.DB.Model(&models.RoleHasRuleCondition{}).Select("role_has_rule_id")).
+		Find(&rules).Error; err != nil {
+		return fmt.Errorf("failed to load policy conditions: %w", err)
+	}
+
+	cache := make(map[string][]models.RoleHasRuleCondition, len(rules))
+	for _, rule := range rules {
+		key := conditionKey(rule.RoleGuardName, rule.RulePolicy, rule.Action, rule.Category, rule.Type)
+		cache[key] = append(cache[key], rule.Conditions...)
+	}
+
+	conditionMu.Lock()
+	conditionCache = cache
+	conditionMu.Unlock()
+	return nil
+}
+
+// ValidatePolicyCondition checks that a condition has a known type and a well-formed value
+func ValidatePolicyCondition(condition models.RoleHasRuleCondition) error {
+	switch condition.ConditionType {
+	case models.ConditionTypeOwner:
+		return nil
+	case models.ConditionTypeIPRange:
+		if _, err := parseCIDRs(condition.Value); err != nil {
+			return err
+		}
+		return nil
+	case models.ConditionTypeTimeWindow:
+		if _, _, err := parseTimeWindow(condition.Value); err != nil {
+			return err
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported condition type: %s", condition.ConditionType)
+	}
+}
+
+// parseCIDRs parses a comma-separated list of CIDRs; bare IPs are treated as single-host ranges
+func parseCIDRs(value string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if !strings.Contains(part, "/") {
+			ip := net.ParseIP(part)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address: %s", part)
+			}
+			if ip.To4() != nil {
+				part += "/32"
+			} else {
+				part += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR: %s", part)
+		}
+		networks = append(networks, network)
+	}
+	if len(networks) == 0 {
+		return nil, errors.New("ip_range condition requires at least one CIDR")
+	}
+	return networks, nil
+}
+
+// parseTimeWindow parses "HH:MM-HH:MM" into minutes since midnight
+func parseTimeWindow(value string) (int, int, error) {
+	parts := strings.Split(value, "-")
+	if len(parts) != 2 {
+		return 0, 0, errors.New("time_window must be formatted as HH:MM-HH:MM")
+	}
+	var minutes [2]int
+	for i, part := range parts {
+		t, err := time.Parse("15:04", strings.TrimSpace(part))
+		if err != nil {
+			return 0, 0, errors.New("time_window must be formatted as HH:MM-HH:MM")
+		}
+		minutes[i] = t.Hour()*60 + t.Minute()
+	}
+	return minutes[0], minutes[1], nil
+}
+
+// conditionHolds evaluates a single condition; malformed conditions never hold
+func conditionHolds(condition models.RoleHasRuleCondition, attrs RequestAttributes) bool {
+	switch condition.ConditionType {
+	case models.ConditionTypeOwner:
+		return attrs.UserID != 0 && attrs.OwnerID == attrs.UserID
+	case models.ConditionTypeIPRange:
+		ip := net.ParseIP(attrs.ClientIP)
+		if ip == nil {
+			return false
+		}
+		networks, err := parseCIDRs(condition.Value)
+		if err != nil {
+			return false
+		}
+		for _, network := range networks {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	case models.ConditionTypeTimeWindow:
+		start, end, err := parseTimeWindow(condition.Value)
+		if err != nil {
+			return false
+		}
+		now := attrs.Time
+		if now.IsZero() {
+			now = time.Now()
+		}
+		current := now.Hour()*60 + now.Minute()
+		if start <= end {
+			return current >= start && current < end
+		}
+		// Window wraps around midnight, e.g. 22:00-06:00
+		return current >= start || current < end
+	default:
+		return false
+	}
+}
+
+// policyConditions is registered as a Casbin function. It is called with the policy fields
+// (sub, obj, act, cat, type) and, for attribute-aware checks, the RequestAttributes.
+// Policies without conditions always match; conditioned policies need their attributes to satisfy every condition.
+func policyConditions(args ...interface{}) (interface{}, error) {
+	if len(args) < 5 {
+		return false, errors.New("policyConditions expects at least 5 arguments")
+	}
+
+	fields := make([]string, 5)
+	for i := 0; i < 5; i++ {
+		fields[i], _ = args[i].(string)
+	}
+
+	conditionMu.RLock()
+	conditions := conditionCache[conditionKey(fields[0], fields[1], fields[2], fields[3], fields[4])]
+	conditionMu.RUnlock()
+
+	if len(conditions) == 0 {
+		return true, nil
+	}
+
+	var attrs RequestAttributes
+	if len(args) > 5 {
+		attrs, _ = args[5].(RequestAttributes)
+	}
+
+	for _, condition := range conditions {
+		if !conditionHolds(condition, attrs) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// RegisterPolicyFunctions adds the custom functions used by config/casbin_model.conf to an enforcer
+func RegisterPolicyFunctions(enforcer *casbin.Enforcer) {
+	enforcer.AddFunction("policyConditions", policyConditions)
+}
+
+// RequestAttributesFromCtx collects the caller, client IP and time of the current request
+func RequestAttributesFromCtx(ctx *fiber.Ctx) RequestAttributes {
+	userID, _ := ctx.Locals("user_id").(int)
+	return RequestAttributes{
+		UserID:   userID,
+		ClientIP: ctx.IP(),
+		Time:     time.Now(),
+	}
+}
+
+// EnforceWithAttributes runs an attribute-aware Casbin check so ABAC conditions can be evaluated
+func EnforceWithAttributes(enforcer *casbin.Enforcer, attrs RequestAttributes, sub, obj, act, cat, typ, docid string) (bool, error) {
+	return enforcer.Enforce(attributeEnforceContext, sub, obj, act, cat, typ, docid, attrs)
+}
+
+// EnforceRequest is EnforceWithAttributes for the current request
+func EnforceRequest(ctx *fiber.Ctx, enforcer *casbin.Enforcer, sub, obj, act, cat, typ, docid string) (bool, error) {
+	return EnforceWithAttributes(enforcer, RequestAttributesFromCtx(ctx), sub, obj, act, cat, typ, docid)
+}
+
+// ReloadCasbinPolicy reloads policies and their conditions into the shared enforcer
+func ReloadCasbinPolicy() {
+	if err := GetCasbinEnforcer().LoadPolicy(); err != nil {
+		log.Printf("Failed to reload Casbin policy: %v", err)
+	}
+	if err := LoadPolicyConditions(); err != nil {
+		log.Printf("%v", err)
+	}
+}
@@ -2,10 +2,14 @@ package main
 
 import (
 	"backend-school/config"
+	"backend-school/helpers"
+	"backend-school/middleware"
 	"backend-school/routes"
+	"backend-school/services"
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -34,6 +38,102 @@ func main() {
 	// Initialize database and Casbin
 	config.ConnectDatabase()
 	config.ConnectCasbin(config.DB)
+	helpers.RegisterPolicyFunctions(config.Enforcer)
+
+	// Policy conditions are cached in memory and reloaded whenever the policies change
+	if err := helpers.LoadPolicyConditions(); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	// Periodically repair drift between role_has_rule and casbin_rule
+	reconcileInterval, err := time.ParseDuration(os.Getenv("POLICY_RECONCILE_INTERVAL"))
+	if err != nil || reconcileInterval <= 0 {
+		reconcileInterval = 15 * time.Minute
+	}
+	services.StartPolicyReconciler(reconcileInterval)
+
+	// Seed the built-in notification templates
+	settingsService := services.NewSettingsService()
+	notificationService := services.NewNotificationService(services.NewEmailService(settingsService), settingsService)
+	if err := notificationService.SyncDefaultTemplates(); err != nil {
+		log.Printf("Failed to seed notification templates: %v", err)
+	}
+
+	// Health risk is assessed in-process; RISK_PROVIDER=external asks the model at RISK_API_URL first and falls
+	// back to the local engine when it fails or does not answer within RISK_API_TIMEOUT
+	if os.Getenv("RISK_PROVIDER") == "external" {
+		riskTimeout, err := time.ParseDuration(os.Getenv("RISK_API_TIMEOUT"))
+		if err != nil || riskTimeout <= 0 {
+			riskTimeout = 5 * time.Second
+		}
+		provider, err := services.NewExternalHealthRiskProvider(os.Getenv("RISK_API_URL"), os.Getenv("RISK_API_VERSION"), riskTimeout)
+		if err != nil {
+			log.Printf("External health risk provider disabled: %v", err)
+		} else {
+			services.SetHealthRiskProvider(provider)
+		}
+	}
+
+	// Select the push provider: "fcm" needs FCM_CREDENTIALS_FILE, "fake" only records messages
+	switch os.Getenv("PUSH_PROVIDER") {
+	case "fcm":
+		provider, err := services.NewFCMProviderFromFile(os.Getenv("FCM_CREDENTIALS_FILE"))
+		if err != nil {
+			log.Printf("Push notifications disabled: %v", err)
+		} else {
+			services.SetPushProvider(provider)
+		}
+	case "fake":
+		services.SetPushProvider(services.NewFakePushProvider())
+	}
+
+	// Deliver queued emails in the background
+	outboxInterval, err := time.ParseDuration(os.Getenv("EMAIL_OUTBOX_INTERVAL"))
+	if err != nil || outboxInterval <= 0 {
+		outboxInterval = 30 * time.Second
+	}
+	services.StartEmailOutboxWorker(outboxInterval, 50)
+
+	// Imports queued or running when the server stopped cannot resume; their archives were temporary
+	if interrupted, err := services.FailInterruptedDocumentImports(); err != nil {
+		log.Printf("Failed to update interrupted document imports: %v", err)
+	} else if interrupted > 0 {
+		log.Printf("Marked %d interrupted document import(s) as failed", interrupted)
+	}
+
+	// Convert health measurements still stored as text; values that cannot be parsed are kept for review
+	if migrated, err := services.MigrateHealthMeasurements(); err != nil {
+		log.Fatalf("Failed to convert health measurements: %v", err)
+	} else if len(migrated.Columns) > 0 {
+		log.Printf("Converted health measurements %v: %d value(s), %d could not be parsed and are listed in health_measurement_issue",
+			migrated.Columns, migrated.Converted, migrated.Issues)
+	}
+
+	// Build the full-text index of documents created before search was available
+	if indexed, err := services.ReindexDocumentSearch(); err != nil {
+		log.Printf("Failed to index documents for search: %v", err)
+	} else if indexed > 0 {
+		log.Printf("Indexed %d document(s) for search", indexed)
+	}
+
+	// Remind document owners about reviews and expiry, and flag overdue documents
+	reviewInterval, err := time.ParseDuration(os.Getenv("DOCUMENT_REVIEW_INTERVAL"))
+	if err != nil || reviewInterval <= 0 {
+		reviewInterval = time.Hour
+	}
+	services.StartDocumentReviewScheduler(reviewInterval)
+
+	// Archive superseded versions and purge files and deleted documents whose retention has passed
+	retentionInterval, err := time.ParseDuration(os.Getenv("DOCUMENT_RETENTION_INTERVAL"))
+	if err != nil || retentionInterval <= 0 {
+		retentionInterval = time.Hour
+	}
+	services.StartDocumentRetentionScheduler(retentionInterval)
+
+	// Apply the settings schema and encrypt secrets still stored as plaintext
+	if err := services.NewSettingsService().SyncSettingSchemas(); err != nil {
+		log.Printf("Failed to sync settings: %v", err)
+	}
 
 	allowedOrigins := os.Getenv("FRONTEND_ORIGINS")
 
@@ -41,7 +141,12 @@ func main() {
 	allowedOriginsArray := strings.Split(allowedOrigins, ",")
 
 	// Create Fiber app
-	app := fiber.New()
+	// Request bodies are streamed so the document import can take archives far larger than Fiber's 4 MB default body
+	// limit without buffering them; multipart uploads are parsed by the handlers, spilling files to disk
+	app := fiber.New(fiber.Config{
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
+	})
 
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     strings.Join(allowedOriginsArray, ","),   // Allow requests from Next.js frontend
@@ -49,6 +154,12 @@ func main() {
 		AllowMethods:     "GET,POST,HEAD,PUT,DELETE,PATCH,OPTIONS", // Specify allowed methods
 	}))
 
+	// Only the upload routes accept bodies over the default limit
+	app.Use(middleware.BodyLimit(fiber.DefaultBodyLimit, map[string]int{
+		"POST /api/user/document-import": services.MaxDocumentImportSize + 1024*1024,
+		"POST /api/admin/health/import":  services.MaxHealthImportSize + 1024*1024,
+	}))
+
 	// Setup routes
 	routes.SetupRoutes(app)
 
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"io"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// BodyLimit caps the size of request bodies. The app streams request bodies instead of reading them up front, so
+// this is where their size is enforced: limit applies to every request except the ones in routeLimits, which maps
+// "METHOD /path" to a larger limit for the routes that take big uploads. Bodies of a known length over the limit are
+// refused before they are read. Chunked bodies are read up to the limit, and refused on routes with a larger limit,
+// whose uploads are streamed to disk and must state their length.
+func BodyLimit(limit int, routeLimits map[string]int) fiber.Handler {
+	// Routes match case-insensitively and with or without a trailing slash
+	normalized := make(map[string]int, len(routeLimits))
+	for route, routeLimit := range routeLimits {
+		normalized[strings.ToUpper(strings.TrimRight(route, "/"))] = routeLimit
+	}
+
+	return func(c *fiber.Ctx) error {
+		contentLength := c.Request().Header.ContentLength()
+		if routeLimit, ok := normalized[strings.ToUpper(c.Method()+" "+strings.TrimRight(c.Path(), "/"))]; ok {
+			if contentLength == -1 {
+				c.Context().SetConnectionClose()
+				return c.Status(fiber.StatusLengthRequired).JSON(fiber.Map{
+					"statusCode": fiber.StatusLengthRequired,
+					"message":    "Content-Length is required",
+				})
+			}
+			if contentLength > routeLimit {
+				return bodyTooLarge(c)
+			}
+			return c.Next()
+		}
+
+		if contentLength > limit {
+			return bodyTooLarge(c)
+		}
+		// A chunked body has no length up front; read it here so no more than the limit is ever held
+		if contentLength == -1 {
+			if stream := c.Request().BodyStream(); stream != nil {
+				body, err := io.ReadAll(io.LimitReader(stream, int64(limit)+1))
+				if err != nil {
+					return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+						"statusCode": fiber.StatusBadRequest,
+						"message":    "Could not read request body",
+					})
+				}
+				if len(body) > limit {
+					return bodyTooLarge(c)
+				}
+				c.Request().SetBody(body)
+			}
+		}
+		return c.Next()
+	}
+}
+
+// bodyTooLarge refuses a request whose body is over its limit. The rest of the body is left unread, so the
+// connection is closed after the response.
+func bodyTooLarge(c *fiber.Ctx) error {
+	c.Context().SetConnectionClose()
+	return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+		"statusCode": fiber.StatusRequestEntityTooLarge,
+		"message":    "Request body is too large",
+	})
+}
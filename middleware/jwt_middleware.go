@@ -3,6 +3,7 @@ package middleware
 import (
 	"backend-school/config"
 	"backend-school/models"
+	"backend-school/services"
 	"errors"
 	"fmt"
 	"strings"
@@ -20,22 +21,8 @@ func LoadCasbinPolicy() error {
 	return nil
 }
 
-// GetUsernameFromToken extracts the username from the JWT token in the Authorization header.
-func GetUsernameFromToken(c *fiber.Ctx) (string, error) {
-	// Retrieve the Authorization header
-	authHeader := c.Get("Authorization")
-	if authHeader == "" {
-		return "", errors.New("authorization header is missing")
-	}
-
-	// Split the header to get the token part
-	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || parts[0] != "Bearer" {
-		return "", errors.New("invalid authorization header format")
-	}
-
-	tokenString := parts[1]
-
+// parseToken verifies the signature and expiry of a JWT and returns its claims
+func parseToken(tokenString string) (jwt.MapClaims, error) {
 	// Parse the token using the secret key
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		// Ensure that the signing method is HMAC before continuing
@@ -46,13 +33,45 @@ func GetUsernameFromToken(c *fiber.Ctx) (string, error) {
 	})
 
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// Extract the claims from the token and validate them
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok || !token.Valid {
-		return "", errors.New("invalid token")
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+// loginClaims parses the token of an Authorization header. Tokens issued for a single purpose, such as stream
+// tokens, are refused.
+func loginClaims(authHeader string) (jwt.MapClaims, error) {
+	if authHeader == "" {
+		return nil, errors.New("authorization header is missing")
+	}
+
+	// Split the header to get the token part
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return nil, errors.New("invalid authorization header format")
+	}
+
+	claims, err := parseToken(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	if purpose, _ := claims["purpose"].(string); purpose != "" {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+// GetUsernameFromToken extracts the username from the JWT token in the Authorization header.
+func GetUsernameFromToken(c *fiber.Ctx) (string, error) {
+	claims, err := loginClaims(c.Get("Authorization"))
+	if err != nil {
+		return "", err
 	}
 
 	// Extract the username from the claims
@@ -64,6 +83,54 @@ func GetUsernameFromToken(c *fiber.Ctx) (string, error) {
 	return username, nil
 }
 
+// getUsernameFromStreamToken extracts the username from a stream token issued by services.IssueStreamToken
+func getUsernameFromStreamToken(tokenString string) (string, error) {
+	if tokenString == "" {
+		return "", errors.New("stream token is missing")
+	}
+	claims, err := parseToken(tokenString)
+	if err != nil {
+		return "", err
+	}
+	if purpose, _ := claims["purpose"].(string); purpose != services.StreamTokenPurpose {
+		return "", errors.New("invalid token")
+	}
+
+	username, ok := claims["username"].(string)
+	if !ok {
+		return "", errors.New("username not found in token")
+	}
+	return username, nil
+}
+
+// setUserLocals stores username, user_id and role_guard_name of the authenticated user in the context. When it
+// returns false the error response has been written.
+func setUserLocals(c *fiber.Ctx, username string) (bool, error) {
+	// Retrieve the user from the database based on the username
+	var user models.User
+	if err := config.DB.Where("username = ?", username).Where("deleted_at", nil).First(&user).Error; err != nil {
+		return false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Could not find user in database",
+		})
+	}
+
+	// Dapatkan role_guard_name dari tabel casbin_rule berdasarkan username
+	roleGuardName, err := GetRoleGuardNameFromCasbinRule(username)
+	if err != nil {
+		return false, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"statusCode": fiber.StatusInternalServerError,
+			"message":    "Could not retrieve role_guard_name: " + err.Error(),
+		})
+	}
+
+	// Simpan username, user_id, dan role_guard_name di context
+	c.Locals("username", username)
+	c.Locals("user_id", int(user.ID)) // Convert uint to int
+	c.Locals("role_guard_name", roleGuardName)
+	return true, nil
+}
+
 // JWTMiddleware validasi JWT, menyimpan username, user_id, dan role_guard_name di konteks
 func JWTMiddleware() fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -85,69 +152,42 @@ func JWTMiddleware() fiber.Handler {
 			})
 		}
 
-		// Retrieve the user from the database based on the username
-		var user models.User
-		if err := config.DB.Where("username = ?", username).Where("deleted_at", nil).First(&user).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"statusCode": fiber.StatusInternalServerError,
-				"message":    "Could not find user in database",
-			})
+		if ok, err := setUserLocals(c, username); !ok {
+			return err
 		}
 
-		// Dapatkan role_guard_name dari tabel casbin_rule berdasarkan username
-		roleGuardName, err := GetRoleGuardNameFromCasbinRule(username)
+		// Lanjutkan ke middleware atau handler berikutnya
+		return c.Next()
+	}
+}
+
+// StreamTokenMiddleware authenticates the notification stream with the ?stream_token= issued by
+// services.IssueStreamToken, since EventSource cannot send an Authorization header. Login tokens are refused here,
+// so they never need to be put in a URL.
+func StreamTokenMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		username, err := getUsernameFromStreamToken(c.Query("stream_token"))
 		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"statusCode": fiber.StatusInternalServerError,
-				"message":    "Could not retrieve role_guard_name: " + err.Error(),
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"statusCode": fiber.StatusUnauthorized,
+				"message":    "Unauthorized: " + err.Error(),
 			})
 		}
 
-		// Simpan username, user_id, dan role_guard_name di context
-		c.Locals("username", username)
-		c.Locals("user_id", int(user.ID)) // Convert uint to int
-		c.Locals("role_guard_name", roleGuardName)
-
-		// Lanjutkan ke middleware atau handler berikutnya
+		if ok, err := setUserLocals(c, username); !ok {
+			return err
+		}
 		return c.Next()
 	}
 }
 
 // ExtractUserIDFromToken extracts the user_id from the JWT token in the Authorization header.
 func ExtractUserIDFromToken(c *fiber.Ctx) (int, error) {
-	// Retrieve the Authorization header
-	authHeader := c.Get("Authorization")
-	if authHeader == "" {
-		return 0, errors.New("authorization header is missing")
-	}
-
-	// Split the header to get the token part
-	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || parts[0] != "Bearer" {
-		return 0, errors.New("invalid authorization header format")
-	}
-
-	tokenString := parts[1]
-
-	// Parse the token using the secret key
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Ensure that the signing method is HMAC before continuing
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(config.SecretKey), nil
-	})
-
+	claims, err := loginClaims(c.Get("Authorization"))
 	if err != nil {
 		return 0, err
 	}
 
-	// Extract the claims from the token and validate them
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok || !token.Valid {
-		return 0, errors.New("invalid token")
-	}
-
 	// Extract the username and fetch user_id from database based on username
 	username, ok := claims["username"].(string)
 	if !ok {
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Push delivery statuses
+const (
+	PushStatusSent         = "sent"
+	PushStatusFailed       = "failed"
+	PushStatusInvalidToken = "invalid_token" // the provider rejected the token, which was pruned
+)
+
+// DeviceToken is a push token registered by one of a user's devices
+type DeviceToken struct {
+	ID         uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UUID       uuid.UUID `json:"uuid" gorm:"type:uuid;default:gen_random_uuid()"`
+	UserID     int       `json:"user_id" gorm:"not null;index"`
+	Token      string    `json:"token" gorm:"type:varchar(512);not null;uniqueIndex"`
+	Platform   string    `json:"platform" gorm:"type:varchar(20)" validate:"omitempty,oneof=android ios web"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// TableName overrides the default table name
+func (DeviceToken) TableName() string {
+	return "device_tokens"
+}
+
+// BeforeCreate is a GORM hook that sets a UUID before creating a DeviceToken
+func (d *DeviceToken) BeforeCreate(tx *gorm.DB) (err error) {
+	if d.UUID == uuid.Nil {
+		d.UUID = uuid.New()
+	}
+	return
+}
+
+// PushDelivery records the outcome of sending one push message to one device
+type PushDelivery struct {
+	ID                uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID            int       `json:"user_id" gorm:"not null;index"`
+	DeviceTokenID     uint      `json:"device_token_id"`
+	Provider          string    `json:"provider" gorm:"type:varchar(20)"`
+	Event             string    `json:"event" gorm:"type:varchar(100)"`
+	Title             string    `json:"title" gorm:"type:varchar(255)"`
+	Status            string    `json:"status" gorm:"type:varchar(20);not null;index"`
+	ProviderMessageID string    `json:"provider_message_id" gorm:"type:varchar(255)"`
+	Error             string    `json:"error" gorm:"type:text"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// TableName overrides the default table name
+func (PushDelivery) TableName() string {
+	return "push_deliveries"
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Document change request statuses. A request is submitted, taken into review and approved or rejected;
+// an approved request is implemented by uploading the new revision. The requester may cancel it until a decision.
+const (
+	DocumentChangeRequestSubmitted   = "submitted"
+	DocumentChangeRequestInReview    = "in_review"
+	DocumentChangeRequestApproved    = "approved"
+	DocumentChangeRequestRejected    = "rejected"
+	DocumentChangeRequestCancelled   = "cancelled"
+	DocumentChangeRequestImplemented = "implemented"
+)
+
+// DocumentChangeRequest (DCR) asks for a revision of a controlled document
+type DocumentChangeRequest struct {
+	ID                uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UUID              uuid.UUID  `json:"uuid" gorm:"type:uuid;default:gen_random_uuid();uniqueIndex"`
+	DocumentControlID int        `json:"document_control_id" gorm:"not null;index"`
+	Reason            string     `json:"reason" gorm:"type:text;not null"`
+	ProposedChanges   string     `json:"proposed_changes" gorm:"type:text"`
+	Status            string     `json:"status" gorm:"type:varchar(20);not null;default:'submitted';index"`
+	RequestedBy       int        `json:"requested_by" gorm:"not null;index"`
+	ReviewedBy        *int       `json:"reviewed_by" gorm:"type:int"`
+	ReviewStartedAt   *time.Time `json:"review_started_at"`
+	DecisionComment   string     `json:"decision_comment" gorm:"type:text"`
+	DecidedAt         *time.Time `json:"decided_at"`
+	DocumentVersionID *int       `json:"document_version_id" gorm:"type:int"` // the version created when implemented
+	RevisionNumber    *int       `json:"revision_number" gorm:"type:int"`     // the revision that version became
+	ImplementedBy     *int       `json:"implemented_by" gorm:"type:int"`
+	ImplementedAt     *time.Time `json:"implemented_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// TableName overrides the default table name
+func (DocumentChangeRequest) TableName() string {
+	return "document_change_request"
+}
+
+// BeforeCreate is a GORM hook that sets a UUID before creating a DocumentChangeRequest
+func (r *DocumentChangeRequest) BeforeCreate(tx *gorm.DB) (err error) {
+	if r.UUID == uuid.Nil {
+		r.UUID = uuid.New()
+	}
+	return
+}
+
+// DocumentChangeRequestAttachment is a supporting file of a change request, such as a marked-up copy
+type DocumentChangeRequestAttachment struct {
+	ID                      uint      `json:"-" gorm:"primaryKey;autoIncrement"`
+	UUID                    uuid.UUID `json:"uuid" gorm:"type:uuid;default:gen_random_uuid();uniqueIndex"`
+	DocumentChangeRequestID uint      `json:"-" gorm:"not null;index"`
+	FileName                string    `json:"file_name" gorm:"type:varchar(255)"`
+	File                    string    `json:"-" gorm:"type:varchar(255)"`
+	MimeType                string    `json:"mime_type" gorm:"type:varchar(100)"`
+	FileSize                int64     `json:"file_size"`
+	UploadedBy              int       `json:"uploaded_by"`
+	CreatedAt               time.Time `json:"created_at"`
+}
+
+// TableName overrides the default table name
+func (DocumentChangeRequestAttachment) TableName() string {
+	return "document_change_request_attachment"
+}
+
+// BeforeCreate is a GORM hook that sets a UUID before creating a DocumentChangeRequestAttachment
+func (a *DocumentChangeRequestAttachment) BeforeCreate(tx *gorm.DB) (err error) {
+	if a.UUID == uuid.Nil {
+		a.UUID = uuid.New()
+	}
+	return
+}
@@ -26,6 +26,26 @@ type DocumentControl struct {
 	SequenceNumber     *int       `gorm:"type:int" json:"sequence_number"`
 	StatusDocumentID   *int       `gorm:"type:int" json:"status_document_id"`
 	CreatedBy          *int       `gorm:"type:int" json:"created_by"`
+	// Periodic review and expiry
+	ReviewIntervalMonths *int       `gorm:"type:int" json:"review_interval_months"`
+	NextReviewDate       *time.Time `gorm:"type:date;index" json:"next_review_date"`
+	LastReviewedAt       *time.Time `json:"last_reviewed_at"`
+	ExpiryDate           *time.Time `gorm:"type:date;index" json:"expiry_date"`
+	ReviewOverdue        bool       `gorm:"default:false" json:"review_overdue"`
+	ReviewReminderSentAt *time.Time `json:"review_reminder_sent_at"`
+	ExpiryReminderSentAt *time.Time `json:"expiry_reminder_sent_at"`
+	ExpiredNotifiedAt    *time.Time `json:"expired_notified_at"`
+	// Full-text index over the metadata and the text of the current version, maintained by the service
+	SearchVector string `gorm:"type:tsvector;->:false" json:"-"`
+	// Check-out lock; a lock past its expiry no longer blocks anyone
+	CheckedOutBy      *int       `gorm:"type:int" json:"checked_out_by"`
+	CheckedOutAt      *time.Time `json:"checked_out_at"`
+	CheckoutExpiresAt *time.Time `json:"checkout_expires_at"`
+	// Incremented by every update and served as the ETag, so edits based on an older state can be rejected
+	LockVersion int `gorm:"not null;default:1" json:"lock_version"`
+	// Trash: a deleted document can be restored until the purge removes it after PurgeAfter
+	DeletedBy  *int       `gorm:"type:int" json:"deleted_by"`
+	PurgeAfter *time.Time `gorm:"index" json:"purge_after"`
 }
 
 // TableName overrides the default table name
@@ -58,6 +78,19 @@ type DocumentControlJoined struct {
 	SequenceNumber     *int       `gorm:"type:int" json:"sequence_number"`
 	StatusDocumentID   *int       `gorm:"type:int" json:"status_document_id"`
 	CreatedBy          *int       `gorm:"type:int" json:"created_by"`
+	// Periodic review and expiry
+	ReviewIntervalMonths *int       `gorm:"type:int" json:"review_interval_months"`
+	NextReviewDate       *time.Time `gorm:"type:date" json:"next_review_date"`
+	LastReviewedAt       *time.Time `json:"last_reviewed_at"`
+	ExpiryDate           *time.Time `gorm:"type:date" json:"expiry_date"`
+	ReviewOverdue        bool       `json:"review_overdue"`
+	// Check-out lock and optimistic concurrency
+	CheckedOutBy      *int       `gorm:"type:int" json:"checked_out_by"`
+	CheckoutExpiresAt *time.Time `json:"checkout_expires_at"`
+	LockVersion       int        `json:"lock_version"`
+	// Trash
+	DeletedBy  *int       `gorm:"type:int" json:"deleted_by"`
+	PurgeAfter *time.Time `json:"purge_after"`
 	// Fields for joined data
 	CategoryName   string `json:"category_name"`
 	CategoryPrefix string `json:"category_prefix"`
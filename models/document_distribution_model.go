@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DocumentDistribution is one entry of a controlled document's distribution list: either a role or a single user
+type DocumentDistribution struct {
+	ID                uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UUID              uuid.UUID `json:"uuid" gorm:"type:uuid;default:gen_random_uuid()"`
+	DocumentControlID int       `json:"document_control_id" gorm:"not null;index"`
+	RoleGuardName     string    `json:"role_guard_name" gorm:"type:varchar(255)"`
+	UserID            *int      `json:"user_id" gorm:"type:int"`
+	CreatedBy         *int      `json:"created_by" gorm:"type:int"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// TableName overrides the default table name
+func (DocumentDistribution) TableName() string {
+	return "document_distribution"
+}
+
+// BeforeCreate is a GORM hook that sets a UUID before creating a DocumentDistribution
+func (d *DocumentDistribution) BeforeCreate(tx *gorm.DB) (err error) {
+	if d.UUID == uuid.Nil {
+		d.UUID = uuid.New()
+	}
+	return
+}
+
+// DocumentAcknowledgement is a recipient's task to confirm they have read a specific document version
+type DocumentAcknowledgement struct {
+	ID                uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UUID              uuid.UUID  `json:"uuid" gorm:"type:uuid;default:gen_random_uuid()"`
+	DocumentControlID int        `json:"document_control_id" gorm:"not null;index"`
+	DocumentVersionID int        `json:"document_version_id" gorm:"not null;uniqueIndex:idx_document_acknowledgement_version_user,priority:1"`
+	UserID            int        `json:"user_id" gorm:"not null;uniqueIndex:idx_document_acknowledgement_version_user,priority:2;index"`
+	AcknowledgedAt    *time.Time `json:"acknowledged_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// TableName overrides the default table name
+func (DocumentAcknowledgement) TableName() string {
+	return "document_acknowledgement"
+}
+
+// BeforeCreate is a GORM hook that sets a UUID before creating a DocumentAcknowledgement
+func (a *DocumentAcknowledgement) BeforeCreate(tx *gorm.DB) (err error) {
+	if a.UUID == uuid.Nil {
+		a.UUID = uuid.New()
+	}
+	return
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Document import job statuses
+const (
+	DocumentImportStatusQueued    = "queued"
+	DocumentImportStatusRunning   = "running"
+	DocumentImportStatusCompleted = "completed"
+	DocumentImportStatusFailed    = "failed" // the archive could not be processed at all
+)
+
+// Document import row results
+const (
+	DocumentImportRowValid   = "valid" // dry run: the row would be imported
+	DocumentImportRowCreated = "created"
+	DocumentImportRowFailed  = "failed"
+)
+
+// DocumentImportJob is a ZIP archive of documents being imported in the background
+type DocumentImportJob struct {
+	ID            uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UUID          uuid.UUID  `json:"uuid" gorm:"type:uuid;default:gen_random_uuid();uniqueIndex"`
+	FileName      string     `json:"file_name" gorm:"type:varchar(255)"`
+	DryRun        bool       `json:"dry_run" gorm:"not null;default:false"`
+	Status        string     `json:"status" gorm:"type:varchar(20);not null;default:'queued';index"`
+	TotalRows     int        `json:"total_rows" gorm:"not null;default:0"`
+	ProcessedRows int        `json:"processed_rows" gorm:"not null;default:0"`
+	SucceededRows int        `json:"succeeded_rows" gorm:"not null;default:0"`
+	FailedRows    int        `json:"failed_rows" gorm:"not null;default:0"`
+	Error         string     `json:"error" gorm:"type:text"`
+	CreatedBy     int        `json:"created_by" gorm:"index"`
+	StartedAt     *time.Time `json:"started_at"`
+	FinishedAt    *time.Time `json:"finished_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// TableName overrides the default table name
+func (DocumentImportJob) TableName() string {
+	return "document_import_job"
+}
+
+// BeforeCreate is a GORM hook that sets a UUID before creating a DocumentImportJob
+func (j *DocumentImportJob) BeforeCreate(tx *gorm.DB) (err error) {
+	if j.UUID == uuid.Nil {
+		j.UUID = uuid.New()
+	}
+	return
+}
+
+// DocumentImportRow is the result of one manifest row of an import job
+type DocumentImportRow struct {
+	ID                  uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	DocumentImportJobID uint       `json:"-" gorm:"not null;index:idx_document_import_row_job,priority:1"`
+	RowNumber           int        `json:"row_number" gorm:"not null;index:idx_document_import_row_job,priority:2"`
+	File                string     `json:"file" gorm:"type:varchar(255)"`
+	DocumentNumber      string     `json:"document_number" gorm:"type:varchar(255)"`
+	DocumentName        string     `json:"document_name" gorm:"type:varchar(255)"`
+	Status              string     `json:"status" gorm:"type:varchar(20);not null"`
+	Message             string     `json:"message" gorm:"type:text"`
+	DocumentControlUUID *uuid.UUID `json:"document_control_uuid" gorm:"type:uuid"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
+
+// TableName overrides the default table name
+func (DocumentImportRow) TableName() string {
+	return "document_import_row"
+}
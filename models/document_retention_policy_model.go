@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DocumentRetentionPolicy sets how many years obsolete versions and deleted documents of a category and/or type
+// are kept before the purge removes them. A policy for both a category and a type wins over one for the type,
+// which wins over one for the category; documents without a policy use the document_retention_default_years setting.
+type DocumentRetentionPolicy struct {
+	ID                 uint      `json:"-" gorm:"primaryKey;autoIncrement"`
+	UUID               uuid.UUID `json:"uuid" gorm:"type:uuid;default:gen_random_uuid();uniqueIndex"`
+	DocumentCategoryID *int      `json:"document_category_id" gorm:"type:int;index"`
+	DocumentTypeID     *int      `json:"document_type_id" gorm:"type:int;index"`
+	RetentionYears     int       `json:"retention_years" gorm:"not null"`
+	Description        string    `json:"description" gorm:"type:text"`
+	CreatedBy          int       `json:"created_by"`
+	UpdatedBy          int       `json:"updated_by"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// TableName overrides the default table name
+func (DocumentRetentionPolicy) TableName() string {
+	return "document_retention_policy"
+}
+
+// BeforeCreate is a GORM hook that sets a UUID before creating a DocumentRetentionPolicy
+func (p *DocumentRetentionPolicy) BeforeCreate(tx *gorm.DB) (err error) {
+	if p.UUID == uuid.Nil {
+		p.UUID = uuid.New()
+	}
+	return
+}
@@ -18,6 +18,19 @@ type DocumentVersion struct {
 	Version           *int       `gorm:"type:int" json:"version"`
 	StatusDocumentID  *int       `gorm:"type:int" json:"status_document_id"`
 	Note              string     `gorm:"type:text" json:"note"`
+	ContentText       string     `gorm:"type:text" json:"-"` // text extracted from the uploaded PDF for search
+	// Determined by the server when the file is uploaded
+	Checksum    string `gorm:"type:varchar(64);index" json:"checksum"` // hex SHA-256 of the file
+	MimeType    string `gorm:"type:varchar(100)" json:"mime_type"`
+	FileSize    int64  `json:"file_size"`
+	PageCount   int    `gorm:"type:int" json:"page_count"`
+	PdfTitle    string `gorm:"type:varchar(255)" json:"pdf_title"`
+	IsEncrypted bool   `gorm:"default:false" json:"is_encrypted"`
+	// The change request this version implements, if it was created through one
+	DocumentChangeRequestID *int `gorm:"type:int;index" json:"document_change_request_id"`
+	// Set when a superseded version is moved to the archive prefix; the purge removes it after RetainUntil
+	ArchivedAt  *time.Time `json:"archived_at"`
+	RetainUntil *time.Time `gorm:"index" json:"retain_until"`
 }
 
 // TableName overrides the default table name
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Email outbox statuses
+const (
+	EmailStatusPending = "pending" // waiting for its next delivery attempt
+	EmailStatusSending = "sending" // claimed by the worker
+	EmailStatusSent    = "sent"
+	EmailStatusDead    = "dead" // gave up after MaxAttempts
+)
+
+// EmailOutbox is an email queued for background delivery by the outbox worker. The body can hold secrets such as
+// password reset links, so it is never serialized and is cleared once the message is sent.
+type EmailOutbox struct {
+	ID            uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UUID          uuid.UUID  `json:"uuid" gorm:"type:uuid;default:gen_random_uuid()"`
+	ToAddress     string     `json:"to_address" gorm:"type:varchar(255);not null"`
+	Subject       string     `json:"subject" gorm:"type:varchar(255)"`
+	Body          string     `json:"-" gorm:"type:text"`
+	ContentType   string     `json:"content_type" gorm:"type:varchar(50);not null;default:'text/html'"`
+	Status        string     `json:"status" gorm:"type:varchar(20);not null;default:'pending';index:idx_email_outbox_due,priority:1"`
+	Attempts      int        `json:"attempts" gorm:"not null;default:0"`
+	MaxAttempts   int        `json:"max_attempts" gorm:"not null;default:5"`
+	LastError     string     `json:"last_error" gorm:"type:text"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"index:idx_email_outbox_due,priority:2"`
+	SentAt        *time.Time `json:"sent_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// TableName overrides the default table name
+func (EmailOutbox) TableName() string {
+	return "email_outbox"
+}
+
+// BeforeCreate is a GORM hook that sets a UUID before creating an EmailOutbox
+func (e *EmailOutbox) BeforeCreate(tx *gorm.DB) (err error) {
+	if e.UUID == uuid.Nil {
+		e.UUID = uuid.New()
+	}
+	return
+}
@@ -15,6 +15,7 @@ type Form struct {
 	Steps           []FormStep `gorm:"foreignKey:FormID" json:"steps"` // Form steps
 	CreatedAt       time.Time  `json:"createdAt"`                      // Automatically created at timestamp
 	UUID            uuid.UUID  `json:"uuid" gorm:"type:uuid;default:gen_random_uuid()"`
+	CreatedBy       *int       `json:"createdBy"` // user who manages the form and is notified of submissions
 }
 
 // FormStep represents a step in the form wizard
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Health access log actions
+const (
+	HealthAccessActionList     = "list"
+	HealthAccessActionView     = "view"
+	HealthAccessActionCreate   = "create"
+	HealthAccessActionUpdate   = "update"
+	HealthAccessActionTimeline = "timeline"
+	HealthAccessActionTrends   = "trends"
+	HealthAccessActionExport   = "export"
+	HealthAccessActionErase    = "erase"
+)
+
+// HealthAccessLog records one read of a health record, or an export or erasure of a person's data. The log holds no
+// measurements, so it is kept when a person's data is erased. Masked is set when the reader did not have clinical
+// access and saw the record without its measurements.
+type HealthAccessLog struct {
+	ID             uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	HealthUUID     *uuid.UUID `json:"health_uuid" gorm:"type:uuid;index"`
+	HealthPersonID *int       `json:"health_person_id" gorm:"index"`
+	UserID         int        `json:"user_id" gorm:"index"`
+	Username       string     `json:"username" gorm:"type:varchar(255)"`
+	Action         string     `json:"action" gorm:"type:varchar(20);not null"`
+	Masked         bool       `json:"masked"`
+	IP             string     `json:"ip" gorm:"type:varchar(45)"`
+	Detail         string     `json:"detail" gorm:"type:text"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"index"`
+}
+
+// TableName overrides the default table name
+func (HealthAccessLog) TableName() string {
+	return "health_access_log"
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// HealthMeasurementIssue keeps a value of health_data that could not be converted to a number when its column
+// changed type, so it can be corrected by hand.
+type HealthMeasurementIssue struct {
+	ID         uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	HealthID   int       `json:"health_id" gorm:"not null;index"`
+	HealthUUID uuid.UUID `json:"health_uuid" gorm:"type:uuid"`
+	Field      string    `json:"field" gorm:"type:varchar(50);not null"`
+	RawValue   string    `json:"raw_value" gorm:"type:text"`
+	Reason     string    `json:"reason" gorm:"type:varchar(255)"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TableName overrides the default table name
+func (HealthMeasurementIssue) TableName() string {
+	return "health_measurement_issue"
+}
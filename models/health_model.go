@@ -7,6 +7,8 @@ import (
 	"gorm.io/gorm"
 )
 
+// Health is one health check. The measurements are nullable because values that could not be converted when the
+// columns changed from text to numbers were cleared; they are kept in health_measurement_issue.
 type Health struct {
 	ID        int            `gorm:"primaryKey;autoIncrement" json:"id"`
 	UUID      uuid.UUID      `gorm:"type:uuid;default:uuid_generate_v4()" json:"uuid"`
@@ -14,23 +16,26 @@ type Health struct {
 	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
 	// UserId                 string         `gorm:"type:varchar(255)" json:"user_id"`
-	Nama                   string `gorm:"type:varchar(255)" json:"nama"`
-	JenisKelamin           string `gorm:"type:varchar(255)" json:"jenis_kelamin"`
-	Umur                   string `gorm:"type:varchar(255)" json:"umur"`
-	Bb                     string `gorm:"type:varchar(255)" json:"bb"`
-	Tb                     string `gorm:"type:varchar(255)" json:"tb"`
-	Systol                 string `gorm:"type:varchar(255)" json:"systol"`
-	Diastol                string `gorm:"type:varchar(255)" json:"diastol"`
-	HeartRate              string `gorm:"type:varchar(255)" json:"heart_rate"`
-	Profesi                string `gorm:"type:varchar(255)" json:"profesi"`
-	Risk                   string `gorm:"type:varchar(255)" json:"risk"`
-	Bmi                    string `gorm:"type:varchar(255)" json:"bmi"`
-	RecommendationFood     string `gorm:"type:varchar(255)" json:"recommendation_food"`
-	RecommendationSport    string `gorm:"type:varchar(255)" json:"recommendation_sport"`
-	RecommendationMedicine string `gorm:"type:varchar(255)" json:"recommendation_medicine"`
-	CreatedBy              string `gorm:"type:varchar(255)" json:"created_by"`
-	UpdatedBy              string `gorm:"type:varchar(255)" json:"updated_by"`
-	DeletedBy              string `gorm:"type:varchar(255)" json:"deleted_by"`
+	HealthPersonID         *int     `gorm:"index" json:"health_person_id"` // person the check belongs to; Nama, JenisKelamin and Profesi are kept as recorded
+	Nama                   string   `gorm:"type:varchar(255)" json:"nama"`
+	JenisKelamin           string   `gorm:"type:varchar(255)" json:"jenis_kelamin"`
+	Umur                   *int     `gorm:"type:integer" json:"umur"`       // years
+	Bb                     *float64 `gorm:"type:numeric(5,1)" json:"bb"`    // kg
+	Tb                     *float64 `gorm:"type:numeric(5,1)" json:"tb"`    // cm
+	Systol                 *int     `gorm:"type:integer" json:"systol"`     // mmHg
+	Diastol                *int     `gorm:"type:integer" json:"diastol"`    // mmHg
+	HeartRate              *int     `gorm:"type:integer" json:"heart_rate"` // beats per minute
+	Profesi                string   `gorm:"type:varchar(255)" json:"profesi"`
+	Risk                   string   `gorm:"type:varchar(255)" json:"risk"`
+	RiskEngine             string   `gorm:"type:varchar(50)" json:"risk_engine"`       // provider that assessed the risk, e.g. "local" or "external"
+	RiskRuleVersion        string   `gorm:"type:varchar(50)" json:"risk_rule_version"` // rule set version of the local engine or the external model version
+	Bmi                    *float64 `gorm:"type:numeric(5,2)" json:"bmi"`              // kg/m²
+	RecommendationFood     string   `gorm:"type:varchar(255)" json:"recommendation_food"`
+	RecommendationSport    string   `gorm:"type:varchar(255)" json:"recommendation_sport"`
+	RecommendationMedicine string   `gorm:"type:varchar(255)" json:"recommendation_medicine"`
+	CreatedBy              string   `gorm:"type:varchar(255)" json:"created_by"`
+	UpdatedBy              string   `gorm:"type:varchar(255)" json:"updated_by"`
+	DeletedBy              string   `gorm:"type:varchar(255)" json:"deleted_by"`
 }
 
 // TableName overrides the default table name for GORM
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// HealthPerson is a student or staff member whose health checks are followed over time. IdentityNumber holds the
+// school's own number for the person (NIS or NIP) and UserID links the person to a login when they have one.
+// UserLinkedBy is who made that link; only a link made by someone with clinical access lets the user read their own
+// data unmasked.
+type HealthPerson struct {
+	ID             uint           `json:"-" gorm:"primaryKey;autoIncrement"`
+	UUID           uuid.UUID      `json:"uuid" gorm:"type:uuid;default:gen_random_uuid();uniqueIndex"`
+	Name           string         `json:"name" gorm:"type:varchar(255);not null"`
+	IdentityNumber *string        `json:"identity_number" gorm:"type:varchar(50);index"`
+	Gender         string         `json:"gender" gorm:"type:varchar(50)"`
+	BirthDate      *time.Time     `json:"birth_date" gorm:"type:date"`
+	Profession     string         `json:"profession" gorm:"type:varchar(255)"`
+	UserID         *int           `json:"user_id" gorm:"index"`
+	UserLinkedBy   *int           `json:"user_linked_by"`
+	CreatedBy      int            `json:"created_by"`
+	UpdatedBy      int            `json:"updated_by"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+}
+
+// TableName overrides the default table name
+func (HealthPerson) TableName() string {
+	return "health_person"
+}
+
+// BeforeCreate is a GORM hook that sets a UUID before creating a HealthPerson
+func (p *HealthPerson) BeforeCreate(tx *gorm.DB) (err error) {
+	if p.UUID == uuid.Nil {
+		p.UUID = uuid.New()
+	}
+	return
+}
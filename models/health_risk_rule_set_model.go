@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// HealthRiskRuleSet is one version of the thresholds used by the local health risk engine. Rule sets are never
+// changed after they are saved: editing the thresholds stores a new version, and the highest version is active.
+type HealthRiskRuleSet struct {
+	ID        uint      `json:"-" gorm:"primaryKey;autoIncrement"`
+	UUID      uuid.UUID `json:"uuid" gorm:"type:uuid;default:gen_random_uuid();uniqueIndex"`
+	Version   int       `json:"version" gorm:"not null;uniqueIndex"`
+	Rules     string    `json:"-" gorm:"type:text;not null"` // JSON encoded services.HealthRiskRules
+	Note      string    `json:"note" gorm:"type:text"`
+	CreatedBy int       `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName overrides the default table name
+func (HealthRiskRuleSet) TableName() string {
+	return "health_risk_rule_set"
+}
+
+// BeforeCreate is a GORM hook that sets a UUID before creating a HealthRiskRuleSet
+func (r *HealthRiskRuleSet) BeforeCreate(tx *gorm.DB) (err error) {
+	if r.UUID == uuid.Nil {
+		r.UUID = uuid.New()
+	}
+	return
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// In-app notification events, in addition to the email events in notification_template_model.go
+const (
+	NotificationEventDocumentStatusChanged = "document_status_changed"
+	NotificationEventRoleAssigned          = "role_assigned"
+	NotificationEventRoleRemoved           = "role_removed"
+	NotificationEventAccountActivated      = "account_activated"
+	NotificationEventPasswordChanged       = "password_changed"
+	NotificationEventDocumentReviewDue     = "document_review_due"
+	NotificationEventDocumentReviewOverdue = "document_review_overdue"
+	NotificationEventDocumentExpiring      = "document_expiring"
+	NotificationEventDocumentExpired       = "document_expired"
+
+	NotificationEventDocumentAcknowledgementRequired = "document_acknowledgement_required"
+	NotificationEventDocumentImportFinished          = "document_import_finished"
+
+	NotificationEventDocumentChangeRequestSubmitted   = "document_change_request_submitted"
+	NotificationEventDocumentChangeRequestDecided     = "document_change_request_decided"
+	NotificationEventDocumentChangeRequestImplemented = "document_change_request_implemented"
+	NotificationEventDocumentCheckoutReleased         = "document_checkout_released"
+)
+
+// Notification is an in-app notification shown in a user's inbox
+type Notification struct {
+	ID        uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UUID      uuid.UUID  `json:"uuid" gorm:"type:uuid;default:gen_random_uuid()"`
+	UserID    int        `json:"user_id" gorm:"not null;index:idx_notifications_user_read,priority:1"`
+	Event     string     `json:"event" gorm:"type:varchar(100);not null"`
+	Title     string     `json:"title" gorm:"type:varchar(255);not null"`
+	Message   string     `json:"message" gorm:"type:text"`
+	Link      string     `json:"link" gorm:"type:varchar(255)"` // optional frontend path, e.g. /document-control/<uuid>
+	ReadAt    *time.Time `json:"read_at" gorm:"index:idx_notifications_user_read,priority:2"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName overrides the default table name
+func (Notification) TableName() string {
+	return "notifications"
+}
+
+// BeforeCreate is a GORM hook that sets a UUID before creating a Notification
+func (n *Notification) BeforeCreate(tx *gorm.DB) (err error) {
+	if n.UUID == uuid.Nil {
+		n.UUID = uuid.New()
+	}
+	return
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Notification events
+const (
+	NotificationEventPasswordReset    = "password_reset"
+	NotificationEventFormSubmitted    = "form_submitted"
+	NotificationEventDocumentApproved = "document_approved"
+	NotificationEventDocumentRejected = "document_rejected"
+)
+
+// DefaultNotificationLocale is used when neither the caller nor the notification_default_locale setting names a locale
+const DefaultNotificationLocale = "en"
+
+// NotificationTemplate is an admin-editable template for one notification event in one locale.
+// Subject is a text/template and Body an html/template, both executed with the event data. IsActive has no column
+// default, since GORM would then store the default instead of false; it is always set when a template is created.
+type NotificationTemplate struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UUID      uuid.UUID `json:"uuid" gorm:"type:uuid;default:gen_random_uuid()"`
+	Event     string    `json:"event" gorm:"type:varchar(100);not null;uniqueIndex:idx_notification_template_event_locale" validate:"required"`
+	Locale    string    `json:"locale" gorm:"type:varchar(10);not null;default:'en';uniqueIndex:idx_notification_template_event_locale"`
+	Subject   string    `json:"subject" gorm:"type:text;not null" validate:"required"`
+	Body      string    `json:"body" gorm:"type:text;not null" validate:"required"`
+	IsActive  bool      `json:"is_active" gorm:"not null"`
+	CreatedBy int       `json:"created_by"`
+	UpdatedBy int       `json:"updated_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName overrides the default table name
+func (NotificationTemplate) TableName() string {
+	return "notification_template"
+}
+
+// BeforeCreate is a GORM hook that sets a UUID before creating a NotificationTemplate
+func (t *NotificationTemplate) BeforeCreate(tx *gorm.DB) (err error) {
+	if t.UUID == uuid.Nil {
+		t.UUID = uuid.New()
+	}
+	return
+}
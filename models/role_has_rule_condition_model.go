@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Condition types supported on a role_has_rule
+const (
+	ConditionTypeOwner      = "owner"       // caller must be the owner (e.g. DocumentControl.CreatedBy) of the resource
+	ConditionTypeIPRange    = "ip_range"    // client IP must be inside one of the comma-separated CIDRs in Value
+	ConditionTypeTimeWindow = "time_window" // request time must fall inside Value, formatted as "HH:MM-HH:MM"
+)
+
+// RoleHasRuleCondition is an optional attribute-based condition attached to a role_has_rule.
+// All conditions of a rule must hold for its Casbin policy to match.
+type RoleHasRuleCondition struct {
+	ID            uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UUID          uuid.UUID `json:"uuid" gorm:"type:uuid;default:gen_random_uuid()"`
+	RoleHasRuleID uint      `json:"role_has_rule_id" gorm:"index;not null"`
+	ConditionType string    `json:"condition_type" gorm:"type:varchar(50);not null"`
+	Value         string    `json:"value" gorm:"type:varchar(255)"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// TableName overrides the default table name
+func (RoleHasRuleCondition) TableName() string {
+	return "role_has_rule_condition"
+}
+
+// BeforeCreate is a GORM hook that sets a UUID before creating a RoleHasRuleCondition
+func (c *RoleHasRuleCondition) BeforeCreate(tx *gorm.DB) (err error) {
+	if c.UUID == uuid.Nil {
+		c.UUID = uuid.New()
+	}
+	return
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Setting history actions
+const (
+	SettingHistoryActionUpdate = "update"
+	SettingHistoryActionDelete = "delete"
+)
+
+// SettingHistory keeps the previous value of a setting each time it is updated or deleted.
+// Secret values are stored encrypted, exactly as they were in settings.
+type SettingHistory struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	SettingID   uint      `json:"setting_id" gorm:"index;not null"`
+	SettingUUID uuid.UUID `json:"setting_uuid" gorm:"type:uuid;index"`
+	Key         string    `json:"key" gorm:"size:255;not null"`
+	Value       string    `json:"value" gorm:"type:text"`
+	Namespace   string    `json:"namespace" gorm:"size:100"`
+	Type        string    `json:"type" gorm:"size:20"`
+	Action      string    `json:"action" gorm:"size:20;not null"`
+	ChangedBy   int       `json:"changed_by"`
+	ChangedAt   time.Time `json:"changed_at" gorm:"default:current_timestamp"`
+}
+
+// TableName overrides the default table name
+func (SettingHistory) TableName() string {
+	return "setting_history"
+}
@@ -6,10 +6,21 @@ import (
 	"github.com/google/uuid"
 )
 
+// Setting value types
+const (
+	SettingTypeString = "string"
+	SettingTypeInt    = "int"
+	SettingTypeBool   = "bool"
+	SettingTypeJSON   = "json"
+	SettingTypeSecret = "secret" // encrypted at rest and masked in admin responses
+)
+
 type Setting struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
-	Key       string    `json:"key" gorm:"size:255;not null"`
-	Value     string    `json:"value" gorm:"size:255"`
+	Key       string    `json:"key" gorm:"size:255;not null;uniqueIndex" validate:"required"`
+	Value     string    `json:"value" gorm:"type:text"`
+	Namespace string    `json:"namespace" gorm:"size:100;not null;default:'general'"`
+	Type      string    `json:"type" gorm:"size:20;not null;default:'string'" validate:"omitempty,oneof=string int bool json secret"`
 	CreatedAt time.Time `json:"created_at" gorm:"default:current_timestamp"`
 	UpdatedAt time.Time `json:"updated_at" gorm:"default:current_timestamp"`
 	CreatedBy int       `json:"created_by"`
@@ -86,13 +86,70 @@ func SetupRoutes(app *fiber.App) {
 	protectedUser.Get("/profile/detail", controllers.GetUserDetailController)
 	protectedUser.Post("/change-password", controllers.ChangePasswordController)
 
+	userNotificationController := controllers.NewUserNotificationController()
+	protectedUser.Get("/notifications", userNotificationController.GetNotifications)                   // List the user's notifications
+	protectedUser.Get("/notifications/stream", userNotificationController.StreamNotifications)         // Live notifications (Server-Sent Events)
+	protectedUser.Post("/notifications/read-all", userNotificationController.MarkAllNotificationsRead) // Mark all notifications as read
+	protectedUser.Post("/notifications/read/:uuid", userNotificationController.MarkNotificationRead)   // Mark a notification as read
+
+	// EventSource cannot send an Authorization header, so browsers open the stream with a short-lived stream token
+	protectedUser.Post("/notifications/stream-token", userNotificationController.IssueNotificationStreamToken)
+	api.Get("/notifications/stream", middleware.StreamTokenMiddleware(), userNotificationController.StreamNotifications)
+
+	userDeviceController := controllers.NewUserDeviceController()
+	protectedUser.Get("/devices", userDeviceController.GetDevices)                   // List the user's push devices
+	protectedUser.Post("/devices", userDeviceController.RegisterDevice)              // Register a push token
+	protectedUser.Post("/devices/unregister", userDeviceController.UnregisterDevice) // Remove a push token
+
+	healthPersonUserController := controllers.NewHealthPersonController()
+	protectedUser.Get("/health/timeline", healthPersonUserController.GetMyHealthTimeline) // The user's own health checks
+	protectedUser.Get("/health/trends", healthPersonUserController.GetMyHealthTrends)     // Trends of the user's own measurements
+
 	documentControlController := controllers.NewDocumentControlController()
-	protectedUser.Get("/document-control/list/internal", documentControlController.GetDocumentInternalControls) // List document controls with pagination
-	protectedUser.Get("/document-control/list/external", documentControlController.GetDocumentExternalControls) // List document controls with pagination
-	protectedUser.Post("/document-control", documentControlController.CreateDocumentControl)                    // Create a new document control
-	protectedUser.Get("/document-control/:uuid", documentControlController.GetDocumentControlByUUID)            // Get a document control by UUID
-	protectedUser.Put("/document-control/update/:uuid", documentControlController.UpdateDocumentControl)        // Update a document control by UUID
-	protectedUser.Delete("/document-control/delete/:uuid", documentControlController.DeleteDocumentControl)
+	protectedUser.Get("/document-control", documentControlController.ListDocumentControls)                        // List document controls with filters, sorting and page or cursor pagination
+	protectedUser.Get("/document-control/list/internal", documentControlController.GetDocumentInternalControls)   // Alias of the listing limited to the internal register
+	protectedUser.Get("/document-control/list/external", documentControlController.GetDocumentExternalControls)   // Alias of the listing limited to the external register
+	protectedUser.Post("/document-control", documentControlController.CreateDocumentControl)                      // Create a new document control
+	protectedUser.Get("/document-control/search", documentControlController.SearchDocumentControls)               // Ranked full-text search with filters
+	protectedUser.Get("/document-control/export", documentControlController.ExportDocumentRegister)               // Master list as XLSX or PDF
+	protectedUser.Get("/document-control/due-for-review", documentControlController.GetDocumentsDueForReview)     // List documents due for review or expiring
+	protectedUser.Get("/document-control/trash", documentControlController.GetTrashedDocumentControls)            // List deleted documents that have not been purged yet
+	protectedUser.Post("/document-control/trash/restore/:uuid", documentControlController.RestoreDocumentControl) // Take a document out of the trash
+	protectedUser.Post("/document-control/review/:uuid", documentControlController.MarkDocumentReviewed)          // Record a completed review
+	protectedUser.Get("/document-control/download/:uuid", documentControlController.DownloadDocumentVersion)      // Download a version, stamped when it is a PDF
+	protectedUser.Get("/document-control/compare/:uuid", documentControlController.CompareDocumentVersions)       // Compare the text and metadata of two versions
+	protectedUser.Get("/document-control/:uuid", documentControlController.GetDocumentControlByUUID)              // Get a document control by UUID
+	protectedUser.Put("/document-control/update/:uuid", documentControlController.UpdateDocumentControl)          // Update a document control by UUID
+	protectedUser.Post("/document-control/checkout/:uuid", documentControlController.CheckOutDocument)            // Lock a document for editing
+	protectedUser.Delete("/document-control/checkout/:uuid", documentControlController.CancelCheckOut)            // Release the requester's check-out
+	protectedUser.Put("/document-control/checkin/:uuid", documentControlController.CheckInDocumentControl)        // Upload the new version and release the check-out
+	protectedUser.Delete("/document-control/delete/:uuid", documentControlController.DeleteDocumentControl)       // Move a document to the trash
+
+	documentDistributionController := controllers.NewDocumentDistributionController()
+	protectedUser.Get("/document-control/distribution/:uuid", documentDistributionController.GetDistribution)                         // List a document's distribution list
+	protectedUser.Post("/document-control/distribution/:uuid", documentDistributionController.AddDistribution)                        // Add a role or user to the distribution list
+	protectedUser.Delete("/document-control/distribution/delete/:uuid/:entryUuid", documentDistributionController.DeleteDistribution) // Remove a distribution entry
+
+	userDocumentAcknowledgementController := controllers.NewUserDocumentAcknowledgementController()
+	protectedUser.Get("/acknowledgements", userDocumentAcknowledgementController.GetMyAcknowledgements)                  // List documents the user must read
+	protectedUser.Post("/acknowledgements/acknowledge/:uuid", userDocumentAcknowledgementController.AcknowledgeDocument) // Confirm a document was read
+
+	documentImportController := controllers.NewDocumentImportController()
+	protectedUser.Post("/document-import", documentImportController.StartImport)             // Upload a ZIP with a manifest; imported in the background
+	protectedUser.Get("/document-import", documentImportController.GetImportJobs)            // List the user's import jobs
+	protectedUser.Get("/document-import/:uuid", documentImportController.GetImportJob)       // Poll the progress of an import job
+	protectedUser.Get("/document-import/:uuid/rows", documentImportController.GetImportRows) // Per-row result report
+
+	documentChangeRequestController := controllers.NewDocumentChangeRequestController()
+	protectedUser.Get("/document-change-request", documentChangeRequestController.GetChangeRequests)                                   // List change requests
+	protectedUser.Post("/document-change-request", documentChangeRequestController.CreateChangeRequest)                                // Submit a change request with attachments
+	protectedUser.Get("/document-change-request/:uuid", documentChangeRequestController.GetChangeRequest)                              // Get a change request
+	protectedUser.Post("/document-change-request/review/:uuid", documentChangeRequestController.StartReview)                           // Take a submitted request into review
+	protectedUser.Post("/document-change-request/approve/:uuid", documentChangeRequestController.ApproveChangeRequest)                 // Approve a request in review
+	protectedUser.Post("/document-change-request/reject/:uuid", documentChangeRequestController.RejectChangeRequest)                   // Reject a request in review
+	protectedUser.Post("/document-change-request/cancel/:uuid", documentChangeRequestController.CancelChangeRequest)                   // Withdraw an undecided request
+	protectedUser.Post("/document-change-request/implement/:uuid", documentChangeRequestController.ImplementChangeRequest)             // Upload the new revision of an approved request
+	protectedUser.Get("/document-change-request/attachment/:uuid/:attachmentUuid", documentChangeRequestController.DownloadAttachment) // Download an attachment
 
 	// **Admin routes, protected by JWT Middleware, under /api/admin**
 	protectedAdmin := api.Group("/admin", middleware.JWTMiddleware()) // Ensure middleware is applied here
@@ -110,15 +167,18 @@ func SetupRoutes(app *fiber.App) {
 	protectedAdmin.Delete("/roles/delete/:uuid", controllers.DeleteRoleByUUIDHandler) //ci
 	protectedAdmin.Post("/roles", controllers.CreateRoleHandler)                      //ci
 
-	protectedAdmin.Post("/role-has-rule", controllers.CreateRoleHasRuleHandler)                      //ci
-	protectedAdmin.Get("/role-has-rule", controllers.GetRoleHasRulesListHandler)                     //ci
-	protectedAdmin.Get("/role-has-rule/paginated", controllers.GetPaginatedRoleHasRulesHandler)      //ci
-	protectedAdmin.Put("/role-has-rule/update/:uuid", controllers.UpdateRoleHasRuleByUUIDHandler)    //ci
-	protectedAdmin.Delete("/role-has-rule/delete/:uuid", controllers.DeleteRoleHasRuleByUUIDHandler) //ci
-	protectedAdmin.Post("/rule/active", controllers.AddCasbinRuleHandler)                            //ci
+	protectedAdmin.Post("/role-has-rule", controllers.CreateRoleHasRuleHandler)                           //ci
+	protectedAdmin.Get("/role-has-rule", controllers.GetRoleHasRulesListHandler)                          //ci
+	protectedAdmin.Get("/role-has-rule/paginated", controllers.GetPaginatedRoleHasRulesHandler)           //ci
+	protectedAdmin.Put("/role-has-rule/update/:uuid", controllers.UpdateRoleHasRuleByUUIDHandler)         //ci
+	protectedAdmin.Delete("/role-has-rule/delete/:uuid", controllers.DeleteRoleHasRuleByUUIDHandler)      //ci
+	protectedAdmin.Put("/role-has-rule/conditions/:uuid", controllers.UpdateRoleHasRuleConditionsHandler) //ci
+	protectedAdmin.Post("/rule/active", controllers.AddCasbinRuleHandler)                                 //ci
 	protectedAdmin.Post("/rule/deactive", controllers.DeleteCasbinRuleHandler)
 	protectedAdmin.Post("/rule/active/bulk", controllers.AddCasbinRuleHandlerBulk) //ci
 	protectedAdmin.Get("/rule-policy", controllers.GetUniqueRulePoliciesHandler)
+	protectedAdmin.Get("/rule-policy/drift", controllers.GetPolicyDriftHandler)         //ci
+	protectedAdmin.Post("/rule-policy/reconcile", controllers.ReconcilePoliciesHandler) //ci
 	protectedAdmin.Get("/actions", controllers.GetActionsHandler)
 	protectedAdmin.Post("/rule", controllers.CreateRoleHasRuleForAdminHandler)
 
@@ -151,12 +211,78 @@ func SetupRoutes(app *fiber.App) {
 	protectedAdmin.Put("/document-type/update/:uuid", documentTypeController.UpdateDocumentType)    // Update a document type by UUID
 	protectedAdmin.Delete("/document-type/delete/:uuid", documentTypeController.DeleteDocumentType) // Delete a document type by UUID
 
+	// Registered before /health/:uuid so these paths are not read as a UUID
 	healthController := controllers.NewHealthController()
-	protectedAdmin.Get("/health", healthController.GetHealths)            // List document types with pagination
-	protectedAdmin.Post("/health", healthController.CreateHealth)         // Create a new document type
-	protectedAdmin.Get("/health/:uuid", healthController.GetHealthByUUID) // Get a document type by UUID
-	// protectedAdmin.Put("/health/update/:uuid", healthController.UpdateHealth)    // Update a document type by UUID
-	protectedAdmin.Delete("/health/delete/:uuid", healthController.DeleteHealth) // Delete a document type by UUID
+	healthRiskController := controllers.NewHealthRiskController()
+	protectedAdmin.Get("/health/risk-rules", healthRiskController.GetHealthRiskRules)               //ci
+	protectedAdmin.Get("/health/risk-rules/history", healthRiskController.GetHealthRiskRuleHistory) //ci
+	protectedAdmin.Post("/health/risk-rules", healthRiskController.UpdateHealthRiskRules)           //ci
+	protectedAdmin.Post("/health/risk-rules/evaluate", healthRiskController.EvaluateHealthRisk)     //ci
+	protectedAdmin.Get("/health/measurement-issues", healthController.GetHealthMeasurementIssues)   //ci
+	protectedAdmin.Get("/health/dashboard", healthController.GetHealthDashboard)                    //ci
+
+	protectedAdmin.Get("/health/trash", healthController.GetTrashedHealths)            //ci
+	protectedAdmin.Post("/health/trash/restore/:uuid", healthController.RestoreHealth) //ci
+	protectedAdmin.Post("/health/import", healthController.ImportHealthScreening)      //ci
+	protectedAdmin.Get("/health/access-log", healthController.GetHealthAccessLog)      //ci
+
+	protectedAdmin.Get("/health", healthController.GetHealths)                   // List health data with pagination
+	protectedAdmin.Post("/health", healthController.CreateHealth)                // Create health data
+	protectedAdmin.Get("/health/:uuid", healthController.GetHealthByUUID)        // Get health data by UUID
+	protectedAdmin.Put("/health/update/:uuid", healthController.UpdateHealth)    // Update health data by UUID
+	protectedAdmin.Delete("/health/delete/:uuid", healthController.DeleteHealth) // Move health data to the trash
+
+	healthPersonController := controllers.NewHealthPersonController()
+	protectedAdmin.Get("/health-person", healthPersonController.GetHealthPersons)                       //ci
+	protectedAdmin.Post("/health-person", healthPersonController.CreateHealthPerson)                    //ci
+	protectedAdmin.Get("/health-person/:uuid", healthPersonController.GetHealthPersonByUUID)            //ci
+	protectedAdmin.Put("/health-person/update/:uuid", healthPersonController.UpdateHealthPerson)        //ci
+	protectedAdmin.Delete("/health-person/delete/:uuid", healthPersonController.DeleteHealthPerson)     //ci
+	protectedAdmin.Post("/health-person/link/:uuid", healthPersonController.LinkHealthRecords)          //ci
+	protectedAdmin.Get("/health-person/timeline/:uuid", healthPersonController.GetHealthPersonTimeline) //ci
+	protectedAdmin.Get("/health-person/trends/:uuid", healthPersonController.GetHealthPersonTrends)     //ci
+	protectedAdmin.Get("/health-person/export/:uuid", healthPersonController.ExportHealthPerson)        //ci
+	protectedAdmin.Delete("/health-person/erase/:uuid", healthPersonController.EraseHealthPerson)       //ci
+
+	settingController := controllers.NewAdminSettingController()
+	protectedAdmin.Get("/setting", settingController.GetAdminSettingsPaginated)       //ci
+	protectedAdmin.Post("/setting", settingController.CreateSetting)                  //ci
+	protectedAdmin.Get("/setting/key/:key", settingController.GetAdminSetting)        //ci
+	protectedAdmin.Get("/setting/history/:uuid", settingController.GetSettingHistory) //ci
+	protectedAdmin.Get("/setting/:uuid", settingController.GetAdminSettingUUID)       //ci
+	protectedAdmin.Put("/setting/update/:uuid", settingController.UpdateSetting)      //ci
+	protectedAdmin.Delete("/setting/delete/:uuid", settingController.DeleteSetting)   //ci
+
+	emailOutboxController := controllers.NewEmailOutboxController()
+	protectedAdmin.Get("/email-outbox", emailOutboxController.GetEmailOutbox)          //ci
+	protectedAdmin.Post("/email-outbox/retry/:uuid", emailOutboxController.RetryEmail) //ci
+
+	notificationTemplateController := controllers.NewNotificationTemplateController()
+	protectedAdmin.Get("/notification-template", notificationTemplateController.GetNotificationTemplates)                   //ci
+	protectedAdmin.Post("/notification-template", notificationTemplateController.CreateNotificationTemplate)                //ci
+	protectedAdmin.Post("/notification-template/preview", notificationTemplateController.PreviewNotificationTemplate)       //ci
+	protectedAdmin.Post("/notification-template/test-send", notificationTemplateController.TestSendNotificationTemplate)    //ci
+	protectedAdmin.Get("/notification-template/:uuid", notificationTemplateController.GetNotificationTemplateByUUID)        //ci
+	protectedAdmin.Put("/notification-template/update/:uuid", notificationTemplateController.UpdateNotificationTemplate)    //ci
+	protectedAdmin.Delete("/notification-template/delete/:uuid", notificationTemplateController.DeleteNotificationTemplate) //ci
+
+	pushDeliveryController := controllers.NewPushDeliveryController()
+	protectedAdmin.Get("/push-delivery", pushDeliveryController.GetPushDeliveries) //ci
+
+	documentAcknowledgementController := controllers.NewDocumentDistributionController()
+	protectedAdmin.Get("/document-acknowledgement/document/:uuid", documentAcknowledgementController.GetDocumentComplianceReport) //ci
+	protectedAdmin.Get("/document-acknowledgement/user/:uuid", documentAcknowledgementController.GetUserComplianceReport)         //ci
+
+	documentControlAdminController := controllers.NewDocumentControlController()
+	protectedAdmin.Post("/document-control/unlock/:uuid", documentControlAdminController.ForceUnlockDocument)   //ci
+	protectedAdmin.Post("/document-control/retention/run", documentControlAdminController.RunDocumentRetention) //ci
+
+	documentRetentionController := controllers.NewDocumentRetentionController()
+	protectedAdmin.Get("/document-retention-policy", documentRetentionController.GetRetentionPolicies)                  //ci
+	protectedAdmin.Post("/document-retention-policy", documentRetentionController.CreateRetentionPolicy)                //ci
+	protectedAdmin.Get("/document-retention-policy/:uuid", documentRetentionController.GetRetentionPolicyByUUID)        //ci
+	protectedAdmin.Put("/document-retention-policy/update/:uuid", documentRetentionController.UpdateRetentionPolicy)    //ci
+	protectedAdmin.Delete("/document-retention-policy/delete/:uuid", documentRetentionController.DeleteRetentionPolicy) //ci
 
 	protectedAdmin.Get("/role-action-master", categoryDocumentController.GetRolesAndActions)
 	// Delete a document control by UUID
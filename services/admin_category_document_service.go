@@ -133,7 +133,7 @@ func (s *CategoryDocumentService) GetCategoryDocumentsPaginated(currentPage, pag
 func (s *CategoryDocumentService) AddCategoryDocument(payload *CategoryDocumentPayload) (*models.CategoryDocument, error) {
 	var categoryDocument models.CategoryDocument
 
-	err := config.DB.Transaction(func(tx *gorm.DB) error {
+	err := withPolicyTransaction(func(tx *gorm.DB) error {
 		// Step 1: Check if Prefix already exists in category_document
 		var existingCategoryDocument models.CategoryDocument
 		if err := tx.Where("prefix = ?", payload.Prefix).First(&existingCategoryDocument).Error; err == nil {
@@ -153,54 +153,8 @@ func (s *CategoryDocumentService) AddCategoryDocument(payload *CategoryDocumentP
 			return fmt.Errorf("failed to create category document: %w", err)
 		}
 
-		// Step 2: Create entries in role_has_rule table, ensuring no duplicates
-		for _, rule := range payload.RoleHasRules {
-			var existingRoleHasRule models.RoleHasRule
-			if err := tx.Where("role_guard_name = ? AND action = ? AND category = ?", rule.RoleGuardName, rule.Action, payload.Prefix).First(&existingRoleHasRule).Error; err == nil {
-				// If a duplicate is found, skip adding this rule
-				continue
-			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
-				return fmt.Errorf("failed to check for existing role has rule: %w", err)
-			}
-
-			// Create new role_has_rule if no duplicate found
-			roleHasRule := models.RoleHasRule{
-				RoleGuardName: rule.RoleGuardName,
-				RulePolicy:    "document",
-				Action:        rule.Action,
-				Category:      payload.Prefix,
-			}
-			if err := tx.Create(&roleHasRule).Error; err != nil {
-				return fmt.Errorf("failed to create role has rule: %w", err)
-			}
-		}
-
-		// Step 3: Create entries in casbin_rule table, ensuring no duplicates
-		for _, rule := range payload.RoleHasRules {
-			var existingCasbinRule models.CasbinRule
-			casbinRule := models.CasbinRule{
-				Ptype: "p",
-				V0:    rule.RoleGuardName,
-				V1:    "document",
-				V2:    rule.Action,
-				V3:    payload.Prefix,
-			}
-
-			// Check for duplicate in casbin_rule
-			if err := tx.Where("ptype = ? AND v0 = ? AND v1 = ? AND v2 = ? AND v3 = ?", casbinRule.Ptype, casbinRule.V0, casbinRule.V1, casbinRule.V2, casbinRule.V3).First(&existingCasbinRule).Error; err == nil {
-				// If duplicate found, skip adding this rule
-				continue
-			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
-				return fmt.Errorf("failed to check for existing casbin rule: %w", err)
-			}
-
-			// Create new casbin_rule if no duplicate found
-			if err := tx.Create(&casbinRule).Error; err != nil {
-				return fmt.Errorf("failed to create casbin rule: %w", err)
-			}
-		}
-
-		return nil
+		// Step 2: Grant the rules in role_has_rule and casbin_rule
+		return setDocumentRules(tx, payload.Prefix, "", nil, payload.RoleHasRules)
 	})
 
 	if err != nil {
@@ -222,65 +176,31 @@ func (s *CategoryDocumentService) UpdateCategoryDocumentByUUID(uuid string, payl
 	}
 
 	// Update name and prefix to match payload
+	oldPrefix := categoryDocument.Prefix
 	categoryDocument.Name = payload.Name
 	categoryDocument.Prefix = payload.Prefix
 	categoryDocument.UpdatedAt = time.Now()
 
 	// Start transaction to handle role updates
-	err := config.DB.Transaction(func(tx *gorm.DB) error {
+	err := withPolicyTransaction(func(tx *gorm.DB) error {
 		// Update the category document's name and prefix
 		if err := tx.Save(&categoryDocument).Error; err != nil {
 			return fmt.Errorf("failed to update category document: %w", err)
 		}
 
-		// Fetch existing RoleHasRule entries for this document's prefix
-		var existingRules []models.RoleHasRule
-		if err := tx.Where("category = ?", categoryDocument.Prefix).Find(&existingRules).Error; err != nil {
-			return fmt.Errorf("failed to fetch existing role rules: %w", err)
+		// The policies of the category and its types follow a new prefix
+		if err := renameDocumentCategoryRules(tx, oldPrefix, categoryDocument.Prefix); err != nil {
+			return err
 		}
 
-		// Prepare maps for quick lookup
-		existingRulesMap := make(map[string]models.RoleHasRule)
-		for _, rule := range existingRules {
-			key := fmt.Sprintf("%s:%s", rule.RoleGuardName, rule.Action)
-			existingRulesMap[key] = rule
-		}
-
-		// Process role_has_rules from payload
-		for _, rule := range payload.RoleHasRules {
-			key := fmt.Sprintf("%s:%s", rule.RoleGuardName, rule.Action)
-
-			if existingRule, found := existingRulesMap[key]; found {
-				// Update existing rule if found
-				existingRule.Category = payload.Prefix
-				existingRule.RulePolicy = "document"
-				if err := tx.Save(&existingRule).Error; err != nil {
-					return fmt.Errorf("failed to update role rule: %w", err)
-				}
-				// Remove from map after processing to track what's left
-				delete(existingRulesMap, key)
-			} else {
-				// Create new rule if not found
-				newRule := models.RoleHasRule{
-					RoleGuardName: rule.RoleGuardName,
-					RulePolicy:    "document",
-					Action:        rule.Action,
-					Category:      payload.Prefix,
-				}
-				if err := tx.Create(&newRule).Error; err != nil {
-					return fmt.Errorf("failed to create new role rule: %w", err)
-				}
-			}
-		}
-
-		// Delete any remaining rules in existingRulesMap that are not in the payload
-		for _, rule := range existingRulesMap {
-			if err := tx.Delete(&rule).Error; err != nil {
-				return fmt.Errorf("failed to delete obsolete role rule: %w", err)
-			}
+		// Replace the rules of the category itself; those of its types are managed with the types
+		var existingRules []models.RoleHasRule
+		if err := tx.Where("rule_policy = ? AND category = ?", "document", categoryDocument.Prefix).
+			Where("COALESCE(NULLIF(type, ''), 'none') = 'none'").
+			Find(&existingRules).Error; err != nil {
+			return fmt.Errorf("failed to fetch existing role rules: %w", err)
 		}
-
-		return nil
+		return setDocumentRules(tx, categoryDocument.Prefix, "", existingRules, payload.RoleHasRules)
 	})
 
 	if err != nil {
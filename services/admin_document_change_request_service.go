@@ -0,0 +1,449 @@
+package services
+
+import (
+	"backend-school/config"
+	"backend-school/models"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxChangeRequestAttachments is the number of files that can be attached to one change request
+const maxChangeRequestAttachments = 10
+
+// documentChangeRequestRequiredSettingKey makes an approved change request the only way to upload a new revision
+const documentChangeRequestRequiredSettingKey = "document_change_request_required"
+
+var (
+	ErrChangeRequestNotFound           = errors.New("change request not found")
+	ErrChangeRequestAttachmentNotFound = errors.New("attachment not found")
+	ErrInvalidChangeRequest            = errors.New("invalid change request")
+	// ErrChangeRequestState is returned for an action the change request's current status does not allow
+	ErrChangeRequestState = errors.New("the change request's status does not allow this action")
+	// ErrChangeRequestSelfReview is returned when the requester tries to review their own change request
+	ErrChangeRequestSelfReview = errors.New("a change request cannot be reviewed by its requester")
+	// ErrChangeRequestNotRequester is returned when someone other than the requester tries to cancel a change request
+	ErrChangeRequestNotRequester = errors.New("only the requester can cancel a change request")
+	// ErrChangeRequestRequired is returned by UpdateDocumentControl for a new file while change requests are required
+	ErrChangeRequestRequired = errors.New("a new revision must be uploaded through an approved change request")
+)
+
+// DocumentChangeRequestService manages change requests (DCRs) of controlled documents: their review and
+// approval, and the new revision created when an approved request is implemented
+type DocumentChangeRequestService struct {
+	documents *DocumentControlService
+}
+
+func NewDocumentChangeRequestService(documents *DocumentControlService) *DocumentChangeRequestService {
+	return &DocumentChangeRequestService{documents: documents}
+}
+
+// DocumentChangeRequestFilter narrows a change request listing; zero values are not applied
+type DocumentChangeRequestFilter struct {
+	DocumentControlID int
+	Status            string
+	RequestedBy       int
+}
+
+// DocumentChangeRequestDetail is a change request with its document, people and attachments
+type DocumentChangeRequestDetail struct {
+	models.DocumentChangeRequest
+	DocumentUUID      uuid.UUID                                `json:"document_uuid"`
+	DocumentNumber    string                                   `json:"document_number"`
+	DocumentName      string                                   `json:"document_name"`
+	RequesterUsername string                                   `json:"requester_username"`
+	ReviewerUsername  string                                   `json:"reviewer_username"`
+	VersionUUID       *uuid.UUID                               `json:"version_uuid"`
+	Attachments       []models.DocumentChangeRequestAttachment `json:"attachments" gorm:"-"`
+}
+
+// documentChangeRequestQuery joins change requests with their document, people and implemented version
+func documentChangeRequestQuery() *gorm.DB {
+	return config.DB.Model(&models.DocumentChangeRequest{}).
+		Select("document_change_request.*, document_control.uuid AS document_uuid, document_control.document_number, " +
+			"document_control.document_name, requester.username AS requester_username, reviewer.username AS reviewer_username, " +
+			"document_version.uuid AS version_uuid").
+		Joins("JOIN document_control ON document_control.id = document_change_request.document_control_id").
+		Joins("LEFT JOIN users AS requester ON requester.id = document_change_request.requested_by").
+		Joins("LEFT JOIN users AS reviewer ON reviewer.id = document_change_request.reviewed_by").
+		Joins("LEFT JOIN document_version ON document_version.id = document_change_request.document_version_id")
+}
+
+// changeRequestsRequired reports whether new revisions may only be uploaded through change requests
+func changeRequestsRequired() bool {
+	required, err := NewSettingsService().GetSettingBool(documentChangeRequestRequiredSettingKey)
+	return err == nil && required
+}
+
+// CreateChangeRequest submits a change request for a document with its supporting files
+func (s *DocumentChangeRequestService) CreateChangeRequest(documentControl *models.DocumentControl, reason, proposedChanges string, files []*multipart.FileHeader, userID int) (*DocumentChangeRequestDetail, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, fmt.Errorf("%w: reason is required", ErrInvalidChangeRequest)
+	}
+	if len(files) > maxChangeRequestAttachments {
+		return nil, fmt.Errorf("%w: at most %d attachments are allowed", ErrInvalidChangeRequest, maxChangeRequestAttachments)
+	}
+	for _, file := range files {
+		if file.Size > maxDocumentFileSize {
+			return nil, fmt.Errorf("%w: %s exceeds the 10 MB limit", ErrInvalidChangeRequest, file.Filename)
+		}
+	}
+
+	request := models.DocumentChangeRequest{
+		DocumentControlID: documentControl.ID,
+		Reason:            reason,
+		ProposedChanges:   strings.TrimSpace(proposedChanges),
+		Status:            models.DocumentChangeRequestSubmitted,
+		RequestedBy:       userID,
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&request).Error; err != nil {
+			return fmt.Errorf("failed to create change request: %w", err)
+		}
+
+		for _, file := range files {
+			contentType, err := sniffAttachmentType(file)
+			if err != nil {
+				return err
+			}
+			fileURL, err := s.documents.uploadFileToMinio(multipartDocumentFile(file), "document-change-requests", contentType)
+			if err != nil {
+				return fmt.Errorf("failed to upload attachment: %w", err)
+			}
+			attachment := models.DocumentChangeRequestAttachment{
+				DocumentChangeRequestID: request.ID,
+				FileName:                filepath.Base(file.Filename),
+				File:                    fileURL,
+				MimeType:                contentType,
+				FileSize:                file.Size,
+				UploadedBy:              userID,
+			}
+			if err := tx.Create(&attachment).Error; err != nil {
+				return fmt.Errorf("failed to save attachment: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Let the document owner know a revision was requested
+	if documentControl.CreatedBy != nil && *documentControl.CreatedBy != userID {
+		notifyUserBestEffort(*documentControl.CreatedBy, models.NotificationEventDocumentChangeRequestSubmitted,
+			fmt.Sprintf("Change requested for %s", documentControl.DocumentNumber),
+			fmt.Sprintf("A change request was submitted for %s: %s", documentControl.DocumentName, reason),
+			"/document-change-request/"+request.UUID.String())
+	}
+
+	return s.GetChangeRequest(request.UUID.String())
+}
+
+// sniffAttachmentType determines the content type of an attachment from its first bytes
+func sniffAttachmentType(file *multipart.FileHeader) (string, error) {
+	f, err := file.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open attachment: %w", err)
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("failed to read attachment: %w", err)
+	}
+	return strings.TrimSpace(strings.Split(http.DetectContentType(head[:n]), ";")[0]), nil
+}
+
+// GetChangeRequest returns a change request with its attachments
+func (s *DocumentChangeRequestService) GetChangeRequest(uuidStr string) (*DocumentChangeRequestDetail, error) {
+	var detail DocumentChangeRequestDetail
+	if err := documentChangeRequestQuery().Where("document_change_request.uuid = ?", uuidStr).Take(&detail).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrChangeRequestNotFound
+		}
+		return nil, errors.New("failed to fetch change request")
+	}
+
+	detail.Attachments = []models.DocumentChangeRequestAttachment{}
+	if err := config.DB.Where("document_change_request_id = ?", detail.ID).Order("id ASC").Find(&detail.Attachments).Error; err != nil {
+		return nil, errors.New("failed to fetch attachments")
+	}
+	return &detail, nil
+}
+
+// GetChangeRequestsPaginated lists change requests, newest first
+func (s *DocumentChangeRequestService) GetChangeRequestsPaginated(filter DocumentChangeRequestFilter, perPage, page int) (map[string]interface{}, error) {
+	var requests []DocumentChangeRequestDetail
+	var totalRecords int64
+
+	// Calculate offset for pagination
+	offset := (page - 1) * perPage
+
+	query := documentChangeRequestQuery()
+	if filter.DocumentControlID != 0 {
+		query = query.Where("document_change_request.document_control_id = ?", filter.DocumentControlID)
+	}
+	if filter.Status != "" {
+		query = query.Where("document_change_request.status = ?", filter.Status)
+	}
+	if filter.RequestedBy != 0 {
+		query = query.Where("document_change_request.requested_by = ?", filter.RequestedBy)
+	}
+
+	// Get the total number of records
+	if err := query.Count(&totalRecords).Error; err != nil {
+		return nil, errors.New("failed to count change requests")
+	}
+
+	if err := query.Order("document_change_request.created_at DESC, document_change_request.id DESC").Limit(perPage).Offset(offset).Find(&requests).Error; err != nil {
+		return nil, errors.New("failed to fetch change requests")
+	}
+
+	// Calculate total pages
+	totalPages := int(math.Ceil(float64(totalRecords) / float64(perPage)))
+
+	return map[string]interface{}{
+		"data":          requests,
+		"current_page":  page,
+		"per_page":      perPage,
+		"total_pages":   totalPages,
+		"total_records": totalRecords,
+	}, nil
+}
+
+// transition moves a change request to a new status when it is in one of the from statuses. The status is checked
+// in the UPDATE itself, so of two concurrent actions only one succeeds.
+func transitionChangeRequest(tx *gorm.DB, request *models.DocumentChangeRequest, from []string, updates map[string]interface{}) error {
+	result := tx.Model(&models.DocumentChangeRequest{}).Where("id = ? AND status IN ?", request.ID, from).Updates(updates)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update change request: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrChangeRequestState
+	}
+	return nil
+}
+
+// StartReview takes a submitted change request into review
+func (s *DocumentChangeRequestService) StartReview(request *DocumentChangeRequestDetail, reviewerID int) (*DocumentChangeRequestDetail, error) {
+	if request.RequestedBy == reviewerID {
+		return nil, ErrChangeRequestSelfReview
+	}
+	now := time.Now()
+	if err := transitionChangeRequest(config.DB, &request.DocumentChangeRequest, []string{models.DocumentChangeRequestSubmitted}, map[string]interface{}{
+		"status":            models.DocumentChangeRequestInReview,
+		"reviewed_by":       reviewerID,
+		"review_started_at": now,
+		"updated_at":        now,
+	}); err != nil {
+		return nil, err
+	}
+	return s.GetChangeRequest(request.UUID.String())
+}
+
+// Decide approves or rejects a change request in review. A rejection needs a comment for the requester.
+func (s *DocumentChangeRequestService) Decide(request *DocumentChangeRequestDetail, approve bool, comment string, reviewerID int) (*DocumentChangeRequestDetail, error) {
+	if request.RequestedBy == reviewerID {
+		return nil, ErrChangeRequestSelfReview
+	}
+	comment = strings.TrimSpace(comment)
+	status := models.DocumentChangeRequestApproved
+	if !approve {
+		status = models.DocumentChangeRequestRejected
+		if comment == "" {
+			return nil, fmt.Errorf("%w: a comment is required to reject", ErrInvalidChangeRequest)
+		}
+	}
+
+	now := time.Now()
+	if err := transitionChangeRequest(config.DB, &request.DocumentChangeRequest, []string{models.DocumentChangeRequestInReview}, map[string]interface{}{
+		"status":           status,
+		"reviewed_by":      reviewerID,
+		"decision_comment": comment,
+		"decided_at":       now,
+		"updated_at":       now,
+	}); err != nil {
+		return nil, err
+	}
+
+	message := fmt.Sprintf("Your change request for %s was %s.", request.DocumentName, status)
+	if comment != "" {
+		message += " " + comment
+	}
+	notifyUserBestEffort(request.RequestedBy, models.NotificationEventDocumentChangeRequestDecided,
+		fmt.Sprintf("Change request for %s %s", request.DocumentNumber, status), message,
+		"/document-change-request/"+request.UUID.String())
+
+	return s.GetChangeRequest(request.UUID.String())
+}
+
+// Cancel withdraws a change request that has not been decided; only the requester may cancel it
+func (s *DocumentChangeRequestService) Cancel(request *DocumentChangeRequestDetail, userID int) (*DocumentChangeRequestDetail, error) {
+	if request.RequestedBy != userID {
+		return nil, ErrChangeRequestNotRequester
+	}
+	if err := transitionChangeRequest(config.DB, &request.DocumentChangeRequest, []string{models.DocumentChangeRequestSubmitted, models.DocumentChangeRequestInReview}, map[string]interface{}{
+		"status":     models.DocumentChangeRequestCancelled,
+		"updated_at": time.Now(),
+	}); err != nil {
+		return nil, err
+	}
+	return s.GetChangeRequest(request.UUID.String())
+}
+
+// Implement creates the next revision of the document from an approved change request. The version gets the
+// incremented revision number and both the version and the request keep a link to each other.
+// statusID sets the status of the document and the new version; 0 keeps the document's current status.
+func (s *DocumentChangeRequestService) Implement(request *DocumentChangeRequestDetail, fileHeader *multipart.FileHeader, note string, statusID, userID int) (*DocumentChangeRequestDetail, error) {
+	if request.Status != models.DocumentChangeRequestApproved {
+		return nil, ErrChangeRequestState
+	}
+
+	var documentControl models.DocumentControl
+	if err := config.DB.Where("id = ? AND deleted_at IS NULL", request.DocumentControlID).First(&documentControl).Error; err != nil {
+		return nil, errors.New("document control not found")
+	}
+
+	// Check the file before anything is stored; an identical re-upload is not a new revision
+	fileInfo, err := inspectDocumentFile(multipartDocumentFile(fileHeader))
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureNewDocumentFile(documentControl.ID, fileInfo.Checksum); err != nil {
+		return nil, err
+	}
+
+	if note = strings.TrimSpace(note); note == "" {
+		note = "Implements change request " + request.UUID.String()
+	}
+
+	var version models.DocumentVersion
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := transitionChangeRequest(tx, &request.DocumentChangeRequest, []string{models.DocumentChangeRequestApproved}, map[string]interface{}{
+			"status":         models.DocumentChangeRequestImplemented,
+			"implemented_by": userID,
+			"implemented_at": now,
+			"updated_at":     now,
+		}); err != nil {
+			return err
+		}
+
+		// Lock the document so concurrent revisions get consecutive numbers
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", documentControl.ID).First(&documentControl).Error; err != nil {
+			return fmt.Errorf("failed to lock document control: %w", err)
+		}
+		if holder := activeCheckout(&documentControl, now); holder != nil && *holder != userID {
+			return checkedOutError(&documentControl)
+		}
+		documentControl.RevisionNumber++
+		documentControl.LockVersion++
+		if statusID != 0 {
+			documentControl.StatusDocumentID = IntPtr(statusID)
+		}
+		if fileInfo.PageCount > 0 {
+			documentControl.PageCount = fileInfo.PageCount
+		}
+		documentControl.UpdatedAt = now
+		if err := tx.Save(&documentControl).Error; err != nil {
+			return fmt.Errorf("failed to update document control: %w", err)
+		}
+
+		filePath, err := s.documents.uploadFileToMinio(multipartDocumentFile(fileHeader), "document-versions", fileInfo.ContentType)
+		if err != nil {
+			return fmt.Errorf("failed to upload file: %w", err)
+		}
+
+		version = models.DocumentVersion{
+			File:                    filePath,
+			DocumentControlID:       &documentControl.ID,
+			Version:                 IntPtr(documentControl.RevisionNumber),
+			StatusDocumentID:        documentControl.StatusDocumentID,
+			Note:                    note,
+			DocumentChangeRequestID: IntPtr(int(request.ID)),
+			CreatedAt:               now,
+			UpdatedAt:               now,
+		}
+		fileInfo.applyTo(&version)
+		if err := tx.Create(&version).Error; err != nil {
+			return fmt.Errorf("failed to create new document version: %w", err)
+		}
+
+		if err := tx.Model(&models.DocumentChangeRequest{}).Where("id = ?", request.ID).Updates(map[string]interface{}{
+			"document_version_id": version.ID,
+			"revision_number":     documentControl.RevisionNumber,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to link change request: %w", err)
+		}
+
+		// Re-index the document with the content of the new revision
+		if err := refreshDocumentSearchVector(tx, documentControl.ID); err != nil {
+			return fmt.Errorf("failed to index document control: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The new revision must be read and acknowledged by the distribution list when it is approved
+	assignAcknowledgementsBestEffort(documentControl, version)
+
+	if request.RequestedBy != userID {
+		notifyUserBestEffort(request.RequestedBy, models.NotificationEventDocumentChangeRequestImplemented,
+			fmt.Sprintf("%s Rev. %d published", documentControl.DocumentNumber, documentControl.RevisionNumber),
+			fmt.Sprintf("Your change request for %s was implemented as revision %d.", documentControl.DocumentName, documentControl.RevisionNumber),
+			"/document-control/"+documentControl.UUID.String())
+	}
+
+	return s.GetChangeRequest(request.UUID.String())
+}
+
+// DownloadAttachment reads an attachment of a change request from storage
+func (s *DocumentChangeRequestService) DownloadAttachment(request *DocumentChangeRequestDetail, attachmentUUID string) (*DocumentDownload, error) {
+	var attachment *models.DocumentChangeRequestAttachment
+	for i := range request.Attachments {
+		if request.Attachments[i].UUID.String() == attachmentUUID {
+			attachment = &request.Attachments[i]
+		}
+	}
+	if attachment == nil {
+		return nil, ErrChangeRequestAttachmentNotFound
+	}
+
+	if s.documents.minioClient == nil {
+		return nil, fmt.Errorf("MinIO client is not initialized")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	object, err := s.documents.minioClient.GetObject(ctx, s.documents.bucketName, documentObjectName(attachment.File, s.documents.bucketName), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch file: %v", err)
+	}
+	defer object.Close()
+	content, err := io.ReadAll(io.LimitReader(object, maxDocumentFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+
+	return &DocumentDownload{
+		FileName:    attachment.FileName,
+		ContentType: attachment.MimeType,
+		Content:     content,
+	}, nil
+}
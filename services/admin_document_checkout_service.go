@@ -0,0 +1,169 @@
+package services
+
+import (
+	"backend-school/config"
+	"backend-school/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// defaultDocumentCheckoutTTL is used when document_checkout_ttl_minutes is not configured
+const defaultDocumentCheckoutTTL = 4 * time.Hour
+
+var (
+	// ErrDocumentCheckedOut is returned when another user holds the check-out of a document
+	ErrDocumentCheckedOut = errors.New("document is checked out by another user")
+	// ErrDocumentNotCheckedOut is returned for a check-in or release without a check-out held by the user
+	ErrDocumentNotCheckedOut = errors.New("document is not checked out by you")
+	// ErrStaleDocument is returned when a document changed after the version the client edited
+	ErrStaleDocument = errors.New("document was modified by someone else; reload it and try again")
+)
+
+// DocumentUpdateOptions carries the concurrency checks of a document update
+type DocumentUpdateOptions struct {
+	UserID      int
+	LockVersion int  // the lock_version the client edited (If-Match); 0 skips the comparison
+	CheckIn     bool // a new file is required and the user's check-out is released
+}
+
+// documentCheckoutTTL returns how long a check-out lasts
+func documentCheckoutTTL() time.Duration {
+	minutes, err := NewSettingsService().GetSettingInt("document_checkout_ttl_minutes")
+	if err != nil || minutes <= 0 {
+		return defaultDocumentCheckoutTTL
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// activeCheckout returns the user holding an unexpired check-out of the document, or nil
+func activeCheckout(documentControl *models.DocumentControl, now time.Time) *int {
+	if documentControl.CheckedOutBy == nil || documentControl.CheckoutExpiresAt == nil || !documentControl.CheckoutExpiresAt.After(now) {
+		return nil
+	}
+	return documentControl.CheckedOutBy
+}
+
+// checkedOutError describes who holds a check-out and until when
+func checkedOutError(documentControl *models.DocumentControl) error {
+	var holder models.User
+	name := "another user"
+	if err := config.DB.Select("username").Where("id = ?", *documentControl.CheckedOutBy).First(&holder).Error; err == nil {
+		name = holder.Username
+	}
+	return fmt.Errorf("%w: held by %s until %s", ErrDocumentCheckedOut, name, documentControl.CheckoutExpiresAt.Format(time.RFC3339))
+}
+
+// reloadDocumentControl reads the current state of a document after a conditional update matched no row
+func reloadDocumentControl(id int) (*models.DocumentControl, error) {
+	var documentControl models.DocumentControl
+	if err := config.DB.Where("id = ?", id).First(&documentControl).Error; err != nil {
+		return nil, fmt.Errorf("failed to find document control: %w", err)
+	}
+	return &documentControl, nil
+}
+
+// CheckOutDocument locks a document to the user until the check-out expires. Checking out a document the user
+// already holds extends the lock.
+func (s *DocumentControlService) CheckOutDocument(documentControl *models.DocumentControl, userID int) (*models.DocumentControl, error) {
+	now := time.Now()
+	expiresAt := now.Add(documentCheckoutTTL())
+
+	// Take the lock only when it is free, expired or already ours, in one statement
+	result := config.DB.Model(&models.DocumentControl{}).
+		Where("id = ? AND (checked_out_by IS NULL OR checkout_expires_at <= ? OR checked_out_by = ?)", documentControl.ID, now, userID).
+		Updates(map[string]interface{}{
+			"checked_out_by":      userID,
+			"checked_out_at":      now,
+			"checkout_expires_at": expiresAt,
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to check out document: %w", result.Error)
+	}
+
+	current, err := reloadDocumentControl(documentControl.ID)
+	if err != nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		return nil, checkedOutError(current)
+	}
+	return current, nil
+}
+
+// CancelCheckOut releases the user's check-out without uploading a new version
+func (s *DocumentControlService) CancelCheckOut(documentControl *models.DocumentControl, userID int) (*models.DocumentControl, error) {
+	result := config.DB.Model(&models.DocumentControl{}).
+		Where("id = ? AND checked_out_by = ? AND checkout_expires_at > ?", documentControl.ID, userID, time.Now()).
+		Updates(map[string]interface{}{"checked_out_by": nil, "checked_out_at": nil, "checkout_expires_at": nil})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to release check-out: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrDocumentNotCheckedOut
+	}
+	return reloadDocumentControl(documentControl.ID)
+}
+
+// ForceUnlockDocument releases a check-out held by anyone; the holder is told their lock was removed
+func (s *DocumentControlService) ForceUnlockDocument(documentControl *models.DocumentControl, adminID int) (*models.DocumentControl, error) {
+	holder := activeCheckout(documentControl, time.Now())
+
+	if err := config.DB.Model(&models.DocumentControl{}).Where("id = ?", documentControl.ID).
+		Updates(map[string]interface{}{"checked_out_by": nil, "checked_out_at": nil, "checkout_expires_at": nil}).Error; err != nil {
+		return nil, fmt.Errorf("failed to unlock document: %w", err)
+	}
+
+	if holder != nil && *holder != adminID {
+		notifyUserBestEffort(*holder, models.NotificationEventDocumentCheckoutReleased,
+			fmt.Sprintf("Check-out of %s was released", documentControl.DocumentNumber),
+			fmt.Sprintf("An administrator released your check-out of %s. Check it out again before uploading a new version.", documentControl.DocumentName),
+			"/document-control/"+documentControl.UUID.String())
+	}
+	return reloadDocumentControl(documentControl.ID)
+}
+
+// saveDocumentControlChecked writes an updated document when nobody changed it since expectedVersion was read.
+// Uploads additionally require that no one else holds the check-out; a check-in requires the user's own
+// check-out and releases it. LockVersion is incremented.
+func saveDocumentControlChecked(tx *gorm.DB, documentControl *models.DocumentControl, expectedVersion int, upload bool, opts DocumentUpdateOptions) error {
+	now := time.Now()
+	documentControl.LockVersion = expectedVersion + 1
+
+	query := tx.Model(&models.DocumentControl{}).Where("id = ? AND lock_version = ?", documentControl.ID, expectedVersion)
+	omit := []string{"ID", "UUID", "CreatedAt", "CreatedBy"}
+	switch {
+	case opts.CheckIn:
+		query = query.Where("checked_out_by = ? AND checkout_expires_at > ?", opts.UserID, now)
+		documentControl.CheckedOutBy, documentControl.CheckedOutAt, documentControl.CheckoutExpiresAt = nil, nil, nil
+	case upload:
+		query = query.Where("(checked_out_by IS NULL OR checkout_expires_at <= ? OR checked_out_by = ?)", now, opts.UserID)
+		omit = append(omit, "CheckedOutBy", "CheckedOutAt", "CheckoutExpiresAt")
+	default:
+		// A metadata edit leaves the lock as it is, whoever took it in the meantime
+		omit = append(omit, "CheckedOutBy", "CheckedOutAt", "CheckoutExpiresAt")
+	}
+
+	result := query.Select("*").Omit(omit...).Updates(documentControl)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update document control: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	// Nothing matched: tell a concurrent edit apart from a lock taken in the meantime
+	current, err := reloadDocumentControl(documentControl.ID)
+	if err != nil {
+		return err
+	}
+	if current.LockVersion != expectedVersion {
+		return ErrStaleDocument
+	}
+	if holder := activeCheckout(current, now); holder != nil && *holder != opts.UserID {
+		return checkedOutError(current)
+	}
+	return ErrDocumentNotCheckedOut
+}
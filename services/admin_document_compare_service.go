@@ -0,0 +1,517 @@
+package services
+
+import (
+	"backend-school/config"
+	"backend-school/models"
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ledongthuc/pdf"
+	"gorm.io/gorm"
+)
+
+// maxCompareLines caps the lines of each version taken into a comparison
+const maxCompareLines = 20000
+
+// maxCompareEdits bounds the work of the diff between two anchors (see diffLineOps); a gap that differs by more
+// lines is reported as one changed block
+const maxCompareEdits = 2000
+
+// Diff block types
+const (
+	DocumentDiffAdded   = "added"
+	DocumentDiffRemoved = "removed"
+	DocumentDiffChanged = "changed"
+)
+
+// DocumentCompareLine is a line of extracted text; Line counts from 1 over the whole document
+type DocumentCompareLine struct {
+	Line int    `json:"line"`
+	Page int    `json:"page"`
+	Text string `json:"text"`
+}
+
+// DocumentDiffBlock is a run of lines that differ between two versions. FromLine and ToLine are where the block
+// starts in each version, so unchanged text in between can be shown side by side.
+type DocumentDiffBlock struct {
+	Type     string                `json:"type"`
+	FromLine int                   `json:"from_line"`
+	ToLine   int                   `json:"to_line"`
+	Removed  []DocumentCompareLine `json:"removed"`
+	Added    []DocumentCompareLine `json:"added"`
+}
+
+// DocumentDiffStats counts the lines of a text comparison
+type DocumentDiffStats struct {
+	Added     int `json:"added"`
+	Removed   int `json:"removed"`
+	Unchanged int `json:"unchanged"`
+}
+
+// DocumentMetadataChange is one field of the compared versions
+type DocumentMetadataChange struct {
+	Field   string      `json:"field"`
+	From    interface{} `json:"from"`
+	To      interface{} `json:"to"`
+	Changed bool        `json:"changed"`
+}
+
+// DocumentVersionSummary identifies a compared version
+type DocumentVersionSummary struct {
+	UUID      string    `json:"uuid"`
+	Version   *int      `json:"version"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DocumentVersionComparison is the result of comparing two versions of a document. The text is only compared
+// when both versions are PDFs; otherwise TextComparable is false and Reason says why.
+type DocumentVersionComparison struct {
+	From           DocumentVersionSummary   `json:"from"`
+	To             DocumentVersionSummary   `json:"to"`
+	Metadata       []DocumentMetadataChange `json:"metadata"`
+	TextComparable bool                     `json:"text_comparable"`
+	Reason         string                   `json:"reason,omitempty"`
+	Truncated      bool                     `json:"truncated"` // a version had more than maxCompareLines lines
+	Stats          DocumentDiffStats        `json:"stats"`
+	Blocks         []DocumentDiffBlock      `json:"blocks"`
+}
+
+// CompareDocumentVersions compares two versions of a document. An empty toUUID means the latest version and an
+// empty fromUUID the version before the compared one.
+func (s *DocumentControlService) CompareDocumentVersions(documentControl *models.DocumentControl, fromUUID, toUUID string) (*DocumentVersionComparison, error) {
+	to, err := findComparedVersion(documentControl.ID, toUUID, 0)
+	if err != nil {
+		return nil, err
+	}
+	from, err := findComparedVersion(documentControl.ID, fromUUID, to.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := comparedVersionStatuses(from, to)
+	comparison := &DocumentVersionComparison{
+		From:     summarizeComparedVersion(from, statuses),
+		To:       summarizeComparedVersion(to, statuses),
+		Metadata: diffVersionMetadata(from, to, statuses),
+		Blocks:   []DocumentDiffBlock{},
+	}
+
+	if !isPDFVersion(from) || !isPDFVersion(to) {
+		comparison.Reason = "text can only be compared between PDF versions"
+		return comparison, nil
+	}
+
+	// A stored file the parser cannot read still gets its metadata compared
+	unreadable := func(err error) (*DocumentVersionComparison, error) {
+		if errors.Is(err, ErrInvalidDocumentFile) {
+			comparison.Reason = err.Error()
+			return comparison, nil
+		}
+		return nil, err
+	}
+	fromLines, fromTruncated, err := s.versionTextLines(from)
+	if err != nil {
+		return unreadable(err)
+	}
+	toLines, toTruncated, err := s.versionTextLines(to)
+	if err != nil {
+		return unreadable(err)
+	}
+
+	comparison.TextComparable = true
+	comparison.Truncated = fromTruncated || toTruncated
+	comparison.Blocks, comparison.Stats = diffDocumentLines(fromLines, toLines)
+	return comparison, nil
+}
+
+// findComparedVersion loads a version of the document by UUID. Without a UUID it takes the latest version, or the
+// one before beforeID when that is set.
+func findComparedVersion(documentControlID int, versionUUID string, beforeID int) (*models.DocumentVersion, error) {
+	query := config.DB.Where("document_control_id = ? AND deleted_at IS NULL", documentControlID)
+	switch {
+	case versionUUID != "":
+		query = query.Where("uuid = ?", versionUUID)
+	case beforeID != 0:
+		query = query.Where("id < ?", beforeID)
+	}
+
+	var version models.DocumentVersion
+	if err := query.Order("id DESC").First(&version).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDocumentVersionNotFound
+		}
+		return nil, errors.New("failed to fetch document version")
+	}
+	return &version, nil
+}
+
+// comparedVersionStatuses returns the names of the statuses of the compared versions by ID
+func comparedVersionStatuses(versions ...*models.DocumentVersion) map[int]string {
+	var ids []int
+	for _, version := range versions {
+		if version.StatusDocumentID != nil {
+			ids = append(ids, *version.StatusDocumentID)
+		}
+	}
+
+	names := make(map[int]string)
+	if len(ids) == 0 {
+		return names
+	}
+	var statuses []models.StatusDocument
+	config.DB.Where("id IN ?", ids).Find(&statuses)
+	for _, status := range statuses {
+		names[int(status.ID)] = status.Name
+	}
+	return names
+}
+
+func statusName(version *models.DocumentVersion, statuses map[int]string) string {
+	if version.StatusDocumentID == nil {
+		return ""
+	}
+	return statuses[*version.StatusDocumentID]
+}
+
+func summarizeComparedVersion(version *models.DocumentVersion, statuses map[int]string) DocumentVersionSummary {
+	return DocumentVersionSummary{
+		UUID:      version.UUID.String(),
+		Version:   version.Version,
+		Status:    statusName(version, statuses),
+		CreatedAt: version.CreatedAt,
+	}
+}
+
+// diffVersionMetadata lists the recorded fields of both versions and whether they differ
+func diffVersionMetadata(from, to *models.DocumentVersion, statuses map[int]string) []DocumentMetadataChange {
+	revision := func(version *models.DocumentVersion) interface{} {
+		if version.Version == nil {
+			return nil
+		}
+		return *version.Version
+	}
+
+	fields := []DocumentMetadataChange{
+		{Field: "revision", From: revision(from), To: revision(to)},
+		{Field: "status", From: statusName(from, statuses), To: statusName(to, statuses)},
+		{Field: "note", From: from.Note, To: to.Note},
+		{Field: "mime_type", From: from.MimeType, To: to.MimeType},
+		{Field: "file_size", From: from.FileSize, To: to.FileSize},
+		{Field: "page_count", From: from.PageCount, To: to.PageCount},
+		{Field: "pdf_title", From: from.PdfTitle, To: to.PdfTitle},
+		{Field: "checksum", From: from.Checksum, To: to.Checksum},
+	}
+	for i := range fields {
+		fields[i].Changed = fields[i].From != fields[i].To
+	}
+	return fields
+}
+
+// isPDFVersion tells whether text can be extracted from a version; versions uploaded before files were
+// inspected have no recorded type and are judged by their file name
+func isPDFVersion(version *models.DocumentVersion) bool {
+	if version.MimeType != "" {
+		return version.MimeType == "application/pdf"
+	}
+	return strings.EqualFold(path.Ext(version.File), ".pdf")
+}
+
+// versionTextLines reads the stored PDF of a version and returns its non-empty text lines in reading order.
+// Stored content_text is not used because it has lost the line breaks.
+func (s *DocumentControlService) versionTextLines(version *models.DocumentVersion) ([]DocumentCompareLine, bool, error) {
+	content, err := s.readDocumentVersionFile(version)
+	if err != nil {
+		return nil, false, err
+	}
+	return extractPDFLines(content)
+}
+
+// extractPDFLines returns the text rows of a PDF, at most maxCompareLines; the parser panics on some malformed files
+func extractPDFLines(content []byte) (lines []DocumentCompareLine, truncated bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			lines, truncated, err = nil, false, fmt.Errorf("%w: PDF is corrupt", ErrInvalidDocumentFile)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: PDF cannot be read: %v", ErrInvalidDocumentFile, err)
+	}
+
+	for page := 1; page <= reader.NumPage(); page++ {
+		p := reader.Page(page)
+		if p.V.IsNull() {
+			continue
+		}
+		rows, err := p.GetTextByRow()
+		if err != nil {
+			return nil, false, fmt.Errorf("%w: PDF cannot be read: %v", ErrInvalidDocumentFile, err)
+		}
+		for _, row := range rows {
+			var text strings.Builder
+			for _, word := range row.Content {
+				text.WriteString(word.S)
+			}
+			line := strings.Join(strings.Fields(strings.ToValidUTF8(strings.ReplaceAll(text.String(), "\x00", ""), "")), " ")
+			if line == "" {
+				continue
+			}
+			if len(lines) == maxCompareLines {
+				return lines, true, nil
+			}
+			lines = append(lines, DocumentCompareLine{Line: len(lines) + 1, Page: page, Text: line})
+		}
+	}
+	return lines, false, nil
+}
+
+// diffDocumentLines groups the differences between two texts into blocks; a run that both removes and adds lines
+// is a changed block.
+func diffDocumentLines(from, to []DocumentCompareLine) ([]DocumentDiffBlock, DocumentDiffStats) {
+	ops := diffLineOps(from, to)
+
+	blocks := []DocumentDiffBlock{}
+	var stats DocumentDiffStats
+	var current *DocumentDiffBlock
+	i, j := 0, 0
+	for _, op := range ops {
+		if op == diffEqual {
+			if current != nil {
+				blocks = append(blocks, *current)
+				current = nil
+			}
+			stats.Unchanged++
+			i++
+			j++
+			continue
+		}
+
+		if current == nil {
+			current = &DocumentDiffBlock{FromLine: i + 1, ToLine: j + 1, Removed: []DocumentCompareLine{}, Added: []DocumentCompareLine{}}
+		}
+		if op == diffRemove {
+			current.Removed = append(current.Removed, from[i])
+			stats.Removed++
+			i++
+		} else {
+			current.Added = append(current.Added, to[j])
+			stats.Added++
+			j++
+		}
+	}
+	if current != nil {
+		blocks = append(blocks, *current)
+	}
+
+	for k := range blocks {
+		switch {
+		case len(blocks[k].Removed) == 0:
+			blocks[k].Type = DocumentDiffAdded
+		case len(blocks[k].Added) == 0:
+			blocks[k].Type = DocumentDiffRemoved
+		default:
+			blocks[k].Type = DocumentDiffChanged
+		}
+	}
+	return blocks, stats
+}
+
+type diffOp byte
+
+const (
+	diffEqual diffOp = iota
+	diffRemove
+	diffAdd
+)
+
+// diffLineOps returns the edit script turning from into to. Lines that occur once in both texts anchor the
+// comparison (patience diff), so a long document with scattered edits splits into small gaps; each gap is
+// diffed with Myers' algorithm once the common head and tail are set aside.
+func diffLineOps(from, to []DocumentCompareLine) []diffOp {
+	head := 0
+	for head < len(from) && head < len(to) && from[head].Text == to[head].Text {
+		head++
+	}
+	tail := 0
+	for tail < len(from)-head && tail < len(to)-head && from[len(from)-1-tail].Text == to[len(to)-1-tail].Text {
+		tail++
+	}
+
+	ops := make([]diffOp, 0, len(from)+len(to))
+	for k := 0; k < head; k++ {
+		ops = append(ops, diffEqual)
+	}
+	a, b := from[head:len(from)-tail], to[head:len(to)-tail]
+	anchors := uniqueLineAnchors(a, b)
+	if len(anchors) == 0 {
+		ops = append(ops, myersDiff(a, b)...)
+	} else {
+		i, j := 0, 0
+		for _, anchor := range anchors {
+			ops = append(ops, diffLineOps(a[i:anchor[0]], b[j:anchor[1]])...)
+			ops = append(ops, diffEqual)
+			i, j = anchor[0]+1, anchor[1]+1
+		}
+		ops = append(ops, diffLineOps(a[i:], b[j:])...)
+	}
+	for k := 0; k < tail; k++ {
+		ops = append(ops, diffEqual)
+	}
+	return ops
+}
+
+// uniqueLineAnchors pairs the lines that occur exactly once in a and in b and keeps the longest run of pairs
+// that appear in the same order in both, as index pairs
+func uniqueLineAnchors(a, b []DocumentCompareLine) [][2]int {
+	type occurrence struct{ countA, countB, indexA, indexB int }
+	seen := make(map[string]*occurrence)
+	for i, line := range a {
+		o, ok := seen[line.Text]
+		if !ok {
+			o = &occurrence{}
+			seen[line.Text] = o
+		}
+		o.countA++
+		o.indexA = i
+	}
+	for j, line := range b {
+		if o, ok := seen[line.Text]; ok {
+			o.countB++
+			o.indexB = j
+		}
+	}
+
+	var pairs [][2]int
+	for i, line := range a {
+		if o := seen[line.Text]; o.countA == 1 && o.countB == 1 {
+			pairs = append(pairs, [2]int{i, o.indexB})
+		}
+	}
+	if len(pairs) == 0 {
+		return nil
+	}
+
+	// Longest increasing subsequence of the b indices (patience sorting)
+	var piles []int // index into pairs of the top of each pile
+	prev := make([]int, len(pairs))
+	for k, pair := range pairs {
+		pile := sort.Search(len(piles), func(p int) bool { return pairs[piles[p]][1] > pair[1] })
+		prev[k] = -1
+		if pile > 0 {
+			prev[k] = piles[pile-1]
+		}
+		if pile == len(piles) {
+			piles = append(piles, k)
+		} else {
+			piles[pile] = k
+		}
+	}
+
+	anchors := make([][2]int, len(piles))
+	for k, p := len(piles)-1, piles[len(piles)-1]; k >= 0; k, p = k-1, prev[p] {
+		anchors[k] = pairs[p]
+	}
+	return anchors
+}
+
+// myersDiff runs Myers' algorithm, keeping the furthest reaching paths of every step to trace the script back.
+// Beyond maxCompareEdits steps everything is reported as removed and added.
+func myersDiff(a, b []DocumentCompareLine) []diffOp {
+	n, m := len(a), len(b)
+	replaceAll := func() []diffOp {
+		ops := make([]diffOp, 0, n+m)
+		for k := 0; k < n; k++ {
+			ops = append(ops, diffRemove)
+		}
+		for k := 0; k < m; k++ {
+			ops = append(ops, diffAdd)
+		}
+		return ops
+	}
+	if n == 0 || m == 0 {
+		return replaceAll()
+	}
+
+	limit := n + m
+	if limit > maxCompareEdits {
+		limit = maxCompareEdits
+	}
+	offset := limit + 1
+	v := make([]int32, 2*limit+3)
+	var trace [][]int32
+
+	for d := 0; d <= limit; d++ {
+		// Only diagonals -d..d can be reached after d steps
+		snapshot := make([]int32, 2*d+1)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = int(v[offset+k+1])
+			} else {
+				x = int(v[offset+k-1]) + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x].Text == b[y].Text {
+				x++
+				y++
+			}
+			v[offset+k] = int32(x)
+			if x >= n && y >= m {
+				trace = append(trace, snapshot)
+				copy(snapshot, v[offset-d:offset+d+1])
+				return traceMyers(trace, n, m)
+			}
+		}
+		copy(snapshot, v[offset-d:offset+d+1])
+		trace = append(trace, snapshot)
+	}
+	return replaceAll()
+}
+
+// traceMyers walks the recorded paths back from (n, m) and returns the script in order
+func traceMyers(trace [][]int32, n, m int) []diffOp {
+	var reversed []diffOp
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d-1]
+		at := func(k int) int { return int(prev[k+d-1]) }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			reversed = append(reversed, diffEqual)
+			x--
+			y--
+		}
+		if x == prevX {
+			reversed = append(reversed, diffAdd)
+		} else {
+			reversed = append(reversed, diffRemove)
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		reversed = append(reversed, diffEqual)
+		x--
+		y--
+	}
+
+	ops := make([]diffOp, len(reversed))
+	for i, op := range reversed {
+		ops[len(reversed)-1-i] = op
+	}
+	return ops
+}
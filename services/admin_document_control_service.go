@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"log"
 	"mime/multipart"
-	"os"
 	"path/filepath"
 	"time"
 
@@ -56,6 +55,11 @@ type DocumentControlPayload struct {
 	StatusDocumentID   int    `json:"status_document_id" form:"status_document_id" validate:"required"`
 	Version            int    `json:"version" form:"version"`
 	CreatedBy          int    `json:"created_by"`
+
+	// Review schedule; next_review_date defaults to publish_date plus the review interval
+	ReviewIntervalMonths int    `json:"review_interval_months" form:"review_interval_months" validate:"gte=0"`
+	NextReviewDate       string `json:"next_review_date" form:"next_review_date" validate:"omitempty,datetime=2006-01-02"`
+	ExpiryDate           string `json:"expiry_date" form:"expiry_date" validate:"omitempty,datetime=2006-01-02"`
 }
 
 // PaginatedResult is a struct to hold paginated data
@@ -86,6 +90,22 @@ type DocumentControlResponse struct {
 	StatusDocumentID   *int       `json:"status_document_id"`
 	CreatedBy          *int       `json:"created_by"`
 
+	// Review schedule
+	ReviewIntervalMonths *int       `json:"review_interval_months"`
+	NextReviewDate       *string    `json:"next_review_date"` // Format this as Y-m-d
+	LastReviewedAt       *time.Time `json:"last_reviewed_at"`
+	ExpiryDate           *string    `json:"expiry_date"` // Format this as Y-m-d
+	ReviewOverdue        bool       `json:"review_overdue"`
+
+	// Check-out lock and optimistic concurrency
+	CheckedOutBy      *int       `json:"checked_out_by"`
+	CheckoutExpiresAt *time.Time `json:"checkout_expires_at"`
+	LockVersion       int        `json:"lock_version"`
+
+	// Trash
+	DeletedBy  *int       `json:"deleted_by"`
+	PurgeAfter *time.Time `json:"purge_after"`
+
 	// Fields for joined data
 	CategoryName   string `json:"category_name"`
 	CategoryPrefix string `json:"category_prefix"`
@@ -97,32 +117,106 @@ type DocumentControlResponse struct {
 // Function to convert DocumentControl to DocumentControlResponse
 func formatDocumentControl(doc models.DocumentControlJoined) DocumentControlResponse {
 	return DocumentControlResponse{
-		ID:                 doc.ID,
-		UUID:               doc.UUID,
-		DocumentName:       doc.DocumentName,
-		Description:        doc.Description,
-		DocumentNumber:     doc.DocumentNumber,
-		ClauseNumber:       doc.ClauseNumber,
-		RevisionNumber:     doc.RevisionNumber,
-		PublishDate:        doc.PublishDate.Format("2006-01-02"), // Format as Y-m-d
-		PageCount:          doc.PageCount,
-		CreatedAt:          doc.CreatedAt,
-		UpdatedAt:          doc.UpdatedAt,
-		DeletedAt:          doc.DeletedAt,
-		DocumentTypeID:     doc.DocumentTypeID,
-		DocumentCategoryID: doc.DocumentCategoryID,
-		SequenceNumber:     doc.SequenceNumber,
-		StatusDocumentID:   doc.StatusDocumentID,
-		CreatedBy:          doc.CreatedBy,
-		CategoryName:       doc.CategoryName,
-		CategoryPrefix:     doc.CategoryPrefix,
-		TypeName:           doc.TypeName,
-		TypePrefix:         doc.TypePrefix,
-		StatusName:         doc.StatusName,
+		ID:                   doc.ID,
+		UUID:                 doc.UUID,
+		DocumentName:         doc.DocumentName,
+		Description:          doc.Description,
+		DocumentNumber:       doc.DocumentNumber,
+		ClauseNumber:         doc.ClauseNumber,
+		RevisionNumber:       doc.RevisionNumber,
+		PublishDate:          doc.PublishDate.Format("2006-01-02"), // Format as Y-m-d
+		PageCount:            doc.PageCount,
+		CreatedAt:            doc.CreatedAt,
+		UpdatedAt:            doc.UpdatedAt,
+		DeletedAt:            doc.DeletedAt,
+		DocumentTypeID:       doc.DocumentTypeID,
+		DocumentCategoryID:   doc.DocumentCategoryID,
+		SequenceNumber:       doc.SequenceNumber,
+		StatusDocumentID:     doc.StatusDocumentID,
+		CreatedBy:            doc.CreatedBy,
+		ReviewIntervalMonths: doc.ReviewIntervalMonths,
+		NextReviewDate:       formatOptionalDate(doc.NextReviewDate),
+		LastReviewedAt:       doc.LastReviewedAt,
+		ExpiryDate:           formatOptionalDate(doc.ExpiryDate),
+		ReviewOverdue:        doc.ReviewOverdue,
+		CheckedOutBy:         doc.CheckedOutBy,
+		CheckoutExpiresAt:    doc.CheckoutExpiresAt,
+		LockVersion:          doc.LockVersion,
+		DeletedBy:            doc.DeletedBy,
+		PurgeAfter:           doc.PurgeAfter,
+		CategoryName:         doc.CategoryName,
+		CategoryPrefix:       doc.CategoryPrefix,
+		TypeName:             doc.TypeName,
+		TypePrefix:           doc.TypePrefix,
+		StatusName:           doc.StatusName,
 	}
 }
 
+// formatOptionalDate formats a nullable date as Y-m-d
+func formatOptionalDate(date *time.Time) *string {
+	if date == nil {
+		return nil
+	}
+	formatted := date.Format("2006-01-02")
+	return &formatted
+}
+
+// parseOptionalDate parses a Y-m-d date, returning nil for an empty value
+func parseOptionalDate(value, field string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date format for %s: expected format is YYYY-MM-DD", field)
+	}
+	return &date, nil
+}
+
+// reviewSchedule resolves the review interval, next review date and expiry date of a payload.
+// Without an explicit next_review_date the next review is due one interval after the publish date.
+func reviewSchedule(payload *DocumentControlPayload, publishDate time.Time) (*int, *time.Time, *time.Time, error) {
+	if payload.ReviewIntervalMonths < 0 {
+		return nil, nil, nil, fmt.Errorf("review_interval_months cannot be negative")
+	}
+
+	var interval *int
+	if payload.ReviewIntervalMonths > 0 {
+		interval = IntPtr(payload.ReviewIntervalMonths)
+	}
+
+	nextReviewDate, err := parseOptionalDate(payload.NextReviewDate, "next_review_date")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if nextReviewDate == nil && interval != nil {
+		next := publishDate.AddDate(0, *interval, 0)
+		nextReviewDate = &next
+	}
+
+	expiryDate, err := parseOptionalDate(payload.ExpiryDate, "expiry_date")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return interval, nextReviewDate, expiryDate, nil
+}
+
+// sameDate reports whether two nullable dates are equal
+func sameDate(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Format("2006-01-02") == b.Format("2006-01-02")
+}
+
 func (s *DocumentControlService) AddDocumentControlWithVersion(payload *DocumentControlPayload, fileHeader *multipart.FileHeader) (*models.DocumentControl, *models.DocumentVersion, error) {
+	// New documents start with the first status
+	return s.createDocumentControl(payload, multipartDocumentFile(fileHeader), 1)
+}
+
+// createDocumentControl creates a document with its first version in the given status
+func (s *DocumentControlService) createDocumentControl(payload *DocumentControlPayload, file documentFile, statusID int) (*models.DocumentControl, *models.DocumentVersion, error) {
 	// Validate and parse publish date
 	if payload.PublishDate == "" {
 		return nil, nil, fmt.Errorf("publish_date is required and cannot be empty")
@@ -133,21 +227,41 @@ func (s *DocumentControlService) AddDocumentControlWithVersion(payload *Document
 		return nil, nil, fmt.Errorf("invalid date format for publish_date: expected format is YYYY-MM-DD")
 	}
 
+	reviewInterval, nextReviewDate, expiryDate, err := reviewSchedule(payload, publishDate)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Check the real file type and read the PDF before anything is stored
+	fileInfo, err := inspectDocumentFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The page count of a PDF is taken from the file, not from the payload
+	pageCount := payload.PageCount
+	if fileInfo.PageCount > 0 {
+		pageCount = fileInfo.PageCount
+	}
+
 	// Initialize DocumentControl instance
 	documentControl := models.DocumentControl{
-		UUID:               uuid.New(),
-		DocumentName:       payload.DocumentName,
-		Description:        payload.Description,
-		DocumentNumber:     payload.DocumentNumber,
-		ClauseNumber:       payload.ClauseNumber,
-		RevisionNumber:     payload.RevisionNumber,
-		PublishDate:        publishDate,
-		PageCount:          payload.PageCount,
-		DocumentTypeID:     IntPtr(payload.DocumentTypeID),
-		DocumentCategoryID: IntPtr(payload.DocumentCategoryID),
-		SequenceNumber:     IntPtr(payload.SequenceNumber),
-		StatusDocumentID:   IntPtr(1),
-		CreatedBy:          IntPtr(payload.CreatedBy),
+		UUID:                 uuid.New(),
+		DocumentName:         payload.DocumentName,
+		Description:          payload.Description,
+		DocumentNumber:       payload.DocumentNumber,
+		ClauseNumber:         payload.ClauseNumber,
+		RevisionNumber:       payload.RevisionNumber,
+		PublishDate:          publishDate,
+		PageCount:            pageCount,
+		DocumentTypeID:       IntPtr(payload.DocumentTypeID),
+		DocumentCategoryID:   IntPtr(payload.DocumentCategoryID),
+		SequenceNumber:       IntPtr(payload.SequenceNumber),
+		StatusDocumentID:     IntPtr(statusID),
+		CreatedBy:            IntPtr(payload.CreatedBy),
+		ReviewIntervalMonths: reviewInterval,
+		NextReviewDate:       nextReviewDate,
+		ExpiryDate:           expiryDate,
 	}
 
 	// Initialize an empty DocumentVersion instance outside the transaction block
@@ -161,7 +275,7 @@ func (s *DocumentControlService) AddDocumentControlWithVersion(payload *Document
 		}
 
 		// Step 2: Upload file to MinIO
-		filePath, err := s.uploadFileToMinio(fileHeader, "document-versions")
+		filePath, err := s.uploadFileToMinio(file, "document-versions", fileInfo.ContentType)
 		if err != nil {
 			// Capture the detailed error from uploadToMinio
 			return fmt.Errorf("failed to upload file to MinIO: %w", err)
@@ -173,17 +287,23 @@ func (s *DocumentControlService) AddDocumentControlWithVersion(payload *Document
 			File:              filePath,
 			DocumentControlID: &documentControl.ID,
 			Version:           IntPtr(payload.Version), // Initial version number
-			StatusDocumentID:  IntPtr(1),
+			StatusDocumentID:  IntPtr(statusID),
 			Note:              "Initial version",
 			CreatedAt:         time.Now(),
 			UpdatedAt:         time.Now(),
 		}
+		fileInfo.applyTo(&documentVersion)
 
 		// Step 4: Save DocumentVersion to the database
 		if err := tx.Create(&documentVersion).Error; err != nil {
 			return fmt.Errorf("failed to create document version: %w", err)
 		}
 
+		// Step 5: Index the metadata and file content for search
+		if err := refreshDocumentSearchVector(tx, documentControl.ID); err != nil {
+			return fmt.Errorf("failed to index document control: %w", err)
+		}
+
 		return nil
 	})
 
@@ -192,32 +312,20 @@ func (s *DocumentControlService) AddDocumentControlWithVersion(payload *Document
 		return nil, nil, fmt.Errorf("transaction error: %w", err)
 	}
 
+	// Ask the distribution list to read the version once it is published
+	assignAcknowledgementsBestEffort(documentControl, documentVersion)
+
 	return &documentControl, &documentVersion, nil
 }
 
-func (s *DocumentControlService) uploadFileToMinio(file *multipart.FileHeader, directory string) (string, error) {
+// uploadFileToMinio stores a file that was already checked by inspectDocumentFile, using its sniffed content type
+func (s *DocumentControlService) uploadFileToMinio(file documentFile, directory string, contentType string) (string, error) {
 	// Check if MinIO client is initialized
 	if s.minioClient == nil {
 		log.Println("Error: MinIO client is not initialized")
 		return "", fmt.Errorf("MinIO client is not initialized")
 	}
 
-	// Validate file
-	const maxFileSize = 10 * 1024 * 1024 // 10 MB limit
-	if file.Size > maxFileSize {
-		return "", fmt.Errorf("file size exceeds maximum limit of %d bytes", maxFileSize)
-	}
-
-	allowedMimeTypes := map[string]bool{
-		"application/pdf": true,
-		"image/jpeg":      true,
-		"image/png":       true,
-	}
-	contentType := file.Header.Get("Content-Type")
-	if !allowedMimeTypes[contentType] {
-		return "", fmt.Errorf("unsupported file type: %s", contentType)
-	}
-
 	// Open file and prepare unique filename
 	src, err := file.Open()
 	if err != nil {
@@ -242,114 +350,25 @@ func (s *DocumentControlService) uploadFileToMinio(file *multipart.FileHeader, d
 	}
 
 	// Return public URL
-	fileURL := fmt.Sprintf("https://%s/%s/%s", os.Getenv("MINIO_ENDPOINT"), s.bucketName, fileName)
-	return fileURL, nil
+	return s.documentObjectURL(fileName), nil
 }
 
+// GetDocumentControlsInternalPaginated lists the internal register; it is ListDocumentControls limited to category 1
 func (s *DocumentControlService) GetDocumentControlsInternalPaginated(currentPage, pageSize int, search string) (*PaginatedResult, error) {
-	var documentControls []models.DocumentControlJoined
-	var totalRecords int64
-
-	offset := (currentPage - 1) * pageSize
-
-	// Base query for fetching document controls with joins
-	query := config.DB.Model(&models.DocumentControlJoined{}).
-		Select("document_control.*, category_document.name AS category_name, category_document.prefix AS category_prefix, "+
-			"document_type.name AS type_name, document_type.prefix AS type_prefix, "+
-			"status_document.name AS status_name").
-		Joins("LEFT JOIN category_document ON category_document.id = document_control.document_category_id").
-		Joins("LEFT JOIN document_type ON document_type.id = document_control.document_type_id").
-		Joins("LEFT JOIN status_document ON status_document.id = document_control.status_document_id").
-		Where("document_control.document_category_id = ?", 1).
-		Where("document_control.deleted_at IS NULL")
-
-	// Apply search filter if provided
-	if search != "" {
-		query = query.Where("LOWER(document_control.document_name) LIKE ?", "%"+search+"%")
-	}
-
-	// Get total record count with applied filters
-	if err := query.Count(&totalRecords).Error; err != nil {
-		return nil, errors.New("failed to count document controls")
-	}
-
-	// Fetch paginated records
-	if err := query.Offset(offset).Limit(pageSize).Find(&documentControls).Error; err != nil {
-		return nil, errors.New("failed to fetch document controls")
-	}
-
-	// Convert each DocumentControl to DocumentControlResponse with formatted date
-	var formattedControls []DocumentControlResponse
-	for _, doc := range documentControls {
-		formattedControls = append(formattedControls, formatDocumentControl(doc))
-	}
-
-	// Calculate total pages based on the record count and page size
-	totalPages := int((totalRecords + int64(pageSize) - 1) / int64(pageSize))
-
-	// Prepare the paginated result
-	result := &PaginatedResult{
-		Data:         formattedControls,
-		CurrentPage:  currentPage,
-		PerPage:      pageSize,
-		TotalPages:   totalPages,
-		TotalRecords: totalRecords,
-	}
-
-	return result, nil
+	return s.ListDocumentControls(DocumentListParams{
+		DocumentListFilter: DocumentListFilter{CategoryID: 1, Search: search},
+		CurrentPage:        currentPage,
+		PageSize:           pageSize,
+	})
 }
 
+// GetDocumentControlsExternalPaginated lists the external register; it is ListDocumentControls limited to category 2
 func (s *DocumentControlService) GetDocumentControlsExternalPaginated(currentPage, pageSize int, search string) (*PaginatedResult, error) {
-	var documentControls []models.DocumentControlJoined
-	var totalRecords int64
-
-	offset := (currentPage - 1) * pageSize
-
-	// Base query for fetching document controls with joins
-	query := config.DB.Model(&models.DocumentControlJoined{}).
-		Select("document_control.*, category_document.name AS category_name, category_document.prefix AS category_prefix, "+
-			"document_type.name AS type_name, document_type.prefix AS type_prefix, "+
-			"status_document.name AS status_name").
-		Joins("LEFT JOIN category_document ON category_document.id = document_control.document_category_id").
-		Joins("LEFT JOIN document_type ON document_type.id = document_control.document_type_id").
-		Joins("LEFT JOIN status_document ON status_document.id = document_control.status_document_id").
-		Where("document_control.document_category_id = ?", 2).
-		Where("document_control.deleted_at IS NULL")
-
-	// Apply search filter if provided
-	if search != "" {
-		query = query.Where("LOWER(document_control.document_name) LIKE ?", "%"+search+"%")
-	}
-
-	// Get total record count with applied filters
-	if err := query.Count(&totalRecords).Error; err != nil {
-		return nil, errors.New("failed to count document controls")
-	}
-
-	// Fetch paginated records
-	if err := query.Offset(offset).Limit(pageSize).Find(&documentControls).Error; err != nil {
-		return nil, errors.New("failed to fetch document controls")
-	}
-
-	// Convert each DocumentControl to DocumentControlResponse with formatted date
-	var formattedControls []DocumentControlResponse
-	for _, doc := range documentControls {
-		formattedControls = append(formattedControls, formatDocumentControl(doc))
-	}
-
-	// Calculate total pages based on the record count and page size
-	totalPages := int((totalRecords + int64(pageSize) - 1) / int64(pageSize))
-
-	// Prepare the paginated result
-	result := &PaginatedResult{
-		Data:         formattedControls,
-		CurrentPage:  currentPage,
-		PerPage:      pageSize,
-		TotalPages:   totalPages,
-		TotalRecords: totalRecords,
-	}
-
-	return result, nil
+	return s.ListDocumentControls(DocumentListParams{
+		DocumentListFilter: DocumentListFilter{CategoryID: 2, Search: search},
+		CurrentPage:        currentPage,
+		PageSize:           pageSize,
+	})
 }
 
 // GetDocumentControlByUUID retrieves a DocumentControl by its UUID
@@ -366,54 +385,9 @@ func (s *DocumentControlService) GetDocumentControlByUUID(uuid string) (*models.
 	return &documentControl, nil
 }
 
-// DeleteDocumentControl deletes a DocumentControl and its associated initial version file from MinIO
-func (s *DocumentControlService) DeleteDocumentControl(uuid string) error {
-	var documentControl models.DocumentControl
-	var documentVersion models.DocumentVersion
-
-	// Start a transaction to delete control and version data atomically
-	err := config.DB.Transaction(func(tx *gorm.DB) error {
-		// Retrieve DocumentControl entry by UUID
-		if err := tx.Where("uuid = ?", uuid).First(&documentControl).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return fmt.Errorf("document control not found")
-			}
-			return fmt.Errorf("failed to retrieve document control: %w", err)
-		}
-
-		// Retrieve the related DocumentVersion
-		if err := tx.Where("document_control_id = ?", documentControl.ID).First(&documentVersion).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return fmt.Errorf("document version not found")
-			}
-			return fmt.Errorf("failed to retrieve document version: %w", err)
-		}
-
-		// Delete file from MinIO
-		if documentVersion.File != "" {
-			err := config.MinioClient.RemoveObject(context.Background(), "document-versions", documentVersion.File, minio.RemoveObjectOptions{})
-			if err != nil {
-				return fmt.Errorf("failed to delete file from MinIO: %w", err)
-			}
-		}
-
-		// Soft delete DocumentVersion entry
-		if err := tx.Delete(&documentVersion).Error; err != nil {
-			return fmt.Errorf("failed to delete document version: %w", err)
-		}
-
-		// Soft delete DocumentControl entry
-		if err := tx.Delete(&documentControl).Error; err != nil {
-			return fmt.Errorf("failed to delete document control: %w", err)
-		}
-
-		return nil
-	})
-
-	return err
-}
-
-func (s *DocumentControlService) UpdateDocumentControl(uuid string, payload *DocumentControlPayload, fileHeader *multipart.FileHeader) (*models.DocumentControl, error) {
+// UpdateDocumentControl saves new metadata and, when fileHeader is set, a new version. The update is rejected when the
+// document changed since opts.LockVersion, and an upload when another user has the document checked out.
+func (s *DocumentControlService) UpdateDocumentControl(uuid string, payload *DocumentControlPayload, fileHeader *multipart.FileHeader, opts DocumentUpdateOptions) (*models.DocumentControl, error) {
 	var documentControl models.DocumentControl
 
 	// Find the document control by UUID
@@ -424,12 +398,60 @@ func (s *DocumentControlService) UpdateDocumentControl(uuid string, payload *Doc
 		return nil, fmt.Errorf("failed to find document control: %w", err)
 	}
 
+	// Reject edits of an older state and uploads while someone else holds the check-out before doing any work
+	expectedVersion := documentControl.LockVersion
+	if opts.LockVersion != 0 && opts.LockVersion != expectedVersion {
+		return nil, ErrStaleDocument
+	}
+	if opts.CheckIn && fileHeader == nil {
+		return nil, fmt.Errorf("%w: a file is required to check in", ErrInvalidDocumentFile)
+	}
+	holder := activeCheckout(&documentControl, time.Now())
+	if opts.CheckIn && (holder == nil || *holder != opts.UserID) {
+		return nil, ErrDocumentNotCheckedOut
+	}
+	if fileHeader != nil && holder != nil && *holder != opts.UserID {
+		return nil, checkedOutError(&documentControl)
+	}
+
 	// Parse the publish date
 	publishDate, err := time.Parse("2006-01-02", payload.PublishDate)
 	if err != nil {
 		return nil, fmt.Errorf("invalid date format for publish_date: %w", err)
 	}
 
+	reviewInterval, nextReviewDate, expiryDate, err := reviewSchedule(payload, publishDate)
+	if err != nil {
+		return nil, err
+	}
+
+	// A new date starts a new reminder cycle
+	if !sameDate(documentControl.NextReviewDate, nextReviewDate) {
+		documentControl.ReviewOverdue = false
+		documentControl.ReviewReminderSentAt = nil
+	}
+	if !sameDate(documentControl.ExpiryDate, expiryDate) {
+		documentControl.ExpiryReminderSentAt = nil
+		documentControl.ExpiredNotifiedAt = nil
+	}
+
+	// Check a new file before anything is stored; an identical re-upload is not a new version
+	var fileInfo *DocumentFileInfo
+	if fileHeader != nil {
+		if changeRequestsRequired() {
+			return nil, ErrChangeRequestRequired
+		}
+		fileInfo, err = inspectDocumentFile(multipartDocumentFile(fileHeader))
+		if err != nil {
+			return nil, err
+		}
+		if err := ensureNewDocumentFile(documentControl.ID, fileInfo.Checksum); err != nil {
+			return nil, err
+		}
+	}
+
+	previousStatusID := documentControl.StatusDocumentID
+
 	// Update document control fields
 	documentControl.DocumentName = payload.DocumentName
 	documentControl.Description = payload.Description
@@ -438,29 +460,38 @@ func (s *DocumentControlService) UpdateDocumentControl(uuid string, payload *Doc
 	documentControl.RevisionNumber = payload.RevisionNumber
 	documentControl.PublishDate = publishDate
 	documentControl.PageCount = payload.PageCount
+	if fileInfo != nil && fileInfo.PageCount > 0 {
+		documentControl.PageCount = fileInfo.PageCount
+	}
 	documentControl.DocumentTypeID = IntPtr(payload.DocumentTypeID)
 	documentControl.DocumentCategoryID = IntPtr(payload.DocumentCategoryID)
 	documentControl.SequenceNumber = IntPtr(payload.SequenceNumber)
 	documentControl.StatusDocumentID = IntPtr(payload.StatusDocumentID)
+	documentControl.ReviewIntervalMonths = reviewInterval
+	documentControl.NextReviewDate = nextReviewDate
+	documentControl.ExpiryDate = expiryDate
 	documentControl.UpdatedAt = time.Now()
 
+	// Set when a new file is uploaded, or when the document is approved without one
+	var newDocumentVersion, approvedVersion *models.DocumentVersion
+
 	// Begin transaction to save updates and manage file versioning
 	err = config.DB.Transaction(func(tx *gorm.DB) error {
-		// Update document control in the database
-		if err := tx.Save(&documentControl).Error; err != nil {
-			return fmt.Errorf("failed to update document control: %w", err)
+		// Update document control in the database unless it changed or was checked out meanwhile
+		if err := saveDocumentControlChecked(tx, &documentControl, expectedVersion, fileHeader != nil, opts); err != nil {
+			return err
 		}
 
 		// Check if a file is provided for version update
 		if fileHeader != nil {
 			// Upload the new file to MinIO
-			filePath, err := s.uploadFileToMinio(fileHeader, "document-versions")
+			filePath, err := s.uploadFileToMinio(multipartDocumentFile(fileHeader), "document-versions", fileInfo.ContentType)
 			if err != nil {
 				return fmt.Errorf("failed to upload file: %w", err)
 			}
 
 			// Create a new document version entry
-			newDocumentVersion := models.DocumentVersion{
+			newDocumentVersion = &models.DocumentVersion{
 				File:              filePath,
 				DocumentControlID: &documentControl.ID,
 				Version:           IntPtr(documentControl.RevisionNumber),
@@ -469,13 +500,34 @@ func (s *DocumentControlService) UpdateDocumentControl(uuid string, payload *Doc
 				CreatedAt:         time.Now(),
 				UpdatedAt:         time.Now(),
 			}
+			fileInfo.applyTo(newDocumentVersion)
 
 			// Save the new document version to the database
-			if err := tx.Create(&newDocumentVersion).Error; err != nil {
+			if err := tx.Create(newDocumentVersion).Error; err != nil {
 				return fmt.Errorf("failed to create new document version: %w", err)
 			}
 		}
 
+		// Approving the document without a new file approves its current version
+		if fileHeader == nil && (previousStatusID == nil || *previousStatusID != payload.StatusDocumentID) && isApprovedStatus(documentControl.StatusDocumentID) {
+			var currentVersion models.DocumentVersion
+			if err := tx.Where("document_control_id = ? AND deleted_at IS NULL", documentControl.ID).
+				Order("id DESC").Limit(1).Find(&currentVersion).Error; err != nil {
+				return fmt.Errorf("failed to fetch current document version: %w", err)
+			}
+			if currentVersion.ID != 0 {
+				if err := tx.Model(&currentVersion).Update("status_document_id", payload.StatusDocumentID).Error; err != nil {
+					return fmt.Errorf("failed to approve current document version: %w", err)
+				}
+				approvedVersion = &currentVersion
+			}
+		}
+
+		// Re-index the updated metadata and, when uploaded, the new file content
+		if err := refreshDocumentSearchVector(tx, documentControl.ID); err != nil {
+			return fmt.Errorf("failed to index document control: %w", err)
+		}
+
 		return nil
 	})
 
@@ -483,5 +535,26 @@ func (s *DocumentControlService) UpdateDocumentControl(uuid string, payload *Doc
 		return nil, err
 	}
 
+	// A new or newly approved version must be read and acknowledged by the distribution list once it is approved
+	if newDocumentVersion != nil {
+		assignAcknowledgementsBestEffort(documentControl, *newDocumentVersion)
+	} else if approvedVersion != nil {
+		assignAcknowledgementsBestEffort(documentControl, *approvedVersion)
+	}
+
+	// Tell the creator about the new status in-app, and by email when it was approved or rejected
+	if previousStatusID == nil || *previousStatusID != payload.StatusDocumentID {
+		var status models.StatusDocument
+		if err := config.DB.Where("id = ?", payload.StatusDocumentID).First(&status).Error; err == nil {
+			if documentControl.CreatedBy != nil {
+				notifyUserBestEffort(*documentControl.CreatedBy, models.NotificationEventDocumentStatusChanged,
+					fmt.Sprintf("Document %s is now %s", documentControl.DocumentNumber, status.Name),
+					fmt.Sprintf("The status of %s changed to %s.", documentControl.DocumentName, status.Name),
+					"/document-control/"+documentControl.UUID.String())
+			}
+			newDefaultNotificationService().notifyDocumentStatus(documentControl, status.Name)
+		}
+	}
+
 	return &documentControl, nil
 }
@@ -0,0 +1,395 @@
+package services
+
+import (
+	"backend-school/config"
+	"backend-school/models"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrDistributionEntryExists   = errors.New("distribution entry already exists")
+	ErrDistributionNotFound      = errors.New("distribution entry not found")
+	ErrAcknowledgementNotFound   = errors.New("acknowledgement not found")
+	ErrDocumentVersionNotFound   = errors.New("document version not found")
+	ErrInvalidDistributionTarget = errors.New("either role_guard_name or user_uuid is required, but not both")
+)
+
+// DocumentDistributionService manages distribution lists of controlled documents and the
+// read-and-acknowledge tasks created when a new version is published
+type DocumentDistributionService struct{}
+
+func NewDocumentDistributionService() *DocumentDistributionService {
+	return &DocumentDistributionService{}
+}
+
+// AcknowledgementTask is an acknowledgement joined with its document and version
+type AcknowledgementTask struct {
+	UUID              uuid.UUID  `json:"uuid"`
+	DocumentControlID int        `json:"document_control_id"`
+	DocumentUUID      uuid.UUID  `json:"document_uuid"`
+	DocumentName      string     `json:"document_name"`
+	DocumentNumber    string     `json:"document_number"`
+	DocumentVersionID int        `json:"document_version_id"`
+	VersionUUID       uuid.UUID  `json:"version_uuid"`
+	Version           *int       `json:"version"`
+	File              string     `json:"file"`
+	AssignedAt        time.Time  `json:"assigned_at"`
+	AcknowledgedAt    *time.Time `json:"acknowledged_at"`
+}
+
+// AcknowledgementRecipient is an acknowledgement joined with the user it is assigned to
+type AcknowledgementRecipient struct {
+	UUID           uuid.UUID  `json:"uuid"`
+	UserID         int        `json:"user_id"`
+	Username       string     `json:"username"`
+	Fullname       string     `json:"fullname"`
+	Email          string     `json:"email"`
+	AssignedAt     time.Time  `json:"assigned_at"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at"`
+}
+
+// acknowledgementTaskSelect selects the columns of AcknowledgementTask
+const acknowledgementTaskSelect = "document_acknowledgement.uuid, document_acknowledgement.document_control_id, " +
+	"document_control.uuid AS document_uuid, document_control.document_name, document_control.document_number, " +
+	"document_acknowledgement.document_version_id, document_version.uuid AS version_uuid, document_version.version, document_version.file, " +
+	"document_acknowledgement.created_at AS assigned_at, document_acknowledgement.acknowledged_at"
+
+// complianceSummary counts acknowledged and pending tasks
+func complianceSummary(total, acknowledged int) map[string]interface{} {
+	rate := 0.0
+	if total > 0 {
+		rate = math.Round(float64(acknowledged)/float64(total)*10000) / 100
+	}
+	return map[string]interface{}{
+		"total":           total,
+		"acknowledged":    acknowledged,
+		"pending":         total - acknowledged,
+		"compliance_rate": rate, // percentage
+	}
+}
+
+// GetDistribution lists the distribution entries of a document
+func (s *DocumentDistributionService) GetDistribution(documentControlID int) ([]models.DocumentDistribution, error) {
+	var entries []models.DocumentDistribution
+	if err := config.DB.Where("document_control_id = ?", documentControlID).Order("created_at ASC").Find(&entries).Error; err != nil {
+		return nil, errors.New("failed to fetch distribution list")
+	}
+	return entries, nil
+}
+
+// AddDistribution adds a role or a user to a document's distribution list. New recipients immediately get
+// a task for the current version; the number of tasks created is returned.
+func (s *DocumentDistributionService) AddDistribution(documentControl *models.DocumentControl, roleGuardName, userUUID string, createdBy int) (*models.DocumentDistribution, int, error) {
+	if (roleGuardName == "") == (userUUID == "") {
+		return nil, 0, ErrInvalidDistributionTarget
+	}
+
+	entry := models.DocumentDistribution{
+		DocumentControlID: documentControl.ID,
+		CreatedBy:         IntPtr(createdBy),
+	}
+
+	query := config.DB.Model(&models.DocumentDistribution{}).Where("document_control_id = ?", documentControl.ID)
+	if roleGuardName != "" {
+		var role models.Role
+		if err := config.DB.Where("guard_name = ?", roleGuardName).First(&role).Error; err != nil {
+			return nil, 0, errors.New("role not found")
+		}
+		entry.RoleGuardName = roleGuardName
+		query = query.Where("role_guard_name = ?", roleGuardName)
+	} else {
+		var user models.UserByAdmin
+		if err := config.DB.Where("uuid = ?", userUUID).Where("deleted_at IS NULL").First(&user).Error; err != nil {
+			return nil, 0, errors.New("user not found")
+		}
+		entry.UserID = IntPtr(int(user.ID))
+		query = query.Where("user_id = ?", user.ID)
+	}
+
+	var existing int64
+	if err := query.Count(&existing).Error; err != nil {
+		return nil, 0, errors.New("failed to check distribution list")
+	}
+	if existing > 0 {
+		return nil, 0, ErrDistributionEntryExists
+	}
+
+	if err := config.DB.Create(&entry).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to add distribution entry: %w", err)
+	}
+
+	version, err := s.latestVersion(documentControl.ID)
+	if err != nil {
+		// A document without a published version has nothing to acknowledge yet
+		return &entry, 0, nil
+	}
+	assigned, err := s.AssignAcknowledgements(*documentControl, *version)
+	if err != nil {
+		return &entry, 0, err
+	}
+	return &entry, assigned, nil
+}
+
+// DeleteDistribution removes an entry from a document's distribution list; tasks already assigned are kept
+func (s *DocumentDistributionService) DeleteDistribution(documentControlID int, entryUUID string) error {
+	result := config.DB.Where("uuid = ? AND document_control_id = ?", entryUUID, documentControlID).Delete(&models.DocumentDistribution{})
+	if result.Error != nil {
+		return errors.New("failed to delete distribution entry")
+	}
+	if result.RowsAffected == 0 {
+		return ErrDistributionNotFound
+	}
+	return nil
+}
+
+// approvedDocumentStatus is the status in which a version is published to the distribution list
+const approvedDocumentStatus = "approved"
+
+// approvedStatusIDs selects the IDs of the approved status
+func approvedStatusIDs() *gorm.DB {
+	return config.DB.Model(&models.StatusDocument{}).Select("id").Where("LOWER(TRIM(name)) = ?", approvedDocumentStatus)
+}
+
+// isApprovedStatus reports whether a status is the approved one
+func isApprovedStatus(statusID *int) bool {
+	if statusID == nil {
+		return false
+	}
+	var count int64
+	if err := approvedStatusIDs().Where("id = ?", *statusID).Count(&count).Error; err != nil {
+		log.Printf("Failed to check document status %d: %v", *statusID, err)
+		return false
+	}
+	return count > 0
+}
+
+// latestVersion returns the most recently published version of a document, the latest approved one
+func (s *DocumentDistributionService) latestVersion(documentControlID int) (*models.DocumentVersion, error) {
+	var version models.DocumentVersion
+	if err := config.DB.Where("document_control_id = ? AND deleted_at IS NULL", documentControlID).
+		Where("status_document_id IN (?)", approvedStatusIDs()).
+		Order("id DESC").First(&version).Error; err != nil {
+		return nil, ErrDocumentVersionNotFound
+	}
+	return &version, nil
+}
+
+// recipientIDs resolves a document's distribution list to active user IDs
+func (s *DocumentDistributionService) recipientIDs(documentControlID int) ([]int, error) {
+	var ids []int
+	err := config.DB.Model(&models.UserByAdmin{}).
+		Where("users.deleted_at IS NULL").
+		Where("users.id IN (?) OR users.username IN (?)",
+			config.DB.Model(&models.DocumentDistribution{}).Select("user_id").
+				Where("document_control_id = ? AND user_id IS NOT NULL", documentControlID),
+			config.DB.Model(&models.CasbinRule{}).Select("v0").
+				Where("ptype = 'g' AND v1 IN (?)", config.DB.Model(&models.DocumentDistribution{}).Select("role_guard_name").
+					Where("document_control_id = ? AND role_guard_name <> ''", documentControlID)),
+		).
+		Pluck("users.id", &ids).Error
+	return ids, err
+}
+
+// AssignAcknowledgements creates a task for every recipient who has not yet been asked to acknowledge the version
+// and notifies them. Pending tasks for older versions are dropped, since only the current revision needs reading, so
+// it must only be called for a published version.
+func (s *DocumentDistributionService) AssignAcknowledgements(documentControl models.DocumentControl, version models.DocumentVersion) (int, error) {
+	recipients, err := s.recipientIDs(documentControl.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve distribution list: %w", err)
+	}
+
+	if err := config.DB.Where("document_control_id = ? AND document_version_id <> ? AND acknowledged_at IS NULL", documentControl.ID, version.ID).
+		Delete(&models.DocumentAcknowledgement{}).Error; err != nil {
+		return 0, fmt.Errorf("failed to drop superseded acknowledgements: %w", err)
+	}
+
+	versionLabel := ""
+	if version.Version != nil {
+		versionLabel = fmt.Sprintf(" version %d", *version.Version)
+	}
+
+	assigned := 0
+	for _, userID := range recipients {
+		task := models.DocumentAcknowledgement{
+			DocumentControlID: documentControl.ID,
+			DocumentVersionID: version.ID,
+			UserID:            userID,
+		}
+		result := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&task)
+		if result.Error != nil {
+			return assigned, fmt.Errorf("failed to create acknowledgement: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		assigned++
+
+		notifyUserBestEffort(userID, models.NotificationEventDocumentAcknowledgementRequired,
+			fmt.Sprintf("Please read document %s", documentControl.DocumentNumber),
+			fmt.Sprintf("%s%s was published. Please read it and confirm.", documentControl.DocumentName, versionLabel),
+			"/document-control/"+documentControl.UUID.String())
+	}
+	return assigned, nil
+}
+
+// assignAcknowledgementsBestEffort is AssignAcknowledgements for publishing, which must not fail because of it. Only
+// an approved version is published; a draft gets no tasks and the pending tasks of the version before it are kept.
+func assignAcknowledgementsBestEffort(documentControl models.DocumentControl, version models.DocumentVersion) {
+	if !isApprovedStatus(version.StatusDocumentID) {
+		return
+	}
+	if _, err := NewDocumentDistributionService().AssignAcknowledgements(documentControl, version); err != nil {
+		log.Printf("Failed to assign acknowledgements for document %s: %v", documentControl.UUID, err)
+	}
+}
+
+// GetMyAcknowledgementsPaginated lists the user's acknowledgement tasks, pending first
+func (s *DocumentDistributionService) GetMyAcknowledgementsPaginated(userID, perPage, page int, pendingOnly bool) (map[string]interface{}, error) {
+	var tasks []AcknowledgementTask
+	var totalRecords, pendingCount int64
+
+	// Calculate offset for pagination
+	offset := (page - 1) * perPage
+
+	query := config.DB.Table("document_acknowledgement").
+		Joins("JOIN document_control ON document_control.id = document_acknowledgement.document_control_id").
+		Joins("JOIN document_version ON document_version.id = document_acknowledgement.document_version_id").
+		Where("document_acknowledgement.user_id = ?", userID).
+		Where("document_control.deleted_at IS NULL")
+	if pendingOnly {
+		query = query.Where("document_acknowledgement.acknowledged_at IS NULL")
+	}
+
+	// Get the total number of records
+	query.Count(&totalRecords)
+
+	if err := query.Select(acknowledgementTaskSelect).
+		Order("document_acknowledgement.acknowledged_at IS NOT NULL, document_acknowledgement.created_at DESC").
+		Limit(perPage).Offset(offset).Scan(&tasks).Error; err != nil {
+		return nil, errors.New("failed to fetch acknowledgements")
+	}
+
+	config.DB.Model(&models.DocumentAcknowledgement{}).Where("user_id = ? AND acknowledged_at IS NULL", userID).Count(&pendingCount)
+
+	// Calculate total pages
+	totalPages := int(math.Ceil(float64(totalRecords) / float64(perPage)))
+
+	return map[string]interface{}{
+		"data":          tasks,
+		"pending_count": pendingCount,
+		"current_page":  page,
+		"per_page":      perPage,
+		"total_pages":   totalPages,
+		"total_records": totalRecords,
+	}, nil
+}
+
+// Acknowledge confirms that the user has read the version of one of their tasks
+func (s *DocumentDistributionService) Acknowledge(userID int, acknowledgementUUID string) (*models.DocumentAcknowledgement, error) {
+	var acknowledgement models.DocumentAcknowledgement
+	if err := config.DB.Where("uuid = ? AND user_id = ?", acknowledgementUUID, userID).First(&acknowledgement).Error; err != nil {
+		return nil, ErrAcknowledgementNotFound
+	}
+
+	if acknowledgement.AcknowledgedAt == nil {
+		now := time.Now()
+		if err := config.DB.Model(&acknowledgement).Update("acknowledged_at", now).Error; err != nil {
+			return nil, errors.New("failed to record acknowledgement")
+		}
+		acknowledgement.AcknowledgedAt = &now
+	}
+	return &acknowledgement, nil
+}
+
+// GetDocumentComplianceReport reports who has acknowledged a version of a document; the latest published version is used when versionUUID is empty
+func (s *DocumentDistributionService) GetDocumentComplianceReport(documentControl *models.DocumentControl, versionUUID string) (map[string]interface{}, error) {
+	var version *models.DocumentVersion
+	if versionUUID == "" {
+		latest, err := s.latestVersion(documentControl.ID)
+		if err != nil {
+			return nil, err
+		}
+		version = latest
+	} else {
+		var selected models.DocumentVersion
+		if err := config.DB.Where("uuid = ? AND document_control_id = ?", versionUUID, documentControl.ID).First(&selected).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrDocumentVersionNotFound
+			}
+			return nil, errors.New("failed to fetch document version")
+		}
+		version = &selected
+	}
+
+	var recipients []AcknowledgementRecipient
+	if err := config.DB.Table("document_acknowledgement").
+		Select("document_acknowledgement.uuid, document_acknowledgement.user_id, users.username, users.fullname, users.email, "+
+			"document_acknowledgement.created_at AS assigned_at, document_acknowledgement.acknowledged_at").
+		Joins("JOIN users ON users.id = document_acknowledgement.user_id").
+		Where("document_acknowledgement.document_version_id = ?", version.ID).
+		Order("document_acknowledgement.acknowledged_at IS NOT NULL, users.fullname ASC").
+		Scan(&recipients).Error; err != nil {
+		return nil, errors.New("failed to fetch acknowledgements")
+	}
+
+	acknowledged := 0
+	for _, recipient := range recipients {
+		if recipient.AcknowledgedAt != nil {
+			acknowledged++
+		}
+	}
+
+	return map[string]interface{}{
+		"document_control": documentControl,
+		"document_version": version,
+		"summary":          complianceSummary(len(recipients), acknowledged),
+		"recipients":       recipients,
+	}, nil
+}
+
+// GetUserComplianceReport reports which documents a user has and has not acknowledged
+func (s *DocumentDistributionService) GetUserComplianceReport(userUUID string) (map[string]interface{}, error) {
+	var user models.UserByAdmin
+	if err := config.DB.Where("uuid = ?", userUUID).Where("deleted_at IS NULL").First(&user).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	var tasks []AcknowledgementTask
+	if err := config.DB.Table("document_acknowledgement").
+		Select(acknowledgementTaskSelect).
+		Joins("JOIN document_control ON document_control.id = document_acknowledgement.document_control_id").
+		Joins("JOIN document_version ON document_version.id = document_acknowledgement.document_version_id").
+		Where("document_acknowledgement.user_id = ?", user.ID).
+		Where("document_control.deleted_at IS NULL").
+		Order("document_acknowledgement.acknowledged_at IS NOT NULL, document_acknowledgement.created_at DESC").
+		Scan(&tasks).Error; err != nil {
+		return nil, errors.New("failed to fetch acknowledgements")
+	}
+
+	acknowledged := 0
+	for _, task := range tasks {
+		if task.AcknowledgedAt != nil {
+			acknowledged++
+		}
+	}
+
+	return map[string]interface{}{
+		"user": map[string]interface{}{
+			"uuid":     user.UUID,
+			"username": user.Username,
+			"fullname": user.Fullname,
+			"email":    user.Email,
+		},
+		"summary":          complianceSummary(len(tasks), acknowledged),
+		"acknowledgements": tasks,
+	}, nil
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"backend-school/config"
+	"backend-school/models"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// maxDocumentFileSize is the largest file accepted for a document version
+const maxDocumentFileSize = 10 * 1024 * 1024 // 10 MB limit
+
+// allowedDocumentMimeTypes are the sniffed content types accepted for document versions
+var allowedDocumentMimeTypes = map[string]bool{
+	"application/pdf": true,
+	"image/jpeg":      true,
+	"image/png":       true,
+}
+
+var (
+	// ErrInvalidDocumentFile is wrapped by every rejection of an uploaded document file
+	ErrInvalidDocumentFile = errors.New("invalid document file")
+	// ErrDuplicateDocumentVersion is returned when a file identical to an existing version is uploaded again
+	ErrDuplicateDocumentVersion = errors.New("an identical file was already uploaded for this document")
+)
+
+// documentFile is the content of a file for a document version, from a multipart upload or an import archive
+type documentFile struct {
+	Filename string
+	Size     int64
+	Open     func() (multipart.File, error)
+}
+
+// memoryFile serves file content held in memory as a multipart.File
+type memoryFile struct {
+	*bytes.Reader
+}
+
+func (memoryFile) Close() error { return nil }
+
+// multipartDocumentFile wraps a file uploaded with a form
+func multipartDocumentFile(header *multipart.FileHeader) documentFile {
+	return documentFile{Filename: header.Filename, Size: header.Size, Open: header.Open}
+}
+
+// memoryDocumentFile wraps file content read into memory
+func memoryDocumentFile(filename string, content []byte) documentFile {
+	return documentFile{
+		Filename: filename,
+		Size:     int64(len(content)),
+		Open: func() (multipart.File, error) {
+			return memoryFile{bytes.NewReader(content)}, nil
+		},
+	}
+}
+
+// DocumentFileInfo is what the server learned about an uploaded file
+type DocumentFileInfo struct {
+	ContentType string // sniffed from the content, not the client's header
+	Size        int64
+	Checksum    string // hex SHA-256
+	PageCount   int    // PDFs only
+	Title       string // PDF document info title, if any
+	Encrypted   bool   // PDF is encrypted but opens without a password
+	Text        string // extracted PDF text for search
+}
+
+// applyTo records the inspected details on a document version
+func (info *DocumentFileInfo) applyTo(version *models.DocumentVersion) {
+	version.Checksum = info.Checksum
+	version.MimeType = info.ContentType
+	version.FileSize = info.Size
+	version.PageCount = info.PageCount
+	version.PdfTitle = info.Title
+	version.IsEncrypted = info.Encrypted
+	version.ContentText = info.Text
+}
+
+// inspectDocumentFile sniffs the real type of an upload, hashes it and, for PDFs, reads page count, title,
+// encryption and text. Corrupt and password-protected PDFs are rejected.
+func inspectDocumentFile(file documentFile) (*DocumentFileInfo, error) {
+	if file.Size > maxDocumentFileSize {
+		return nil, fmt.Errorf("%w: file size exceeds maximum limit of %d bytes", ErrInvalidDocumentFile, maxDocumentFileSize)
+	}
+	if file.Size == 0 {
+		return nil, fmt.Errorf("%w: file is empty", ErrInvalidDocumentFile)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %v", err)
+	}
+	defer src.Close()
+
+	// Sniff the content type from the first bytes
+	head := make([]byte, 512)
+	n, err := io.ReadFull(src, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+	contentType := strings.TrimSpace(strings.Split(http.DetectContentType(head[:n]), ";")[0])
+	if !allowedDocumentMimeTypes[contentType] {
+		return nil, fmt.Errorf("%w: unsupported file type: %s", ErrInvalidDocumentFile, contentType)
+	}
+
+	// Hash the whole file
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, src); err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+
+	info := &DocumentFileInfo{
+		ContentType: contentType,
+		Size:        file.Size,
+		Checksum:    hex.EncodeToString(hash.Sum(nil)),
+	}
+
+	if contentType == "application/pdf" {
+		if err := inspectPDF(src, file.Size, info); err != nil {
+			return nil, err
+		}
+	}
+	return info, nil
+}
+
+// inspectPDF fills in the PDF details of info; the parser panics on some malformed files, which count as corrupt
+func inspectPDF(src io.ReaderAt, size int64, info *DocumentFileInfo) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: PDF is corrupt", ErrInvalidDocumentFile)
+		}
+	}()
+
+	reader, err := pdf.NewReader(src, size)
+	if err != nil {
+		if errors.Is(err, pdf.ErrInvalidPassword) || strings.Contains(strings.ToLower(err.Error()), "encrypt") {
+			return fmt.Errorf("%w: PDF is password-protected", ErrInvalidDocumentFile)
+		}
+		return fmt.Errorf("%w: PDF is corrupt: %v", ErrInvalidDocumentFile, err)
+	}
+
+	info.PageCount = reader.NumPage()
+	if info.PageCount == 0 {
+		return fmt.Errorf("%w: PDF has no pages", ErrInvalidDocumentFile)
+	}
+	info.Encrypted = !reader.Trailer().Key("Encrypt").IsNull()
+	info.Title = strings.TrimSpace(reader.Trailer().Key("Info").Key("Title").Text())
+
+	text, err := extractPDFText(reader)
+	if err != nil {
+		return fmt.Errorf("%w: PDF is corrupt: %v", ErrInvalidDocumentFile, err)
+	}
+	info.Text = text
+	return nil
+}
+
+// ensureNewDocumentFile rejects a file identical to one of the document's existing versions
+func ensureNewDocumentFile(documentControlID int, checksum string) error {
+	var count int64
+	if err := config.DB.Model(&models.DocumentVersion{}).
+		Where("document_control_id = ? AND checksum = ? AND deleted_at IS NULL", documentControlID, checksum).
+		Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check for duplicate versions: %w", err)
+	}
+	if count > 0 {
+		return ErrDuplicateDocumentVersion
+	}
+	return nil
+}
@@ -0,0 +1,670 @@
+package services
+
+import (
+	"archive/zip"
+	"backend-school/config"
+	"backend-school/models"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MaxDocumentImportSize is the largest ZIP archive accepted for an import
+const MaxDocumentImportSize = 200 * 1024 * 1024 // 200 MB limit
+
+// maxDocumentImportRows caps the number of manifest rows of one import
+const maxDocumentImportRows = 2000
+
+var (
+	// ErrInvalidDocumentImport is wrapped by every rejection of an import archive or its manifest
+	ErrInvalidDocumentImport = errors.New("invalid import archive")
+	// ErrDocumentImportNotFound is returned when an import job does not exist or belongs to another user
+	ErrDocumentImportNotFound = errors.New("import job not found")
+)
+
+// documentImportColumns are the manifest columns; the required ones must be present in every row
+var documentImportColumns = map[string]bool{
+	"file":                   true,
+	"document_name":          true,
+	"description":            true,
+	"document_number":        true,
+	"clause_number":          false,
+	"category_prefix":        true,
+	"type_prefix":            true,
+	"status":                 false,
+	"version":                false,
+	"revision_number":        false,
+	"publish_date":           true,
+	"review_interval_months": false,
+	"next_review_date":       false,
+	"expiry_date":            false,
+}
+
+// requiredImportColumns lists the required manifest columns in a stable order
+func requiredImportColumns() []string {
+	var columns []string
+	for column, required := range documentImportColumns {
+		if required {
+			columns = append(columns, column)
+		}
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// documentImportSlot lets one import run at a time; the others wait in the queued state
+var documentImportSlot = make(chan struct{}, 1)
+
+type DocumentImportService struct {
+	DocumentService *DocumentControlService
+}
+
+func NewDocumentImportService(documentService *DocumentControlService) *DocumentImportService {
+	return &DocumentImportService{DocumentService: documentService}
+}
+
+// documentImportManifest is the parsed manifest of an archive, one map of column values per row
+type documentImportManifest struct {
+	Name string
+	Rows []map[string]string
+}
+
+// readDocumentImportManifest finds manifest.csv or manifest.json in an archive, preferring the one closest to the root
+func readDocumentImportManifest(archive *zip.Reader) (*documentImportManifest, error) {
+	var manifestFile *zip.File
+	for _, file := range archive.File {
+		name := strings.ToLower(path.Base(file.Name))
+		if file.FileInfo().IsDir() || (name != "manifest.csv" && name != "manifest.json") {
+			continue
+		}
+		if manifestFile == nil || strings.Count(file.Name, "/") < strings.Count(manifestFile.Name, "/") {
+			manifestFile = file
+		}
+	}
+	if manifestFile == nil {
+		return nil, fmt.Errorf("%w: manifest.csv or manifest.json not found", ErrInvalidDocumentImport)
+	}
+
+	src, err := manifestFile.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: cannot open manifest: %v", ErrInvalidDocumentImport, err)
+	}
+	defer src.Close()
+
+	manifest := &documentImportManifest{Name: manifestFile.Name}
+	if strings.HasSuffix(strings.ToLower(manifestFile.Name), ".json") {
+		manifest.Rows, err = parseJSONManifest(src)
+	} else {
+		manifest.Rows, err = parseCSVManifest(src)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(manifest.Rows) == 0 {
+		return nil, fmt.Errorf("%w: manifest has no rows", ErrInvalidDocumentImport)
+	}
+	if len(manifest.Rows) > maxDocumentImportRows {
+		return nil, fmt.Errorf("%w: manifest has more than %d rows", ErrInvalidDocumentImport, maxDocumentImportRows)
+	}
+	return manifest, nil
+}
+
+// parseCSVManifest reads a CSV manifest whose first line names the columns
+func parseCSVManifest(src io.Reader) ([]map[string]string, error) {
+	reader := csv.NewReader(src)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: cannot read manifest header: %v", ErrInvalidDocumentImport, err)
+	}
+	for i, column := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))
+	}
+	if err := checkManifestColumns(header); err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: cannot read manifest: %v", ErrInvalidDocumentImport, err)
+		}
+		row := make(map[string]string, len(header))
+		for i, column := range header {
+			if i < len(record) {
+				row[column] = strings.TrimSpace(record[i])
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// parseJSONManifest reads a JSON manifest: an array of objects keyed by column name
+func parseJSONManifest(src io.Reader) ([]map[string]string, error) {
+	decoder := json.NewDecoder(src)
+	decoder.UseNumber()
+
+	var records []map[string]interface{}
+	if err := decoder.Decode(&records); err != nil {
+		return nil, fmt.Errorf("%w: manifest is not a JSON array of objects: %v", ErrInvalidDocumentImport, err)
+	}
+
+	rows := make([]map[string]string, 0, len(records))
+	var columns []string
+	seen := map[string]bool{}
+	for _, record := range records {
+		row := make(map[string]string, len(record))
+		for key, value := range record {
+			column := strings.ToLower(strings.TrimSpace(key))
+			if value != nil {
+				row[column] = strings.TrimSpace(fmt.Sprint(value))
+			}
+			if !seen[column] {
+				seen[column] = true
+				columns = append(columns, column)
+			}
+		}
+		rows = append(rows, row)
+	}
+	if err := checkManifestColumns(columns); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// checkManifestColumns rejects unknown columns and requires the mandatory ones
+func checkManifestColumns(columns []string) error {
+	present := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		if _, known := documentImportColumns[column]; !known {
+			return fmt.Errorf("%w: unknown manifest column %q", ErrInvalidDocumentImport, column)
+		}
+		present[column] = true
+	}
+
+	var missing []string
+	for _, column := range requiredImportColumns() {
+		if !present[column] {
+			missing = append(missing, column)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: manifest is missing column(s) %s", ErrInvalidDocumentImport, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// DocumentStatusAccess reports whether the requester may put documents of a category and type, given by their
+// prefixes, in a status
+type DocumentStatusAccess func(status, categoryPrefix, typePrefix string) (bool, error)
+
+// StartImport checks an uploaded archive and queues it for import in the background. Rows with a status other than
+// the initial one fail unless access allows that status for the row's category and type.
+// The archive is removed once the job finishes.
+func (s *DocumentImportService) StartImport(archivePath, fileName string, dryRun bool, userID int, access DocumentStatusAccess) (*models.DocumentImportJob, error) {
+	archive, err := zip.OpenReader(archivePath)
+	if err != nil {
+		os.Remove(archivePath)
+		return nil, fmt.Errorf("%w: not a ZIP archive", ErrInvalidDocumentImport)
+	}
+	manifest, err := readDocumentImportManifest(&archive.Reader)
+	archive.Close()
+	if err != nil {
+		os.Remove(archivePath)
+		return nil, err
+	}
+
+	job := models.DocumentImportJob{
+		FileName:  fileName,
+		DryRun:    dryRun,
+		Status:    models.DocumentImportStatusQueued,
+		TotalRows: len(manifest.Rows),
+		CreatedBy: userID,
+	}
+	if err := config.DB.Create(&job).Error; err != nil {
+		os.Remove(archivePath)
+		return nil, errors.New("failed to create import job")
+	}
+
+	go s.runImport(job, archivePath, access)
+	return &job, nil
+}
+
+// runImport processes every manifest row of a job and records a result for each
+func (s *DocumentImportService) runImport(job models.DocumentImportJob, archivePath string, access DocumentStatusAccess) {
+	defer os.Remove(archivePath)
+
+	documentImportSlot <- struct{}{}
+	defer func() { <-documentImportSlot }()
+
+	startedAt := time.Now()
+	config.DB.Model(&models.DocumentImportJob{}).Where("id = ?", job.ID).
+		Updates(map[string]interface{}{"status": models.DocumentImportStatusRunning, "started_at": startedAt})
+
+	err := s.processImport(&job, archivePath, access)
+
+	finishedAt := time.Now()
+	updates := map[string]interface{}{"status": models.DocumentImportStatusCompleted, "finished_at": finishedAt}
+	message := fmt.Sprintf("%d of %d row(s) imported, %d failed.", job.SucceededRows, job.TotalRows, job.FailedRows)
+	if job.DryRun {
+		message = fmt.Sprintf("Dry run: %d of %d row(s) can be imported, %d have errors.", job.SucceededRows, job.TotalRows, job.FailedRows)
+	}
+	if err != nil {
+		log.Printf("Document import %s failed: %v", job.UUID, err)
+		updates["status"] = models.DocumentImportStatusFailed
+		updates["error"] = err.Error()
+		message = "The import stopped: " + err.Error()
+	}
+	if err := config.DB.Model(&models.DocumentImportJob{}).Where("id = ?", job.ID).Updates(updates).Error; err != nil {
+		log.Printf("Failed to finish document import %s: %v", job.UUID, err)
+	}
+
+	notifyUserBestEffort(job.CreatedBy, models.NotificationEventDocumentImportFinished,
+		fmt.Sprintf("Document import of %s finished", job.FileName), message,
+		"/document-import/"+job.UUID.String())
+}
+
+// processImport works through the manifest rows, updating the job's progress after each one
+func (s *DocumentImportService) processImport(job *models.DocumentImportJob, archivePath string, access DocumentStatusAccess) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("unexpected error: %v", r)
+		}
+	}()
+
+	archive, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("cannot open archive: %v", err)
+	}
+	defer archive.Close()
+
+	manifest, err := readDocumentImportManifest(&archive.Reader)
+	if err != nil {
+		return err
+	}
+
+	// File paths in the manifest are relative to the manifest's folder
+	baseDir := path.Dir(manifest.Name)
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		if !file.FileInfo().IsDir() {
+			files[path.Clean(file.Name)] = file
+		}
+	}
+
+	lookups := newDocumentImportLookups()
+	seenNumbers := make(map[string]int)
+
+	for i, values := range manifest.Rows {
+		row := s.importRow(job, i+2, values, files, baseDir, lookups, seenNumbers, access)
+		if err := config.DB.Create(&row).Error; err != nil {
+			return fmt.Errorf("failed to record row %d: %v", row.RowNumber, err)
+		}
+
+		job.ProcessedRows++
+		if row.Status == models.DocumentImportRowFailed {
+			job.FailedRows++
+		} else {
+			job.SucceededRows++
+		}
+		if err := config.DB.Model(&models.DocumentImportJob{}).Where("id = ?", job.ID).Updates(map[string]interface{}{
+			"processed_rows": job.ProcessedRows,
+			"succeeded_rows": job.SucceededRows,
+			"failed_rows":    job.FailedRows,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update progress: %v", err)
+		}
+	}
+	return nil
+}
+
+// importRow validates one manifest row and, unless the job is a dry run, creates its document.
+// rowNumber is the line of the row in a CSV manifest (the header is line 1).
+func (s *DocumentImportService) importRow(job *models.DocumentImportJob, rowNumber int, values map[string]string, files map[string]*zip.File,
+	baseDir string, lookups *documentImportLookups, seenNumbers map[string]int, access DocumentStatusAccess) models.DocumentImportRow {
+	row := models.DocumentImportRow{
+		DocumentImportJobID: job.ID,
+		RowNumber:           rowNumber,
+		File:                values["file"],
+		DocumentNumber:      values["document_number"],
+		DocumentName:        values["document_name"],
+		Status:              models.DocumentImportRowFailed,
+	}
+
+	payload, statusID, problems := lookups.payload(values)
+	payload.CreatedBy = job.CreatedBy
+
+	// A document created through the API starts in the initial status; any other status must be one the requester
+	// may set for the category and type
+	if len(problems) == 0 && statusID != 1 {
+		status := strings.ToLower(lookups.status(values["status"]).Name)
+		categoryPrefix := lookups.category(values["category_prefix"]).Prefix
+		typePrefix := lookups.documentType(values["type_prefix"], payload.DocumentCategoryID).Prefix
+		allowed, err := access(status, categoryPrefix, typePrefix)
+		switch {
+		case err != nil:
+			problems = append(problems, "failed to check access permissions")
+		case !allowed:
+			problems = append(problems, fmt.Sprintf("no permission to set status %q for category %s and type %s", values["status"], categoryPrefix, typePrefix))
+		}
+	}
+
+	// Document numbers must be new, in the register and within the manifest
+	if number := strings.ToLower(payload.DocumentNumber); number != "" {
+		if previous, ok := seenNumbers[number]; ok {
+			problems = append(problems, fmt.Sprintf("document_number is also used by row %d", previous))
+		} else {
+			seenNumbers[number] = rowNumber
+			var count int64
+			config.DB.Model(&models.DocumentControl{}).
+				Where("LOWER(document_number) = ? AND deleted_at IS NULL", number).Count(&count)
+			if count > 0 {
+				problems = append(problems, "document_number already exists")
+			}
+		}
+	}
+
+	// Read and inspect the file the same way as an upload
+	var file documentFile
+	if row.File != "" {
+		entry, ok := files[path.Join(baseDir, path.Clean("/" + row.File)[1:])]
+		switch {
+		case !ok:
+			problems = append(problems, "file not found in archive")
+		case entry.UncompressedSize64 > maxDocumentFileSize:
+			problems = append(problems, fmt.Sprintf("file size exceeds maximum limit of %d bytes", maxDocumentFileSize))
+		default:
+			content, err := readArchiveFile(entry)
+			if err != nil {
+				problems = append(problems, err.Error())
+				break
+			}
+			file = memoryDocumentFile(path.Base(entry.Name), content)
+			if _, err := inspectDocumentFile(file); err != nil {
+				problems = append(problems, strings.TrimPrefix(err.Error(), ErrInvalidDocumentFile.Error()+": "))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		row.Message = strings.Join(problems, "; ")
+		return row
+	}
+
+	if job.DryRun {
+		row.Status = models.DocumentImportRowValid
+		return row
+	}
+
+	documentControl, _, err := s.DocumentService.createDocumentControl(payload, file, statusID)
+	if err != nil {
+		row.Message = err.Error()
+		return row
+	}
+	row.Status = models.DocumentImportRowCreated
+	row.DocumentControlUUID = &documentControl.UUID
+	return row
+}
+
+// readArchiveFile reads an archive entry, refusing entries that inflate beyond the file size limit
+func readArchiveFile(entry *zip.File) ([]byte, error) {
+	src, err := entry.Open()
+	if err != nil {
+		return nil, fmt.Errorf("cannot open file: %v", err)
+	}
+	defer src.Close()
+
+	content, err := io.ReadAll(io.LimitReader(src, maxDocumentFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read file: %v", err)
+	}
+	if len(content) > maxDocumentFileSize {
+		return nil, fmt.Errorf("file size exceeds maximum limit of %d bytes", maxDocumentFileSize)
+	}
+	return content, nil
+}
+
+// documentImportLookups resolves category and type prefixes and status names, caching them for the job
+type documentImportLookups struct {
+	categories map[string]*models.CategoryDocument
+	types      map[string][]models.DocumentType
+	statuses   map[string]*models.StatusDocument
+}
+
+func newDocumentImportLookups() *documentImportLookups {
+	return &documentImportLookups{
+		categories: map[string]*models.CategoryDocument{},
+		types:      map[string][]models.DocumentType{},
+		statuses:   map[string]*models.StatusDocument{},
+	}
+}
+
+func (l *documentImportLookups) category(prefix string) *models.CategoryDocument {
+	key := strings.ToLower(prefix)
+	if category, ok := l.categories[key]; ok {
+		return category
+	}
+	var category models.CategoryDocument
+	if err := config.DB.Where("LOWER(prefix) = ?", key).First(&category).Error; err != nil {
+		l.categories[key] = nil
+		return nil
+	}
+	l.categories[key] = &category
+	return &category
+}
+
+// documentType finds a type by prefix, preferring one that belongs to the category
+func (l *documentImportLookups) documentType(prefix string, categoryID int) *models.DocumentType {
+	key := strings.ToLower(prefix)
+	types, ok := l.types[key]
+	if !ok {
+		config.DB.Where("LOWER(prefix) = ?", key).Order("id").Find(&types)
+		l.types[key] = types
+	}
+	for i := range types {
+		if types[i].DocumentCategoryID != nil && *types[i].DocumentCategoryID == categoryID {
+			return &types[i]
+		}
+	}
+	for i := range types {
+		if types[i].DocumentCategoryID == nil {
+			return &types[i]
+		}
+	}
+	return nil
+}
+
+func (l *documentImportLookups) status(name string) *models.StatusDocument {
+	key := strings.ToLower(name)
+	if status, ok := l.statuses[key]; ok {
+		return status
+	}
+	var status models.StatusDocument
+	if err := config.DB.Where("LOWER(name) = ?", key).First(&status).Error; err != nil {
+		l.statuses[key] = nil
+		return nil
+	}
+	l.statuses[key] = &status
+	return &status
+}
+
+// payload converts a manifest row into a create payload and a status, listing every problem found
+func (l *documentImportLookups) payload(values map[string]string) (*DocumentControlPayload, int, []string) {
+	var problems []string
+
+	number := func(column string, fallback int) int {
+		value := values[column]
+		if value == "" {
+			return fallback
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			problems = append(problems, column+" must be a whole number")
+			return fallback
+		}
+		return n
+	}
+
+	payload := &DocumentControlPayload{
+		DocumentName:         values["document_name"],
+		Description:          values["description"],
+		DocumentNumber:       values["document_number"],
+		ClauseNumber:         values["clause_number"],
+		RevisionNumber:       number("revision_number", 0),
+		PublishDate:          values["publish_date"],
+		Version:              number("version", 1),
+		ReviewIntervalMonths: number("review_interval_months", 0),
+		NextReviewDate:       values["next_review_date"],
+		ExpiryDate:           values["expiry_date"],
+	}
+
+	for _, column := range requiredImportColumns() {
+		if values[column] == "" {
+			problems = append(problems, column+" is required")
+		}
+	}
+
+	if prefix := values["category_prefix"]; prefix != "" {
+		if category := l.category(prefix); category == nil {
+			problems = append(problems, fmt.Sprintf("unknown category_prefix %q", prefix))
+		} else {
+			payload.DocumentCategoryID = category.ID
+		}
+	}
+	if prefix := values["type_prefix"]; prefix != "" {
+		if documentType := l.documentType(prefix, payload.DocumentCategoryID); documentType == nil {
+			problems = append(problems, fmt.Sprintf("unknown type_prefix %q for this category", prefix))
+		} else {
+			payload.DocumentTypeID = documentType.ID
+		}
+	}
+
+	// New documents start with the first status unless the manifest says otherwise
+	statusID := 1
+	if name := values["status"]; name != "" {
+		if status := l.status(name); status == nil {
+			problems = append(problems, fmt.Sprintf("unknown status %q", name))
+		} else {
+			statusID = int(status.ID)
+		}
+	}
+	payload.StatusDocumentID = statusID
+
+	if payload.PublishDate != "" {
+		if publishDate, err := time.Parse("2006-01-02", payload.PublishDate); err != nil {
+			problems = append(problems, "publish_date must be in YYYY-MM-DD format")
+		} else if _, _, _, err := reviewSchedule(payload, publishDate); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	return payload, statusID, problems
+}
+
+// GetImportJob returns an import job of a user with its progress
+func (s *DocumentImportService) GetImportJob(uuid string, userID int) (*models.DocumentImportJob, error) {
+	var job models.DocumentImportJob
+	if err := config.DB.Where("uuid = ? AND created_by = ?", uuid, userID).First(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDocumentImportNotFound
+		}
+		return nil, errors.New("failed to fetch import job")
+	}
+	return &job, nil
+}
+
+// GetImportJobsPaginated lists a user's import jobs, newest first
+func (s *DocumentImportService) GetImportJobsPaginated(userID, perPage, page int) (map[string]interface{}, error) {
+	var jobs []models.DocumentImportJob
+	var totalRecords int64
+
+	// Calculate offset for pagination
+	offset := (page - 1) * perPage
+
+	query := config.DB.Model(&models.DocumentImportJob{}).Where("created_by = ?", userID)
+
+	// Get the total number of records
+	query.Count(&totalRecords)
+
+	if err := query.Order("created_at DESC, id DESC").Limit(perPage).Offset(offset).Find(&jobs).Error; err != nil {
+		return nil, errors.New("failed to fetch import jobs")
+	}
+
+	// Calculate total pages
+	totalPages := int(math.Ceil(float64(totalRecords) / float64(perPage)))
+
+	return map[string]interface{}{
+		"data":          jobs,
+		"current_page":  page,
+		"per_page":      perPage,
+		"total_pages":   totalPages,
+		"total_records": totalRecords,
+	}, nil
+}
+
+// GetImportRowsPaginated returns the per-row report of an import job, optionally only rows with one result
+func (s *DocumentImportService) GetImportRowsPaginated(job *models.DocumentImportJob, perPage, page int, status string) (map[string]interface{}, error) {
+	var rows []models.DocumentImportRow
+	var totalRecords int64
+
+	// Calculate offset for pagination
+	offset := (page - 1) * perPage
+
+	query := config.DB.Model(&models.DocumentImportRow{}).Where("document_import_job_id = ?", job.ID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	// Get the total number of records
+	query.Count(&totalRecords)
+
+	if err := query.Order("row_number ASC").Limit(perPage).Offset(offset).Find(&rows).Error; err != nil {
+		return nil, errors.New("failed to fetch import rows")
+	}
+
+	// Calculate total pages
+	totalPages := int(math.Ceil(float64(totalRecords) / float64(perPage)))
+
+	return map[string]interface{}{
+		"data":          rows,
+		"job":           job,
+		"current_page":  page,
+		"per_page":      perPage,
+		"total_pages":   totalPages,
+		"total_records": totalRecords,
+	}, nil
+}
+
+// FailInterruptedDocumentImports marks jobs left queued or running by a previous process as failed; their archives are gone
+func FailInterruptedDocumentImports() (int64, error) {
+	result := config.DB.Model(&models.DocumentImportJob{}).
+		Where("status IN ?", []string{models.DocumentImportStatusQueued, models.DocumentImportStatusRunning}).
+		Updates(map[string]interface{}{
+			"status":      models.DocumentImportStatusFailed,
+			"error":       "interrupted by a server restart",
+			"finished_at": time.Now(),
+		})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to update interrupted import jobs: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
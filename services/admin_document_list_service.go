@@ -0,0 +1,394 @@
+package services
+
+import (
+	"backend-school/config"
+	"backend-school/models"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidDocumentSort is returned for a sort on an unknown or repeated field
+	ErrInvalidDocumentSort = errors.New("invalid sort")
+	// ErrInvalidDocumentCursor is returned for a cursor that is malformed or was issued for another sort
+	ErrInvalidDocumentCursor = errors.New("invalid cursor")
+)
+
+// DocumentListFilter narrows a document listing. Zero values are not applied; date ranges include both ends.
+type DocumentListFilter struct {
+	CategoryID  int
+	TypeID      int
+	StatusID    int
+	CreatedBy   int
+	Search      string
+	PublishFrom *time.Time
+	PublishTo   *time.Time
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	UpdatedFrom *time.Time
+	UpdatedTo   *time.Time
+	Trashed     bool               // list the documents in the trash instead of the live ones
+	Scope       *DocumentReadScope // documents the requester may read; nil lists every document
+}
+
+// DocumentReadGrant is a status, category and type a user may read the documents of, as in a "document" policy
+type DocumentReadGrant struct {
+	Status         string // lower-cased status name
+	CategoryPrefix string
+	TypePrefix     string
+}
+
+// DocumentReadScope limits a listing to the documents a user may read: the ones they created and the ones matching
+// one of their grants
+type DocumentReadScope struct {
+	UserID int
+	Grants []DocumentReadGrant
+}
+
+// applyDocumentReadScope limits a query joined with status_document, category_document and document_type to the
+// documents of scope; a nil scope is not applied
+func applyDocumentReadScope(query *gorm.DB, scope *DocumentReadScope) *gorm.DB {
+	if scope == nil {
+		return query
+	}
+	if len(scope.Grants) == 0 {
+		return query.Where("document_control.created_by = ?", scope.UserID)
+	}
+	grants := make([][]interface{}, len(scope.Grants))
+	for i, grant := range scope.Grants {
+		grants[i] = []interface{}{grant.Status, grant.CategoryPrefix, grant.TypePrefix}
+	}
+	return query.Where("(document_control.created_by = ? OR "+
+		"(LOWER(status_document.name), category_document.prefix, document_type.prefix) IN ?)", scope.UserID, grants)
+}
+
+// DocumentListParams holds the filters, sort and page of a document listing.
+// Sort is a comma-separated list of fields, each optionally prefixed with "-" for descending order.
+type DocumentListParams struct {
+	DocumentListFilter
+	Sort        string
+	CurrentPage int
+	PageSize    int
+	Cursor      string // cursor listings only: the next_cursor of the previous page, empty for the first page
+}
+
+// DocumentCursorResult is a page of a cursor listing
+type DocumentCursorResult struct {
+	Data       []DocumentControlResponse `json:"data"`
+	PerPage    int                       `json:"per_page"`
+	NextCursor string                    `json:"next_cursor"`
+	HasMore    bool                      `json:"has_more"`
+}
+
+// Kinds of sort values, which decide how a cursor value is decoded
+const (
+	documentSortString = iota
+	documentSortInt
+	documentSortDate
+	documentSortTime
+)
+
+// documentSortField is a field the listing can be sorted on. Nullable columns are coalesced so that
+// rows compare the same way in ORDER BY and in the cursor condition.
+type documentSortField struct {
+	column string
+	kind   int
+	value  func(doc models.DocumentControlJoined) interface{}
+}
+
+// documentSortFields are the fields accepted by the sort parameter
+var documentSortFields = map[string]documentSortField{
+	"document_number": {"COALESCE(document_control.document_number, '')", documentSortString, func(doc models.DocumentControlJoined) interface{} { return doc.DocumentNumber }},
+	"document_name":   {"COALESCE(document_control.document_name, '')", documentSortString, func(doc models.DocumentControlJoined) interface{} { return doc.DocumentName }},
+	"clause_number":   {"COALESCE(document_control.clause_number, '')", documentSortString, func(doc models.DocumentControlJoined) interface{} { return doc.ClauseNumber }},
+	"revision_number": {"COALESCE(document_control.revision_number, 0)", documentSortInt, func(doc models.DocumentControlJoined) interface{} { return doc.RevisionNumber }},
+	"publish_date":    {"COALESCE(document_control.publish_date, '0001-01-01')", documentSortDate, func(doc models.DocumentControlJoined) interface{} { return doc.PublishDate }},
+	"created_at":      {"document_control.created_at", documentSortTime, func(doc models.DocumentControlJoined) interface{} { return doc.CreatedAt }},
+	"updated_at":      {"document_control.updated_at", documentSortTime, func(doc models.DocumentControlJoined) interface{} { return doc.UpdatedAt }},
+	"category_name":   {"COALESCE(category_document.name, '')", documentSortString, func(doc models.DocumentControlJoined) interface{} { return doc.CategoryName }},
+	"type_name":       {"COALESCE(document_type.name, '')", documentSortString, func(doc models.DocumentControlJoined) interface{} { return doc.TypeName }},
+	"status_name":     {"COALESCE(status_document.name, '')", documentSortString, func(doc models.DocumentControlJoined) interface{} { return doc.StatusName }},
+	"deleted_at":      {"COALESCE(document_control.deleted_at, '0001-01-01')", documentSortTime, func(doc models.DocumentControlJoined) interface{} { return optionalSortTime(doc.DeletedAt) }},
+	"purge_after":     {"COALESCE(document_control.purge_after, '0001-01-01')", documentSortTime, func(doc models.DocumentControlJoined) interface{} { return optionalSortTime(doc.PurgeAfter) }},
+}
+
+// optionalSortTime is the sort value of a nullable timestamp, matching its COALESCE
+func optionalSortTime(t *time.Time) time.Time {
+	if t == nil {
+		return time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return *t
+}
+
+// documentSortKey is one field of a parsed sort
+type documentSortKey struct {
+	documentSortField
+	desc bool
+}
+
+// documentIDSortKey ends every sort so that the order, and with it the cursor, is unambiguous
+var documentIDSortKey = documentSortKey{documentSortField{"document_control.id", documentSortInt, func(doc models.DocumentControlJoined) interface{} { return doc.ID }}, false}
+
+// parseDocumentSort parses a sort such as "-publish_date,document_number"
+func parseDocumentSort(sort string) ([]documentSortKey, error) {
+	var keys []documentSortKey
+	seen := map[string]bool{}
+	for _, part := range strings.Split(sort, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name := strings.TrimPrefix(part, "-")
+		field, ok := documentSortFields[name]
+		if !ok || seen[name] {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidDocumentSort, name)
+		}
+		seen[name] = true
+		keys = append(keys, documentSortKey{field, strings.HasPrefix(part, "-")})
+	}
+	return append(keys, documentIDSortKey), nil
+}
+
+// documentControlListQuery is the joined query behind the document listings and the register export
+func documentControlListQuery(filter DocumentListFilter) *gorm.DB {
+	query := config.DB.Model(&models.DocumentControlJoined{}).
+		Select("document_control.*, category_document.name AS category_name, category_document.prefix AS category_prefix, " +
+			"document_type.name AS type_name, document_type.prefix AS type_prefix, " +
+			"status_document.name AS status_name").
+		Joins("LEFT JOIN category_document ON category_document.id = document_control.document_category_id").
+		Joins("LEFT JOIN document_type ON document_type.id = document_control.document_type_id").
+		Joins("LEFT JOIN status_document ON status_document.id = document_control.status_document_id")
+	if filter.Trashed {
+		query = query.Where("document_control.deleted_at IS NOT NULL")
+	} else {
+		query = query.Where("document_control.deleted_at IS NULL")
+	}
+
+	for column, id := range map[string]int{
+		"document_control.document_category_id": filter.CategoryID,
+		"document_control.document_type_id":     filter.TypeID,
+		"document_control.status_document_id":   filter.StatusID,
+		"document_control.created_by":           filter.CreatedBy,
+	} {
+		if id != 0 {
+			query = query.Where(column+" = ?", id)
+		}
+	}
+
+	// publish_date is a date, so the bounds are compared as dates
+	if filter.PublishFrom != nil {
+		query = query.Where("document_control.publish_date >= ?", filter.PublishFrom.Format("2006-01-02"))
+	}
+	if filter.PublishTo != nil {
+		query = query.Where("document_control.publish_date <= ?", filter.PublishTo.Format("2006-01-02"))
+	}
+
+	// Timestamps up to the end of the "to" day are included
+	for column, bounds := range map[string][2]*time.Time{
+		"document_control.created_at": {filter.CreatedFrom, filter.CreatedTo},
+		"document_control.updated_at": {filter.UpdatedFrom, filter.UpdatedTo},
+	} {
+		if bounds[0] != nil {
+			query = query.Where(column+" >= ?", *bounds[0])
+		}
+		if bounds[1] != nil {
+			query = query.Where(column+" < ?", bounds[1].AddDate(0, 0, 1))
+		}
+	}
+
+	query = applyDocumentReadScope(query, filter.Scope)
+
+	// Apply search filter if provided
+	if filter.Search != "" {
+		pattern := "%" + strings.ToLower(filter.Search) + "%"
+		query = query.Where("(LOWER(document_control.document_name) LIKE ? OR LOWER(document_control.description) LIKE ? "+
+			"OR LOWER(document_control.document_number) LIKE ? OR LOWER(document_control.clause_number) LIKE ?)",
+			pattern, pattern, pattern, pattern)
+	}
+	return query
+}
+
+// orderDocumentList applies a parsed sort to a listing query
+func orderDocumentList(query *gorm.DB, keys []documentSortKey) *gorm.DB {
+	for _, key := range keys {
+		direction := " ASC"
+		if key.desc {
+			direction = " DESC"
+		}
+		query = query.Order(key.column + direction)
+	}
+	return query
+}
+
+// ListDocumentControls returns a page of documents matching the filters, numbered by currentPage
+func (s *DocumentControlService) ListDocumentControls(params DocumentListParams) (*PaginatedResult, error) {
+	keys, err := parseDocumentSort(params.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	var documentControls []models.DocumentControlJoined
+	var totalRecords int64
+
+	query := documentControlListQuery(params.DocumentListFilter)
+
+	// Get total record count with applied filters
+	if err := query.Count(&totalRecords).Error; err != nil {
+		return nil, errors.New("failed to count document controls")
+	}
+
+	// Fetch paginated records
+	offset := (params.CurrentPage - 1) * params.PageSize
+	if err := orderDocumentList(query, keys).Offset(offset).Limit(params.PageSize).Find(&documentControls).Error; err != nil {
+		return nil, errors.New("failed to fetch document controls")
+	}
+
+	// Convert each DocumentControl to DocumentControlResponse with formatted date
+	var formattedControls []DocumentControlResponse
+	for _, doc := range documentControls {
+		formattedControls = append(formattedControls, formatDocumentControl(doc))
+	}
+
+	// Calculate total pages based on the record count and page size
+	totalPages := int((totalRecords + int64(params.PageSize) - 1) / int64(params.PageSize))
+
+	return &PaginatedResult{
+		Data:         formattedControls,
+		CurrentPage:  params.CurrentPage,
+		PerPage:      params.PageSize,
+		TotalPages:   totalPages,
+		TotalRecords: totalRecords,
+	}, nil
+}
+
+// documentCursor is the position after the last row of a page, tied to the sort it was issued for
+type documentCursor struct {
+	Sort   string            `json:"s"`
+	Values []json.RawMessage `json:"v"`
+}
+
+// encodeDocumentCursor records the sort values of the last row of a page
+func encodeDocumentCursor(sort string, keys []documentSortKey, doc models.DocumentControlJoined) (string, error) {
+	cursor := documentCursor{Sort: sort}
+	for _, key := range keys {
+		value := key.value(doc)
+		if key.kind == documentSortDate {
+			value = value.(time.Time).Format("2006-01-02")
+		}
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		cursor.Values = append(cursor.Values, raw)
+	}
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeDocumentCursor returns the sort values recorded in a cursor, typed for the query
+func decodeDocumentCursor(encoded, sort string, keys []documentSortKey) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidDocumentCursor
+	}
+	var cursor documentCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Sort != sort || len(cursor.Values) != len(keys) {
+		return nil, ErrInvalidDocumentCursor
+	}
+
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		var err error
+		switch key.kind {
+		case documentSortString, documentSortDate:
+			// Dates stay strings so that they compare as dates rather than as timestamps in the session time zone
+			var value string
+			err = json.Unmarshal(cursor.Values[i], &value)
+			values[i] = value
+		case documentSortInt:
+			var value int
+			err = json.Unmarshal(cursor.Values[i], &value)
+			values[i] = value
+		case documentSortTime:
+			var value time.Time
+			err = json.Unmarshal(cursor.Values[i], &value)
+			values[i] = value
+		}
+		if err != nil {
+			return nil, ErrInvalidDocumentCursor
+		}
+	}
+	return values, nil
+}
+
+// afterDocumentCursor limits a query to the rows sorted after the cursor values:
+// (a > va) OR (a = va AND b > vb) OR ..., with "<" for descending fields
+func afterDocumentCursor(query *gorm.DB, keys []documentSortKey, values []interface{}) *gorm.DB {
+	var clauses []string
+	var args []interface{}
+	for i, key := range keys {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, keys[j].column+" = ?")
+			args = append(args, values[j])
+		}
+		operator := " > ?"
+		if key.desc {
+			operator = " < ?"
+		}
+		parts = append(parts, key.column+operator)
+		args = append(args, values[i])
+		clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+	}
+	return query.Where("("+strings.Join(clauses, " OR ")+")", args...)
+}
+
+// ListDocumentControlsByCursor returns the page of documents after params.Cursor. Unlike page numbers, a cursor
+// keeps its place when documents are added or removed while the client pages through the list.
+func (s *DocumentControlService) ListDocumentControlsByCursor(params DocumentListParams) (*DocumentCursorResult, error) {
+	keys, err := parseDocumentSort(params.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	query := documentControlListQuery(params.DocumentListFilter)
+	if params.Cursor != "" {
+		values, err := decodeDocumentCursor(params.Cursor, params.Sort, keys)
+		if err != nil {
+			return nil, err
+		}
+		query = afterDocumentCursor(query, keys, values)
+	}
+
+	// One extra row tells whether another page follows
+	var documentControls []models.DocumentControlJoined
+	if err := orderDocumentList(query, keys).Limit(params.PageSize + 1).Find(&documentControls).Error; err != nil {
+		return nil, errors.New("failed to fetch document controls")
+	}
+
+	result := &DocumentCursorResult{
+		Data:    []DocumentControlResponse{},
+		PerPage: params.PageSize,
+		HasMore: len(documentControls) > params.PageSize,
+	}
+	if result.HasMore {
+		documentControls = documentControls[:params.PageSize]
+		result.NextCursor, err = encodeDocumentCursor(params.Sort, keys, documentControls[len(documentControls)-1])
+		if err != nil {
+			return nil, errors.New("failed to encode cursor")
+		}
+	}
+	for _, doc := range documentControls {
+		result.Data = append(result.Data, formatDocumentControl(doc))
+	}
+	return result, nil
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"backend-school/config"
+	"backend-school/helpers"
+	"backend-school/models"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Document register export formats
+const (
+	DocumentRegisterFormatXLSX = "xlsx"
+	DocumentRegisterFormatPDF  = "pdf"
+)
+
+// DocumentRegisterCategories maps the registers of the list endpoints to their document category
+var DocumentRegisterCategories = map[string]int{
+	"internal": 1,
+	"external": 2,
+}
+
+// documentRegisterColumns are the columns of the master list; widths are relative
+var documentRegisterColumns = []helpers.PDFTableColumn{
+	{Title: "Document Number", Width: 12},
+	{Title: "Document Name", Width: 24},
+	{Title: "Clause", Width: 7},
+	{Title: "Category", Width: 11},
+	{Title: "Type", Width: 11},
+	{Title: "Status", Width: 9},
+	{Title: "Revision", Width: 6},
+	{Title: "Publish Date", Width: 8},
+	{Title: "Next Review", Width: 8},
+	{Title: "Owner", Width: 13},
+}
+
+// documentRegisterRow is a document of the register with its owner
+type documentRegisterRow struct {
+	models.DocumentControlJoined
+	OwnerUsername string
+	OwnerFullname string
+}
+
+// values returns the cells of a register row in column order
+func (row documentRegisterRow) values() []string {
+	nextReview := ""
+	if row.NextReviewDate != nil {
+		nextReview = row.NextReviewDate.Format("2006-01-02")
+	}
+	owner := row.OwnerFullname
+	if owner == "" {
+		owner = row.OwnerUsername
+	}
+	return []string{
+		row.DocumentNumber,
+		row.DocumentName,
+		row.ClauseNumber,
+		row.CategoryName,
+		row.TypeName,
+		row.StatusName,
+		strconv.Itoa(row.RevisionNumber),
+		row.PublishDate.Format("2006-01-02"),
+		nextReview,
+		owner,
+	}
+}
+
+// eachDocumentRegisterRow runs fn for every document of the register, reading rows one at a time from the database
+func eachDocumentRegisterRow(filter DocumentListFilter, fn func(documentRegisterRow) error) error {
+	rows, err := documentControlListQuery(filter).
+		Select("document_control.*, category_document.name AS category_name, category_document.prefix AS category_prefix, " +
+			"document_type.name AS type_name, document_type.prefix AS type_prefix, " +
+			"status_document.name AS status_name, users.username AS owner_username, users.fullname AS owner_fullname").
+		Joins("LEFT JOIN users ON users.id = document_control.created_by").
+		Order("category_document.name, document_control.document_number, document_control.id").
+		Rows()
+	if err != nil {
+		return errors.New("failed to fetch document controls")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row documentRegisterRow
+		if err := config.DB.ScanRows(rows, &row); err != nil {
+			return errors.New("failed to read document controls")
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ExportDocumentRegister writes the master list of documents matching filter as XLSX or PDF.
+// Rows are streamed from the database to w.
+func (s *DocumentControlService) ExportDocumentRegister(format string, filter DocumentListFilter, w io.Writer) error {
+	switch format {
+	case DocumentRegisterFormatXLSX:
+		return exportDocumentRegisterXLSX(filter, w)
+	case DocumentRegisterFormatPDF:
+		return exportDocumentRegisterPDF(filter, w)
+	}
+	return fmt.Errorf("unsupported export format: %s", format)
+}
+
+// exportDocumentRegisterXLSX uses the excelize stream writer, which moves rows to a temporary file as the sheet grows
+func exportDocumentRegisterXLSX(filter DocumentListFilter, w io.Writer) error {
+	file := excelize.NewFile()
+	defer file.Close()
+
+	const sheet = "Register"
+	if err := file.SetSheetName("Sheet1", sheet); err != nil {
+		return err
+	}
+	stream, err := file.NewStreamWriter(sheet)
+	if err != nil {
+		return err
+	}
+
+	bold, err := file.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return err
+	}
+
+	header := make([]interface{}, len(documentRegisterColumns))
+	for i, column := range documentRegisterColumns {
+		header[i] = excelize.Cell{Value: column.Title, StyleID: bold}
+		if err := stream.SetColWidth(i+1, i+1, column.Width*1.5); err != nil {
+			return err
+		}
+	}
+	if err := stream.SetPanes(&excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return err
+	}
+	if err := stream.SetRow("A1", header); err != nil {
+		return err
+	}
+
+	rowNumber := 1
+	err = eachDocumentRegisterRow(filter, func(row documentRegisterRow) error {
+		rowNumber++
+		values := row.values()
+		cells := make([]interface{}, len(values))
+		for i, value := range values {
+			cells[i] = value
+		}
+		cells[6] = row.RevisionNumber
+		cell, _ := excelize.CoordinatesToCellName(1, rowNumber)
+		return stream.SetRow(cell, cells)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := stream.Flush(); err != nil {
+		return err
+	}
+	_, err = file.WriteTo(w)
+	return err
+}
+
+// exportDocumentRegisterPDF writes each page of the register as soon as it is full
+func exportDocumentRegisterPDF(filter DocumentListFilter, w io.Writer) error {
+	subtitle := "Generated " + time.Now().Format("2006-01-02 15:04")
+	if filter.Search != "" {
+		subtitle += " - filtered by \"" + filter.Search + "\""
+	}
+	table := helpers.NewPDFTableWriter(w, "Master List of Controlled Documents", subtitle, documentRegisterColumns)
+
+	if err := eachDocumentRegisterRow(filter, func(row documentRegisterRow) error {
+		return table.WriteRow(row.values())
+	}); err != nil {
+		return err
+	}
+	return table.Close()
+}
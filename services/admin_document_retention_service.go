@@ -0,0 +1,602 @@
+package services
+
+import (
+	"backend-school/config"
+	"backend-school/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// defaultDocumentRetentionYears applies when no policy matches and document_retention_default_years is not set
+const defaultDocumentRetentionYears = 7
+
+// maxDocumentRetentionYears bounds the retention of a policy
+const maxDocumentRetentionYears = 100
+
+// documentArchivePrefix is the object prefix superseded versions are moved under. Archived objects are not public;
+// they are served through the download endpoint.
+const documentArchivePrefix = "archive/"
+
+// documentRetentionBatchSize is how many rows the archival and the purge read at a time
+const documentRetentionBatchSize = 100
+
+var (
+	// ErrRetentionPolicyNotFound is returned when a retention policy does not exist
+	ErrRetentionPolicyNotFound = errors.New("retention policy not found")
+	// ErrRetentionPolicyExists is returned for a second policy on the same category and type
+	ErrRetentionPolicyExists = errors.New("a retention policy for this category and type already exists")
+	// ErrInvalidRetentionPolicy is wrapped by every rejected retention policy
+	ErrInvalidRetentionPolicy = errors.New("invalid retention policy")
+	// ErrDocumentNotInTrash is returned when a document to restore is not in the trash
+	ErrDocumentNotInTrash = errors.New("document not found in trash")
+	// ErrDocumentNumberInUse is returned when a document cannot be restored because a live document took its number
+	ErrDocumentNumberInUse = errors.New("another document already uses this document number")
+)
+
+// DocumentRetentionService manages the retention policies
+type DocumentRetentionService struct{}
+
+func NewDocumentRetentionService() *DocumentRetentionService {
+	return &DocumentRetentionService{}
+}
+
+// DocumentRetentionPolicyPayload is the editable part of a retention policy; a category, a type or both are required
+type DocumentRetentionPolicyPayload struct {
+	DocumentCategoryID *int   `json:"document_category_id"`
+	DocumentTypeID     *int   `json:"document_type_id"`
+	RetentionYears     int    `json:"retention_years"`
+	Description        string `json:"description"`
+}
+
+// DocumentRetentionPolicyDetail is a retention policy with the names of its category and type
+type DocumentRetentionPolicyDetail struct {
+	models.DocumentRetentionPolicy
+	CategoryName string `json:"category_name"`
+	TypeName     string `json:"type_name"`
+}
+
+// DocumentRetentionResult counts what a retention run did
+type DocumentRetentionResult struct {
+	ArchivedVersions int `json:"archived_versions"`
+	PurgedVersions   int `json:"purged_versions"`
+	PurgedDocuments  int `json:"purged_documents"`
+}
+
+func retentionPolicyDetailQuery() *gorm.DB {
+	return config.DB.Table("document_retention_policy").
+		Select("document_retention_policy.*, category_document.name AS category_name, document_type.name AS type_name").
+		Joins("LEFT JOIN category_document ON category_document.id = document_retention_policy.document_category_id").
+		Joins("LEFT JOIN document_type ON document_type.id = document_retention_policy.document_type_id")
+}
+
+// GetPoliciesPaginated lists the retention policies
+func (s *DocumentRetentionService) GetPoliciesPaginated(perPage, page int) (map[string]interface{}, error) {
+	var policies []DocumentRetentionPolicyDetail
+	var totalRecords int64
+
+	// Calculate offset for pagination
+	offset := (page - 1) * perPage
+
+	if err := config.DB.Model(&models.DocumentRetentionPolicy{}).Count(&totalRecords).Error; err != nil {
+		return nil, errors.New("failed to count retention policies")
+	}
+	if err := retentionPolicyDetailQuery().Order("document_retention_policy.id ASC").
+		Limit(perPage).Offset(offset).Scan(&policies).Error; err != nil {
+		return nil, errors.New("failed to fetch retention policies")
+	}
+
+	// Calculate total pages
+	totalPages := int(math.Ceil(float64(totalRecords) / float64(perPage)))
+
+	return map[string]interface{}{
+		"data":          policies,
+		"current_page":  page,
+		"per_page":      perPage,
+		"total_pages":   totalPages,
+		"total_records": totalRecords,
+	}, nil
+}
+
+// GetPolicyByUUID returns a retention policy by UUID
+func (s *DocumentRetentionService) GetPolicyByUUID(uuid string) (*DocumentRetentionPolicyDetail, error) {
+	var policies []DocumentRetentionPolicyDetail
+	if err := retentionPolicyDetailQuery().Where("document_retention_policy.uuid = ?", uuid).Limit(1).Scan(&policies).Error; err != nil {
+		return nil, errors.New("failed to fetch retention policy")
+	}
+	if len(policies) == 0 {
+		return nil, ErrRetentionPolicyNotFound
+	}
+	return &policies[0], nil
+}
+
+// CreatePolicy adds a retention policy for a category and/or type that has none yet
+func (s *DocumentRetentionService) CreatePolicy(payload *DocumentRetentionPolicyPayload, userID int) (*models.DocumentRetentionPolicy, error) {
+	if err := validateRetentionPolicy(payload, 0); err != nil {
+		return nil, err
+	}
+
+	policy := models.DocumentRetentionPolicy{
+		DocumentCategoryID: payload.DocumentCategoryID,
+		DocumentTypeID:     payload.DocumentTypeID,
+		RetentionYears:     payload.RetentionYears,
+		Description:        strings.TrimSpace(payload.Description),
+		CreatedBy:          userID,
+		UpdatedBy:          userID,
+	}
+	if err := config.DB.Create(&policy).Error; err != nil {
+		return nil, fmt.Errorf("failed to create retention policy: %w", err)
+	}
+	return &policy, nil
+}
+
+// UpdatePolicy changes a retention policy. Archived versions keep the retention they were given;
+// documents deleted from now on get the new one.
+func (s *DocumentRetentionService) UpdatePolicy(uuid string, payload *DocumentRetentionPolicyPayload, userID int) (*models.DocumentRetentionPolicy, error) {
+	var policy models.DocumentRetentionPolicy
+	if err := config.DB.Where("uuid = ?", uuid).First(&policy).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRetentionPolicyNotFound
+		}
+		return nil, errors.New("failed to fetch retention policy")
+	}
+	if err := validateRetentionPolicy(payload, policy.ID); err != nil {
+		return nil, err
+	}
+
+	policy.DocumentCategoryID = payload.DocumentCategoryID
+	policy.DocumentTypeID = payload.DocumentTypeID
+	policy.RetentionYears = payload.RetentionYears
+	policy.Description = strings.TrimSpace(payload.Description)
+	policy.UpdatedBy = userID
+	if err := config.DB.Save(&policy).Error; err != nil {
+		return nil, fmt.Errorf("failed to update retention policy: %w", err)
+	}
+	return &policy, nil
+}
+
+// DeletePolicy removes a retention policy; its documents fall back to a broader policy or the default
+func (s *DocumentRetentionService) DeletePolicy(uuid string) error {
+	result := config.DB.Where("uuid = ?", uuid).Delete(&models.DocumentRetentionPolicy{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete retention policy: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrRetentionPolicyNotFound
+	}
+	return nil
+}
+
+// validateRetentionPolicy checks a policy payload; excludeID is the policy being updated
+func validateRetentionPolicy(payload *DocumentRetentionPolicyPayload, excludeID uint) error {
+	if payload.DocumentCategoryID == nil && payload.DocumentTypeID == nil {
+		return fmt.Errorf("%w: document_category_id or document_type_id is required", ErrInvalidRetentionPolicy)
+	}
+	if payload.RetentionYears < 1 || payload.RetentionYears > maxDocumentRetentionYears {
+		return fmt.Errorf("%w: retention_years must be between 1 and %d", ErrInvalidRetentionPolicy, maxDocumentRetentionYears)
+	}
+
+	if payload.DocumentCategoryID != nil {
+		var count int64
+		config.DB.Model(&models.CategoryDocument{}).Where("id = ?", *payload.DocumentCategoryID).Count(&count)
+		if count == 0 {
+			return fmt.Errorf("%w: document category not found", ErrInvalidRetentionPolicy)
+		}
+	}
+	if payload.DocumentTypeID != nil {
+		var count int64
+		config.DB.Model(&models.DocumentType{}).Where("id = ?", *payload.DocumentTypeID).Count(&count)
+		if count == 0 {
+			return fmt.Errorf("%w: document type not found", ErrInvalidRetentionPolicy)
+		}
+	}
+
+	query := config.DB.Model(&models.DocumentRetentionPolicy{}).Where("id <> ?", excludeID)
+	for column, id := range map[string]*int{"document_category_id": payload.DocumentCategoryID, "document_type_id": payload.DocumentTypeID} {
+		if id == nil {
+			query = query.Where(column + " IS NULL")
+		} else {
+			query = query.Where(column+" = ?", *id)
+		}
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check retention policies: %w", err)
+	}
+	if count > 0 {
+		return ErrRetentionPolicyExists
+	}
+	return nil
+}
+
+// documentRetentionRules resolves how long documents are retained
+type documentRetentionRules struct {
+	policies     []models.DocumentRetentionPolicy
+	defaultYears int
+}
+
+func loadDocumentRetentionRules() (*documentRetentionRules, error) {
+	rules := &documentRetentionRules{defaultYears: defaultDocumentRetentionYears}
+	if years, err := NewSettingsService().GetSettingInt("document_retention_default_years"); err == nil && years > 0 {
+		rules.defaultYears = years
+	}
+	if err := config.DB.Find(&rules.policies).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch retention policies: %w", err)
+	}
+	return rules, nil
+}
+
+// years returns the retention of a document: the policy on its category and type, else on its type,
+// else on its category, else the default
+func (r *documentRetentionRules) years(categoryID, typeID *int) int {
+	matches := func(policyID, documentID *int) bool {
+		return policyID == nil || (documentID != nil && *policyID == *documentID)
+	}
+
+	years, best := r.defaultYears, 0
+	for _, policy := range r.policies {
+		if !matches(policy.DocumentCategoryID, categoryID) || !matches(policy.DocumentTypeID, typeID) {
+			continue
+		}
+		rank := 1
+		if policy.DocumentTypeID != nil {
+			rank = 2
+			if policy.DocumentCategoryID != nil {
+				rank = 3
+			}
+		}
+		if rank > best {
+			years, best = policy.RetentionYears, rank
+		}
+	}
+	return years
+}
+
+// DeleteDocumentControl moves a document to the trash. Its files stay in storage and it can be restored until
+// the retention of its category and type has passed; the purge then removes every version.
+func (s *DocumentControlService) DeleteDocumentControl(uuid string, userID int) error {
+	documentControl, err := s.GetDocumentControlByUUID(uuid)
+	if err != nil {
+		return err
+	}
+
+	rules, err := loadDocumentRetentionRules()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	purgeAfter := now.AddDate(rules.years(documentControl.DocumentCategoryID, documentControl.DocumentTypeID), 0, 0)
+
+	// A check-out does not survive the trash
+	if err := config.DB.Model(&models.DocumentControl{}).Where("id = ? AND deleted_at IS NULL", documentControl.ID).
+		Updates(map[string]interface{}{
+			"deleted_at":          now,
+			"deleted_by":          userID,
+			"purge_after":         purgeAfter,
+			"checked_out_by":      nil,
+			"checked_out_at":      nil,
+			"checkout_expires_at": nil,
+			"lock_version":        gorm.Expr("lock_version + 1"),
+		}).Error; err != nil {
+		return fmt.Errorf("failed to delete document control: %w", err)
+	}
+	return nil
+}
+
+// ListTrashedDocumentControls returns a page of the trash, most recently deleted first unless params.Sort is set
+func (s *DocumentControlService) ListTrashedDocumentControls(params DocumentListParams) (*PaginatedResult, error) {
+	params.Trashed = true
+	if params.Sort == "" {
+		params.Sort = "-deleted_at"
+	}
+	return s.ListDocumentControls(params)
+}
+
+// GetTrashedDocumentControlByUUID retrieves a document in the trash
+func (s *DocumentControlService) GetTrashedDocumentControlByUUID(uuid string) (*models.DocumentControl, error) {
+	var documentControl models.DocumentControl
+	if err := config.DB.Where("uuid = ? AND deleted_at IS NOT NULL", uuid).First(&documentControl).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDocumentNotInTrash
+		}
+		return nil, fmt.Errorf("failed to fetch document control: %w", err)
+	}
+	return &documentControl, nil
+}
+
+// RestoreDocumentControl takes a document out of the trash, unless a live document now has its number
+func (s *DocumentControlService) RestoreDocumentControl(documentControl *models.DocumentControl) (*models.DocumentControl, error) {
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.DocumentControl{}).
+			Where("LOWER(document_number) = LOWER(?) AND deleted_at IS NULL AND id <> ?", documentControl.DocumentNumber, documentControl.ID).
+			Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to check document number: %w", err)
+		}
+		if count > 0 {
+			return ErrDocumentNumberInUse
+		}
+
+		result := tx.Model(&models.DocumentControl{}).Where("id = ? AND deleted_at IS NOT NULL", documentControl.ID).
+			Updates(map[string]interface{}{
+				"deleted_at":   nil,
+				"deleted_by":   nil,
+				"purge_after":  nil,
+				"lock_version": gorm.Expr("lock_version + 1"),
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to restore document control: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrDocumentNotInTrash
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reloadDocumentControl(documentControl.ID)
+}
+
+// documentObjectURL is the public URL stored for an object of the document bucket
+func (s *DocumentControlService) documentObjectURL(objectName string) string {
+	return fmt.Sprintf("https://%s/%s/%s", os.Getenv("MINIO_ENDPOINT"), s.bucketName, objectName)
+}
+
+// removeDocumentObject deletes the object behind a stored file URL; an object that is already gone is not an error
+func (s *DocumentControlService) removeDocumentObject(fileURL string) error {
+	if fileURL == "" {
+		return nil
+	}
+	if s.minioClient == nil {
+		return fmt.Errorf("MinIO client is not initialized")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	err := s.minioClient.RemoveObject(ctx, s.bucketName, documentObjectName(fileURL, s.bucketName), minio.RemoveObjectOptions{})
+	if err != nil && minio.ToErrorResponse(err).Code != "NoSuchKey" {
+		return fmt.Errorf("failed to delete file from MinIO: %w", err)
+	}
+	return nil
+}
+
+// archiveVersion copies a superseded version under the archive prefix, points the version at the copy and then
+// removes the original object
+func (s *DocumentControlService) archiveVersion(version *models.DocumentVersion, now, retainUntil time.Time) error {
+	fileURL := version.File
+	source := documentObjectName(version.File, s.bucketName)
+	if version.File != "" && !strings.HasPrefix(source, documentArchivePrefix) {
+		if s.minioClient == nil {
+			return fmt.Errorf("MinIO client is not initialized")
+		}
+		destination := documentArchivePrefix + source
+
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+		if _, err := s.minioClient.CopyObject(ctx,
+			minio.CopyDestOptions{Bucket: s.bucketName, Object: destination},
+			minio.CopySrcOptions{Bucket: s.bucketName, Object: source}); err != nil {
+			return fmt.Errorf("failed to copy file to the archive: %w", err)
+		}
+		fileURL = s.documentObjectURL(destination)
+	}
+
+	result := config.DB.Model(&models.DocumentVersion{}).Where("id = ? AND archived_at IS NULL", version.ID).
+		Updates(map[string]interface{}{"file": fileURL, "archived_at": now, "retain_until": retainUntil})
+	if result.Error != nil {
+		return fmt.Errorf("failed to archive document version: %w", result.Error)
+	}
+	if result.RowsAffected == 0 || fileURL == version.File {
+		// Archived by a concurrent run, which wrote the same copy
+		return nil
+	}
+
+	if err := s.removeDocumentObject(version.File); err != nil {
+		log.Printf("Archived document version %s but could not remove the original: %v", version.UUID, err)
+	}
+	return nil
+}
+
+// ArchiveSupersededVersions moves every version that has a newer one to the archive prefix and keeps it for the
+// retention of its document's category and type
+func (s *DocumentControlService) ArchiveSupersededVersions(now time.Time) (int, error) {
+	rules, err := loadDocumentRetentionRules()
+	if err != nil {
+		return 0, err
+	}
+
+	type supersededVersion struct {
+		models.DocumentVersion
+		DocumentCategoryID *int
+		DocumentTypeID     *int
+	}
+
+	archived, lastID := 0, 0
+	for {
+		var versions []supersededVersion
+		if err := config.DB.Table("document_version").
+			Select("document_version.*, document_control.document_category_id, document_control.document_type_id").
+			Joins("JOIN document_control ON document_control.id = document_version.document_control_id").
+			Where("document_version.id > ? AND document_version.archived_at IS NULL AND document_version.deleted_at IS NULL", lastID).
+			Where("EXISTS (SELECT 1 FROM document_version newer WHERE newer.document_control_id = document_version.document_control_id " +
+				"AND newer.deleted_at IS NULL AND newer.id > document_version.id)").
+			Order("document_version.id ASC").Limit(documentRetentionBatchSize).Scan(&versions).Error; err != nil {
+			return archived, fmt.Errorf("failed to fetch superseded versions: %w", err)
+		}
+
+		for _, version := range versions {
+			lastID = version.ID
+			retainUntil := now.AddDate(rules.years(version.DocumentCategoryID, version.DocumentTypeID), 0, 0)
+			if err := s.archiveVersion(&version.DocumentVersion, now, retainUntil); err != nil {
+				log.Printf("Failed to archive document version %s: %v", version.UUID, err)
+				continue
+			}
+			archived++
+		}
+		if len(versions) < documentRetentionBatchSize {
+			return archived, nil
+		}
+	}
+}
+
+// PurgeExpiredVersions removes the files of archived versions whose retention has passed. The version records
+// are kept, marked deleted, so the history of the document still lists them.
+func (s *DocumentControlService) PurgeExpiredVersions(now time.Time) (int, error) {
+	purged, lastID := 0, 0
+	for {
+		var versions []models.DocumentVersion
+		if err := config.DB.Where("id > ? AND archived_at IS NOT NULL AND deleted_at IS NULL AND retain_until <= ?", lastID, now).
+			Order("id ASC").Limit(documentRetentionBatchSize).Find(&versions).Error; err != nil {
+			return purged, fmt.Errorf("failed to fetch expired versions: %w", err)
+		}
+
+		for _, version := range versions {
+			lastID = version.ID
+			if err := s.removeDocumentObject(version.File); err != nil {
+				log.Printf("Failed to purge document version %s: %v", version.UUID, err)
+				continue
+			}
+			if err := config.DB.Model(&models.DocumentVersion{}).Where("id = ? AND deleted_at IS NULL", version.ID).
+				Update("deleted_at", now).Error; err != nil {
+				log.Printf("Failed to mark document version %s as purged: %v", version.UUID, err)
+				continue
+			}
+			purged++
+		}
+		if len(versions) < documentRetentionBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// PurgeTrashedDocuments permanently deletes documents whose time in the trash has passed, with the files of
+// every version and change request attachment
+func (s *DocumentControlService) PurgeTrashedDocuments(now time.Time) (int, error) {
+	purged, lastID := 0, 0
+	for {
+		var ids []int
+		if err := config.DB.Model(&models.DocumentControl{}).
+			Where("id > ? AND deleted_at IS NOT NULL AND purge_after <= ?", lastID, now).
+			Order("id ASC").Limit(documentRetentionBatchSize).Pluck("id", &ids).Error; err != nil {
+			return purged, fmt.Errorf("failed to fetch documents to purge: %w", err)
+		}
+
+		for _, id := range ids {
+			lastID = id
+			ok, err := s.purgeDocumentControl(id, now)
+			if err != nil {
+				log.Printf("Failed to purge document %d: %v", id, err)
+				continue
+			}
+			if ok {
+				purged++
+			}
+		}
+		if len(ids) < documentRetentionBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// purgeDocumentControl deletes one document from the trash. The row stays locked while its files are removed,
+// so it cannot be restored halfway; it reports false when the document was restored in the meantime.
+func (s *DocumentControlService) purgeDocumentControl(id int, now time.Time) (bool, error) {
+	purged := false
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var documentControl models.DocumentControl
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND deleted_at IS NOT NULL AND purge_after <= ?", id, now).First(&documentControl).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return fmt.Errorf("failed to lock document control: %w", err)
+		}
+
+		var files []string
+		if err := tx.Model(&models.DocumentVersion{}).Where("document_control_id = ?", id).Pluck("file", &files).Error; err != nil {
+			return fmt.Errorf("failed to fetch document versions: %w", err)
+		}
+		var changeRequestIDs []uint
+		if err := tx.Model(&models.DocumentChangeRequest{}).Where("document_control_id = ?", id).Pluck("id", &changeRequestIDs).Error; err != nil {
+			return fmt.Errorf("failed to fetch change requests: %w", err)
+		}
+		if len(changeRequestIDs) > 0 {
+			var attachmentFiles []string
+			if err := tx.Model(&models.DocumentChangeRequestAttachment{}).Where("document_change_request_id IN ?", changeRequestIDs).
+				Pluck("file", &attachmentFiles).Error; err != nil {
+				return fmt.Errorf("failed to fetch change request attachments: %w", err)
+			}
+			files = append(files, attachmentFiles...)
+		}
+
+		for _, file := range files {
+			if err := s.removeDocumentObject(file); err != nil {
+				return err
+			}
+		}
+
+		if len(changeRequestIDs) > 0 {
+			if err := tx.Where("document_change_request_id IN ?", changeRequestIDs).Delete(&models.DocumentChangeRequestAttachment{}).Error; err != nil {
+				return fmt.Errorf("failed to delete change request attachments: %w", err)
+			}
+		}
+		for _, model := range []interface{}{&models.DocumentChangeRequest{}, &models.DocumentAcknowledgement{}, &models.DocumentDistribution{}, &models.DocumentVersion{}} {
+			if err := tx.Where("document_control_id = ?", id).Delete(model).Error; err != nil {
+				return fmt.Errorf("failed to delete document records: %w", err)
+			}
+		}
+		if err := tx.Delete(&models.DocumentControl{}, id).Error; err != nil {
+			return fmt.Errorf("failed to delete document control: %w", err)
+		}
+		purged = true
+		return nil
+	})
+	return purged, err
+}
+
+// ProcessDocumentRetention archives superseded versions and purges what has outlived its retention
+func (s *DocumentControlService) ProcessDocumentRetention(now time.Time) (*DocumentRetentionResult, error) {
+	result := &DocumentRetentionResult{}
+	var err error
+	if result.ArchivedVersions, err = s.ArchiveSupersededVersions(now); err != nil {
+		return result, err
+	}
+	if result.PurgedVersions, err = s.PurgeExpiredVersions(now); err != nil {
+		return result, err
+	}
+	if result.PurgedDocuments, err = s.PurgeTrashedDocuments(now); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// StartDocumentRetentionScheduler archives and purges documents in the background
+func StartDocumentRetentionScheduler(interval time.Duration) {
+	service := NewDocumentControlService(config.MinioClient, os.Getenv("MINIO_BUCKET"), NewMinioService(config.MinioClient))
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+		for range ticker.C {
+			result, err := service.ProcessDocumentRetention(time.Now())
+			if err != nil {
+				log.Printf("Document retention failed: %v", err)
+				continue
+			}
+			if result.ArchivedVersions > 0 || result.PurgedVersions > 0 || result.PurgedDocuments > 0 {
+				log.Printf("Document retention archived %d version(s), purged %d version(s) and %d document(s)",
+					result.ArchivedVersions, result.PurgedVersions, result.PurgedDocuments)
+			}
+		}
+	}()
+}
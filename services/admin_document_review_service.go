@@ -0,0 +1,280 @@
+package services
+
+import (
+	"backend-school/config"
+	"backend-school/models"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// defaultReviewReminderDays is used when document_review_reminder_days is not configured
+const defaultReviewReminderDays = 14
+
+// DocumentReviewService schedules periodic reviews and tracks the expiry of controlled documents
+type DocumentReviewService struct {
+	SettingsService *SettingsService
+}
+
+func NewDocumentReviewService() *DocumentReviewService {
+	return &DocumentReviewService{SettingsService: NewSettingsService()}
+}
+
+// reminderDays returns how many days ahead owners are told about an upcoming review or expiry
+func (s *DocumentReviewService) reminderDays() int {
+	days, err := s.SettingsService.GetSettingInt("document_review_reminder_days")
+	if err != nil || days < 0 {
+		return defaultReviewReminderDays
+	}
+	return days
+}
+
+// startOfDay truncates a time to midnight in its location, matching the date columns
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// GetDueForReviewPaginated lists documents whose review or expiry falls within the given number of days,
+// including overdue and expired ones. A negative withinDays uses the configured reminder period. Only documents in
+// scope are listed.
+func (s *DocumentReviewService) GetDueForReviewPaginated(currentPage, pageSize, withinDays int, overdueOnly bool, createdBy int, scope *DocumentReadScope) (*PaginatedResult, error) {
+	var documentControls []models.DocumentControlJoined
+	var totalRecords int64
+
+	offset := (currentPage - 1) * pageSize
+
+	if withinDays < 0 {
+		withinDays = s.reminderDays()
+	}
+	today := startOfDay(time.Now())
+	horizon := today.AddDate(0, 0, withinDays)
+
+	query := config.DB.Model(&models.DocumentControlJoined{}).
+		Select("document_control.*, category_document.name AS category_name, category_document.prefix AS category_prefix, " +
+			"document_type.name AS type_name, document_type.prefix AS type_prefix, " +
+			"status_document.name AS status_name").
+		Joins("LEFT JOIN category_document ON category_document.id = document_control.document_category_id").
+		Joins("LEFT JOIN document_type ON document_type.id = document_control.document_type_id").
+		Joins("LEFT JOIN status_document ON status_document.id = document_control.status_document_id").
+		Where("document_control.deleted_at IS NULL")
+	query = applyDocumentReadScope(query, scope)
+
+	if overdueOnly {
+		query = query.Where("(document_control.next_review_date < ? OR document_control.expiry_date < ?)", today, today)
+	} else {
+		query = query.Where("(document_control.next_review_date <= ? OR document_control.expiry_date <= ?)", horizon, horizon)
+	}
+
+	if createdBy != 0 {
+		query = query.Where("document_control.created_by = ?", createdBy)
+	}
+
+	// Get total record count with applied filters
+	if err := query.Count(&totalRecords).Error; err != nil {
+		return nil, errors.New("failed to count documents due for review")
+	}
+
+	// Most urgent first; LEAST ignores whichever date is not set
+	if err := query.Order("LEAST(document_control.next_review_date, document_control.expiry_date) ASC, document_control.id ASC").
+		Offset(offset).Limit(pageSize).Find(&documentControls).Error; err != nil {
+		return nil, errors.New("failed to fetch documents due for review")
+	}
+
+	formattedControls := make([]DocumentControlResponse, 0, len(documentControls))
+	for _, doc := range documentControls {
+		formattedControls = append(formattedControls, formatDocumentControl(doc))
+	}
+
+	// Calculate total pages based on the record count and page size
+	totalPages := int((totalRecords + int64(pageSize) - 1) / int64(pageSize))
+
+	return &PaginatedResult{
+		Data:         formattedControls,
+		CurrentPage:  currentPage,
+		PerPage:      pageSize,
+		TotalPages:   totalPages,
+		TotalRecords: totalRecords,
+	}, nil
+}
+
+// MarkReviewed records a completed review and schedules the next one, either on the given date or one interval from
+// today. A document checked out by another user is not marked.
+func (s *DocumentReviewService) MarkReviewed(uuid string, nextReviewDate string, userID int) (*models.DocumentControl, error) {
+	next, err := parseOptionalDate(nextReviewDate, "next_review_date")
+	if err != nil {
+		return nil, err
+	}
+
+	var documentControl models.DocumentControl
+	now := time.Now()
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the document so a check-out taken meanwhile is seen
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("uuid = ?", uuid).Where("deleted_at IS NULL").
+			First(&documentControl).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("document control not found")
+			}
+			return fmt.Errorf("failed to find document control: %w", err)
+		}
+		if holder := activeCheckout(&documentControl, now); holder != nil && *holder != userID {
+			return checkedOutError(&documentControl)
+		}
+
+		if next == nil && documentControl.ReviewIntervalMonths != nil && *documentControl.ReviewIntervalMonths > 0 {
+			scheduled := startOfDay(now).AddDate(0, *documentControl.ReviewIntervalMonths, 0)
+			next = &scheduled
+		}
+
+		documentControl.LastReviewedAt = &now
+		documentControl.NextReviewDate = next
+		documentControl.ReviewOverdue = false
+		documentControl.ReviewReminderSentAt = nil
+
+		if err := tx.Model(&documentControl).Updates(map[string]interface{}{
+			"last_reviewed_at":        documentControl.LastReviewedAt,
+			"next_review_date":        documentControl.NextReviewDate,
+			"review_overdue":          false,
+			"review_reminder_sent_at": nil,
+			"lock_version":            gorm.Expr("lock_version + 1"),
+		}).Error; err != nil {
+			return fmt.Errorf("failed to record review: %w", err)
+		}
+		documentControl.LockVersion++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &documentControl, nil
+}
+
+// claimReviewFlag sets a reminder column on one document unless another run already did, so each reminder is sent once
+func claimReviewFlag(documentID int, column string, value interface{}, unsetCondition string) (bool, error) {
+	result := config.DB.Model(&models.DocumentControl{}).
+		Where("id = ?", documentID).
+		Where(unsetCondition).
+		Update(column, value)
+	return result.RowsAffected == 1, result.Error
+}
+
+// ProcessReviewReminders notifies owners of upcoming reviews and expiries and flags overdue documents.
+// It returns the number of documents that changed.
+func (s *DocumentReviewService) ProcessReviewReminders(now time.Time) (int, error) {
+	today := startOfDay(now)
+	horizon := today.AddDate(0, 0, s.reminderDays())
+
+	checks := []struct {
+		condition      string
+		args           []interface{}
+		column         string
+		value          interface{}
+		unsetCondition string
+		event          string
+		title          func(models.DocumentControl) string
+		message        func(models.DocumentControl) string
+	}{
+		{
+			condition:      "next_review_date >= ? AND next_review_date <= ? AND review_reminder_sent_at IS NULL",
+			args:           []interface{}{today, horizon},
+			column:         "review_reminder_sent_at",
+			value:          now,
+			unsetCondition: "review_reminder_sent_at IS NULL",
+			event:          models.NotificationEventDocumentReviewDue,
+			title: func(d models.DocumentControl) string {
+				return fmt.Sprintf("Document %s is due for review", d.DocumentNumber)
+			},
+			message: func(d models.DocumentControl) string {
+				return fmt.Sprintf("%s must be reviewed by %s.", d.DocumentName, d.NextReviewDate.Format("2006-01-02"))
+			},
+		},
+		{
+			condition:      "next_review_date < ? AND review_overdue = false",
+			args:           []interface{}{today},
+			column:         "review_overdue",
+			value:          true,
+			unsetCondition: "review_overdue = false",
+			event:          models.NotificationEventDocumentReviewOverdue,
+			title: func(d models.DocumentControl) string {
+				return fmt.Sprintf("Review of document %s is overdue", d.DocumentNumber)
+			},
+			message: func(d models.DocumentControl) string {
+				return fmt.Sprintf("%s was due for review on %s.", d.DocumentName, d.NextReviewDate.Format("2006-01-02"))
+			},
+		},
+		{
+			condition:      "expiry_date >= ? AND expiry_date <= ? AND expiry_reminder_sent_at IS NULL",
+			args:           []interface{}{today, horizon},
+			column:         "expiry_reminder_sent_at",
+			value:          now,
+			unsetCondition: "expiry_reminder_sent_at IS NULL",
+			event:          models.NotificationEventDocumentExpiring,
+			title: func(d models.DocumentControl) string {
+				return fmt.Sprintf("Document %s expires soon", d.DocumentNumber)
+			},
+			message: func(d models.DocumentControl) string {
+				return fmt.Sprintf("%s expires on %s.", d.DocumentName, d.ExpiryDate.Format("2006-01-02"))
+			},
+		},
+		{
+			condition:      "expiry_date < ? AND expired_notified_at IS NULL",
+			args:           []interface{}{today},
+			column:         "expired_notified_at",
+			value:          now,
+			unsetCondition: "expired_notified_at IS NULL",
+			event:          models.NotificationEventDocumentExpired,
+			title: func(d models.DocumentControl) string {
+				return fmt.Sprintf("Document %s has expired", d.DocumentNumber)
+			},
+			message: func(d models.DocumentControl) string {
+				return fmt.Sprintf("%s expired on %s.", d.DocumentName, d.ExpiryDate.Format("2006-01-02"))
+			},
+		},
+	}
+
+	changed := 0
+	for _, check := range checks {
+		var documents []models.DocumentControl
+		if err := config.DB.Where("deleted_at IS NULL").Where(check.condition, check.args...).Find(&documents).Error; err != nil {
+			return changed, fmt.Errorf("failed to fetch documents for %s: %w", check.event, err)
+		}
+
+		for _, document := range documents {
+			claimed, err := claimReviewFlag(document.ID, check.column, check.value, check.unsetCondition)
+			if err != nil {
+				log.Printf("Failed to update document %s for %s: %v", document.UUID, check.event, err)
+				continue
+			}
+			if !claimed {
+				continue
+			}
+			changed++
+
+			if document.CreatedBy != nil {
+				notifyUserBestEffort(*document.CreatedBy, check.event, check.title(document), check.message(document),
+					"/document-control/"+document.UUID.String())
+			}
+		}
+	}
+	return changed, nil
+}
+
+// StartDocumentReviewScheduler checks review and expiry dates in the background
+func StartDocumentReviewScheduler(interval time.Duration) {
+	service := NewDocumentReviewService()
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := service.ProcessReviewReminders(time.Now()); err != nil {
+				log.Printf("Document review scheduling failed: %v", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"backend-school/config"
+	"backend-school/models"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ledongthuc/pdf"
+	"gorm.io/gorm"
+)
+
+// documentSearchConfig is the text search configuration; "simple" does not stem, so it works for any language
+const documentSearchConfig = "simple"
+
+// maxIndexedTextLength keeps extracted text well below the 1 MB tsvector limit
+const maxIndexedTextLength = 512 * 1024
+
+// Matches are delimited by control characters in the headline and marked up once the rest of it has been escaped
+const (
+	documentSearchMatchStart = "\x02"
+	documentSearchMatchStop  = "\x03"
+)
+
+// documentSearchHeadlineOptions delimits matches in the highlight returned with each result
+const documentSearchHeadlineOptions = "StartSel=" + documentSearchMatchStart + ", StopSel=" + documentSearchMatchStop +
+	", MaxWords=35, MinWords=15, MaxFragments=3"
+
+// documentSearchHighlightMarks turns the match delimiters of an escaped headline into <mark> elements
+var documentSearchHighlightMarks = strings.NewReplacer(documentSearchMatchStart, "<mark>", documentSearchMatchStop, "</mark>")
+
+// latestVersionContentSQL selects the extracted text of a document's current version
+const latestVersionContentSQL = "(SELECT document_version.content_text FROM document_version " +
+	"WHERE document_version.document_control_id = document_control.id AND document_version.deleted_at IS NULL " +
+	"ORDER BY document_version.id DESC LIMIT 1)"
+
+// extractPDFText returns the plain text of a PDF, truncated to maxIndexedTextLength
+func extractPDFText(reader *pdf.Reader) (string, error) {
+	plain, err := reader.GetPlainText()
+	if err != nil {
+		return "", err
+	}
+
+	content, err := io.ReadAll(io.LimitReader(plain, maxIndexedTextLength))
+	if err != nil {
+		return "", err
+	}
+
+	// Postgres rejects NUL bytes and invalid UTF-8 in text columns
+	text := strings.ToValidUTF8(strings.ReplaceAll(string(content), "\x00", ""), "")
+	return strings.TrimSpace(text), nil
+}
+
+// refreshDocumentSearchVector rebuilds the full-text index of a document from its metadata and current version.
+// Name and numbers weigh most, then the description, then the file content.
+func refreshDocumentSearchVector(tx *gorm.DB, documentControlID int) error {
+	return tx.Exec(fmt.Sprintf(`UPDATE document_control SET search_vector =
+		setweight(to_tsvector('%[1]s', coalesce(document_name, '')), 'A') ||
+		setweight(to_tsvector('%[1]s', coalesce(document_number, '') || ' ' || coalesce(clause_number, '')), 'A') ||
+		setweight(to_tsvector('%[1]s', coalesce(description, '')), 'B') ||
+		setweight(to_tsvector('%[1]s', coalesce(%[2]s, '')), 'C')
+		WHERE id = ?`, documentSearchConfig, latestVersionContentSQL), documentControlID).Error
+}
+
+// ReindexDocumentSearch builds the full-text index of documents that do not have one yet and returns how many were indexed
+func ReindexDocumentSearch() (int, error) {
+	var ids []int
+	if err := config.DB.Model(&models.DocumentControl{}).Where("search_vector IS NULL").Pluck("id", &ids).Error; err != nil {
+		return 0, fmt.Errorf("failed to find documents to index: %w", err)
+	}
+
+	for i, id := range ids {
+		if err := refreshDocumentSearchVector(config.DB, id); err != nil {
+			return i, fmt.Errorf("failed to index document %d: %w", id, err)
+		}
+	}
+	return len(ids), nil
+}
+
+// DocumentSearchParams holds the search term and filters of a document search
+type DocumentSearchParams struct {
+	Query       string
+	CategoryID  int
+	TypeID      int
+	StatusID    int
+	PublishFrom *time.Time
+	PublishTo   *time.Time
+	Scope       *DocumentReadScope // documents the searcher may read; nil searches every document
+	CurrentPage int
+	PageSize    int
+}
+
+// DocumentSearchResult is a document matched by a search, with its relevance and highlighted excerpt. The excerpt is
+// HTML: its text is escaped and the matches are wrapped in <mark>.
+type DocumentSearchResult struct {
+	DocumentControlResponse
+	Rank      float64 `json:"rank"`
+	Highlight string  `json:"highlight"`
+}
+
+// documentSearchRow is the scan target of SearchDocumentControls
+type documentSearchRow struct {
+	models.DocumentControlJoined
+	Rank      float64
+	Highlight string
+}
+
+// SearchDocumentControls runs a ranked full-text search over document metadata and PDF content.
+// Without a search term the filtered documents are listed newest first.
+func (s *DocumentControlService) SearchDocumentControls(params DocumentSearchParams) (*PaginatedResult, error) {
+	var rows []documentSearchRow
+	var totalRecords int64
+
+	offset := (params.CurrentPage - 1) * params.PageSize
+	term := strings.TrimSpace(params.Query)
+
+	query := config.DB.Table("document_control").
+		Joins("LEFT JOIN category_document ON category_document.id = document_control.document_category_id").
+		Joins("LEFT JOIN document_type ON document_type.id = document_control.document_type_id").
+		Joins("LEFT JOIN status_document ON status_document.id = document_control.status_document_id").
+		Where("document_control.deleted_at IS NULL")
+
+	if term != "" {
+		query = query.
+			Joins(fmt.Sprintf("CROSS JOIN websearch_to_tsquery('%s', ?) AS search_query", documentSearchConfig), term).
+			Where("document_control.search_vector @@ search_query")
+	}
+
+	// Apply filters
+	if params.CategoryID != 0 {
+		query = query.Where("document_control.document_category_id = ?", params.CategoryID)
+	}
+	if params.TypeID != 0 {
+		query = query.Where("document_control.document_type_id = ?", params.TypeID)
+	}
+	if params.StatusID != 0 {
+		query = query.Where("document_control.status_document_id = ?", params.StatusID)
+	}
+	if params.PublishFrom != nil {
+		query = query.Where("document_control.publish_date >= ?", *params.PublishFrom)
+	}
+	if params.PublishTo != nil {
+		query = query.Where("document_control.publish_date <= ?", *params.PublishTo)
+	}
+	query = applyDocumentReadScope(query, params.Scope)
+
+	// Get total record count with applied filters
+	if err := query.Session(&gorm.Session{}).Count(&totalRecords).Error; err != nil {
+		return nil, errors.New("failed to count document controls")
+	}
+
+	selectColumns := "document_control.*, category_document.name AS category_name, category_document.prefix AS category_prefix, " +
+		"document_type.name AS type_name, document_type.prefix AS type_prefix, " +
+		"status_document.name AS status_name"
+	if term != "" {
+		selectColumns += fmt.Sprintf(", ts_rank_cd(document_control.search_vector, search_query) AS rank, "+
+			"ts_headline('%s', coalesce(document_control.document_name, '') || ' ' || coalesce(document_control.description, '') || ' ' || coalesce(%s, ''), "+
+			"search_query, '%s') AS highlight", documentSearchConfig, latestVersionContentSQL, documentSearchHeadlineOptions)
+		query = query.Order("rank DESC")
+	}
+
+	// Fetch paginated records
+	if err := query.Select(selectColumns).
+		Order("document_control.publish_date DESC, document_control.id DESC").
+		Offset(offset).Limit(params.PageSize).Scan(&rows).Error; err != nil {
+		return nil, errors.New("failed to search document controls")
+	}
+
+	results := make([]DocumentSearchResult, 0, len(rows))
+	for _, row := range rows {
+		results = append(results, DocumentSearchResult{
+			DocumentControlResponse: formatDocumentControl(row.DocumentControlJoined),
+			Rank:                    row.Rank,
+			Highlight:               documentSearchHighlightMarks.Replace(html.EscapeString(row.Highlight)),
+		})
+	}
+
+	// Calculate total pages based on the record count and page size
+	totalPages := int((totalRecords + int64(params.PageSize) - 1) / int64(params.PageSize))
+
+	return &PaginatedResult{
+		Data:         results,
+		CurrentPage:  params.CurrentPage,
+		PerPage:      params.PageSize,
+		TotalPages:   totalPages,
+		TotalRecords: totalRecords,
+	}, nil
+}
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"backend-school/config"
+	"backend-school/models"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"gorm.io/gorm"
+)
+
+// documentStampSettingKey holds the JSON stamp configuration (see DocumentStampSettings)
+const documentStampSettingKey = "document_stamp"
+
+// supersededStampStatus is the status rule applied to every version except the latest
+const supersededStampStatus = "superseded"
+
+// DocumentStampRule is what a downloaded copy shows for one document status
+type DocumentStampRule struct {
+	Label     string `json:"label"`     // e.g. CONTROLLED COPY, available to header and footer as {{.Label}}
+	Watermark string `json:"watermark"` // large diagonal text; empty for none
+}
+
+// DocumentStampSettings configures the stamping of downloaded PDFs. Header and footer are Go templates over
+// DocumentStampData; pdfcpu replaces %p and %P with the page number and page count.
+// Styles are pdfcpu stamp descriptions.
+type DocumentStampSettings struct {
+	Enabled        bool                         `json:"enabled"`
+	Header         string                       `json:"header"`
+	Footer         string                       `json:"footer"`
+	HeaderStyle    string                       `json:"header_style"`
+	FooterStyle    string                       `json:"footer_style"`
+	WatermarkStyle string                       `json:"watermark_style"`
+	Statuses       map[string]DocumentStampRule `json:"statuses"` // keyed by lowercase status name, plus "superseded"
+	Default        DocumentStampRule            `json:"default"`  // statuses without a rule
+}
+
+// DocumentStampData is the data available to the header and footer templates
+type DocumentStampData struct {
+	DocumentNumber string
+	DocumentName   string
+	RevisionNumber int
+	Version        int
+	Status         string
+	Label          string
+	Username       string
+	DownloadedAt   string
+}
+
+// DocumentDownload is a document version file ready to be sent to a client
+type DocumentDownload struct {
+	FileName    string
+	ContentType string
+	Content     []byte
+	Stamped     bool
+}
+
+// defaultDocumentStampSettings is used for every field missing from the document_stamp setting
+func defaultDocumentStampSettings() DocumentStampSettings {
+	return DocumentStampSettings{
+		Enabled:        true,
+		Header:         "{{.DocumentNumber}} Rev. {{.RevisionNumber}} - {{.DocumentName}}",
+		Footer:         "{{.Label}} - Downloaded by {{.Username}} on {{.DownloadedAt}} - Page %p of %P",
+		HeaderStyle:    "font:Helvetica, points:9, pos:tc, off:0 -12, scale:1 abs, rot:0, fillcolor:#000000, op:1",
+		FooterStyle:    "font:Helvetica, points:9, pos:bc, off:0 12, scale:1 abs, rot:0, fillcolor:#000000, op:1",
+		WatermarkStyle: "font:Helvetica-Bold, points:72, pos:c, scale:0.8 rel, rot:45, fillcolor:#C00000, op:0.2",
+		Statuses: map[string]DocumentStampRule{
+			"approved":            {Label: "CONTROLLED COPY"},
+			"draft":               {Label: "UNCONTROLLED WHEN PRINTED", Watermark: "DRAFT"},
+			supersededStampStatus: {Label: "UNCONTROLLED WHEN PRINTED - SUPERSEDED", Watermark: "SUPERSEDED"},
+		},
+		Default: DocumentStampRule{Label: "UNCONTROLLED WHEN PRINTED"},
+	}
+}
+
+// loadDocumentStampSettings reads the document_stamp setting over the defaults
+func loadDocumentStampSettings() DocumentStampSettings {
+	settings := defaultDocumentStampSettings()
+	if err := NewSettingsService().GetSettingJSON(documentStampSettingKey, &settings); err != nil && !errors.Is(err, ErrSettingNotFound) {
+		log.Printf("Using default document stamp settings: %v", err)
+		return defaultDocumentStampSettings()
+	}
+	return settings
+}
+
+// rule returns the stamp rule of a status
+func (settings DocumentStampSettings) rule(status string) DocumentStampRule {
+	if rule, ok := settings.Statuses[strings.ToLower(strings.TrimSpace(status))]; ok {
+		return rule
+	}
+	return settings.Default
+}
+
+// documentObjectName turns the public URL stored in DocumentVersion.File into the object name within bucket
+func documentObjectName(fileURL, bucket string) string {
+	objectPath := fileURL
+	if parsed, err := url.Parse(fileURL); err == nil && parsed.Path != "" {
+		objectPath = parsed.Path
+	}
+	objectPath = strings.TrimPrefix(objectPath, "/")
+	return strings.TrimPrefix(objectPath, bucket+"/")
+}
+
+// readDocumentVersionFile reads the stored file of a version
+func (s *DocumentControlService) readDocumentVersionFile(version *models.DocumentVersion) ([]byte, error) {
+	if s.minioClient == nil {
+		return nil, fmt.Errorf("MinIO client is not initialized")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	object, err := s.minioClient.GetObject(ctx, s.bucketName, documentObjectName(version.File, s.bucketName), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch file: %v", err)
+	}
+	defer object.Close()
+	content, err := io.ReadAll(io.LimitReader(object, maxDocumentFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+	return content, nil
+}
+
+// DownloadDocumentVersion returns a version of a document (the latest when versionUUID is empty).
+// PDFs are stamped with the header, footer and watermark of the document's status; the stored object is not modified.
+func (s *DocumentControlService) DownloadDocumentVersion(documentControl *models.DocumentControl, versionUUID, username string) (*DocumentDownload, error) {
+	var latest models.DocumentVersion
+	if err := config.DB.Where("document_control_id = ? AND deleted_at IS NULL", documentControl.ID).
+		Order("id DESC").First(&latest).Error; err != nil {
+		return nil, ErrDocumentVersionNotFound
+	}
+
+	version := &latest
+	if versionUUID != "" && versionUUID != latest.UUID.String() {
+		var selected models.DocumentVersion
+		if err := config.DB.Where("uuid = ? AND document_control_id = ? AND deleted_at IS NULL", versionUUID, documentControl.ID).
+			First(&selected).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrDocumentVersionNotFound
+			}
+			return nil, errors.New("failed to fetch document version")
+		}
+		version = &selected
+	}
+
+	content, err := s.readDocumentVersionFile(version)
+	if err != nil {
+		return nil, err
+	}
+
+	versionNumber := 0
+	if version.Version != nil {
+		versionNumber = *version.Version
+	}
+
+	download := &DocumentDownload{
+		FileName:    path.Base(documentObjectName(version.File, s.bucketName)),
+		ContentType: version.MimeType,
+		Content:     content,
+	}
+	if documentControl.DocumentNumber != "" {
+		download.FileName = fmt.Sprintf("%s-rev%d-v%d%s", documentControl.DocumentNumber, documentControl.RevisionNumber, versionNumber, path.Ext(download.FileName))
+	}
+	if download.ContentType == "" {
+		// Versions uploaded before files were inspected have no recorded type
+		download.ContentType = strings.TrimSpace(strings.Split(http.DetectContentType(content), ";")[0])
+	}
+	if download.ContentType != "application/pdf" {
+		return download, nil
+	}
+
+	settings := loadDocumentStampSettings()
+	if !settings.Enabled {
+		return download, nil
+	}
+
+	// Older versions are stamped as superseded whatever the document's status
+	status := supersededStampStatus
+	if version.ID == latest.ID {
+		status = ""
+		var statusDocument models.StatusDocument
+		if documentControl.StatusDocumentID != nil &&
+			config.DB.Where("id = ?", *documentControl.StatusDocumentID).First(&statusDocument).Error == nil {
+			status = statusDocument.Name
+		}
+	}
+	rule := settings.rule(status)
+
+	data := DocumentStampData{
+		DocumentNumber: documentControl.DocumentNumber,
+		DocumentName:   documentControl.DocumentName,
+		RevisionNumber: documentControl.RevisionNumber,
+		Version:        versionNumber,
+		Status:         status,
+		Label:          rule.Label,
+		Username:       username,
+		DownloadedAt:   time.Now().Format("2006-01-02 15:04"),
+	}
+
+	stamped, err := stampPDF(content, settings, rule, data)
+	if err != nil {
+		return nil, err
+	}
+	download.Content = stamped
+	download.Stamped = true
+	return download, nil
+}
+
+// renderStampText fills a header or footer template
+func renderStampText(name, text string, data DocumentStampData) (string, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid document stamp %s: %w", name, err)
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("invalid document stamp %s: %w", name, err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// stampPDF adds the header, footer and watermark to every page of a PDF
+func stampPDF(content []byte, settings DocumentStampSettings, rule DocumentStampRule, data DocumentStampData) ([]byte, error) {
+	header, err := renderStampText("header", settings.Header, data)
+	if err != nil {
+		return nil, err
+	}
+	footer, err := renderStampText("footer", settings.Footer, data)
+	if err != nil {
+		return nil, err
+	}
+
+	stamps := []struct{ text, style string }{
+		{rule.Watermark, settings.WatermarkStyle},
+		{header, settings.HeaderStyle},
+		{footer, settings.FooterStyle},
+	}
+
+	api.DisableConfigDir()
+	for _, stamp := range stamps {
+		if stamp.text == "" {
+			continue
+		}
+		watermark, err := api.TextWatermark(stamp.text, stamp.style, true, false, types.POINTS)
+		if err != nil {
+			return nil, fmt.Errorf("invalid document stamp style: %w", err)
+		}
+		var out bytes.Buffer
+		if err := api.AddWatermarks(bytes.NewReader(content), &out, nil, watermark, nil); err != nil {
+			return nil, fmt.Errorf("failed to stamp PDF: %w", err)
+		}
+		content = out.Bytes()
+	}
+	return content, nil
+}
@@ -1,10 +1,25 @@
 package services
 
 import (
+	"backend-school/config"
+	"backend-school/models"
+	"errors"
 	"fmt"
-	"strconv"
+	"log"
+	"math"
+	"time"
 
 	"github.com/go-gomail/gomail"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultEmailMaxAttempts = 5
+	emailRetryBaseDelay     = 30 * time.Second
+	emailRetryMaxDelay      = 6 * time.Hour
+	// emailSendingTimeout returns messages left in "sending" by a crashed worker to the queue
+	emailSendingTimeout = 10 * time.Minute
 )
 
 type EmailService struct {
@@ -15,15 +30,44 @@ func NewEmailService(settingsService *SettingsService) *EmailService {
 	return &EmailService{SettingsService: settingsService}
 }
 
+// SendEmail queues an HTML email in the outbox; the outbox worker delivers it
 func (s *EmailService) SendEmail(to string, subject string, body string) error {
+	_, err := s.EnqueueEmail(to, subject, body, "text/html")
+	return err
+}
+
+// EnqueueEmail stores an email in the outbox for background delivery
+func (s *EmailService) EnqueueEmail(to, subject, body, contentType string) (*models.EmailOutbox, error) {
+	maxAttempts, err := s.SettingsService.GetSettingInt("email_max_attempts")
+	if err != nil || maxAttempts <= 0 {
+		maxAttempts = defaultEmailMaxAttempts
+	}
+
+	message := models.EmailOutbox{
+		ToAddress:     to,
+		Subject:       subject,
+		Body:          body,
+		ContentType:   contentType,
+		Status:        models.EmailStatusPending,
+		MaxAttempts:   maxAttempts,
+		NextAttemptAt: time.Now(),
+	}
+	if err := config.DB.Create(&message).Error; err != nil {
+		return nil, fmt.Errorf("failed to queue email: %w", err)
+	}
+	return &message, nil
+}
+
+// deliverEmail sends a single outbox message over SMTP using the smtp_* settings
+func (s *EmailService) deliverEmail(message models.EmailOutbox) error {
 	// Fetch SMTP settings from the settings table
 	smtpHost, err := s.SettingsService.GetSetting("smtp_host")
 	if err != nil {
 		return err
 	}
-	smtpPortStr, err := s.SettingsService.GetSetting("smtp_port")
+	smtpPort, err := s.SettingsService.GetSettingInt("smtp_port")
 	if err != nil {
-		return err
+		return fmt.Errorf("invalid SMTP port: %v", err)
 	}
 	smtpEmail, err := s.SettingsService.GetSetting("smtp_email")
 	if err != nil {
@@ -33,18 +77,13 @@ func (s *EmailService) SendEmail(to string, subject string, body string) error {
 	if err != nil {
 		return err
 	}
-	// Convert smtpPort string to int
-	smtpPort, err := strconv.Atoi(smtpPortStr)
-	if err != nil {
-		return fmt.Errorf("invalid SMTP port: %v", err)
-	}
 
 	// Prepare the email message
 	m := gomail.NewMessage()
 	m.SetHeader("From", smtpEmail)
-	m.SetHeader("To", to)
-	m.SetHeader("Subject", subject)
-	m.SetBody("text/html", body)
+	m.SetHeader("To", message.ToAddress)
+	m.SetHeader("Subject", message.Subject)
+	m.SetBody(message.ContentType, message.Body)
 
 	// Create the SMTP dialer
 	d := gomail.NewDialer(smtpHost, smtpPort, smtpEmail, smtpPassword)
@@ -52,3 +91,162 @@ func (s *EmailService) SendEmail(to string, subject string, body string) error {
 	// Send the email
 	return d.DialAndSend(m)
 }
+
+// emailRetryDelay returns the exponential backoff before the next attempt
+func emailRetryDelay(attempts int) time.Duration {
+	delay := time.Duration(float64(emailRetryBaseDelay) * math.Pow(2, float64(attempts-1)))
+	if delay <= 0 || delay > emailRetryMaxDelay {
+		return emailRetryMaxDelay
+	}
+	return delay
+}
+
+// claimDueEmails marks up to limit due messages as sending so concurrent workers don't pick them up twice
+func claimDueEmails(limit int) ([]models.EmailOutbox, error) {
+	var messages []models.EmailOutbox
+	now := time.Now()
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND next_attempt_at <= ?) OR (status = ? AND updated_at <= ?)",
+				models.EmailStatusPending, now, models.EmailStatusSending, now.Add(-emailSendingTimeout)).
+			Order("next_attempt_at ASC").
+			Limit(limit).
+			Find(&messages).Error; err != nil {
+			return err
+		}
+		if len(messages) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, len(messages))
+		for i, message := range messages {
+			ids[i] = message.ID
+		}
+		return tx.Model(&models.EmailOutbox{}).Where("id IN ?", ids).
+			Updates(map[string]interface{}{"status": models.EmailStatusSending, "updated_at": now}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox emails: %w", err)
+	}
+	return messages, nil
+}
+
+// ProcessEmailOutbox delivers up to limit due messages and returns how many were sent
+func (s *EmailService) ProcessEmailOutbox(limit int) (int, error) {
+	messages, err := claimDueEmails(limit)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, message := range messages {
+		attempts := message.Attempts + 1
+		updates := map[string]interface{}{"attempts": attempts}
+
+		if err := s.deliverEmail(message); err != nil {
+			updates["last_error"] = err.Error()
+			if attempts >= message.MaxAttempts {
+				updates["status"] = models.EmailStatusDead
+				log.Printf("Email %s dead-lettered after %d attempt(s): %v", message.UUID, attempts, err)
+			} else {
+				updates["status"] = models.EmailStatusPending
+				updates["next_attempt_at"] = time.Now().Add(emailRetryDelay(attempts))
+			}
+		} else {
+			now := time.Now()
+			updates["status"] = models.EmailStatusSent
+			updates["sent_at"] = &now
+			updates["last_error"] = ""
+			// A delivered body is not needed again and may carry a live reset link
+			updates["body"] = ""
+			sent++
+		}
+
+		if err := config.DB.Model(&models.EmailOutbox{}).Where("id = ?", message.ID).Updates(updates).Error; err != nil {
+			log.Printf("Failed to update outbox email %s: %v", message.UUID, err)
+		}
+	}
+	return sent, nil
+}
+
+// StartEmailOutboxWorker polls the outbox and delivers due emails in the background
+func StartEmailOutboxWorker(interval time.Duration, batchSize int) {
+	service := NewEmailService(NewSettingsService())
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := service.ProcessEmailOutbox(batchSize); err != nil {
+				log.Printf("Email outbox processing failed: %v", err)
+			}
+		}
+	}()
+}
+
+// GetEmailOutboxPaginated lists outbox messages without their bodies, optionally filtered by status, with a count
+// per status
+func (s *EmailService) GetEmailOutboxPaginated(perPage, page int, status string) (map[string]interface{}, error) {
+	var messages []models.EmailOutbox
+	var totalRecords int64
+
+	// Calculate offset for pagination
+	offset := (page - 1) * perPage
+
+	query := config.DB.Model(&models.EmailOutbox{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	// Get the total number of records
+	query.Count(&totalRecords)
+
+	if err := query.Omit("body").Order("created_at DESC").Limit(perPage).Offset(offset).Find(&messages).Error; err != nil {
+		return nil, errors.New("failed to fetch email outbox")
+	}
+
+	var counts []struct {
+		Status string
+		Count  int64
+	}
+	if err := config.DB.Model(&models.EmailOutbox{}).Select("status, COUNT(*) AS count").Group("status").Scan(&counts).Error; err != nil {
+		return nil, errors.New("failed to count email outbox")
+	}
+	statusCounts := make(map[string]int64, len(counts))
+	for _, count := range counts {
+		statusCounts[count.Status] = count.Count
+	}
+
+	// Calculate total pages
+	totalPages := int(math.Ceil(float64(totalRecords) / float64(perPage)))
+
+	return map[string]interface{}{
+		"data":          messages,
+		"status_counts": statusCounts,
+		"current_page":  page,
+		"per_page":      perPage,
+		"total_pages":   totalPages,
+		"total_records": totalRecords,
+	}, nil
+}
+
+// RetryEmail puts a dead-lettered message back in the queue with a fresh attempt budget
+func (s *EmailService) RetryEmail(uuid string) (*models.EmailOutbox, error) {
+	var message models.EmailOutbox
+	if err := config.DB.Where("uuid = ?", uuid).First(&message).Error; err != nil {
+		return nil, errors.New("email not found")
+	}
+	if message.Status != models.EmailStatusDead {
+		return nil, errors.New("only dead-lettered emails can be retried")
+	}
+
+	if err := config.DB.Model(&message).Updates(map[string]interface{}{
+		"status":          models.EmailStatusPending,
+		"attempts":        0,
+		"next_attempt_at": time.Now(),
+	}).Error; err != nil {
+		return nil, errors.New("failed to retry email")
+	}
+	return &message, nil
+}
@@ -0,0 +1,304 @@
+package services
+
+import (
+	"backend-school/config"
+	"backend-school/models"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/xuri/excelize/v2"
+)
+
+// MaxHealthImportSize is the largest CSV or XLSX file accepted for a screening import
+const MaxHealthImportSize = 10 * 1024 * 1024 // 10 MB limit
+
+// maxHealthImportRows caps the number of rows of one import
+const maxHealthImportRows = 1000
+
+// ErrInvalidHealthImport is wrapped by every rejection of a screening file as a whole
+var ErrInvalidHealthImport = errors.New("invalid screening file")
+
+// healthImportColumns are the columns of a screening file; the required ones must be in the header. A row is linked
+// to a person by person_uuid or by the identity number of the person.
+var healthImportColumns = map[string]bool{
+	"nama":            false,
+	"jenis_kelamin":   false,
+	"umur":            false,
+	"bb":              true,
+	"tb":              true,
+	"systol":          true,
+	"diastol":         true,
+	"heart_rate":      true,
+	"profesi":         false,
+	"person_uuid":     false,
+	"identity_number": false,
+}
+
+// Results of an imported row
+const (
+	HealthImportRowCreated = "created"
+	HealthImportRowValid   = "valid" // dry run
+	HealthImportRowFailed  = "failed"
+)
+
+// HealthImportRow is the outcome of one row. Row is the line of the row in the file (the header is line 1) and
+// Errors is keyed by column, or by "row" for problems that are not about one column.
+type HealthImportRow struct {
+	Row        int               `json:"row"`
+	Status     string            `json:"status"`
+	Nama       string            `json:"nama"`
+	HealthUUID *uuid.UUID        `json:"health_uuid,omitempty"`
+	Bmi        *float64          `json:"bmi,omitempty"`
+	Risk       string            `json:"risk,omitempty"`
+	Errors     map[string]string `json:"errors,omitempty"`
+}
+
+// HealthImportResult reports a screening import row by row
+type HealthImportResult struct {
+	FileName  string            `json:"file_name"`
+	DryRun    bool              `json:"dry_run"`
+	TotalRows int               `json:"total_rows"`
+	Succeeded int               `json:"succeeded_rows"`
+	Failed    int               `json:"failed_rows"`
+	Rows      []HealthImportRow `json:"rows"`
+}
+
+// healthImportSheetRow is a non-blank row of a screening file keyed by column
+type healthImportSheetRow struct {
+	line   int
+	values map[string]string
+}
+
+var healthImportHeaderSpaces = regexp.MustCompile(`[\s-]+`)
+
+// readHealthImportFile reads the first sheet of an XLSX file or a CSV file separated by commas or semicolons
+func readHealthImportFile(src io.Reader, fileName string) ([]healthImportSheetRow, error) {
+	content, err := io.ReadAll(io.LimitReader(src, MaxHealthImportSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: cannot read file: %v", ErrInvalidHealthImport, err)
+	}
+	if len(content) > MaxHealthImportSize {
+		return nil, fmt.Errorf("%w: file size exceeds maximum limit of %d bytes", ErrInvalidHealthImport, MaxHealthImportSize)
+	}
+
+	var records [][]string
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".xlsx":
+		records, err = readHealthImportXLSX(content)
+	case ".csv":
+		records, err = readHealthImportCSV(content)
+	default:
+		return nil, fmt.Errorf("%w: a .csv or .xlsx file is required", ErrInvalidHealthImport)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: file is empty", ErrInvalidHealthImport)
+	}
+
+	header := make([]string, len(records[0]))
+	for i, column := range records[0] {
+		column = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))
+		header[i] = healthImportHeaderSpaces.ReplaceAllString(column, "_")
+	}
+	if err := checkHealthImportColumns(header); err != nil {
+		return nil, err
+	}
+
+	var rows []healthImportSheetRow
+	for i, record := range records[1:] {
+		row := healthImportSheetRow{line: i + 2, values: make(map[string]string, len(header))}
+		blank := true
+		for j, column := range header {
+			if column == "" || j >= len(record) {
+				continue
+			}
+			value := strings.TrimSpace(record[j])
+			row.values[column] = value
+			if value != "" {
+				blank = false
+			}
+		}
+		if blank {
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: file has no rows", ErrInvalidHealthImport)
+	}
+	if len(rows) > maxHealthImportRows {
+		return nil, fmt.Errorf("%w: file has more than %d rows", ErrInvalidHealthImport, maxHealthImportRows)
+	}
+	return rows, nil
+}
+
+func readHealthImportXLSX(content []byte) ([][]string, error) {
+	file, err := excelize.OpenReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("%w: not an XLSX workbook", ErrInvalidHealthImport)
+	}
+	defer file.Close()
+
+	sheets := file.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, fmt.Errorf("%w: workbook has no sheets", ErrInvalidHealthImport)
+	}
+	records, err := file.GetRows(sheets[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: cannot read sheet %q: %v", ErrInvalidHealthImport, sheets[0], err)
+	}
+	return records, nil
+}
+
+// readHealthImportCSV reads a CSV file. Spreadsheets saved with a decimal comma separate fields with semicolons, so
+// the separator is taken from the header line.
+func readHealthImportCSV(content []byte) ([][]string, error) {
+	headerLine, _, _ := bytes.Cut(content, []byte("\n"))
+	reader := csv.NewReader(bytes.NewReader(content))
+	if bytes.Count(headerLine, []byte(";")) > bytes.Count(headerLine, []byte(",")) {
+		reader.Comma = ';'
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: cannot read CSV: %v", ErrInvalidHealthImport, err)
+	}
+	return records, nil
+}
+
+// checkHealthImportColumns rejects unknown and repeated columns and requires the mandatory ones
+func checkHealthImportColumns(header []string) error {
+	present := make(map[string]bool, len(header))
+	for _, column := range header {
+		if column == "" {
+			continue
+		}
+		if _, known := healthImportColumns[column]; !known {
+			return fmt.Errorf("%w: unknown column %q", ErrInvalidHealthImport, column)
+		}
+		if present[column] {
+			return fmt.Errorf("%w: column %q appears more than once", ErrInvalidHealthImport, column)
+		}
+		present[column] = true
+	}
+
+	var missing []string
+	for _, m := range healthMeasurements {
+		if healthImportColumns[m.field] && !present[m.field] {
+			missing = append(missing, m.field)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: missing column(s) %s", ErrInvalidHealthImport, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// ImportScreening records the results of a screening day from a CSV or XLSX file. Each row goes through the same
+// validation, BMI calculation, risk assessment and recommendations as a single health check; rows with errors are
+// reported and skipped while the others are saved. A dry run only validates and assesses the rows.
+func (s *HealthService) ImportScreening(src io.Reader, fileName string, dryRun bool, username string) (*HealthImportResult, error) {
+	rows, err := readHealthImportFile(src, fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &HealthImportResult{
+		FileName:  fileName,
+		DryRun:    dryRun,
+		TotalRows: len(rows),
+		Rows:      make([]HealthImportRow, 0, len(rows)),
+	}
+	for _, row := range rows {
+		outcome := s.importScreeningRow(row, dryRun, username)
+		if outcome.Status == HealthImportRowFailed {
+			result.Failed++
+		} else {
+			result.Succeeded++
+		}
+		result.Rows = append(result.Rows, outcome)
+	}
+	return result, nil
+}
+
+// importScreeningRow validates, assesses and, unless it is a dry run, saves one row
+func (s *HealthService) importScreeningRow(row healthImportSheetRow, dryRun bool, username string) HealthImportRow {
+	values := row.values
+	outcome := HealthImportRow{Row: row.line, Status: HealthImportRowFailed, Nama: values["nama"]}
+	fail := func(column, message string) HealthImportRow {
+		outcome.Errors = map[string]string{column: message}
+		return outcome
+	}
+
+	// Spreadsheets saved with a decimal comma write 55,5 for 55.5
+	decimal := func(column string) string {
+		return strings.Replace(values[column], ",", ".", 1)
+	}
+	payload := &HealthPayload{
+		Nama:         values["nama"],
+		JenisKelamin: values["jenis_kelamin"],
+		Umur:         decimal("umur"),
+		Bb:           decimal("bb"),
+		Tb:           decimal("tb"),
+		Systol:       decimal("systol"),
+		Diastol:      decimal("diastol"),
+		HeartRate:    decimal("heart_rate"),
+		Profesi:      values["profesi"],
+		PersonUUID:   values["person_uuid"],
+	}
+
+	if identityNumber := values["identity_number"]; identityNumber != "" && payload.PersonUUID == "" {
+		var personUUIDs []uuid.UUID
+		if err := config.DB.Model(&models.HealthPerson{}).
+			Where("LOWER(identity_number) = LOWER(?)", identityNumber).
+			Pluck("uuid", &personUUIDs).Error; err != nil {
+			return fail("row", "failed to look up identity_number")
+		}
+		if len(personUUIDs) == 0 {
+			return fail("identity_number", "identity_number does not match a person")
+		}
+		payload.PersonUUID = personUUIDs[0].String()
+	}
+	if payload.Nama == "" && payload.PersonUUID == "" {
+		return fail("nama", "nama is required when the row is not linked to a person")
+	}
+
+	health, err := buildHealth(payload)
+	if err != nil {
+		var validationErr *HealthValidationError
+		if errors.As(err, &validationErr) {
+			outcome.Errors = validationErr.Errors
+			return outcome
+		}
+		return fail("row", err.Error())
+	}
+	outcome.Nama = health.Nama
+	outcome.Bmi = health.Bmi
+	outcome.Risk = health.Risk
+
+	if dryRun {
+		outcome.Status = HealthImportRowValid
+		return outcome
+	}
+
+	health.CreatedBy = username
+	health.UpdatedBy = username
+	if err := config.DB.Create(health).Error; err != nil {
+		return fail("row", "failed to save health data")
+	}
+	outcome.Status = HealthImportRowCreated
+	outcome.HealthUUID = &health.UUID
+	return outcome
+}
@@ -0,0 +1,411 @@
+package services
+
+import (
+	"backend-school/config"
+	"backend-school/models"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// healthMeasurement is a numeric column of health_data with its unit and the physiological range accepted for it
+type healthMeasurement struct {
+	field   string // JSON and form name, which is also the column name
+	name    string // field name in models.Health
+	unit    string
+	min     float64
+	max     float64
+	integer bool
+}
+
+// healthMeasurements lists the numeric columns; bmi is calculated from bb and tb rather than entered
+var healthMeasurements = []healthMeasurement{
+	{field: "umur", name: "Umur", unit: "years", min: 0, max: 130, integer: true},
+	{field: "bb", name: "Bb", unit: "kg", min: 1, max: 500},
+	{field: "tb", name: "Tb", unit: "cm", min: 30, max: 300},
+	{field: "systol", name: "Systol", unit: "mmHg", min: 50, max: 300, integer: true},
+	{field: "diastol", name: "Diastol", unit: "mmHg", min: 20, max: 200, integer: true},
+	{field: "heart_rate", name: "HeartRate", unit: "bpm", min: 20, max: 300, integer: true},
+	{field: "bmi", name: "Bmi", unit: "kg/m²", min: 5, max: 200},
+}
+
+// HealthMeasurementUnits maps each numeric field of health data to its unit
+func HealthMeasurementUnits() map[string]string {
+	units := make(map[string]string, len(healthMeasurements))
+	for _, m := range healthMeasurements {
+		units[m.field] = m.unit
+	}
+	return units
+}
+
+func findHealthMeasurement(field string) (healthMeasurement, bool) {
+	for _, m := range healthMeasurements {
+		if m.field == field {
+			return m, true
+		}
+	}
+	return healthMeasurement{}, false
+}
+
+func (m healthMeasurement) rangeMessage() string {
+	return fmt.Sprintf("%s must be between %s and %s %s", m.field, formatMeasurement(m.min), formatMeasurement(m.max), m.unit)
+}
+
+// parse reads a value of the measurement. Former text values may use a decimal comma and are rounded when the
+// column holds whole numbers; new input must be a whole number there. The returned message explains a rejection.
+func (m healthMeasurement) parse(raw string, legacy bool) (float64, string) {
+	value := strings.TrimSpace(raw)
+	if value == "" {
+		return 0, fmt.Sprintf("%s is required", m.field)
+	}
+	if legacy {
+		value = strings.Replace(value, ",", ".", 1)
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(parsed) || math.IsInf(parsed, 0) {
+		return 0, fmt.Sprintf("%s must be a number", m.field)
+	}
+	if m.integer {
+		if legacy {
+			parsed = math.Round(parsed)
+		} else if parsed != math.Trunc(parsed) {
+			return 0, fmt.Sprintf("%s must be a whole number", m.field)
+		}
+	}
+	if parsed < m.min || parsed > m.max {
+		return 0, m.rangeMessage()
+	}
+	return parsed, ""
+}
+
+// HealthValidationError carries field-level validation errors for health data
+type HealthValidationError struct {
+	Errors map[string]string
+}
+
+func (e *HealthValidationError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, message := range e.Errors {
+		messages = append(messages, message)
+	}
+	sort.Strings(messages)
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap lets callers match validation errors with ErrInvalidHealthData
+func (e *HealthValidationError) Unwrap() error {
+	return ErrInvalidHealthData
+}
+
+// healthMeasurementValues are the validated measurements of a payload
+type healthMeasurementValues struct {
+	Umur      int
+	Bb        float64
+	Tb        float64
+	Systol    int
+	Diastol   int
+	HeartRate int
+	Bmi       float64
+}
+
+func (v healthMeasurementValues) riskInput() HealthRiskInput {
+	return HealthRiskInput{
+		Age:       float64(v.Umur),
+		Systolic:  float64(v.Systol),
+		Diastolic: float64(v.Diastol),
+		HeartRate: float64(v.HeartRate),
+		BMI:       v.Bmi,
+	}
+}
+
+// validateHealthMeasurements checks every measurement of the payload against its range and calculates the BMI
+func validateHealthMeasurements(payload *HealthPayload) (*healthMeasurementValues, error) {
+	errs := make(map[string]string)
+	raw := map[string]string{
+		"umur":       payload.Umur,
+		"bb":         payload.Bb,
+		"tb":         payload.Tb,
+		"systol":     payload.Systol,
+		"diastol":    payload.Diastol,
+		"heart_rate": payload.HeartRate,
+	}
+	parsed := make(map[string]float64, len(raw))
+	for _, m := range healthMeasurements {
+		value, ok := raw[m.field]
+		if !ok {
+			continue
+		}
+		number, message := m.parse(value, false)
+		if message != "" {
+			errs[m.field] = message
+			continue
+		}
+		parsed[m.field] = number
+	}
+
+	values := &healthMeasurementValues{
+		Umur:      int(parsed["umur"]),
+		Bb:        parsed["bb"],
+		Tb:        parsed["tb"],
+		Systol:    int(parsed["systol"]),
+		Diastol:   int(parsed["diastol"]),
+		HeartRate: int(parsed["heart_rate"]),
+	}
+	if errs["systol"] == "" && errs["diastol"] == "" && values.Diastol >= values.Systol {
+		errs["diastol"] = "diastol must be lower than systol"
+	}
+	if errs["bb"] == "" && errs["tb"] == "" {
+		values.Bmi = math.Round(CalculateBMI(values.Bb, values.Tb)*100) / 100
+		if bmi, _ := findHealthMeasurement("bmi"); values.Bmi < bmi.min || values.Bmi > bmi.max {
+			errs["bmi"] = fmt.Sprintf("bb and tb give a BMI of %s, %s", formatMeasurement(values.Bmi), bmi.rangeMessage())
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, &HealthValidationError{Errors: errs}
+	}
+	return values, nil
+}
+
+// ErrInvalidHealthSort is returned for a sort on an unknown or repeated field
+var ErrInvalidHealthSort = errors.New("invalid sort")
+
+// HealthMeasurementRange filters a measurement; nil bounds are not applied and both ends are included
+type HealthMeasurementRange struct {
+	Min *float64
+	Max *float64
+}
+
+// HealthListFilter narrows and orders the health data listing. Sort is a comma-separated list of fields, each
+// optionally prefixed with "-" for descending order; records without a value come last. Trashed lists the deleted
+// records instead of the live ones.
+type HealthListFilter struct {
+	Risk         string
+	JenisKelamin string
+	Profesi      string
+	Ranges       map[string]HealthMeasurementRange // keyed by measurement field
+	Sort         string
+	Trashed      bool
+}
+
+// ClinicalFields lists the fields of HealthMaskedFields the filter or sort uses. Filtering or sorting on a masked
+// field would reveal its values, so readers without clinical access may not use them.
+func (f HealthListFilter) ClinicalFields() []string {
+	used := map[string]bool{}
+	if f.Risk != "" {
+		used["risk"] = true
+	}
+	for field := range f.Ranges {
+		used[field] = true
+	}
+	for _, part := range strings.Split(f.Sort, ",") {
+		used[strings.TrimPrefix(strings.TrimSpace(part), "-")] = true
+	}
+
+	var fields []string
+	for _, field := range HealthMaskedFields {
+		if used[field] {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// apply adds the filter and sort to a health_data query
+func (f HealthListFilter) apply(query *gorm.DB) (*gorm.DB, error) {
+	if f.Risk != "" {
+		query = query.Where("risk = ?", f.Risk)
+	}
+	if f.JenisKelamin != "" {
+		query = query.Where("LOWER(jenis_kelamin) = ?", strings.ToLower(f.JenisKelamin))
+	}
+	if f.Profesi != "" {
+		query = query.Where("LOWER(profesi) = ?", strings.ToLower(f.Profesi))
+	}
+	for _, m := range healthMeasurements {
+		r, ok := f.Ranges[m.field]
+		if !ok {
+			continue
+		}
+		if r.Min != nil {
+			query = query.Where(m.field+" >= ?", *r.Min)
+		}
+		if r.Max != nil {
+			query = query.Where(m.field+" <= ?", *r.Max)
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, part := range strings.Split(f.Sort, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name := strings.TrimPrefix(part, "-")
+		_, numeric := findHealthMeasurement(name)
+		if seen[name] || !(numeric || name == "nama" || name == "risk" || name == "created_at" || (f.Trashed && name == "deleted_at")) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidHealthSort, name)
+		}
+		seen[name] = true
+		direction := "ASC"
+		if strings.HasPrefix(part, "-") {
+			direction = "DESC"
+		}
+		query = query.Order(name + " " + direction + " NULLS LAST")
+	}
+	switch {
+	case len(seen) > 0:
+		query = query.Order("id")
+	case f.Trashed:
+		query = query.Order("deleted_at DESC").Order("id DESC")
+	default:
+		query = query.Order("created_at DESC").Order("id DESC")
+	}
+	return query, nil
+}
+
+// HealthMeasurementMigrationResult summarises the conversion of text measurement columns
+type HealthMeasurementMigrationResult struct {
+	Columns   []string `json:"columns"`
+	Converted int      `json:"converted"`
+	Issues    int      `json:"issues"`
+}
+
+const healthMigrationBatchSize = 500
+
+// MigrateHealthMeasurements converts measurement columns of health_data that still hold text into numbers. Values
+// that cannot be parsed or are outside the physiological range are cleared and kept in health_measurement_issue.
+// Each column is converted in its own transaction, so running it again only picks up columns not yet converted.
+func MigrateHealthMeasurements() (*HealthMeasurementMigrationResult, error) {
+	result := &HealthMeasurementMigrationResult{}
+	if !config.DB.Migrator().HasTable(&models.Health{}) {
+		return result, nil
+	}
+
+	columnTypes, err := config.DB.Migrator().ColumnTypes(&models.Health{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read health_data columns: %w", err)
+	}
+	textColumns := map[string]bool{}
+	for _, column := range columnTypes {
+		typeName := strings.ToLower(column.DatabaseTypeName())
+		if strings.Contains(typeName, "char") || strings.Contains(typeName, "text") {
+			textColumns[column.Name()] = true
+		}
+	}
+
+	for _, m := range healthMeasurements {
+		if !textColumns[m.field] {
+			continue
+		}
+		converted, issues, err := migrateHealthMeasurement(m)
+		if err != nil {
+			return nil, err
+		}
+		result.Columns = append(result.Columns, m.field)
+		result.Converted += converted
+		result.Issues += issues
+	}
+	return result, nil
+}
+
+// migrateHealthMeasurement moves the text column aside, adds the numeric column and copies the values over
+func migrateHealthMeasurement(m healthMeasurement) (int, int, error) {
+	legacyColumn := m.field + "_text"
+	converted, issues := 0, 0
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Migrator().RenameColumn(&models.Health{}, m.field, legacyColumn); err != nil {
+			return fmt.Errorf("failed to rename health_data.%s: %w", m.field, err)
+		}
+		if err := tx.Migrator().AddColumn(&models.Health{}, m.name); err != nil {
+			return fmt.Errorf("failed to add health_data.%s: %w", m.field, err)
+		}
+
+		type legacyRow struct {
+			ID    int
+			UUID  uuid.UUID
+			Value *string
+		}
+		lastID := 0
+		for {
+			var rows []legacyRow
+			if err := tx.Table("health_data").
+				Select("id, uuid, "+legacyColumn+" AS value").
+				Where("id > ?", lastID).
+				Order("id").
+				Limit(healthMigrationBatchSize).
+				Scan(&rows).Error; err != nil {
+				return fmt.Errorf("failed to read health_data.%s: %w", legacyColumn, err)
+			}
+			if len(rows) == 0 {
+				break
+			}
+
+			for _, row := range rows {
+				lastID = row.ID
+				if row.Value == nil || strings.TrimSpace(*row.Value) == "" {
+					continue
+				}
+				number, message := m.parse(*row.Value, true)
+				if message != "" {
+					issue := models.HealthMeasurementIssue{
+						HealthID:   row.ID,
+						HealthUUID: row.UUID,
+						Field:      m.field,
+						RawValue:   *row.Value,
+						Reason:     message,
+					}
+					if err := tx.Create(&issue).Error; err != nil {
+						return fmt.Errorf("failed to record health measurement issue: %w", err)
+					}
+					issues++
+					continue
+				}
+				if err := tx.Table("health_data").Where("id = ?", row.ID).Update(m.field, number).Error; err != nil {
+					return fmt.Errorf("failed to convert health_data.%s: %w", m.field, err)
+				}
+				converted++
+			}
+		}
+
+		if err := tx.Exec("ALTER TABLE health_data DROP COLUMN " + legacyColumn).Error; err != nil {
+			return fmt.Errorf("failed to drop health_data.%s: %w", legacyColumn, err)
+		}
+		return nil
+	})
+	return converted, issues, err
+}
+
+// GetMeasurementIssuesPaginated lists the values that could not be converted, newest first
+func (s *HealthService) GetMeasurementIssuesPaginated(currentPage, pageSize int, field string) (map[string]interface{}, error) {
+	var issues []models.HealthMeasurementIssue
+	var totalRecords int64
+
+	query := config.DB.Model(&models.HealthMeasurementIssue{})
+	if field != "" {
+		query = query.Where("field = ?", field)
+	}
+	if err := query.Count(&totalRecords).Error; err != nil {
+		return nil, fmt.Errorf("failed to count health measurement issues: %w", err)
+	}
+
+	offset := (currentPage - 1) * pageSize
+	if err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&issues).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch health measurement issues: %w", err)
+	}
+
+	return map[string]interface{}{
+		"data":          issues,
+		"current_page":  currentPage,
+		"per_page":      pageSize,
+		"total_pages":   int(math.Ceil(float64(totalRecords) / float64(pageSize))),
+		"total_records": totalRecords,
+	}, nil
+}
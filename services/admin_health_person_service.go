@@ -0,0 +1,317 @@
+package services
+
+import (
+	"backend-school/config"
+	"backend-school/models"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrHealthPersonNotFound is returned when a person does not exist or was deleted
+	ErrHealthPersonNotFound = errors.New("person not found")
+	// ErrHealthPersonExists is returned for a second live person with the same identity number
+	ErrHealthPersonExists = errors.New("a person with this identity number already exists")
+	// ErrInvalidHealthPerson is wrapped by every rejected person
+	ErrInvalidHealthPerson = errors.New("invalid person")
+	// ErrHealthPersonLinkForbidden is returned when a requester without clinical access changes the login of a
+	// person or attaches health checks to a person with a login
+	ErrHealthPersonLinkForbidden = errors.New("clinical access is required to change the health data a login can read")
+)
+
+// HealthPersonService manages the people health checks are recorded for
+type HealthPersonService struct{}
+
+func NewHealthPersonService() *HealthPersonService {
+	return &HealthPersonService{}
+}
+
+// HealthPersonPayload is the editable part of a person; BirthDate is formatted as YYYY-MM-DD
+type HealthPersonPayload struct {
+	Name           string `json:"name"`
+	IdentityNumber string `json:"identity_number"`
+	Gender         string `json:"gender"`
+	BirthDate      string `json:"birth_date"`
+	Profession     string `json:"profession"`
+	UserID         *int   `json:"user_id"`
+}
+
+// HealthPersonDetail is a person with a summary of their health checks
+type HealthPersonDetail struct {
+	models.HealthPerson
+	RecordCount int64      `json:"record_count"`
+	LastCheckAt *time.Time `json:"last_check_at"`
+}
+
+// ageOn returns the age in whole years on the given day
+func ageOn(birthDate, day time.Time) int {
+	age := day.Year() - birthDate.Year()
+	if day.Month() < birthDate.Month() || (day.Month() == birthDate.Month() && day.Day() < birthDate.Day()) {
+		age--
+	}
+	return age
+}
+
+// findHealthPerson returns a live person by UUID
+func findHealthPerson(db *gorm.DB, personUUID string) (*models.HealthPerson, error) {
+	if _, err := uuid.Parse(personUUID); err != nil {
+		return nil, ErrHealthPersonNotFound
+	}
+	var person models.HealthPerson
+	if err := db.Where("uuid = ?", personUUID).First(&person).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrHealthPersonNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch person: %w", err)
+	}
+	return &person, nil
+}
+
+// sameUserID reports whether two optional user IDs are equal
+func sameUserID(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// applyHealthPersonPayload validates the payload and copies it onto the person. excludeID skips the person itself
+// in the uniqueness checks of an update. A linked user reads their own data unmasked, so changing the login of the
+// person needs clinical access; userID is recorded as who made the link.
+func applyHealthPersonPayload(tx *gorm.DB, person *models.HealthPerson, payload *HealthPersonPayload, excludeID uint, userID int, clinical bool) error {
+	name := strings.TrimSpace(payload.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidHealthPerson)
+	}
+
+	var birthDate *time.Time
+	if value := strings.TrimSpace(payload.BirthDate); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return fmt.Errorf("%w: birth_date must be formatted as YYYY-MM-DD", ErrInvalidHealthPerson)
+		}
+		if parsed.After(time.Now()) {
+			return fmt.Errorf("%w: birth_date must not be in the future", ErrInvalidHealthPerson)
+		}
+		birthDate = &parsed
+	}
+
+	var identityNumber *string
+	if value := strings.TrimSpace(payload.IdentityNumber); value != "" {
+		var count int64
+		if err := tx.Model(&models.HealthPerson{}).
+			Where("LOWER(identity_number) = LOWER(?) AND id <> ?", value, excludeID).
+			Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to check identity number: %w", err)
+		}
+		if count > 0 {
+			return ErrHealthPersonExists
+		}
+		identityNumber = &value
+	}
+
+	linkChanged := !sameUserID(person.UserID, payload.UserID)
+	if linkChanged && !clinical {
+		return ErrHealthPersonLinkForbidden
+	}
+	if linkChanged && payload.UserID != nil {
+		var users int64
+		if err := tx.Model(&models.UserRegister{}).Where("id = ?", *payload.UserID).Count(&users).Error; err != nil {
+			return fmt.Errorf("failed to check user: %w", err)
+		}
+		if users == 0 {
+			return fmt.Errorf("%w: user %d does not exist", ErrInvalidHealthPerson, *payload.UserID)
+		}
+		var linked int64
+		if err := tx.Model(&models.HealthPerson{}).Where("user_id = ? AND id <> ?", *payload.UserID, excludeID).Count(&linked).Error; err != nil {
+			return fmt.Errorf("failed to check user: %w", err)
+		}
+		if linked > 0 {
+			return fmt.Errorf("%w: user %d is already linked to another person", ErrInvalidHealthPerson, *payload.UserID)
+		}
+	}
+
+	person.Name = name
+	person.IdentityNumber = identityNumber
+	person.Gender = strings.TrimSpace(payload.Gender)
+	person.BirthDate = birthDate
+	person.Profession = strings.TrimSpace(payload.Profession)
+	if linkChanged {
+		person.UserID = payload.UserID
+		person.UserLinkedBy = nil
+		if payload.UserID != nil {
+			person.UserLinkedBy = &userID
+		}
+	}
+	return nil
+}
+
+// GetPersonsPaginated lists the people, searching name and identity number
+func (s *HealthPersonService) GetPersonsPaginated(perPage, page int, search string) (map[string]interface{}, error) {
+	var persons []models.HealthPerson
+	var totalRecords int64
+
+	query := config.DB.Model(&models.HealthPerson{})
+	if search = strings.TrimSpace(search); search != "" {
+		like := "%" + strings.ToLower(search) + "%"
+		query = query.Where("LOWER(name) LIKE ? OR LOWER(COALESCE(identity_number, '')) LIKE ?", like, like)
+	}
+
+	if err := query.Count(&totalRecords).Error; err != nil {
+		return nil, errors.New("failed to count persons")
+	}
+
+	offset := (page - 1) * perPage
+	if err := query.Order("name ASC").Order("id ASC").Offset(offset).Limit(perPage).Find(&persons).Error; err != nil {
+		return nil, errors.New("failed to fetch persons")
+	}
+
+	return map[string]interface{}{
+		"data":          persons,
+		"current_page":  page,
+		"per_page":      perPage,
+		"total_pages":   int(math.Ceil(float64(totalRecords) / float64(perPage))),
+		"total_records": totalRecords,
+	}, nil
+}
+
+// GetPersonByUUID returns a person with the number and date of their health checks
+func (s *HealthPersonService) GetPersonByUUID(personUUID string) (*HealthPersonDetail, error) {
+	person, err := findHealthPerson(config.DB, personUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &HealthPersonDetail{HealthPerson: *person}
+	records := config.DB.Model(&models.Health{}).Where("health_person_id = ?", person.ID)
+	if err := records.Count(&detail.RecordCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to count health checks: %w", err)
+	}
+	var latest []models.Health
+	if err := records.Order("created_at DESC").Limit(1).Find(&latest).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch the latest health check: %w", err)
+	}
+	if len(latest) > 0 {
+		detail.LastCheckAt = &latest[0].CreatedAt
+	}
+	return detail, nil
+}
+
+// CreatePerson adds a person. clinical tells whether the requester has clinical access, which linking a login needs.
+func (s *HealthPersonService) CreatePerson(payload *HealthPersonPayload, userID int, clinical bool) (*models.HealthPerson, error) {
+	person := models.HealthPerson{CreatedBy: userID, UpdatedBy: userID}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := applyHealthPersonPayload(tx, &person, payload, 0, userID, clinical); err != nil {
+			return err
+		}
+		if err := tx.Create(&person).Error; err != nil {
+			return fmt.Errorf("failed to create person: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &person, nil
+}
+
+// UpdatePerson changes a person. Health checks already recorded keep the name, gender and profession they were
+// recorded with. clinical tells whether the requester has clinical access, which changing the login needs.
+func (s *HealthPersonService) UpdatePerson(personUUID string, payload *HealthPersonPayload, userID int, clinical bool) (*models.HealthPerson, error) {
+	var person *models.HealthPerson
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if person, err = findHealthPerson(tx, personUUID); err != nil {
+			return err
+		}
+		if err := applyHealthPersonPayload(tx, person, payload, person.ID, userID, clinical); err != nil {
+			return err
+		}
+		person.UpdatedBy = userID
+		if err := tx.Select("Name", "IdentityNumber", "Gender", "BirthDate", "Profession", "UserID", "UserLinkedBy", "UpdatedBy").
+			Updates(person).Error; err != nil {
+			return fmt.Errorf("failed to update person: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return person, nil
+}
+
+// DeletePerson soft deletes a person; their health checks stay linked
+func (s *HealthPersonService) DeletePerson(personUUID string) error {
+	person, err := findHealthPerson(config.DB, personUUID)
+	if err != nil {
+		return err
+	}
+	if err := config.DB.Delete(person).Error; err != nil {
+		return fmt.Errorf("failed to delete person: %w", err)
+	}
+	return nil
+}
+
+// LinkHealthRecords attaches existing health checks, for example ones recorded before people were tracked, to a
+// person. It returns how many checks were linked. A person linked to a login reads their checks unmasked, so
+// attaching checks to them needs clinical access.
+func (s *HealthPersonService) LinkHealthRecords(personUUID string, healthUUIDs []string, clinical bool) (int64, error) {
+	person, err := findHealthPerson(config.DB, personUUID)
+	if err != nil {
+		return 0, err
+	}
+	if person.UserID != nil && !clinical {
+		return 0, ErrHealthPersonLinkForbidden
+	}
+	if len(healthUUIDs) == 0 {
+		return 0, fmt.Errorf("%w: health_uuids is required", ErrInvalidHealthPerson)
+	}
+	for _, healthUUID := range healthUUIDs {
+		if _, err := uuid.Parse(healthUUID); err != nil {
+			return 0, fmt.Errorf("%w: %s is not a valid UUID", ErrInvalidHealthPerson, strconv.Quote(healthUUID))
+		}
+	}
+
+	personID := int(person.ID)
+	result := config.DB.Model(&models.Health{}).Where("uuid IN ?", healthUUIDs).Update("health_person_id", personID)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to link health data: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// resolveHealthPayloadPerson links a new health check to its person and fills the details the payload leaves out
+// from the person
+func resolveHealthPayloadPerson(payload *HealthPayload) (*models.HealthPerson, error) {
+	if strings.TrimSpace(payload.PersonUUID) == "" {
+		return nil, nil
+	}
+	person, err := findHealthPerson(config.DB, strings.TrimSpace(payload.PersonUUID))
+	if errors.Is(err, ErrHealthPersonNotFound) {
+		return nil, &HealthValidationError{Errors: map[string]string{"person_uuid": "person_uuid does not match a person"}}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(payload.Nama) == "" {
+		payload.Nama = person.Name
+	}
+	if strings.TrimSpace(payload.JenisKelamin) == "" {
+		payload.JenisKelamin = person.Gender
+	}
+	if strings.TrimSpace(payload.Profesi) == "" {
+		payload.Profesi = person.Profession
+	}
+	if strings.TrimSpace(payload.Umur) == "" && person.BirthDate != nil {
+		payload.Umur = strconv.Itoa(ageOn(*person.BirthDate, time.Now()))
+	}
+	return person, nil
+}
@@ -0,0 +1,270 @@
+package services
+
+import (
+	"backend-school/config"
+	"backend-school/models"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrHealthErasureReason is returned when an erasure request gives no reason
+var ErrHealthErasureReason = errors.New("a reason is required to erase a person's health data")
+
+// HealthMaskedFields are the clinical fields of health data hidden from readers without clinical access
+var HealthMaskedFields = []string{
+	"bb", "tb", "systol", "diastol", "heart_rate", "bmi", "risk", "risk_engine", "risk_rule_version",
+	"recommendation_food", "recommendation_sport", "recommendation_medicine",
+}
+
+// maskHealth clears the clinical fields of a record, keeping who it is about and when it was taken
+func maskHealth(health *models.Health) {
+	health.Bb, health.Tb, health.Bmi = nil, nil, nil
+	health.Systol, health.Diastol, health.HeartRate = nil, nil, nil
+	health.Risk, health.RiskEngine, health.RiskRuleVersion = "", "", ""
+	health.RecommendationFood, health.RecommendationSport, health.RecommendationMedicine = "", "", ""
+}
+
+// HealthAccessor is the user reading health data. Clinical is false for roles that may only see health data with
+// its clinical fields masked.
+type HealthAccessor struct {
+	UserID   int
+	Username string
+	IP       string
+	Clinical bool
+}
+
+// logHealthAccess writes one access log entry per record
+func (a HealthAccessor) logHealthAccess(db *gorm.DB, action string, records []models.Health, masked bool) error {
+	if len(records) == 0 {
+		return nil
+	}
+	entries := make([]models.HealthAccessLog, len(records))
+	for i := range records {
+		entries[i] = models.HealthAccessLog{
+			HealthUUID:     &records[i].UUID,
+			HealthPersonID: records[i].HealthPersonID,
+			UserID:         a.UserID,
+			Username:       a.Username,
+			Action:         action,
+			Masked:         masked,
+			IP:             a.IP,
+		}
+	}
+	if err := db.CreateInBatches(entries, 200).Error; err != nil {
+		return fmt.Errorf("failed to log health data access: %w", err)
+	}
+	return nil
+}
+
+// Read logs the read of every record and masks their clinical fields when the accessor has no clinical access. The
+// records are masked in place. The records are not returned when the read could not be logged.
+func (a HealthAccessor) Read(action string, records []models.Health) error {
+	if err := a.logHealthAccess(config.DB, action, records, !a.Clinical); err != nil {
+		return err
+	}
+	if !a.Clinical {
+		for i := range records {
+			maskHealth(&records[i])
+		}
+	}
+	return nil
+}
+
+// ReadTrends logs the read of every record a trend summary discloses a measurement of. Trends are clinical data
+// and are never masked.
+func (a HealthAccessor) ReadTrends(summary *HealthTrendSummary) error {
+	personID := int(summary.Person.ID)
+	seen := map[uuid.UUID]bool{}
+	var records []models.Health
+	add := func(healthUUID uuid.UUID) {
+		if !seen[healthUUID] {
+			seen[healthUUID] = true
+			records = append(records, models.Health{UUID: healthUUID, HealthPersonID: &personID})
+		}
+	}
+	for _, metric := range summary.Metrics {
+		for _, point := range metric.Points {
+			add(point.HealthUUID)
+		}
+	}
+	for _, point := range summary.BloodPressure {
+		add(point.HealthUUID)
+	}
+	return a.logHealthAccess(config.DB, models.HealthAccessActionTrends, records, false)
+}
+
+// HealthAccessLogFilter narrows the access log; empty fields are not applied
+type HealthAccessLogFilter struct {
+	HealthUUID string
+	PersonUUID string
+	Username   string
+	Action     string
+}
+
+// GetAccessLogPaginated lists the access log, newest first. The person is looked up among deleted persons too;
+// the log of an erased person can still be found by their health record UUIDs.
+func (s *HealthService) GetAccessLogPaginated(currentPage, pageSize int, filter HealthAccessLogFilter) (map[string]interface{}, error) {
+	var entries []models.HealthAccessLog
+	var totalRecords int64
+
+	query := config.DB.Model(&models.HealthAccessLog{})
+	if filter.HealthUUID != "" {
+		if _, err := uuid.Parse(filter.HealthUUID); err != nil {
+			return nil, fmt.Errorf("%w: health_uuid is not a valid UUID", ErrInvalidHealthData)
+		}
+		query = query.Where("health_uuid = ?", filter.HealthUUID)
+	}
+	if filter.PersonUUID != "" {
+		if _, err := uuid.Parse(filter.PersonUUID); err != nil {
+			return nil, fmt.Errorf("%w: person_uuid is not a valid UUID", ErrInvalidHealthData)
+		}
+		query = query.Where("health_person_id IN (?)",
+			config.DB.Unscoped().Model(&models.HealthPerson{}).Select("id").Where("uuid = ?", filter.PersonUUID))
+	}
+	if filter.Username != "" {
+		query = query.Where("LOWER(username) = ?", strings.ToLower(filter.Username))
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+
+	if err := query.Count(&totalRecords).Error; err != nil {
+		return nil, fmt.Errorf("failed to count health access log: %w", err)
+	}
+
+	offset := (currentPage - 1) * pageSize
+	if err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch health access log: %w", err)
+	}
+
+	return map[string]interface{}{
+		"data":          entries,
+		"current_page":  currentPage,
+		"per_page":      pageSize,
+		"total_pages":   int(math.Ceil(float64(totalRecords) / float64(pageSize))),
+		"total_records": totalRecords,
+	}, nil
+}
+
+// HealthPersonExport is everything held about a person's health, for a data-protection access request
+type HealthPersonExport struct {
+	ExportedAt        time.Time                       `json:"exported_at"`
+	ExportedBy        string                          `json:"exported_by"`
+	Person            models.HealthPerson             `json:"person"`
+	Records           []models.Health                 `json:"records"` // including deleted ones
+	MeasurementIssues []models.HealthMeasurementIssue `json:"measurement_issues"`
+	AccessLog         []models.HealthAccessLog        `json:"access_log"`
+	Units             map[string]string               `json:"units"`
+}
+
+// ExportPerson collects a person's details, every health check linked to them, the measurements that could not be
+// converted and who read their data. The export itself is logged for every record.
+func (s *HealthPersonService) ExportPerson(personUUID string, accessor HealthAccessor) (*HealthPersonExport, error) {
+	person, err := findHealthPerson(config.DB, personUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &HealthPersonExport{
+		ExportedAt: time.Now(),
+		ExportedBy: accessor.Username,
+		Person:     *person,
+		Units:      HealthMeasurementUnits(),
+	}
+	if err := config.DB.Unscoped().Where("health_person_id = ?", person.ID).
+		Order("created_at ASC").Order("id ASC").Find(&export.Records).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch health data: %w", err)
+	}
+	ids := make([]int, len(export.Records))
+	for i, record := range export.Records {
+		ids[i] = record.ID
+	}
+	export.MeasurementIssues = []models.HealthMeasurementIssue{}
+	if len(ids) > 0 {
+		if err := config.DB.Where("health_id IN ?", ids).Order("id ASC").Find(&export.MeasurementIssues).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch health measurement issues: %w", err)
+		}
+	}
+	if err := config.DB.Where("health_person_id = ?", person.ID).Order("id ASC").Find(&export.AccessLog).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch health access log: %w", err)
+	}
+
+	if err := accessor.logHealthAccess(config.DB, models.HealthAccessActionExport, export.Records, false); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// HealthErasureResult counts what an erasure removed
+type HealthErasureResult struct {
+	PersonUUID        uuid.UUID `json:"person_uuid"`
+	Records           int64     `json:"records"`
+	MeasurementIssues int64     `json:"measurement_issues"`
+}
+
+// ErasePerson permanently deletes a person and every health check linked to them, deleted ones included, with
+// their measurement issues. Checks that were never linked to the person are not found; link them first. The access
+// log holds no health data and is kept, with an entry recording the erasure and its reason.
+func (s *HealthPersonService) ErasePerson(personUUID, reason string, accessor HealthAccessor) (*HealthErasureResult, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrHealthErasureReason
+	}
+	if _, err := uuid.Parse(personUUID); err != nil {
+		return nil, ErrHealthPersonNotFound
+	}
+
+	result := &HealthErasureResult{}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// A person that was only deleted can still be erased
+		var person models.HealthPerson
+		if err := tx.Unscoped().Where("uuid = ?", personUUID).First(&person).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrHealthPersonNotFound
+			}
+			return fmt.Errorf("failed to fetch person: %w", err)
+		}
+		result.PersonUUID = person.UUID
+
+		records := tx.Unscoped().Model(&models.Health{}).Select("id").Where("health_person_id = ?", person.ID)
+		issues := tx.Where("health_id IN (?)", records).Delete(&models.HealthMeasurementIssue{})
+		if issues.Error != nil {
+			return fmt.Errorf("failed to erase health measurement issues: %w", issues.Error)
+		}
+		result.MeasurementIssues = issues.RowsAffected
+
+		deleted := tx.Unscoped().Where("health_person_id = ?", person.ID).Delete(&models.Health{})
+		if deleted.Error != nil {
+			return fmt.Errorf("failed to erase health data: %w", deleted.Error)
+		}
+		result.Records = deleted.RowsAffected
+
+		if err := tx.Unscoped().Delete(&person).Error; err != nil {
+			return fmt.Errorf("failed to erase person: %w", err)
+		}
+
+		personID := int(person.ID)
+		entry := models.HealthAccessLog{
+			HealthPersonID: &personID,
+			UserID:         accessor.UserID,
+			Username:       accessor.Username,
+			Action:         models.HealthAccessActionErase,
+			IP:             accessor.IP,
+			Detail:         fmt.Sprintf("%s (person %s, %d record(s) erased)", reason, person.UUID, result.Records),
+		}
+		if err := tx.Create(&entry).Error; err != nil {
+			return fmt.Errorf("failed to log erasure: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
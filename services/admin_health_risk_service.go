@@ -0,0 +1,452 @@
+package services
+
+import (
+	"backend-school/config"
+	"backend-school/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Risk levels stored on health data; GenerateRecommendations relies on these values
+const (
+	HealthRiskLow    = "Low Risk"
+	HealthRiskMedium = "Medium Risk"
+	HealthRiskHigh   = "High Risk"
+)
+
+// localHealthRiskEngineName is stored as the risk engine of entries assessed in-process
+const localHealthRiskEngineName = "local"
+
+var (
+	ErrInvalidHealthData      = errors.New("invalid health data")
+	ErrInvalidHealthRiskRules = errors.New("invalid health risk rules")
+)
+
+// HealthRiskInput holds the measurements a risk assessment is based on
+type HealthRiskInput struct {
+	Age       float64 `json:"age"`
+	Systolic  float64 `json:"systolic"`
+	Diastolic float64 `json:"diastolic"`
+	HeartRate float64 `json:"heart_rate"`
+	BMI       float64 `json:"bmi"`
+}
+
+// HealthRiskFactor explains how one measurement contributed to the score of the local engine
+type HealthRiskFactor struct {
+	Factor   string `json:"factor"`
+	Value    string `json:"value"`
+	Category string `json:"category"`
+	Points   int    `json:"points"`
+}
+
+// HealthRiskAssessment is the result of a risk provider. Score and Factors are only set by the local engine.
+type HealthRiskAssessment struct {
+	Risk        string             `json:"risk"`
+	Score       *int               `json:"score"`
+	Factors     []HealthRiskFactor `json:"factors"`
+	Engine      string             `json:"engine"`
+	RuleVersion string             `json:"rule_version"`
+	Fallback    bool               `json:"fallback"` // the configured provider failed and the local engine was used
+}
+
+// HealthRiskProvider assesses the risk level of a set of measurements
+type HealthRiskProvider interface {
+	Name() string
+	Assess(ctx context.Context, input HealthRiskInput) (*HealthRiskAssessment, error)
+}
+
+var (
+	healthRiskProviderMu sync.RWMutex
+	healthRiskProvider   HealthRiskProvider
+)
+
+// SetHealthRiskProvider selects a provider that is asked before the local engine; nil uses only the local engine
+func SetHealthRiskProvider(provider HealthRiskProvider) {
+	healthRiskProviderMu.Lock()
+	healthRiskProvider = provider
+	healthRiskProviderMu.Unlock()
+}
+
+func currentHealthRiskProvider() HealthRiskProvider {
+	healthRiskProviderMu.RLock()
+	defer healthRiskProviderMu.RUnlock()
+	return healthRiskProvider
+}
+
+// HealthRiskBand scores a measurement between Min (inclusive) and Max (exclusive); a nil bound is open
+type HealthRiskBand struct {
+	Label  string   `json:"label"`
+	Min    *float64 `json:"min"`
+	Max    *float64 `json:"max"`
+	Points int      `json:"points"`
+}
+
+func (b HealthRiskBand) contains(value float64) bool {
+	return (b.Min == nil || value >= *b.Min) && (b.Max == nil || value < *b.Max)
+}
+
+// HealthBloodPressureCategory matches a reading whose systolic or diastolic pressure reaches its minimum. Categories
+// are checked in order, so the most severe comes first; the last category has no minimums and matches the rest.
+type HealthBloodPressureCategory struct {
+	Label        string   `json:"label"`
+	SystolicMin  *float64 `json:"systolic_min"`
+	DiastolicMin *float64 `json:"diastolic_min"`
+	Points       int      `json:"points"`
+}
+
+func (c HealthBloodPressureCategory) matches(systolic, diastolic float64) bool {
+	if c.SystolicMin == nil && c.DiastolicMin == nil {
+		return true
+	}
+	return (c.SystolicMin != nil && systolic >= *c.SystolicMin) || (c.DiastolicMin != nil && diastolic >= *c.DiastolicMin)
+}
+
+// HealthRiskLevels turns the total score into a risk level
+type HealthRiskLevels struct {
+	MediumFrom int `json:"medium_from"`
+	HighFrom   int `json:"high_from"`
+}
+
+// HealthRiskRules are the thresholds of the local engine. The points of the blood pressure category, heart rate,
+// BMI and age bands are added up and the total is mapped to a risk level.
+type HealthRiskRules struct {
+	BloodPressure []HealthBloodPressureCategory `json:"blood_pressure"`
+	HeartRate     []HealthRiskBand              `json:"heart_rate"`
+	BMI           []HealthRiskBand              `json:"bmi"`
+	Age           []HealthRiskBand              `json:"age"`
+	Levels        HealthRiskLevels              `json:"levels"`
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+// DefaultHealthRiskRules are used until an administrator saves a rule set. Blood pressure follows the AHA categories.
+func DefaultHealthRiskRules() HealthRiskRules {
+	return HealthRiskRules{
+		BloodPressure: []HealthBloodPressureCategory{
+			{Label: "Hypertensive crisis", SystolicMin: floatPtr(180), DiastolicMin: floatPtr(120), Points: 4},
+			{Label: "Hypertension stage 2", SystolicMin: floatPtr(140), DiastolicMin: floatPtr(90), Points: 3},
+			{Label: "Hypertension stage 1", SystolicMin: floatPtr(130), DiastolicMin: floatPtr(80), Points: 2},
+			{Label: "Elevated", SystolicMin: floatPtr(120), Points: 1},
+			{Label: "Normal", Points: 0},
+		},
+		HeartRate: []HealthRiskBand{
+			{Label: "Severe bradycardia", Max: floatPtr(40), Points: 2},
+			{Label: "Bradycardia", Min: floatPtr(40), Max: floatPtr(60), Points: 1},
+			{Label: "Normal", Min: floatPtr(60), Max: floatPtr(100), Points: 0},
+			{Label: "Tachycardia", Min: floatPtr(100), Max: floatPtr(120), Points: 1},
+			{Label: "Severe tachycardia", Min: floatPtr(120), Points: 2},
+		},
+		BMI: []HealthRiskBand{
+			{Label: "Underweight", Max: floatPtr(18.5), Points: 1},
+			{Label: "Normal", Min: floatPtr(18.5), Max: floatPtr(25), Points: 0},
+			{Label: "Overweight", Min: floatPtr(25), Max: floatPtr(30), Points: 1},
+			{Label: "Obese", Min: floatPtr(30), Points: 2},
+		},
+		Age: []HealthRiskBand{
+			{Label: "Under 45", Max: floatPtr(45), Points: 0},
+			{Label: "45 to 64", Min: floatPtr(45), Max: floatPtr(65), Points: 1},
+			{Label: "65 and over", Min: floatPtr(65), Points: 2},
+		},
+		Levels: HealthRiskLevels{MediumFrom: 2, HighFrom: 4},
+	}
+}
+
+// validate checks that every measurement can be scored and that the levels are ordered
+func (r HealthRiskRules) validate() error {
+	if len(r.BloodPressure) == 0 {
+		return fmt.Errorf("%w: at least one blood pressure category is required", ErrInvalidHealthRiskRules)
+	}
+	for i, category := range r.BloodPressure {
+		if strings.TrimSpace(category.Label) == "" {
+			return fmt.Errorf("%w: blood pressure category %d needs a label", ErrInvalidHealthRiskRules, i+1)
+		}
+		if category.Points < 0 {
+			return fmt.Errorf("%w: points of blood pressure category %q must not be negative", ErrInvalidHealthRiskRules, category.Label)
+		}
+		last := i == len(r.BloodPressure)-1
+		open := category.SystolicMin == nil && category.DiastolicMin == nil
+		if last && !open {
+			return fmt.Errorf("%w: the last blood pressure category must have no minimums", ErrInvalidHealthRiskRules)
+		}
+		if !last && open {
+			return fmt.Errorf("%w: blood pressure category %q needs a systolic or diastolic minimum", ErrInvalidHealthRiskRules, category.Label)
+		}
+	}
+
+	factors := []struct {
+		name  string
+		bands []HealthRiskBand
+	}{{"heart_rate", r.HeartRate}, {"bmi", r.BMI}, {"age", r.Age}}
+	for _, f := range factors {
+		factor := f.name
+		for i, band := range f.bands {
+			if strings.TrimSpace(band.Label) == "" {
+				return fmt.Errorf("%w: %s band %d needs a label", ErrInvalidHealthRiskRules, factor, i+1)
+			}
+			if band.Points < 0 {
+				return fmt.Errorf("%w: points of %s band %q must not be negative", ErrInvalidHealthRiskRules, factor, band.Label)
+			}
+			if band.Min != nil && band.Max != nil && *band.Min >= *band.Max {
+				return fmt.Errorf("%w: %s band %q must have a minimum below its maximum", ErrInvalidHealthRiskRules, factor, band.Label)
+			}
+		}
+	}
+
+	if r.Levels.MediumFrom < 1 || r.Levels.HighFrom <= r.Levels.MediumFrom {
+		return fmt.Errorf("%w: levels need 0 < medium_from < high_from", ErrInvalidHealthRiskRules)
+	}
+	return nil
+}
+
+// scoreBand returns the first band containing the value; values outside every band score nothing
+func scoreBand(factor string, value float64, bands []HealthRiskBand) HealthRiskFactor {
+	for _, band := range bands {
+		if band.contains(value) {
+			return HealthRiskFactor{Factor: factor, Value: formatMeasurement(value), Category: band.Label, Points: band.Points}
+		}
+	}
+	return HealthRiskFactor{Factor: factor, Value: formatMeasurement(value), Category: "Unclassified"}
+}
+
+// bloodPressureCategory returns the label and points of the first category matching the reading
+func (r HealthRiskRules) bloodPressureCategory(systolic, diastolic float64) (string, int) {
+	for _, category := range r.BloodPressure {
+		if category.matches(systolic, diastolic) {
+			return category.Label, category.Points
+		}
+	}
+	return "Unclassified", 0
+}
+
+// evaluateHealthRisk scores the input against the rules
+func evaluateHealthRisk(rules HealthRiskRules, input HealthRiskInput) (string, int, []HealthRiskFactor) {
+	factors := make([]HealthRiskFactor, 0, 4)
+
+	bloodPressure := HealthRiskFactor{
+		Factor: "blood_pressure",
+		Value:  formatMeasurement(input.Systolic) + "/" + formatMeasurement(input.Diastolic),
+	}
+	bloodPressure.Category, bloodPressure.Points = rules.bloodPressureCategory(input.Systolic, input.Diastolic)
+	factors = append(factors,
+		bloodPressure,
+		scoreBand("heart_rate", input.HeartRate, rules.HeartRate),
+		scoreBand("bmi", math.Round(input.BMI*100)/100, rules.BMI),
+		scoreBand("age", input.Age, rules.Age),
+	)
+
+	score := 0
+	for _, factor := range factors {
+		score += factor.Points
+	}
+
+	risk := HealthRiskLow
+	switch {
+	case score >= rules.Levels.HighFrom:
+		risk = HealthRiskHigh
+	case score >= rules.Levels.MediumFrom:
+		risk = HealthRiskMedium
+	}
+	return risk, score, factors
+}
+
+// HealthRiskRuleSetResponse is a rule set with its decoded thresholds. Version 0 stands for the built-in defaults.
+type HealthRiskRuleSetResponse struct {
+	UUID        *uuid.UUID      `json:"uuid"`
+	Version     int             `json:"version"`
+	RuleVersion string          `json:"rule_version"`
+	Note        string          `json:"note"`
+	CreatedBy   int             `json:"created_by"`
+	CreatedAt   *time.Time      `json:"created_at"`
+	Rules       HealthRiskRules `json:"rules"`
+}
+
+// healthRiskRuleVersion is the rule version stored on health data assessed with a rule set
+func healthRiskRuleVersion(version int) string {
+	if version == 0 {
+		return "default"
+	}
+	return fmt.Sprintf("v%d", version)
+}
+
+func formatHealthRiskRuleSet(ruleSet models.HealthRiskRuleSet) (*HealthRiskRuleSetResponse, error) {
+	var rules HealthRiskRules
+	if err := json.Unmarshal([]byte(ruleSet.Rules), &rules); err != nil {
+		return nil, fmt.Errorf("health risk rule set %d is not valid JSON: %w", ruleSet.Version, err)
+	}
+	ruleUUID := ruleSet.UUID
+	createdAt := ruleSet.CreatedAt
+	return &HealthRiskRuleSetResponse{
+		UUID:        &ruleUUID,
+		Version:     ruleSet.Version,
+		RuleVersion: healthRiskRuleVersion(ruleSet.Version),
+		Note:        ruleSet.Note,
+		CreatedBy:   ruleSet.CreatedBy,
+		CreatedAt:   &createdAt,
+		Rules:       rules,
+	}, nil
+}
+
+// HealthRiskService manages the rule sets of the local engine and assesses health data
+type HealthRiskService struct{}
+
+// NewHealthRiskService initializes a new HealthRiskService
+func NewHealthRiskService() *HealthRiskService {
+	return &HealthRiskService{}
+}
+
+// HealthRiskRulesPayload stores a new rule set version
+type HealthRiskRulesPayload struct {
+	Rules *HealthRiskRules `json:"rules"`
+	Note  string           `json:"note"`
+}
+
+// GetActiveRules returns the newest rule set, or the built-in defaults when none was saved
+func (s *HealthRiskService) GetActiveRules() (*HealthRiskRuleSetResponse, error) {
+	var ruleSets []models.HealthRiskRuleSet
+	if err := config.DB.Order("version DESC").Limit(1).Find(&ruleSets).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch health risk rules: %w", err)
+	}
+	if len(ruleSets) == 0 {
+		return &HealthRiskRuleSetResponse{RuleVersion: healthRiskRuleVersion(0), Rules: DefaultHealthRiskRules()}, nil
+	}
+	return formatHealthRiskRuleSet(ruleSets[0])
+}
+
+// GetRuleHistory lists the saved rule sets, newest first
+func (s *HealthRiskService) GetRuleHistory(pageSize, currentPage int) (map[string]interface{}, error) {
+	var ruleSets []models.HealthRiskRuleSet
+	var totalRecords int64
+
+	if err := config.DB.Model(&models.HealthRiskRuleSet{}).Count(&totalRecords).Error; err != nil {
+		return nil, fmt.Errorf("failed to count health risk rules: %w", err)
+	}
+
+	offset := (currentPage - 1) * pageSize
+	if err := config.DB.Order("version DESC").Offset(offset).Limit(pageSize).Find(&ruleSets).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch health risk rules: %w", err)
+	}
+
+	items := make([]HealthRiskRuleSetResponse, 0, len(ruleSets))
+	for _, ruleSet := range ruleSets {
+		item, err := formatHealthRiskRuleSet(ruleSet)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *item)
+	}
+
+	return map[string]interface{}{
+		"data":          items,
+		"current_page":  currentPage,
+		"per_page":      pageSize,
+		"total_pages":   int(math.Ceil(float64(totalRecords) / float64(pageSize))),
+		"total_records": totalRecords,
+	}, nil
+}
+
+// SaveRules validates the thresholds and stores them as the next rule set version
+func (s *HealthRiskService) SaveRules(payload *HealthRiskRulesPayload, userID int) (*HealthRiskRuleSetResponse, error) {
+	if payload.Rules == nil {
+		return nil, fmt.Errorf("%w: rules are required", ErrInvalidHealthRiskRules)
+	}
+	if err := payload.Rules.validate(); err != nil {
+		return nil, err
+	}
+	encoded, err := json.Marshal(payload.Rules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode health risk rules: %w", err)
+	}
+
+	var ruleSet models.HealthRiskRuleSet
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var latest int
+		if err := tx.Model(&models.HealthRiskRuleSet{}).Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+			return fmt.Errorf("failed to read health risk rule version: %w", err)
+		}
+		ruleSet = models.HealthRiskRuleSet{
+			Version:   latest + 1,
+			Rules:     string(encoded),
+			Note:      strings.TrimSpace(payload.Note),
+			CreatedBy: userID,
+		}
+		if err := tx.Create(&ruleSet).Error; err != nil {
+			return fmt.Errorf("failed to save health risk rules: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return formatHealthRiskRuleSet(ruleSet)
+}
+
+// LocalHealthRiskEngine assesses risk in-process with the active rule set
+type LocalHealthRiskEngine struct {
+	rules *HealthRiskService
+}
+
+// NewLocalHealthRiskEngine creates the local engine
+func NewLocalHealthRiskEngine() *LocalHealthRiskEngine {
+	return &LocalHealthRiskEngine{rules: NewHealthRiskService()}
+}
+
+func (e *LocalHealthRiskEngine) Name() string {
+	return localHealthRiskEngineName
+}
+
+// Assess scores the input against the active rule set
+func (e *LocalHealthRiskEngine) Assess(ctx context.Context, input HealthRiskInput) (*HealthRiskAssessment, error) {
+	ruleSet, err := e.rules.GetActiveRules()
+	if err != nil {
+		return nil, err
+	}
+	risk, score, factors := evaluateHealthRisk(ruleSet.Rules, input)
+	return &HealthRiskAssessment{
+		Risk:        risk,
+		Score:       &score,
+		Factors:     factors,
+		Engine:      localHealthRiskEngineName,
+		RuleVersion: ruleSet.RuleVersion,
+	}, nil
+}
+
+// Assess asks the configured provider and falls back to the local engine when it fails or none is configured
+func (s *HealthRiskService) Assess(ctx context.Context, input HealthRiskInput) (*HealthRiskAssessment, error) {
+	fallback := false
+	if provider := currentHealthRiskProvider(); provider != nil {
+		assessment, err := provider.Assess(ctx, input)
+		if err == nil {
+			return assessment, nil
+		}
+		log.Printf("Health risk provider %s failed, using the local engine: %v", provider.Name(), err)
+		fallback = true
+	}
+
+	assessment, err := NewLocalHealthRiskEngine().Assess(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	assessment.Fallback = fallback
+	return assessment, nil
+}
+
+// Evaluate validates the measurements of a health payload and assesses them without storing anything
+func (s *HealthRiskService) Evaluate(ctx context.Context, payload *HealthPayload) (*HealthRiskAssessment, error) {
+	values, err := validateHealthMeasurements(payload)
+	if err != nil {
+		return nil, err
+	}
+	return s.Assess(ctx, values.riskInput())
+}
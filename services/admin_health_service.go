@@ -3,25 +3,23 @@ package services
 import (
 	"backend-school/config"
 	"backend-school/models"
-	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"math"
-	"net/http"
-	"os"
-	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
-type RiskResponse struct {
-	Risk   string `json:"risk"`
-	Sample int    `json:"sample"`
-}
+var (
+	// ErrHealthNotFound is returned when health data does not exist or was deleted
+	ErrHealthNotFound = errors.New("health data not found")
+	// ErrHealthNotInTrash is returned when restoring health data that does not exist or was not deleted
+	ErrHealthNotInTrash = errors.New("health data is not in the trash")
+)
 
 type HealthService struct{}
 
@@ -30,29 +28,41 @@ func NewHealthService() *HealthService {
 	return &HealthService{}
 }
 
-// defines the structure for the create request payload
+// defines the structure for the create and update request payload, sent as JSON or as a form
 type HealthPayload struct {
-	Nama         string `json:"nama"`
-	JenisKelamin string `json:"jenis_kelamin"`
-	Umur         string `json:"umur"`
-	Bb           string `json:"bb"`
-	Tb           string `json:"tb"`
-	Systol       string `json:"systol"`
-	Diastol      string `json:"diastol"`
-	HeartRate    string `json:"heart_rate"`
-	Profesi      string `json:"profesi"`
+	Nama         string `json:"nama" form:"nama"`
+	JenisKelamin string `json:"jenis_kelamin" form:"jenis_kelamin"`
+	Umur         string `json:"umur" form:"umur"`
+	Bb           string `json:"bb" form:"bb"`
+	Tb           string `json:"tb" form:"tb"`
+	Systol       string `json:"systol" form:"systol"`
+	Diastol      string `json:"diastol" form:"diastol"`
+	HeartRate    string `json:"heart_rate" form:"heart_rate"`
+	Profesi      string `json:"profesi" form:"profesi"`
+	PersonUUID   string `json:"person_uuid" form:"person_uuid"` // optional; blank name, gender, profession and age are taken from the person
 }
 
-func (s *HealthService) GetHealthsPaginated(currentPage, pageSize int, search string, showAll string) (map[string]interface{}, error) {
+// GetHealthsPaginated lists health data. search matches the name and profession of a record and the identity
+// number of its person.
+func (s *HealthService) GetHealthsPaginated(currentPage, pageSize int, search string, showAll string, filter HealthListFilter) (map[string]interface{}, error) {
 	var Healths []models.Health
 	var totalRecords int64
 
-	offset := (currentPage) * pageSize
+	offset := (currentPage - 1) * pageSize
 
 	// Query dasar untuk health data
-	query := config.DB.Model(&models.Health{}).Where("deleted_at IS NULL")
-	if search != "" {
-		query = query.Where("LOWER(name) LIKE ?", "%"+search+"%")
+	query := config.DB.Model(&models.Health{})
+	if filter.Trashed {
+		query = query.Unscoped().Where("deleted_at IS NOT NULL")
+	}
+	if search = strings.TrimSpace(search); search != "" {
+		like := "%" + strings.ToLower(search) + "%"
+		query = query.Where("LOWER(nama) LIKE ? OR LOWER(profesi) LIKE ? OR health_person_id IN (?)", like, like,
+			config.DB.Model(&models.HealthPerson{}).Select("id").Where("LOWER(identity_number) LIKE ?", like))
+	}
+	query, err := filter.apply(query)
+	if err != nil {
+		return nil, err
 	}
 
 	// Hitung total dokumen yang sesuai dengan query
@@ -81,31 +91,16 @@ func (s *HealthService) GetHealthsPaginated(currentPage, pageSize int, search st
 		"per_page":      pageSize,
 		"total_pages":   totalPages,
 		"total_records": totalRecords,
+		"units":         HealthMeasurementUnits(),
 	}
 
 	return result, nil
 }
 
-func CalculateBMI(weight, height string) (float64, error) {
-	// Convert weight and height to float64
-	w, err := strconv.ParseFloat(weight, 64)
-	if err != nil {
-		return 0, fmt.Errorf("invalid weight: %v", err)
-	}
-
-	h, err := strconv.ParseFloat(height, 64)
-	if err != nil {
-		return 0, fmt.Errorf("invalid height: %v", err)
-	}
-
-	if h <= 0 {
-		return 0, fmt.Errorf("height must be greater than zero")
-	}
-
-	// Calculate BMI (Height is converted to meters)
-	hMeters := h / 100
-	bmi := w / math.Pow(hMeters, 2)
-	return bmi, nil
+// CalculateBMI returns the body mass index for a weight in kg and a height in cm
+func CalculateBMI(weightKg, heightCm float64) float64 {
+	hMeters := heightCm / 100
+	return weightKg / math.Pow(hMeters, 2)
 }
 
 // GenerateRecommendations generates food, sport, and medicine recommendations based on risk and BMI.
@@ -149,129 +144,162 @@ func GenerateRecommendations(risk string, bmi float64) (string, string, string)
 	return food, sport, medicine
 }
 
-// CalculateRisk sends a POST request to the external API to get the risk level.
-func CalculateRisk(systol, diastol, heartRate string) (string, error) {
-	// Get the URL from .env
-	apiURL := os.Getenv("RISK_API_URL")
-	if apiURL == "" {
-		return "", errors.New("RISK_API_URL is not set in the environment variables")
-	}
-
-	// Prepare the request payload
-	payload := map[string]interface{}{
-		"systol":     systol,
-		"diastol":    diastol,
-		"heart_rate": heartRate,
-	}
-
-	payloadBytes, err := json.Marshal(payload)
+// buildHealth validates a payload and assesses it into a health record that is not saved yet: the person is
+// resolved, the BMI calculated, the risk assessed and the recommendations generated
+func buildHealth(payload *HealthPayload) (*models.Health, error) {
+	person, err := resolveHealthPayloadPerson(payload)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal payload: %v", err)
+		return nil, err
 	}
-
-	// Send the POST request
-	resp, err := http.Post(apiURL, "application/json", bytes.NewBuffer(payloadBytes))
+	values, err := validateHealthMeasurements(payload)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request to %s: %v", apiURL, err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := ioutil.ReadAll(resp.Body)
-		return "", fmt.Errorf("received non-200 response: %d, body: %s", resp.StatusCode, string(body))
+	// Assess the risk before any transaction is opened; an external provider may take a while
+	assessment, err := NewHealthRiskService().Assess(context.Background(), values.riskInput())
+	if err != nil {
+		return nil, fmt.Errorf("failed to assess health risk: %w", err)
 	}
 
-	// Parse the response
-	var riskResponse []RiskResponse
-	if err := json.NewDecoder(resp.Body).Decode(&riskResponse); err != nil {
-		return "", fmt.Errorf("failed to decode response: %v", err)
+	// Generate recommendations
+	food, sport, medicine := GenerateRecommendations(assessment.Risk, values.Bmi)
+
+	health := &models.Health{
+		Nama:                   payload.Nama,
+		JenisKelamin:           payload.JenisKelamin,
+		Umur:                   &values.Umur,
+		Bb:                     &values.Bb,
+		Tb:                     &values.Tb,
+		Systol:                 &values.Systol,
+		Diastol:                &values.Diastol,
+		HeartRate:              &values.HeartRate,
+		Profesi:                payload.Profesi,
+		Risk:                   assessment.Risk,
+		RiskEngine:             assessment.Engine,
+		RiskRuleVersion:        assessment.RuleVersion,
+		Bmi:                    &values.Bmi,
+		RecommendationFood:     food,
+		RecommendationSport:    sport,
+		RecommendationMedicine: medicine,
 	}
-
-	if len(riskResponse) > 0 {
-		return riskResponse[0].Risk, nil
+	if person != nil {
+		personID := int(person.ID)
+		health.HealthPersonID = &personID
 	}
-
-	return "", errors.New("no risk data found in response")
+	return health, nil
 }
 
-func (s *HealthService) AddHealth(payload *HealthPayload) (*models.Health, error) {
-	var Health models.Health
-
-	err := config.DB.Transaction(func(tx *gorm.DB) error {
-		// Create the  entry
-		risk, _ := CalculateRisk(payload.Systol, payload.Diastol, payload.HeartRate)
-		bmi, _ := CalculateBMI(payload.Bb, payload.Tb)
-
-		// Generate recommendations
-		food, sport, medicine := GenerateRecommendations(risk, bmi)
-
-		Health = models.Health{
-			UUID:                   uuid.New(),
-			Nama:                   payload.Nama,
-			JenisKelamin:           payload.JenisKelamin,
-			Umur:                   payload.Umur,
-			Bb:                     payload.Bb,
-			Tb:                     payload.Tb,
-			Systol:                 payload.Systol,
-			Diastol:                payload.Diastol,
-			HeartRate:              payload.HeartRate,
-			Profesi:                payload.Profesi,
-			Risk:                   risk,
-			Bmi:                    fmt.Sprintf("%.2f", bmi),
-			RecommendationFood:     food,
-			RecommendationSport:    sport,
-			RecommendationMedicine: medicine,
-		}
-
-		if err := tx.Create(&Health).Error; err != nil {
-			return fmt.Errorf("failed to create health data: %w", err)
+// findHealth returns a live health record by UUID
+func findHealth(db *gorm.DB, healthUUID string) (*models.Health, error) {
+	if _, err := uuid.Parse(healthUUID); err != nil {
+		return nil, ErrHealthNotFound
+	}
+	var health models.Health
+	if err := db.Where("uuid = ?", healthUUID).First(&health).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrHealthNotFound
 		}
+		return nil, fmt.Errorf("failed to fetch health data: %w", err)
+	}
+	return &health, nil
+}
 
-		return nil
-	})
-
+// AddHealth records a health check
+func (s *HealthService) AddHealth(payload *HealthPayload, username string) (*models.Health, error) {
+	health, err := buildHealth(payload)
 	if err != nil {
-		return nil, fmt.Errorf("transaction failed: %w", err)
+		return nil, err
 	}
+	health.CreatedBy = username
+	health.UpdatedBy = username
 
-	return &Health, nil
+	if err := config.DB.Create(health).Error; err != nil {
+		return nil, fmt.Errorf("failed to create health data: %w", err)
+	}
+	return health, nil
 }
 
 // GetHealthByUUID fetches a Health by UUID
 func (s *HealthService) GetHealthByUUID(uuid string) (*models.Health, error) {
-	var Health models.Health
+	return findHealth(config.DB, uuid)
+}
 
-	// Fetch the health data with RoleHasRules
-	if err := config.DB.
-		Preload("RoleHasRules").
-		Where("uuid = ?", uuid).
-		First(&Health).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("health data not found")
-		}
-		return nil, fmt.Errorf("failed to fetch health data: %w", err)
+// UpdateHealth replaces the details and measurements of a health check and assesses it again with the active rules.
+// A blank person_uuid keeps the person the check is linked to.
+func (s *HealthService) UpdateHealth(healthUUID string, payload *HealthPayload, username string) (*models.Health, error) {
+	if _, err := findHealth(config.DB, healthUUID); err != nil {
+		return nil, err
+	}
+	updated, err := buildHealth(payload)
+	if err != nil {
+		return nil, err
 	}
 
-	return &Health, nil
+	var health *models.Health
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// The record may have been deleted while the risk was assessed
+		if health, err = findHealth(tx, healthUUID); err != nil {
+			return err
+		}
+		if updated.HealthPersonID == nil {
+			updated.HealthPersonID = health.HealthPersonID
+		}
+		updated.ID = health.ID
+		updated.UUID = health.UUID
+		updated.CreatedAt = health.CreatedAt
+		updated.CreatedBy = health.CreatedBy
+		updated.UpdatedBy = username
+
+		if err := tx.Model(health).Select("HealthPersonID", "Nama", "JenisKelamin", "Umur", "Bb", "Tb", "Systol", "Diastol",
+			"HeartRate", "Profesi", "Risk", "RiskEngine", "RiskRuleVersion", "Bmi", "RecommendationFood",
+			"RecommendationSport", "RecommendationMedicine", "UpdatedBy").
+			Updates(updated).Error; err != nil {
+			return fmt.Errorf("failed to update health data: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return findHealth(config.DB, healthUUID)
 }
 
 // DeleteHealth soft deletes a health data by setting the deleted_at timestamp
-func (s *HealthService) DeleteHealth(uuid string) error {
-	currentTime := time.Now()
-
-	// Check if health data with UUID exists
-	var Health models.Health
-	if err := config.DB.Where("uuid = ?", uuid).First(&Health).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return fmt.Errorf("health data not found")
-		}
-		return errors.New("failed to check health data")
+func (s *HealthService) DeleteHealth(uuid string, username string) error {
+	health, err := findHealth(config.DB, uuid)
+	if err != nil {
+		return err
 	}
 
 	// Soft delete by setting deleted_at timestamp
-	if err := config.DB.Model(&Health).Update("deleted_at", currentTime).Error; err != nil {
+	if err := config.DB.Model(health).Updates(map[string]interface{}{
+		"deleted_at": time.Now(),
+		"deleted_by": username,
+	}).Error; err != nil {
 		return errors.New("failed to soft delete health data")
 	}
 
 	return nil
 }
+
+// RestoreHealth takes a deleted health check out of the trash
+func (s *HealthService) RestoreHealth(healthUUID string, username string) (*models.Health, error) {
+	if _, err := uuid.Parse(healthUUID); err != nil {
+		return nil, ErrHealthNotInTrash
+	}
+	result := config.DB.Unscoped().Model(&models.Health{}).
+		Where("uuid = ? AND deleted_at IS NOT NULL", healthUUID).
+		Updates(map[string]interface{}{
+			"deleted_at": nil,
+			"deleted_by": "",
+			"updated_by": username,
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to restore health data: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrHealthNotInTrash
+	}
+	return findHealth(config.DB, healthUUID)
+}
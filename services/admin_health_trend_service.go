@@ -0,0 +1,348 @@
+package services
+
+import (
+	"backend-school/config"
+	"backend-school/models"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// healthTrendMetric is a measurement followed over time. changeThreshold is the difference between two consecutive
+// checks that is reported as a change.
+type healthTrendMetric struct {
+	field           string
+	changeThreshold float64
+	value           func(h models.Health) *float64
+}
+
+func intMeasurement(v *int) *float64 {
+	if v == nil {
+		return nil
+	}
+	f := float64(*v)
+	return &f
+}
+
+var healthTrendMetrics = []healthTrendMetric{
+	{"bmi", 1, func(h models.Health) *float64 { return h.Bmi }},
+	{"bb", 3, func(h models.Health) *float64 { return h.Bb }},
+	{"systol", 10, func(h models.Health) *float64 { return intMeasurement(h.Systol) }},
+	{"diastol", 5, func(h models.Health) *float64 { return intMeasurement(h.Diastol) }},
+	{"heart_rate", 15, func(h models.Health) *float64 { return intMeasurement(h.HeartRate) }},
+}
+
+// HealthTrendPoint is one value of a metric
+type HealthTrendPoint struct {
+	HealthUUID uuid.UUID `json:"health_uuid"`
+	Date       time.Time `json:"date"`
+	Value      float64   `json:"value"`
+}
+
+// HealthMetricTrend summarises a metric over the checks of a person. SlopePerYear is the least-squares change per
+// year and needs checks on at least two different days.
+type HealthMetricTrend struct {
+	Metric             string             `json:"metric"`
+	Unit               string             `json:"unit"`
+	Points             []HealthTrendPoint `json:"points"`
+	First              *float64           `json:"first"`
+	Latest             *float64           `json:"latest"`
+	Min                *float64           `json:"min"`
+	Max                *float64           `json:"max"`
+	Mean               *float64           `json:"mean"`
+	ChangeFromPrevious *float64           `json:"change_from_previous"`
+	ChangeFromFirst    *float64           `json:"change_from_first"`
+	SlopePerYear       *float64           `json:"slope_per_year"`
+}
+
+// HealthBloodPressurePoint is a blood pressure reading with its category under the active risk rules
+type HealthBloodPressurePoint struct {
+	HealthUUID uuid.UUID `json:"health_uuid"`
+	Date       time.Time `json:"date"`
+	Systol     int       `json:"systol"`
+	Diastol    int       `json:"diastol"`
+	Category   string    `json:"category"`
+}
+
+// HealthChange is a notable difference between a check and the one before it
+type HealthChange struct {
+	HealthUUID uuid.UUID `json:"health_uuid"`
+	Date       time.Time `json:"date"`
+	Metric     string    `json:"metric"`
+	Kind       string    `json:"kind"` // "increase", "decrease" or "category"
+	From       string    `json:"from"`
+	To         string    `json:"to"`
+	Delta      *float64  `json:"delta,omitempty"`
+}
+
+// HealthTrendSummary is the trend of every metric of a person over a period
+type HealthTrendSummary struct {
+	Person        models.HealthPerson        `json:"person"`
+	Records       int                        `json:"records"`
+	From          *time.Time                 `json:"from"`
+	To            *time.Time                 `json:"to"`
+	Metrics       []HealthMetricTrend        `json:"metrics"`
+	BloodPressure []HealthBloodPressurePoint `json:"blood_pressure"`
+	Changes       []HealthChange             `json:"changes"`
+}
+
+// HealthTimeline is the health checks of a person, oldest first
+type HealthTimeline struct {
+	Person  models.HealthPerson `json:"person"`
+	Records []models.Health     `json:"records"`
+}
+
+func roundTrend(v float64) *float64 {
+	rounded := math.Round(v*100) / 100
+	return &rounded
+}
+
+// FindHealthPersonByUserID returns the person linked to a login
+func FindHealthPersonByUserID(userID int) (*models.HealthPerson, error) {
+	var person models.HealthPerson
+	if err := config.DB.Where("user_id = ?", userID).First(&person).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrHealthPersonNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch person: %w", err)
+	}
+	return &person, nil
+}
+
+// personHealthRecords returns the checks of a person between from and to (both optional and inclusive), oldest first
+func personHealthRecords(person *models.HealthPerson, from, to *time.Time) ([]models.Health, error) {
+	query := config.DB.Where("health_person_id = ?", person.ID)
+	if from != nil {
+		query = query.Where("created_at >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("created_at <= ?", *to)
+	}
+	var records []models.Health
+	if err := query.Order("created_at ASC").Order("id ASC").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch health data: %w", err)
+	}
+	return records, nil
+}
+
+// GetTimeline returns the checks of a person between from and to
+func (s *HealthPersonService) GetTimeline(person *models.HealthPerson, from, to *time.Time) (*HealthTimeline, error) {
+	records, err := personHealthRecords(person, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return &HealthTimeline{Person: *person, Records: records}, nil
+}
+
+// GetTrends summarises the metrics of a person between from and to and reports the changes between consecutive
+// checks: metrics that moved by at least their threshold, blood pressure category changes and risk level changes
+func (s *HealthPersonService) GetTrends(person *models.HealthPerson, from, to *time.Time) (*HealthTrendSummary, error) {
+	records, err := personHealthRecords(person, from, to)
+	if err != nil {
+		return nil, err
+	}
+	ruleSet, err := NewHealthRiskService().GetActiveRules()
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &HealthTrendSummary{
+		Person:        *person,
+		Records:       len(records),
+		From:          from,
+		To:            to,
+		Metrics:       make([]HealthMetricTrend, 0, len(healthTrendMetrics)),
+		BloodPressure: []HealthBloodPressurePoint{},
+		Changes:       []HealthChange{},
+	}
+
+	units := HealthMeasurementUnits()
+	for _, metric := range healthTrendMetrics {
+		trend := HealthMetricTrend{Metric: metric.field, Unit: units[metric.field], Points: []HealthTrendPoint{}}
+		var previous *HealthTrendPoint
+		for _, record := range records {
+			value := metric.value(record)
+			if value == nil {
+				continue
+			}
+			point := HealthTrendPoint{HealthUUID: record.UUID, Date: record.CreatedAt, Value: *value}
+			if previous != nil {
+				delta := point.Value - previous.Value
+				if math.Abs(delta) >= metric.changeThreshold {
+					kind := "increase"
+					if delta < 0 {
+						kind = "decrease"
+					}
+					summary.Changes = append(summary.Changes, HealthChange{
+						HealthUUID: record.UUID,
+						Date:       record.CreatedAt,
+						Metric:     metric.field,
+						Kind:       kind,
+						From:       formatMeasurement(previous.Value),
+						To:         formatMeasurement(point.Value),
+						Delta:      roundTrend(delta),
+					})
+				}
+			}
+			trend.Points = append(trend.Points, point)
+			previous = &trend.Points[len(trend.Points)-1]
+		}
+		summariseHealthTrend(&trend)
+		summary.Metrics = append(summary.Metrics, trend)
+	}
+
+	var previousBP *HealthBloodPressurePoint
+	var previousRisk *models.Health
+	for i, record := range records {
+		if record.Systol != nil && record.Diastol != nil {
+			category, _ := ruleSet.Rules.bloodPressureCategory(float64(*record.Systol), float64(*record.Diastol))
+			point := HealthBloodPressurePoint{
+				HealthUUID: record.UUID,
+				Date:       record.CreatedAt,
+				Systol:     *record.Systol,
+				Diastol:    *record.Diastol,
+				Category:   category,
+			}
+			if previousBP != nil && previousBP.Category != category {
+				summary.Changes = append(summary.Changes, HealthChange{
+					HealthUUID: record.UUID,
+					Date:       record.CreatedAt,
+					Metric:     "blood_pressure",
+					Kind:       "category",
+					From:       previousBP.Category,
+					To:         category,
+				})
+			}
+			summary.BloodPressure = append(summary.BloodPressure, point)
+			previousBP = &summary.BloodPressure[len(summary.BloodPressure)-1]
+		}
+		if record.Risk != "" {
+			if previousRisk != nil && previousRisk.Risk != record.Risk {
+				summary.Changes = append(summary.Changes, HealthChange{
+					HealthUUID: record.UUID,
+					Date:       record.CreatedAt,
+					Metric:     "risk",
+					Kind:       "category",
+					From:       previousRisk.Risk,
+					To:         record.Risk,
+				})
+			}
+			previousRisk = &records[i]
+		}
+	}
+	sort.SliceStable(summary.Changes, func(i, j int) bool {
+		return summary.Changes[i].Date.Before(summary.Changes[j].Date)
+	})
+	return summary, nil
+}
+
+// summariseHealthTrend fills the statistics of a trend from its points
+func summariseHealthTrend(trend *HealthMetricTrend) {
+	n := len(trend.Points)
+	if n == 0 {
+		return
+	}
+	first, latest := trend.Points[0], trend.Points[n-1]
+	minValue, maxValue, sum := first.Value, first.Value, 0.0
+	for _, point := range trend.Points {
+		minValue = math.Min(minValue, point.Value)
+		maxValue = math.Max(maxValue, point.Value)
+		sum += point.Value
+	}
+	trend.First = roundTrend(first.Value)
+	trend.Latest = roundTrend(latest.Value)
+	trend.Min = roundTrend(minValue)
+	trend.Max = roundTrend(maxValue)
+	trend.Mean = roundTrend(sum / float64(n))
+	if n < 2 {
+		return
+	}
+	trend.ChangeFromPrevious = roundTrend(latest.Value - trend.Points[n-2].Value)
+	trend.ChangeFromFirst = roundTrend(latest.Value - first.Value)
+
+	// Least-squares slope with time measured in years since the first check
+	const hoursPerYear = 365.25 * 24
+	var sumX, sumY, sumXY, sumXX float64
+	for _, point := range trend.Points {
+		x := point.Date.Sub(first.Date).Hours() / hoursPerYear
+		sumX += x
+		sumY += point.Value
+		sumXY += x * point.Value
+		sumXX += x * x
+	}
+	denominator := float64(n)*sumXX - sumX*sumX
+	if latest.Date.Sub(first.Date) >= 24*time.Hour && denominator != 0 {
+		trend.SlopePerYear = roundTrend((float64(n)*sumXY - sumX*sumY) / denominator)
+	}
+}
+
+// HealthDashboardBucket counts the checks with a value of a dimension
+type HealthDashboardBucket struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// HealthDashboardCell counts the checks of a profession, gender and risk combination
+type HealthDashboardCell struct {
+	Profesi      string `json:"profesi"`
+	JenisKelamin string `json:"jenis_kelamin"`
+	Risk         string `json:"risk"`
+	Count        int64  `json:"count"`
+}
+
+// HealthDashboard aggregates health checks by profession, gender and risk level
+type HealthDashboard struct {
+	LatestOnly   bool                    `json:"latest_only"`
+	Total        int64                   `json:"total"`
+	ByProfession []HealthDashboardBucket `json:"by_profession"`
+	ByGender     []HealthDashboardBucket `json:"by_gender"`
+	ByRisk       []HealthDashboardBucket `json:"by_risk"`
+	Breakdown    []HealthDashboardCell   `json:"breakdown"`
+}
+
+// GetDashboard aggregates the health checks. With latestOnly each person counts once, with their latest check;
+// checks that are not linked to a person always count.
+func (s *HealthService) GetDashboard(latestOnly bool) (*HealthDashboard, error) {
+	base := func() *gorm.DB {
+		query := config.DB.Model(&models.Health{})
+		if latestOnly {
+			query = query.Where("health_person_id IS NULL OR id IN (?)",
+				config.DB.Model(&models.Health{}).Select("MAX(id)").Where("health_person_id IS NOT NULL").Group("health_person_id"))
+		}
+		return query
+	}
+
+	dashboard := &HealthDashboard{LatestOnly: latestOnly}
+	if err := base().Count(&dashboard.Total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count health data: %w", err)
+	}
+
+	for _, dimension := range []struct {
+		column  string
+		buckets *[]HealthDashboardBucket
+	}{{"profesi", &dashboard.ByProfession}, {"jenis_kelamin", &dashboard.ByGender}, {"risk", &dashboard.ByRisk}} {
+		*dimension.buckets = []HealthDashboardBucket{}
+		if err := base().
+			Select("COALESCE(" + dimension.column + ", '') AS value, COUNT(*) AS count").
+			Group("COALESCE(" + dimension.column + ", '')").
+			Order("count DESC").Order("value ASC").
+			Scan(dimension.buckets).Error; err != nil {
+			return nil, fmt.Errorf("failed to aggregate health data by %s: %w", dimension.column, err)
+		}
+	}
+
+	dashboard.Breakdown = []HealthDashboardCell{}
+	if err := base().
+		Select("COALESCE(profesi, '') AS profesi, COALESCE(jenis_kelamin, '') AS jenis_kelamin, COALESCE(risk, '') AS risk, COUNT(*) AS count").
+		Group("COALESCE(profesi, ''), COALESCE(jenis_kelamin, ''), COALESCE(risk, '')").
+		Order("profesi ASC").Order("jenis_kelamin ASC").Order("risk ASC").
+		Scan(&dashboard.Breakdown).Error; err != nil {
+		return nil, fmt.Errorf("failed to aggregate health data: %w", err)
+	}
+	return dashboard, nil
+}
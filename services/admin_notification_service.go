@@ -0,0 +1,376 @@
+package services
+
+import (
+	"backend-school/config"
+	"backend-school/models"
+	"backend-school/templates"
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"math"
+	"strings"
+	texttemplate "text/template"
+)
+
+// ErrNotificationTemplateNotFound is returned when a notification template does not exist
+var ErrNotificationTemplateNotFound = errors.New("notification template not found")
+
+// NotificationService renders event templates and delivers notifications through the email outbox
+type NotificationService struct {
+	EmailService    *EmailService
+	SettingsService *SettingsService
+}
+
+// RenderedNotification is a template executed with event data
+type RenderedNotification struct {
+	Event   string `json:"event"`
+	Locale  string `json:"locale"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// NotificationPreviewRequest renders either the stored template of Event/Locale or,
+// when Subject and Body are given, an unsaved draft. Data defaults to the event's sample data.
+type NotificationPreviewRequest struct {
+	Event   string                 `json:"event"`
+	Locale  string                 `json:"locale"`
+	Subject string                 `json:"subject"`
+	Body    string                 `json:"body"`
+	Data    map[string]interface{} `json:"data"`
+	To      string                 `json:"to"` // recipient, only used by test-send
+}
+
+// NotificationTemplatePayload is the editable part of a notification template
+type NotificationTemplatePayload struct {
+	Subject  string `json:"subject"`
+	Body     string `json:"body"`
+	IsActive *bool  `json:"is_active"`
+}
+
+func NewNotificationService(emailService *EmailService, settingsService *SettingsService) *NotificationService {
+	return &NotificationService{EmailService: emailService, SettingsService: settingsService}
+}
+
+// newDefaultNotificationService builds a NotificationService for callers without one injected
+func newDefaultNotificationService() *NotificationService {
+	settingsService := NewSettingsService()
+	return NewNotificationService(NewEmailService(settingsService), settingsService)
+}
+
+// IsKnownNotificationEvent reports whether event has a built-in template
+func IsKnownNotificationEvent(event string) bool {
+	_, ok := templates.DefaultNotificationTemplates[event]
+	return ok
+}
+
+// SyncDefaultTemplates stores the built-in template of every event that has none in the default locale
+func (s *NotificationService) SyncDefaultTemplates() error {
+	for event, defaults := range templates.DefaultNotificationTemplates {
+		template := models.NotificationTemplate{
+			Event:    event,
+			Locale:   models.DefaultNotificationLocale,
+			Subject:  strings.TrimSpace(defaults.Subject),
+			Body:     strings.TrimSpace(defaults.Body),
+			IsActive: true,
+		}
+		if err := config.DB.Where("event = ? AND locale = ?", event, template.Locale).
+			FirstOrCreate(&template).Error; err != nil {
+			return fmt.Errorf("failed to seed notification template %s: %w", event, err)
+		}
+	}
+	return nil
+}
+
+// defaultLocale returns the notification_default_locale setting or DefaultNotificationLocale
+func (s *NotificationService) defaultLocale() string {
+	locale, err := s.SettingsService.GetSetting("notification_default_locale")
+	if err != nil || locale == "" {
+		return models.DefaultNotificationLocale
+	}
+	return locale
+}
+
+// localeCandidates lists the locales to try in order, e.g. "id-ID" -> id-ID, id, <default>, en
+func (s *NotificationService) localeCandidates(locale string) []string {
+	var candidates []string
+	seen := make(map[string]bool)
+	add := func(value string) {
+		value = strings.TrimSpace(value)
+		if value != "" && !seen[value] {
+			seen[value] = true
+			candidates = append(candidates, value)
+		}
+	}
+
+	add(locale)
+	if base, _, found := strings.Cut(locale, "-"); found {
+		add(base)
+	}
+	add(s.defaultLocale())
+	add(models.DefaultNotificationLocale)
+	return candidates
+}
+
+// findTemplate returns the best active template for event and locale, falling back to the built-in default
+func (s *NotificationService) findTemplate(event, locale string) (*models.NotificationTemplate, error) {
+	defaults, ok := templates.DefaultNotificationTemplates[event]
+	if !ok {
+		return nil, fmt.Errorf("unknown notification event: %s", event)
+	}
+
+	candidates := s.localeCandidates(locale)
+	var stored []models.NotificationTemplate
+	if err := config.DB.Where("event = ? AND locale IN ? AND is_active = ?", event, candidates, true).
+		Find(&stored).Error; err != nil {
+		return nil, fmt.Errorf("failed to load notification template: %w", err)
+	}
+
+	for _, candidate := range candidates {
+		for i := range stored {
+			if stored[i].Locale == candidate {
+				return &stored[i], nil
+			}
+		}
+	}
+
+	return &models.NotificationTemplate{
+		Event:   event,
+		Locale:  models.DefaultNotificationLocale,
+		Subject: defaults.Subject,
+		Body:    defaults.Body,
+	}, nil
+}
+
+// renderTemplate executes a subject (text/template) and body (html/template) with data
+func renderTemplate(subjectTemplate, bodyTemplate string, data map[string]interface{}) (string, string, error) {
+	subjectTpl, err := texttemplate.New("subject").Option("missingkey=zero").Parse(subjectTemplate)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid subject template: %w", err)
+	}
+	bodyTpl, err := htmltemplate.New("body").Option("missingkey=zero").Parse(bodyTemplate)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid body template: %w", err)
+	}
+
+	var subject, body bytes.Buffer
+	if err := subjectTpl.Execute(&subject, data); err != nil {
+		return "", "", fmt.Errorf("failed to render subject: %w", err)
+	}
+	if err := bodyTpl.Execute(&body, data); err != nil {
+		return "", "", fmt.Errorf("failed to render body: %w", err)
+	}
+	return strings.TrimSpace(subject.String()), body.String(), nil
+}
+
+// Render executes the template of event in locale with data
+func (s *NotificationService) Render(event, locale string, data map[string]interface{}) (*RenderedNotification, error) {
+	template, err := s.findTemplate(event, locale)
+	if err != nil {
+		return nil, err
+	}
+
+	subject, body, err := renderTemplate(template.Subject, template.Body, data)
+	if err != nil {
+		return nil, err
+	}
+	return &RenderedNotification{Event: event, Locale: template.Locale, Subject: subject, Body: body}, nil
+}
+
+// Notify renders the template of event and queues it for delivery to the recipient
+func (s *NotificationService) Notify(event, locale, to string, data map[string]interface{}) error {
+	rendered, err := s.Render(event, locale, data)
+	if err != nil {
+		return err
+	}
+	if _, err := s.EmailService.EnqueueEmail(to, rendered.Subject, rendered.Body, "text/html"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// NotifyBestEffort is Notify for callers that must not fail when a notification cannot be queued; errors are logged
+func (s *NotificationService) NotifyBestEffort(event, locale, to string, data map[string]interface{}) {
+	if err := s.Notify(event, locale, to, data); err != nil {
+		log.Printf("Failed to queue %s notification for %s: %v", event, to, err)
+	}
+}
+
+// validateNotificationTemplate checks the event and that both templates parse
+func validateNotificationTemplate(template *models.NotificationTemplate) error {
+	if !IsKnownNotificationEvent(template.Event) {
+		return fmt.Errorf("unknown notification event: %s", template.Event)
+	}
+	if template.Locale == "" {
+		template.Locale = models.DefaultNotificationLocale
+	}
+	if _, err := texttemplate.New("subject").Parse(template.Subject); err != nil {
+		return fmt.Errorf("invalid subject template: %w", err)
+	}
+	if _, err := htmltemplate.New("body").Parse(template.Body); err != nil {
+		return fmt.Errorf("invalid body template: %w", err)
+	}
+	return nil
+}
+
+// GetTemplatesPaginated lists notification templates, optionally filtered by event
+func (s *NotificationService) GetTemplatesPaginated(perPage, page int, event string) (map[string]interface{}, error) {
+	var notificationTemplates []models.NotificationTemplate
+	var totalRecords int64
+
+	// Calculate offset for pagination
+	offset := (page - 1) * perPage
+
+	query := config.DB.Model(&models.NotificationTemplate{})
+	if event != "" {
+		query = query.Where("event = ?", event)
+	}
+
+	// Get the total number of records
+	query.Count(&totalRecords)
+
+	if err := query.Order("event ASC, locale ASC").Limit(perPage).Offset(offset).Find(&notificationTemplates).Error; err != nil {
+		return nil, errors.New("failed to fetch notification templates")
+	}
+
+	// Calculate total pages
+	totalPages := int(math.Ceil(float64(totalRecords) / float64(perPage)))
+
+	return map[string]interface{}{
+		"data":          notificationTemplates,
+		"current_page":  page,
+		"per_page":      perPage,
+		"total_pages":   totalPages,
+		"total_records": totalRecords,
+	}, nil
+}
+
+// GetTemplateByUUID returns a notification template by UUID
+func (s *NotificationService) GetTemplateByUUID(uuid string) (*models.NotificationTemplate, error) {
+	var template models.NotificationTemplate
+	if err := config.DB.Where("uuid = ?", uuid).First(&template).Error; err != nil {
+		return nil, ErrNotificationTemplateNotFound
+	}
+	return &template, nil
+}
+
+// CreateTemplate stores a template for an event and locale that don't have one yet
+func (s *NotificationService) CreateTemplate(template *models.NotificationTemplate) error {
+	if err := validateNotificationTemplate(template); err != nil {
+		return err
+	}
+
+	var count int64
+	config.DB.Model(&models.NotificationTemplate{}).Where("event = ? AND locale = ?", template.Event, template.Locale).Count(&count)
+	if count > 0 {
+		return errors.New("a template for this event and locale already exists")
+	}
+
+	if err := config.DB.Create(template).Error; err != nil {
+		return fmt.Errorf("failed to create notification template: %w", err)
+	}
+	return nil
+}
+
+// UpdateTemplate replaces the subject and body of a template and optionally its active flag
+func (s *NotificationService) UpdateTemplate(uuid string, payload NotificationTemplatePayload, updatedBy int) (*models.NotificationTemplate, error) {
+	template, err := s.GetTemplateByUUID(uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.Subject != "" {
+		template.Subject = payload.Subject
+	}
+	if payload.Body != "" {
+		template.Body = payload.Body
+	}
+	if payload.IsActive != nil {
+		template.IsActive = *payload.IsActive
+	}
+	template.UpdatedBy = updatedBy
+	if err := validateNotificationTemplate(template); err != nil {
+		return nil, err
+	}
+
+	if err := config.DB.Model(template).Select("subject", "body", "is_active", "updated_by", "updated_at").Updates(template).Error; err != nil {
+		return nil, errors.New("failed to update notification template")
+	}
+	return template, nil
+}
+
+// DeleteTemplate removes a template; the event falls back to another locale or its built-in default
+func (s *NotificationService) DeleteTemplate(uuid string) error {
+	result := config.DB.Where("uuid = ?", uuid).Delete(&models.NotificationTemplate{})
+	if result.Error != nil {
+		return errors.New("failed to delete notification template")
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotificationTemplateNotFound
+	}
+	return nil
+}
+
+// Preview renders a stored template or an unsaved draft without sending anything
+func (s *NotificationService) Preview(req NotificationPreviewRequest) (*RenderedNotification, error) {
+	defaults, ok := templates.DefaultNotificationTemplates[req.Event]
+	if !ok {
+		return nil, fmt.Errorf("unknown notification event: %s", req.Event)
+	}
+
+	data := req.Data
+	if data == nil {
+		data = defaults.SampleData
+	}
+
+	if req.Subject != "" && req.Body != "" {
+		subject, body, err := renderTemplate(req.Subject, req.Body, data)
+		if err != nil {
+			return nil, err
+		}
+		return &RenderedNotification{Event: req.Event, Locale: req.Locale, Subject: subject, Body: body}, nil
+	}
+	return s.Render(req.Event, req.Locale, data)
+}
+
+// TestSend renders a preview and queues it for delivery to req.To
+func (s *NotificationService) TestSend(req NotificationPreviewRequest) (*models.EmailOutbox, error) {
+	if req.To == "" {
+		return nil, errors.New("recipient is required")
+	}
+
+	rendered, err := s.Preview(req)
+	if err != nil {
+		return nil, err
+	}
+	return s.EmailService.EnqueueEmail(req.To, "[Test] "+rendered.Subject, rendered.Body, "text/html")
+}
+
+// notifyDocumentStatus notifies the creator of a document when it moves to an approved or rejected status
+func (s *NotificationService) notifyDocumentStatus(document models.DocumentControl, statusName string) {
+	var event string
+	switch strings.ToLower(strings.TrimSpace(statusName)) {
+	case "approved":
+		event = models.NotificationEventDocumentApproved
+	case "rejected":
+		event = models.NotificationEventDocumentRejected
+	default:
+		return
+	}
+	if document.CreatedBy == nil {
+		return
+	}
+
+	var creator models.UserRegister
+	if err := config.DB.Where("id = ?", *document.CreatedBy).First(&creator).Error; err != nil || creator.Email == "" {
+		return
+	}
+
+	s.NotifyBestEffort(event, "", creator.Email, map[string]interface{}{
+		"DocumentName":   document.DocumentName,
+		"DocumentNumber": document.DocumentNumber,
+		"RevisionNumber": document.RevisionNumber,
+		"Status":         statusName,
+	})
+}
@@ -0,0 +1,574 @@
+package services
+
+import (
+	"backend-school/config"
+	"backend-school/helpers"
+	"backend-school/models"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PolicyRule identifies a single permission as it is stored in role_has_rule and casbin_rule
+type PolicyRule struct {
+	RoleGuardName string `json:"role_guard_name"`
+	RulePolicy    string `json:"rule_policy"`
+	Action        string `json:"action"`
+	Category      string `json:"category"`
+	Type          string `json:"type"`
+}
+
+// PolicyChange marks a rule as active (present in casbin_rule) or inactive
+type PolicyChange struct {
+	PolicyRule
+	Active bool `json:"active"`
+}
+
+// PolicyDiff lists the rules that only exist on one side of role_has_rule and casbin_rule
+type PolicyDiff struct {
+	OnlyInCasbinRule  []PolicyRule `json:"only_in_casbin_rule"`
+	OnlyInRoleHasRule []PolicyRule `json:"only_in_role_has_rule"`
+	Repaired          int          `json:"repaired"`
+	CheckedAt         time.Time    `json:"checked_at"`
+}
+
+// PolicyService keeps role_has_rule and casbin_rule in sync by writing both in one transaction
+type PolicyService struct{}
+
+// policyMu serializes policy writes so the enforcer is never reloaded halfway through another write
+var policyMu sync.Mutex
+
+// NewPolicyService creates a new instance of PolicyService.
+func NewPolicyService() *PolicyService {
+	return &PolicyService{}
+}
+
+// normalizePolicyField maps empty category/type values to the "none" placeholder used by the Casbin model
+func normalizePolicyField(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
+
+func (r PolicyRule) normalized() PolicyRule {
+	r.Category = normalizePolicyField(r.Category)
+	r.Type = normalizePolicyField(r.Type)
+	return r
+}
+
+func (r PolicyRule) key() string {
+	n := r.normalized()
+	return fmt.Sprintf("%s|%s|%s|%s|%s", n.RoleGuardName, n.RulePolicy, n.Action, n.Category, n.Type)
+}
+
+// ParsePolicyPayload converts the `rules`/`permissions` payload used by the admin API into policy changes
+func ParsePolicyPayload(roleGuardName string, rules []map[string]interface{}) ([]PolicyChange, error) {
+	var changes []PolicyChange
+
+	for _, ruleData := range rules {
+		rulePolicy, ok := ruleData["rule_policy"].(string)
+		if !ok || rulePolicy == "" {
+			return nil, errors.New("invalid or missing rule_policy")
+		}
+
+		actions, ok := ruleData["action"].(map[string]interface{})
+		if !ok {
+			return nil, errors.New("invalid or missing actions for rule")
+		}
+
+		category, _ := ruleData["category"].(string)
+		typeCR, _ := ruleData["type"].(string)
+
+		for action, enabled := range actions {
+			isEnabled, ok := enabled.(bool)
+			if !ok {
+				return nil, errors.New("action value must be boolean")
+			}
+
+			changes = append(changes, PolicyChange{
+				PolicyRule: PolicyRule{
+					RoleGuardName: roleGuardName,
+					RulePolicy:    rulePolicy,
+					Action:        action,
+					Category:      category,
+					Type:          typeCR,
+				},
+				Active: isEnabled,
+			})
+		}
+	}
+
+	return changes, nil
+}
+
+// casbinRuleScope scopes a query to the casbin_rule rows of a policy, treating empty v3/v4/v5 as "none". Rules are
+// never tied to a single document, so policies with a docid in v5 are left out.
+func casbinRuleScope(tx *gorm.DB, rule PolicyRule) *gorm.DB {
+	n := rule.normalized()
+	return tx.Model(&models.CasbinRule{}).
+		Where("ptype = ? AND v0 = ? AND v1 = ? AND v2 = ?", "p", n.RoleGuardName, n.RulePolicy, n.Action).
+		Where("COALESCE(NULLIF(v3, ''), 'none') = ? AND COALESCE(NULLIF(v4, ''), 'none') = ?", n.Category, n.Type).
+		Where("COALESCE(NULLIF(v5, ''), 'none') = 'none'")
+}
+
+// roleHasRulePolicy returns the policy a role_has_rule row stands for
+func roleHasRulePolicy(rule models.RoleHasRule) PolicyRule {
+	return PolicyRule{
+		RoleGuardName: rule.RoleGuardName,
+		RulePolicy:    rule.RulePolicy,
+		Action:        rule.Action,
+		Category:      rule.Category,
+		Type:          rule.Type,
+	}
+}
+
+// ensureRoleHasRule creates the role_has_rule row for a policy if it doesn't exist yet
+func ensureRoleHasRule(tx *gorm.DB, rule PolicyRule) (*models.RoleHasRule, bool, error) {
+	n := rule.normalized()
+	var existing models.RoleHasRule
+	err := tx.Where("role_guard_name = ? AND rule_policy = ? AND action = ?", n.RoleGuardName, n.RulePolicy, n.Action).
+		Where("COALESCE(NULLIF(category, ''), 'none') = ? AND COALESCE(NULLIF(type, ''), 'none') = ?", n.Category, n.Type).
+		First(&existing).Error
+	if err == nil {
+		return &existing, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, err
+	}
+
+	created := models.RoleHasRule{
+		RoleGuardName: rule.RoleGuardName,
+		RulePolicy:    rule.RulePolicy,
+		Action:        rule.Action,
+		Category:      rule.Category,
+		Type:          rule.Type,
+	}
+	if err := tx.Create(&created).Error; err != nil {
+		return nil, false, err
+	}
+	return &created, true, nil
+}
+
+// setCasbinRuleActive inserts or removes the casbin_rule row of a policy and reports whether anything changed
+func setCasbinRuleActive(tx *gorm.DB, rule PolicyRule, active bool) (bool, error) {
+	var count int64
+	if err := casbinRuleScope(tx, rule).Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	if active {
+		if count > 0 {
+			return false, nil
+		}
+		n := rule.normalized()
+		casbinRule := models.CasbinRule{
+			Ptype: "p",
+			V0:    n.RoleGuardName,
+			V1:    n.RulePolicy,
+			V2:    n.Action,
+			V3:    n.Category,
+			V4:    n.Type,
+			V5:    "none",
+		}
+		if err := tx.Create(&casbinRule).Error; err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	if count == 0 {
+		return false, nil
+	}
+	if err := casbinRuleScope(tx, rule).Delete(&models.CasbinRule{}).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// deleteRoleHasRule deletes a role_has_rule row together with its conditions and casbin_rule policy
+func deleteRoleHasRule(tx *gorm.DB, rule models.RoleHasRule) error {
+	if _, err := setCasbinRuleActive(tx, roleHasRulePolicy(rule), false); err != nil {
+		return err
+	}
+	if err := tx.Where("role_has_rule_id = ?", rule.ID).Delete(&models.RoleHasRuleCondition{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&rule).Error
+}
+
+// setDocumentRules makes rules the active "document" policies of a category and type prefix (empty for the rules
+// of a whole category). existing are the rules the category or type had; those missing from rules are deleted.
+func setDocumentRules(tx *gorm.DB, category, typ string, existing []models.RoleHasRule, rules []RoleHasRulePayload) error {
+	kept := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		policy := PolicyRule{
+			RoleGuardName: rule.RoleGuardName,
+			RulePolicy:    "document",
+			Action:        rule.Action,
+			Category:      category,
+			Type:          typ,
+		}
+		if _, _, err := ensureRoleHasRule(tx, policy); err != nil {
+			return fmt.Errorf("failed to create role has rule: %w", err)
+		}
+		if _, err := setCasbinRuleActive(tx, policy, true); err != nil {
+			return fmt.Errorf("failed to create casbin rule: %w", err)
+		}
+		kept[policy.key()] = true
+	}
+
+	for _, rule := range existing {
+		if kept[roleHasRulePolicy(rule).key()] {
+			continue
+		}
+		if err := deleteRoleHasRule(tx, rule); err != nil {
+			return fmt.Errorf("failed to delete obsolete role rule: %w", err)
+		}
+	}
+	return nil
+}
+
+// renameDocumentCategoryRules moves the "document" policies of a category, those of its types included, to a new
+// prefix in role_has_rule and casbin_rule alike
+func renameDocumentCategoryRules(tx *gorm.DB, from, to string) error {
+	if from == to {
+		return nil
+	}
+	if err := tx.Model(&models.RoleHasRule{}).Where("rule_policy = ? AND category = ?", "document", from).
+		Update("category", to).Error; err != nil {
+		return fmt.Errorf("failed to rename role rules: %w", err)
+	}
+	if err := tx.Model(&models.CasbinRule{}).Where("ptype = ? AND v1 = ? AND v3 = ?", "p", "document", from).
+		Update("v3", to).Error; err != nil {
+		return fmt.Errorf("failed to rename casbin rules: %w", err)
+	}
+	return nil
+}
+
+// renameDocumentTypeRules moves the "document" policies of a type to a new category and type prefix in
+// role_has_rule and casbin_rule alike
+func renameDocumentTypeRules(tx *gorm.DB, fromCategory, fromType, toCategory, toType string) error {
+	if fromCategory == toCategory && fromType == toType {
+		return nil
+	}
+	if err := tx.Model(&models.RoleHasRule{}).
+		Where("rule_policy = ? AND category = ? AND type = ?", "document", fromCategory, fromType).
+		Updates(map[string]interface{}{"category": toCategory, "type": toType}).Error; err != nil {
+		return fmt.Errorf("failed to rename role rules: %w", err)
+	}
+	if err := tx.Model(&models.CasbinRule{}).
+		Where("ptype = ? AND v1 = ? AND v3 = ? AND v4 = ?", "p", "document", fromCategory, fromType).
+		Updates(map[string]interface{}{"v3": toCategory, "v4": toType}).Error; err != nil {
+		return fmt.Errorf("failed to rename casbin rules: %w", err)
+	}
+	return nil
+}
+
+// withPolicyTransaction runs fn in a single DB transaction and reloads the enforcer once it commits
+func withPolicyTransaction(fn func(tx *gorm.DB) error) error {
+	policyMu.Lock()
+	defer policyMu.Unlock()
+
+	if err := config.DB.Transaction(fn); err != nil {
+		return err
+	}
+
+	// Refresh the in-memory policies and conditions now that both tables are committed
+	helpers.ReloadCasbinPolicy()
+	return nil
+}
+
+// CreateRoleWithRules creates the role if needed and grants every active rule in one transaction.
+// It returns the role_has_rule rows that were newly created.
+func (s *PolicyService) CreateRoleWithRules(roleGuardName string, roleName string, changes []PolicyChange) ([]models.RoleHasRule, error) {
+	var createdRules []models.RoleHasRule
+
+	err := withPolicyTransaction(func(tx *gorm.DB) error {
+		var role models.Role
+		if err := tx.Where("guard_name = ?", roleGuardName).First(&role).Error; err != nil {
+			role = models.Role{
+				Name:      roleName,
+				GuardName: roleGuardName,
+			}
+			if err := tx.Create(&role).Error; err != nil {
+				return errors.New("failed to create role in roles table")
+			}
+		}
+
+		for _, change := range changes {
+			if !change.Active {
+				continue
+			}
+
+			rule, created, err := ensureRoleHasRule(tx, change.PolicyRule)
+			if err != nil {
+				return errors.New("failed to create rule in role_has_rules table")
+			}
+			if created {
+				createdRules = append(createdRules, *rule)
+			} else {
+				log.Printf("Rule with this role_guard_name, rule_policy, and action already exists: %s, %s, %s", change.RoleGuardName, change.RulePolicy, change.Action)
+			}
+
+			if _, err := setCasbinRuleActive(tx, change.PolicyRule, true); err != nil {
+				return errors.New("failed to add policy to Casbin")
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return createdRules, nil
+}
+
+// ApplyChanges records every rule in role_has_rule and activates or deactivates it in casbin_rule in one transaction
+func (s *PolicyService) ApplyChanges(changes []PolicyChange) error {
+	return withPolicyTransaction(func(tx *gorm.DB) error {
+		for _, change := range changes {
+			if _, _, err := ensureRoleHasRule(tx, change.PolicyRule); err != nil {
+				log.Printf("Failed to create rule in role_has_rule for %s on %s with action %s: %v", change.RoleGuardName, change.RulePolicy, change.Action, err)
+				return err
+			}
+
+			if _, err := setCasbinRuleActive(tx, change.PolicyRule, change.Active); err != nil {
+				log.Printf("Failed to set '%s' rule for %s on %s active=%t: %v", change.Action, change.RoleGuardName, change.RulePolicy, change.Active, err)
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SetRuleActive activates or deactivates a single rule and reports whether casbin_rule changed
+func (s *PolicyService) SetRuleActive(rule PolicyRule, active bool) (bool, error) {
+	var changed bool
+
+	err := withPolicyTransaction(func(tx *gorm.DB) error {
+		if _, _, err := ensureRoleHasRule(tx, rule); err != nil {
+			return err
+		}
+
+		var err error
+		changed, err = setCasbinRuleActive(tx, rule, active)
+		return err
+	})
+
+	return changed, err
+}
+
+// UpdateRoleHasRule updates a role_has_rule row and moves its casbin_rule policy along with it
+func (s *PolicyService) UpdateRoleHasRule(uuidStr string, rule PolicyRule) (*models.RoleHasRule, error) {
+	var roleHasRule models.RoleHasRule
+
+	uuidVal, err := uuid.Parse(uuidStr)
+	if err != nil {
+		return nil, errors.New("invalid UUID format")
+	}
+
+	err = withPolicyTransaction(func(tx *gorm.DB) error {
+		if err := tx.Where("uuid = ?", uuidVal).First(&roleHasRule).Error; err != nil {
+			return errors.New("role_has_rule not found")
+		}
+
+		previous := roleHasRulePolicy(roleHasRule)
+
+		roleHasRule.RoleGuardName = rule.RoleGuardName
+		roleHasRule.RulePolicy = rule.RulePolicy
+		roleHasRule.Action = rule.Action
+		if err := tx.Save(&roleHasRule).Error; err != nil {
+			return err
+		}
+
+		// Carry the active state of the old policy over to the new one
+		rule.Category = roleHasRule.Category
+		rule.Type = roleHasRule.Type
+		wasActive, err := setCasbinRuleActive(tx, previous, false)
+		if err != nil {
+			return err
+		}
+		if wasActive {
+			if _, err := setCasbinRuleActive(tx, rule, true); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &roleHasRule, nil
+}
+
+// DeleteRoleHasRule deletes a role_has_rule row together with its casbin_rule policy
+func (s *PolicyService) DeleteRoleHasRule(uuidStr string) error {
+	uuidVal, err := uuid.Parse(uuidStr)
+	if err != nil {
+		return errors.New("invalid UUID format")
+	}
+
+	return withPolicyTransaction(func(tx *gorm.DB) error {
+		var roleHasRule models.RoleHasRule
+		if err := tx.Where("uuid = ?", uuidVal).First(&roleHasRule).Error; err != nil {
+			return errors.New("role_has_rule not found")
+		}
+
+		return deleteRoleHasRule(tx, roleHasRule)
+	})
+}
+
+// DiffPolicies compares role_has_rule with the "p" rows of casbin_rule
+func (s *PolicyService) DiffPolicies() (*PolicyDiff, error) {
+	return diffPolicies(config.DB)
+}
+
+func diffPolicies(db *gorm.DB) (*PolicyDiff, error) {
+	var roleRules []models.RoleHasRule
+	if err := db.Find(&roleRules).Error; err != nil {
+		return nil, err
+	}
+
+	var casbinRules []models.CasbinRule
+	if err := db.Where("ptype = ?", "p").Find(&casbinRules).Error; err != nil {
+		return nil, err
+	}
+
+	roleKeys := make(map[string]bool)
+	for _, rule := range roleRules {
+		roleKeys[roleHasRulePolicy(rule).key()] = true
+	}
+
+	casbinKeys := make(map[string]bool)
+	diff := &PolicyDiff{
+		OnlyInCasbinRule:  []PolicyRule{},
+		OnlyInRoleHasRule: []PolicyRule{},
+		CheckedAt:         time.Now(),
+	}
+	for _, rule := range casbinRules {
+		// Policies for a single document have no role_has_rule counterpart
+		if normalizePolicyField(rule.V5) != "none" {
+			continue
+		}
+		policy := PolicyRule{
+			RoleGuardName: rule.V0,
+			RulePolicy:    rule.V1,
+			Action:        rule.V2,
+			Category:      rule.V3,
+			Type:          rule.V4,
+		}.normalized()
+		casbinKeys[policy.key()] = true
+		if !roleKeys[policy.key()] {
+			diff.OnlyInCasbinRule = append(diff.OnlyInCasbinRule, policy)
+		}
+	}
+
+	for _, rule := range roleRules {
+		policy := roleHasRulePolicy(rule).normalized()
+		if !casbinKeys[policy.key()] {
+			diff.OnlyInRoleHasRule = append(diff.OnlyInRoleHasRule, policy)
+		}
+	}
+
+	return diff, nil
+}
+
+// ReconcilePolicies repairs drift by recording every active Casbin policy in role_has_rule.
+// Rules that only exist in role_has_rule are inactive rules and are left untouched.
+func (s *PolicyService) ReconcilePolicies() (*PolicyDiff, error) {
+	var diff *PolicyDiff
+
+	err := withPolicyTransaction(func(tx *gorm.DB) error {
+		var err error
+		diff, err = diffPolicies(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, rule := range diff.OnlyInCasbinRule {
+			if _, created, err := ensureRoleHasRule(tx, rule); err != nil {
+				return err
+			} else if created {
+				diff.Repaired++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return diff, nil
+}
+
+// StartPolicyReconciler periodically repairs drift between role_has_rule and casbin_rule
+func StartPolicyReconciler(interval time.Duration) {
+	service := NewPolicyService()
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+		for range ticker.C {
+			diff, err := service.ReconcilePolicies()
+			if err != nil {
+				log.Printf("Policy reconciliation failed: %v", err)
+				continue
+			}
+			if diff.Repaired > 0 {
+				log.Printf("Policy reconciliation repaired %d rule(s)", diff.Repaired)
+			}
+		}
+	}()
+}
+
+// SetRuleConditions replaces the ABAC conditions of a role_has_rule
+func (s *PolicyService) SetRuleConditions(uuidStr string, conditions []models.RoleHasRuleCondition) (*models.RoleHasRule, error) {
+	uuidVal, err := uuid.Parse(uuidStr)
+	if err != nil {
+		return nil, errors.New("invalid UUID format")
+	}
+
+	for _, condition := range conditions {
+		if err := helpers.ValidatePolicyCondition(condition); err != nil {
+			return nil, err
+		}
+	}
+
+	var roleHasRule models.RoleHasRule
+	err = withPolicyTransaction(func(tx *gorm.DB) error {
+		if err := tx.Where("uuid = ?", uuidVal).First(&roleHasRule).Error; err != nil {
+			return errors.New("role_has_rule not found")
+		}
+
+		if err := tx.Where("role_has_rule_id = ?", roleHasRule.ID).Delete(&models.RoleHasRuleCondition{}).Error; err != nil {
+			return err
+		}
+
+		for _, condition := range conditions {
+			newCondition := models.RoleHasRuleCondition{
+				RoleHasRuleID: roleHasRule.ID,
+				ConditionType: condition.ConditionType,
+				Value:         condition.Value,
+			}
+			if err := tx.Create(&newCondition).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Preload("Conditions").First(&roleHasRule, roleHasRule.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &roleHasRule, nil
+}
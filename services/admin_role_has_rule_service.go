@@ -2,13 +2,10 @@ package services
 
 import (
 	"backend-school/config"
-	"backend-school/helpers"
 	"backend-school/models"
 	"errors"
 	"log"
 	"math"
-
-	"github.com/google/uuid"
 )
 
 // RoleWithRules represents the structure for roles with associated rules
@@ -26,11 +23,12 @@ type RuleSummary struct {
 
 // RoleHasRuleSummary represents the structure for each role with its associated rule
 type RoleHasRuleSummary struct {
-	ID            uint   `json:"id"`
-	UUID          string `json:"uuid"`
-	RoleGuardName string `json:"role_guard_name"`
-	RulePolicy    string `json:"rule_policy"`
-	Action        string `json:"action"`
+	ID            uint                          `json:"id"`
+	UUID          string                        `json:"uuid"`
+	RoleGuardName string                        `json:"role_guard_name"`
+	RulePolicy    string                        `json:"rule_policy"`
+	Action        string                        `json:"action"`
+	Conditions    []models.RoleHasRuleCondition `json:"conditions"`
 }
 
 // GetPaginatedRoleHasRules retrieves a paginated list of role_has_rules with ID and UUID
@@ -48,7 +46,7 @@ func GetPaginatedRoleHasRules(page int, pageSize int) ([]RoleHasRuleSummary, Pag
 	}
 
 	// Fetch paginated records
-	if err := config.DB.Limit(pageSize).Offset(offset).Find(&roleRules).Error; err != nil {
+	if err := config.DB.Preload("Conditions").Limit(pageSize).Offset(offset).Find(&roleRules).Error; err != nil {
 		return nil, PaginationData{}, err
 	}
 
@@ -60,6 +58,7 @@ func GetPaginatedRoleHasRules(page int, pageSize int) ([]RoleHasRuleSummary, Pag
 			RoleGuardName: rule.RoleGuardName,
 			RulePolicy:    rule.RulePolicy,
 			Action:        rule.Action,
+			Conditions:    rule.Conditions,
 		})
 	}
 
@@ -77,102 +76,14 @@ func GetPaginatedRoleHasRules(page int, pageSize int) ([]RoleHasRuleSummary, Pag
 	return roleHasRuleSummaries, paginationData, nil
 }
 
-// CreateRoleWithRules processes the payload, adds entries to roles, role_has_rules, and Casbin policies.
+// CreateRoleHasRules processes the payload and adds entries to roles, role_has_rules, and Casbin policies in one transaction.
 func CreateRoleHasRules(roleGuardName string, roleName string, rules []map[string]interface{}) ([]models.RoleHasRule, error) {
-	var createdRules []models.RoleHasRule
-
-	// Start a database transaction
-	tx := config.DB.Begin()
-	if tx.Error != nil {
-		return nil, tx.Error
-	}
-
-	// Get Casbin enforcer
-	enforcer := helpers.GetCasbinEnforcer()
-
-	// Step 1: Create the Role in `roles` table (if it doesn’t exist)
-	var role models.Role
-	if err := tx.Where("guard_name = ?", roleGuardName).First(&role).Error; err != nil {
-		// If role doesn't exist, create it
-		role = models.Role{
-			Name:      roleName,
-			GuardName: roleGuardName,
-		}
-		if err := tx.Create(&role).Error; err != nil {
-			tx.Rollback()
-			return nil, errors.New("failed to create role in roles table")
-		}
-	}
-
-	// Step 2: Process each rule in the `rules` array and add entries to `role_has_rules`
-	for _, ruleData := range rules {
-		// Extract rule_policy
-		rulePolicy, ok := ruleData["rule_policy"].(string)
-		if !ok || rulePolicy == "" {
-			tx.Rollback()
-			return nil, errors.New("invalid or missing rule_policy")
-		}
-
-		// Extract actions map
-		actions, ok := ruleData["action"].(map[string]interface{})
-		if !ok {
-			tx.Rollback()
-			return nil, errors.New("invalid or missing actions for rule")
-		}
-
-		// Process each action (create, read, update, delete)
-		for action, enabled := range actions {
-			isEnabled, ok := enabled.(bool)
-			if !ok {
-				tx.Rollback()
-				return nil, errors.New("action value must be boolean")
-			}
-
-			// Only create the rule if the action is enabled (set to true)
-			if isEnabled {
-				// Define the role_has_rule entry
-				rule := models.RoleHasRule{
-					RoleGuardName: roleGuardName,
-					RulePolicy:    rulePolicy,
-					Action:        action,
-				}
-
-				// Check if the rule already exists in `role_has_rules`
-				var existingRule models.RoleHasRule
-				if err := tx.Where("role_guard_name = ? AND rule_policy = ? AND action = ?", roleGuardName, rulePolicy, action).
-					First(&existingRule).Error; err == nil {
-					log.Printf("Rule with this role_guard_name, rule_policy, and action already exists: %s, %s, %s", roleGuardName, rulePolicy, action)
-					continue // Skip adding duplicate rule
-				}
-
-				// Insert the rule into the `role_has_rules` table
-				if err := tx.Create(&rule).Error; err != nil {
-					tx.Rollback()
-					return nil, errors.New("failed to create rule in role_has_rules table")
-				}
-				createdRules = append(createdRules, rule)
-
-				// Step 3: Add rule to Casbin as a policy
-				success, err := enforcer.AddPolicy(roleGuardName, rulePolicy, action)
-				if err != nil || !success {
-					tx.Rollback()
-					return nil, errors.New("failed to add policy to Casbin")
-				}
-			}
-		}
-	}
-
-	// Commit the transaction for `roles` and `role_has_rules`
-	if err := tx.Commit().Error; err != nil {
+	changes, err := ParsePolicyPayload(roleGuardName, rules)
+	if err != nil {
 		return nil, err
 	}
 
-	// Save policies to the Casbin backend (e.g., database)
-	if err := enforcer.SavePolicy(); err != nil {
-		return nil, errors.New("failed to save Casbin policies to storage")
-	}
-
-	return createdRules, nil
+	return NewPolicyService().CreateRoleWithRules(roleGuardName, roleName, changes)
 }
 
 // GetRoleHasRulesList retrieves the list of roles with associated rules
@@ -208,53 +119,18 @@ func GetRoleHasRulesList() ([]RoleWithRules, error) {
 	return roleWithRulesList, nil
 }
 
-// UpdateRoleHasRuleByUUID updates a role_has_rule record by its UUID
+// UpdateRoleHasRuleByUUID updates a role_has_rule record by its UUID and keeps its Casbin policy in sync
 func UpdateRoleHasRuleByUUID(uuidStr string, roleGuardName string, rulePolicy string, action string) (*models.RoleHasRule, error) {
-	var roleHasRule models.RoleHasRule
-
-	// Parse the UUID string to a UUID type
-	uuidVal, err := uuid.Parse(uuidStr)
-	if err != nil {
-		return nil, errors.New("invalid UUID format")
-	}
-
-	// Find the record by UUID
-	if err := config.DB.Where("uuid = ?", uuidVal).First(&roleHasRule).Error; err != nil {
-		return nil, errors.New("role_has_rule not found")
-	}
-
-	// Update the fields
-	roleHasRule.RoleGuardName = roleGuardName
-	roleHasRule.RulePolicy = rulePolicy
-	roleHasRule.Action = action
-
-	// Save the updated record
-	if err := config.DB.Save(&roleHasRule).Error; err != nil {
-		return nil, err
-	}
-
-	return &roleHasRule, nil
+	return NewPolicyService().UpdateRoleHasRule(uuidStr, PolicyRule{
+		RoleGuardName: roleGuardName,
+		RulePolicy:    rulePolicy,
+		Action:        action,
+	})
 }
-func DeleteRoleHasRuleByUUID(uuidStr string) error {
-	var roleHasRule models.RoleHasRule
-
-	// Parse the UUID string to a UUID type
-	uuidVal, err := uuid.Parse(uuidStr)
-	if err != nil {
-		return errors.New("invalid UUID format")
-	}
-
-	// Find the record by UUID
-	if err := config.DB.Where("uuid = ?", uuidVal).First(&roleHasRule).Error; err != nil {
-		return errors.New("role_has_rule not found")
-	}
 
-	// Delete the record
-	if err := config.DB.Delete(&roleHasRule).Error; err != nil {
-		return err
-	}
-
-	return nil
+// DeleteRoleHasRuleByUUID deletes a role_has_rule record by its UUID along with its Casbin policy
+func DeleteRoleHasRuleByUUID(uuidStr string) error {
+	return NewPolicyService().DeleteRoleHasRule(uuidStr)
 }
 
 // CheckRuleInCasbin checks if a rule exists in the casbin_rule table
@@ -272,34 +148,26 @@ func CheckRuleInCasbin(roleGuardName string, rulePolicy string, action string) (
 	return count > 0, nil
 }
 
-// AddCasbinRule adds a new rule to the Casbin enforcer and stores it in the database
+// AddCasbinRule adds a new rule to Casbin and records it in role_has_rule in the same transaction
 func AddCasbinRule(roleGuardName string, rulePolicy string, action string, category string, typeCR string) (bool, error) {
-	// Get Casbin enforcer
-	enforcer := helpers.GetCasbinEnforcer()
-
-	// Add a new policy (roleGuardName, rulePolicy, action) to Casbin
-	success, err := enforcer.AddPolicy(roleGuardName, rulePolicy, action, category, typeCR, "none")
-	if err != nil {
-		return false, err
-	}
-
-	// If the policy was successfully added, return true
-	return success, nil
+	return NewPolicyService().SetRuleActive(PolicyRule{
+		RoleGuardName: roleGuardName,
+		RulePolicy:    rulePolicy,
+		Action:        action,
+		Category:      category,
+		Type:          typeCR,
+	}, true)
 }
 
-// DeleteCasbinRule removes a rule from the Casbin enforcer and the database
+// DeleteCasbinRule removes a rule from Casbin while keeping it available in role_has_rule
 func DeleteCasbinRule(roleGuardName string, rulePolicy string, action string, category string, typeCR string) (bool, error) {
-	// Get Casbin enforcer
-	enforcer := helpers.GetCasbinEnforcer()
-
-	// Remove the policy (roleGuardName, rulePolicy, action) from Casbin
-	success, err := enforcer.RemovePolicy(roleGuardName, rulePolicy, action, category, typeCR)
-	if err != nil {
-		return false, err
-	}
-
-	// If the policy was successfully removed, return true
-	return success, nil
+	return NewPolicyService().SetRuleActive(PolicyRule{
+		RoleGuardName: roleGuardName,
+		RulePolicy:    rulePolicy,
+		Action:        action,
+		Category:      category,
+		Type:          typeCR,
+	}, false)
 }
 
 // GetUniqueRulePolicies retrieves a unique list of rule policies from the role_has_rules table
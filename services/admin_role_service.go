@@ -10,7 +10,6 @@ import (
 	"math"
 
 	"github.com/google/uuid"
-	"gorm.io/gorm"
 )
 
 // RoleWithCasbinRules holds a role and its associated Casbin rules
@@ -215,61 +214,15 @@ func DeleteRoleByUUID(uuidStr string) error {
 
 // ActivateCasbinRulesBulk activates multiple Casbin rules for a given role based on the provided permissions payload structure
 func ActivateCasbinRulesBulk(roleGuardName string, permissions []map[string]interface{}) error {
-	// Get Casbin enforcer
-	enforcer := helpers.GetCasbinEnforcer()
-
-	// Iterate over the permissions array
-	for _, permissionData := range permissions {
-		rulePolicy, _ := permissionData["rule_policy"].(string)
-		actions, _ := permissionData["action"].(map[string]interface{})
-
-		// Iterate over each action in the action map to handle it dynamically
-		for action, allowed := range actions {
-			// Check if the action-policy combination already exists in role_has_rule
-			var existingRule models.RoleHasRule
-			err := config.DB.Where("role_guard_name = ? AND rule_policy = ? AND action = ?", roleGuardName, rulePolicy, action).First(&existingRule).Error
-
-			if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
-				// If the rule does not exist, create it in the role_has_rule table
-				newRule := models.RoleHasRule{
-					RoleGuardName: roleGuardName,
-					RulePolicy:    rulePolicy,
-					Action:        action,
-				}
-
-				// Insert the new rule
-				if err := config.DB.Create(&newRule).Error; err != nil {
-					log.Printf("Failed to create rule in role_has_rule for %s on %s with action %s: %v", roleGuardName, rulePolicy, action, err)
-					return err
-				}
-			} else if err != nil {
-				log.Printf("Error checking existing rule in role_has_rule: %v", err)
-				return err
-			}
-
-			// Add or remove policy in Casbin based on the allowed status
-			if allowed.(bool) {
-				// Add policy if action is allowed
-				_, err := enforcer.AddPolicy(roleGuardName, rulePolicy, action)
-				if err != nil {
-					log.Printf("Failed to add '%s' rule for %s on %s: %v", action, roleGuardName, rulePolicy, err)
-					return err
-				}
-			} else {
-				// Remove policy if action is not allowed
-				_, err := enforcer.RemovePolicy(roleGuardName, rulePolicy, action)
-				if err != nil {
-					log.Printf("Failed to remove '%s' rule for %s on %s: %v", action, roleGuardName, rulePolicy, err)
-					return err
-				}
-			}
-		}
+	changes, err := ParsePolicyPayload(roleGuardName, permissions)
+	if err != nil {
+		log.Printf("Invalid permissions payload for %s: %v", roleGuardName, err)
+		return err
 	}
 
-	// Save policies to the backend (e.g., database)
-	err := enforcer.SavePolicy()
-	if err != nil {
-		log.Printf("Failed to save Casbin policy: %v", err)
+	// Write role_has_rule and casbin_rule together so a failure never leaves them out of sync
+	if err := NewPolicyService().ApplyChanges(changes); err != nil {
+		log.Printf("Failed to activate rules for %s: %v", roleGuardName, err)
 		return err
 	}
 
@@ -4,59 +4,262 @@ import (
 	"backend-school/config"
 	"backend-school/helpers"
 	"backend-school/models"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"math"
-	"mime/multipart"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
-	"crypto/rand"
-	"encoding/hex"
+	"gorm.io/gorm"
 )
 
-type AdminSettingsService struct{}
+// SettingsService is the single entry point for reading and administering settings
+type SettingsService struct{}
 
-// GenerateRandomString generates a random string of a given length
-func GenerateRandomStringAdminSetting(n int) (string, error) {
-	bytes := make([]byte, n)
-	if _, err := rand.Read(bytes); err != nil {
-		return "", err
+// ErrSettingKeyExists is returned when a setting key is already in use
+var ErrSettingKeyExists = errors.New("setting key already exists")
+
+// ErrSettingNotFound is returned when a setting does not exist
+var ErrSettingNotFound = errors.New("setting not found")
+
+// SettingSchema describes the namespace and type expected for a well-known setting key
+type SettingSchema struct {
+	Namespace string
+	Type      string
+}
+
+// settingSchemas lists the settings read by the application itself; other keys default to general/string
+var settingSchemas = map[string]SettingSchema{
+	"smtp_host":     {Namespace: "smtp", Type: models.SettingTypeString},
+	"smtp_port":     {Namespace: "smtp", Type: models.SettingTypeInt},
+	"smtp_email":    {Namespace: "smtp", Type: models.SettingTypeString},
+	"smtp_password": {Namespace: "smtp", Type: models.SettingTypeSecret},
+	"admin_email":   {Namespace: "general", Type: models.SettingTypeString},
+
+	"email_max_attempts":            {Namespace: "smtp", Type: models.SettingTypeInt},
+	"notification_default_locale":   {Namespace: "notification", Type: models.SettingTypeString},
+	"document_review_reminder_days": {Namespace: "document", Type: models.SettingTypeInt},
+	"document_stamp":                {Namespace: "document", Type: models.SettingTypeJSON},
+
+	"document_change_request_required": {Namespace: "document", Type: models.SettingTypeBool},
+	"document_checkout_ttl_minutes":    {Namespace: "document", Type: models.SettingTypeInt},
+	"document_retention_default_years": {Namespace: "document", Type: models.SettingTypeInt},
+}
+
+// maskedSecretValue replaces secret values in admin responses; sending it back on update keeps the stored secret
+const maskedSecretValue = "********"
+
+// settingCache holds decrypted setting values by key and is cleared on every write
+var (
+	settingCacheMu sync.RWMutex
+	settingCache   = map[string]string{}
+)
+
+// SettingValidationError carries field-level validation errors for a setting
+type SettingValidationError struct {
+	Errors map[string]string
+}
+
+func (e *SettingValidationError) Error() string {
+	var messages []string
+	for _, message := range e.Errors {
+		messages = append(messages, message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+func invalidateSettingCache() {
+	settingCacheMu.Lock()
+	settingCache = map[string]string{}
+	settingCacheMu.Unlock()
+}
+
+// applySettingSchema enforces the schema of well-known keys and fills in defaults
+func applySettingSchema(setting *models.Setting) {
+	if schema, ok := settingSchemas[setting.Key]; ok {
+		setting.Namespace = schema.Namespace
+		setting.Type = schema.Type
+	}
+	if setting.Namespace == "" {
+		setting.Namespace = "general"
+	}
+	if setting.Type == "" {
+		setting.Type = models.SettingTypeString
+	}
+}
+
+// validateSettingValue checks that a plaintext value matches the setting type
+func validateSettingValue(setting *models.Setting) error {
+	errs := make(map[string]string)
+
+	switch setting.Type {
+	case models.SettingTypeString, models.SettingTypeSecret:
+	case models.SettingTypeInt:
+		if _, err := strconv.Atoi(setting.Value); err != nil {
+			errs["Value"] = "Value must be an integer"
+		}
+	case models.SettingTypeBool:
+		if _, err := strconv.ParseBool(setting.Value); err != nil {
+			errs["Value"] = "Value must be true or false"
+		}
+	case models.SettingTypeJSON:
+		if !json.Valid([]byte(setting.Value)) {
+			errs["Value"] = "Value must be valid JSON"
+		}
+	default:
+		errs["Type"] = "Type must be one of the following: string int bool json secret"
+	}
+
+	if len(errs) > 0 {
+		return &SettingValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// encodeSettingValue encrypts secret values before they are stored
+func encodeSettingValue(setting *models.Setting) error {
+	if setting.Type != models.SettingTypeSecret || helpers.IsEncryptedSecret(setting.Value) {
+		return nil
 	}
-	return hex.EncodeToString(bytes), nil
+	encrypted, err := helpers.EncryptSecret(setting.Value)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt secret setting: %w", err)
+	}
+	setting.Value = encrypted
+	return nil
 }
 
-func NewAdminSettingsService() (*AdminSettingsService, error) {
-	return &AdminSettingsService{}, nil
+// maskSetting hides the value of secret settings
+func maskSetting(setting *models.Setting) {
+	if setting.Type == models.SettingTypeSecret && setting.Value != "" {
+		setting.Value = maskedSecretValue
+	}
 }
 
-func (s *AdminSettingsService) GetAdminSetting(key string) (string, error) {
+// SyncSettingSchemas applies the schema to existing rows and encrypts secrets still stored as plaintext
+func (s *SettingsService) SyncSettingSchemas() error {
+	var settings []models.Setting
+	if err := config.DB.Find(&settings).Error; err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	for _, setting := range settings {
+		original := setting
+		applySettingSchema(&setting)
+		if err := encodeSettingValue(&setting); err != nil {
+			log.Printf("Skipping encryption of setting %s: %v", setting.Key, err)
+			setting.Value = original.Value
+		}
+
+		if setting.Namespace == original.Namespace && setting.Type == original.Type && setting.Value == original.Value {
+			continue
+		}
+		if err := config.DB.Model(&models.Setting{}).Where("id = ?", setting.ID).
+			Updates(map[string]interface{}{"namespace": setting.Namespace, "type": setting.Type, "value": setting.Value}).Error; err != nil {
+			return fmt.Errorf("failed to update setting %s: %w", setting.Key, err)
+		}
+	}
+
+	invalidateSettingCache()
+	return nil
+}
+
+func NewSettingsService() *SettingsService {
+	return &SettingsService{}
+}
+
+// GetSetting returns the plaintext value of a setting, decrypting secrets. Values are cached until the next settings write.
+func (s *SettingsService) GetSetting(key string) (string, error) {
+	settingCacheMu.RLock()
+	value, ok := settingCache[key]
+	settingCacheMu.RUnlock()
+	if ok {
+		return value, nil
+	}
+
 	var setting struct {
 		Value string
 	}
 	if err := config.DB.Table("settings").Select("value").Where("key = ?", key).First(&setting).Error; err != nil {
-		return "", errors.New("setting not found")
+		return "", ErrSettingNotFound
 	}
-	return setting.Value, nil
+
+	value, err := helpers.DecryptSecret(setting.Value)
+	if err != nil {
+		return "", fmt.Errorf("failed to read setting %s: %w", key, err)
+	}
+
+	settingCacheMu.Lock()
+	settingCache[key] = value
+	settingCacheMu.Unlock()
+	return value, nil
 }
 
-func (s *AdminSettingsService) GetAdminSettingBySlug(slug string) (*models.Setting, error) {
+// GetSettingInt returns a setting parsed as an integer
+func (s *SettingsService) GetSettingInt(key string) (int, error) {
+	value, err := s.GetSetting(key)
+	if err != nil {
+		return 0, err
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("setting %s is not an integer", key)
+	}
+	return number, nil
+}
+
+// GetSettingBool returns a setting parsed as a boolean
+func (s *SettingsService) GetSettingBool(key string) (bool, error) {
+	value, err := s.GetSetting(key)
+	if err != nil {
+		return false, err
+	}
+	flag, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("setting %s is not a boolean", key)
+	}
+	return flag, nil
+}
+
+// GetSettingJSON decodes a JSON setting into target
+func (s *SettingsService) GetSettingJSON(key string, target interface{}) error {
+	value, err := s.GetSetting(key)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(value), target); err != nil {
+		return fmt.Errorf("setting %s is not valid JSON", key)
+	}
+	return nil
+}
+
+// GetSettingByKey returns a setting record by key with secrets masked
+func (s *SettingsService) GetSettingByKey(key string) (*models.Setting, error) {
 	var setting models.Setting
-	if err := config.DB.Where("slug = ?", slug).First(&setting).Error; err != nil {
-		return nil, err
+	if err := config.DB.Where("key = ?", key).First(&setting).Error; err != nil {
+		return nil, ErrSettingNotFound
 	}
+	maskSetting(&setting)
 	return &setting, nil
 }
 
-func (s *AdminSettingsService) GetAdminSettingByUUID(uuid string) (*models.Setting, error) {
+// GetSettingByUUID returns a setting record by UUID with secrets masked
+func (s *SettingsService) GetSettingByUUID(uuid string) (*models.Setting, error) {
 	var setting models.Setting
 	if err := config.DB.Where("uuid = ?", uuid).First(&setting).Error; err != nil {
-		return nil, err
+		return nil, ErrSettingNotFound
 	}
+	maskSetting(&setting)
 	return &setting, nil
 }
 
-// GetAdminSettingsPaginated fetches paginated settings with sorting
-func (s *AdminSettingsService) GetAdminSettingsPaginated(perPage, page int, sortBy string, sortDesc bool) (map[string]interface{}, error) {
+// GetSettingsPaginated fetches paginated settings with sorting, optionally limited to one namespace.
+// Secret values are masked.
+func (s *SettingsService) GetSettingsPaginated(perPage, page int, sortBy string, sortDesc bool, namespace string) (map[string]interface{}, error) {
 	var settings []models.Setting
 	var totalRecords int64
 	var sortOrder string
@@ -64,6 +267,12 @@ func (s *AdminSettingsService) GetAdminSettingsPaginated(perPage, page int, sort
 	// Calculate offset for pagination
 	offset := (page - 1) * perPage
 
+	// Only allow sorting on known columns
+	allowedSortColumns := map[string]bool{"id": true, "key": true, "namespace": true, "type": true, "created_at": true, "updated_at": true}
+	if !allowedSortColumns[sortBy] {
+		sortBy = "id"
+	}
+
 	// Set the sort order based on the `sortDesc` flag
 	if sortDesc {
 		sortOrder = sortBy + " DESC"
@@ -71,14 +280,24 @@ func (s *AdminSettingsService) GetAdminSettingsPaginated(perPage, page int, sort
 		sortOrder = sortBy + " ASC"
 	}
 
+	query := config.DB.Model(&models.Setting{})
+	if namespace != "" {
+		query = query.Where("namespace = ?", namespace)
+	}
+
 	// Get the total number of records
-	config.DB.Model(&models.Setting{}).Count(&totalRecords)
+	query.Count(&totalRecords)
 
 	// Fetch the paginated data with sorting
-	if err := config.DB.Order(sortOrder).Limit(perPage).Offset(offset).Find(&settings).Error; err != nil {
+	if err := query.Order(sortOrder).Limit(perPage).Offset(offset).Find(&settings).Error; err != nil {
 		return nil, errors.New("failed to fetch settings")
 	}
 
+	// Never expose secret values
+	for i := range settings {
+		maskSetting(&settings[i])
+	}
+
 	// Calculate total pages
 	totalPages := int(math.Ceil(float64(totalRecords) / float64(perPage)))
 
@@ -94,48 +313,176 @@ func (s *AdminSettingsService) GetAdminSettingsPaginated(perPage, page int, sort
 	return result, nil
 }
 
-// CreateSetting handles the creation of a setting along with the image upload
-func (s *AdminSettingsService) CreateSetting(setting *models.Setting, img *multipart.FileHeader) error {
-	db, err := config.DB.DB()
-	if err != nil {
-		log.Printf("Failed to get database connection: %v", err)
-		return fmt.Errorf("failed to get database connection: %w", err)
+// settingKeyTaken reports whether another setting already uses key
+func settingKeyTaken(tx *gorm.DB, key string, excludeID uint) (bool, error) {
+	var count int64
+	query := tx.Model(&models.Setting{}).Where("key = ?", key)
+	if excludeID != 0 {
+		query = query.Where("id <> ?", excludeID)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
 	}
+	return count > 0, nil
+}
 
-	// Ensure the sequence is correctly set to avoid conflicts
-	if err := helpers.ResetSequenceToMax(db, "settings", "id", "settings_id_seq"); err != nil {
-		log.Printf("Failed to reset sequence: %v", err)
-		return fmt.Errorf("failed to reset sequence: %w", err)
+// recordSettingHistory stores the current state of a setting before it changes
+func recordSettingHistory(tx *gorm.DB, setting models.Setting, action string, changedBy int) error {
+	history := models.SettingHistory{
+		SettingID:   setting.ID,
+		SettingUUID: setting.UUID,
+		Key:         setting.Key,
+		Value:       setting.Value,
+		Namespace:   setting.Namespace,
+		Type:        setting.Type,
+		Action:      action,
+		ChangedBy:   changedBy,
+		ChangedAt:   time.Now(),
 	}
-	// Save the setting to the database
-	if err := config.DB.Create(setting).Error; err != nil {
-		return fmt.Errorf("failed to create setting: %v", err)
+	if err := tx.Create(&history).Error; err != nil {
+		return fmt.Errorf("failed to record setting history: %w", err)
 	}
+	return nil
+}
 
+// CreateSetting validates, encrypts when needed and stores a new setting
+func (s *SettingsService) CreateSetting(setting *models.Setting) error {
+	// Validate the value against its type and encrypt secrets
+	applySettingSchema(setting)
+	if err := validateSettingValue(setting); err != nil {
+		return err
+	}
+	if err := encodeSettingValue(setting); err != nil {
+		return err
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		taken, err := settingKeyTaken(tx, setting.Key, 0)
+		if err != nil {
+			return fmt.Errorf("failed to check setting key: %w", err)
+		}
+		if taken {
+			return ErrSettingKeyExists
+		}
+
+		// Save the setting to the database
+		if err := tx.Create(setting).Error; err != nil {
+			return fmt.Errorf("failed to create setting: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	invalidateSettingCache()
+	maskSetting(setting)
 	return nil
 }
 
-// UpdateSetting updates an existing setting by its UUID and handles image uploads
-func (s *AdminSettingsService) UpdateSetting(uuid string, updatedSetting *models.Setting, img *multipart.FileHeader) error {
+// UpdateSetting updates an existing setting by its UUID, keeping its previous value in setting_history
+func (s *SettingsService) UpdateSetting(uuid string, updatedSetting *models.Setting) error {
 	var setting models.Setting
 
 	// Fetch the existing setting by UUID
 	if err := config.DB.Where("uuid = ?", uuid).First(&setting).Error; err != nil {
-		return errors.New("setting not found")
+		return ErrSettingNotFound
 	}
 
-	// Update the setting record with the new data
-	if err := config.DB.Model(&setting).Updates(updatedSetting).Error; err != nil {
-		return errors.New("failed to update setting")
+	// Resolve the key and type the update applies to
+	if updatedSetting.Key == "" {
+		updatedSetting.Key = setting.Key
+	}
+	if updatedSetting.Type == "" {
+		updatedSetting.Type = setting.Type
+	}
+	applySettingSchema(updatedSetting)
+
+	// An empty value, or the masked value of a secret, keeps the stored value. A secret that stays a secret keeps its
+	// ciphertext; when the type changes the stored value is decrypted, checked against the new type and encrypted
+	// again only if it is still a secret.
+	keepValue := updatedSetting.Value == "" || (setting.Type == models.SettingTypeSecret && updatedSetting.Value == maskedSecretValue)
+	if keepValue && setting.Type == models.SettingTypeSecret && updatedSetting.Type == models.SettingTypeSecret {
+		updatedSetting.Value = ""
+	} else {
+		if keepValue {
+			value, err := helpers.DecryptSecret(setting.Value)
+			if err != nil {
+				return fmt.Errorf("failed to read setting %s: %w", setting.Key, err)
+			}
+			updatedSetting.Value = value
+		}
+		if err := validateSettingValue(updatedSetting); err != nil {
+			return err
+		}
+		if err := encodeSettingValue(updatedSetting); err != nil {
+			return err
+		}
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		taken, err := settingKeyTaken(tx, updatedSetting.Key, setting.ID)
+		if err != nil {
+			return fmt.Errorf("failed to check setting key: %w", err)
+		}
+		if taken {
+			return ErrSettingKeyExists
+		}
+
+		if err := recordSettingHistory(tx, setting, models.SettingHistoryActionUpdate, updatedSetting.UpdatedBy); err != nil {
+			return err
+		}
+
+		// Update the setting record with the new data
+		if err := tx.Model(&setting).Updates(updatedSetting).Error; err != nil {
+			return errors.New("failed to update setting")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
+	invalidateSettingCache()
+	maskSetting(updatedSetting)
 	return nil
 }
 
-// DeleteSetting deletes a setting by its slug
-func (s *AdminSettingsService) DeleteSetting(uuid string) error {
-	if err := config.DB.Where("uuid = ?", uuid).Delete(&models.Setting{}).Error; err != nil {
-		return errors.New("failed to delete setting")
+// DeleteSetting deletes a setting by its UUID, keeping its last value in setting_history
+func (s *SettingsService) DeleteSetting(uuid string, deletedBy int) error {
+	var setting models.Setting
+	if err := config.DB.Where("uuid = ?", uuid).First(&setting).Error; err != nil {
+		return ErrSettingNotFound
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := recordSettingHistory(tx, setting, models.SettingHistoryActionDelete, deletedBy); err != nil {
+			return err
+		}
+		if err := tx.Delete(&setting).Error; err != nil {
+			return errors.New("failed to delete setting")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
+
+	invalidateSettingCache()
 	return nil
 }
+
+// GetSettingHistory lists the previous values of a setting, newest first, with secrets masked
+func (s *SettingsService) GetSettingHistory(uuid string) ([]models.SettingHistory, error) {
+	var history []models.SettingHistory
+	if err := config.DB.Where("setting_uuid = ?", uuid).Order("changed_at DESC, id DESC").Find(&history).Error; err != nil {
+		return nil, errors.New("failed to fetch setting history")
+	}
+
+	for i := range history {
+		if history[i].Type == models.SettingTypeSecret && history[i].Value != "" {
+			history[i].Value = maskedSecretValue
+		}
+	}
+	return history, nil
+}
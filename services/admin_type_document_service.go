@@ -96,7 +96,7 @@ func (s *DocumentTypeService) GetDocumentTypesPaginated(currentPage, pageSize in
 func (s *DocumentTypeService) AddDocumentType(payload *DocumentTypePayload) (*models.DocumentType, error) {
 	var documentType models.DocumentType
 
-	err := config.DB.Transaction(func(tx *gorm.DB) error {
+	err := withPolicyTransaction(func(tx *gorm.DB) error {
 		// Step 1: Check if Prefix already exists in
 		var existingDocumentType models.DocumentType
 		if err := tx.Where("prefix = ?", payload.Prefix).First(&existingDocumentType).Error; err == nil {
@@ -120,65 +120,12 @@ func (s *DocumentTypeService) AddDocumentType(payload *DocumentTypePayload) (*mo
 		var documentCategory models.CategoryDocument
 
 		// Query untuk mencari data berdasarkan `status_document_id` dari request
-		if err := config.DB.Where("id = ?", documentType.DocumentCategoryID).First(&documentCategory).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				// Jika data tidak ditemukan
-				return fmt.Errorf("failed to check category document: %w", err)
-			}
-			// Jika ada error lain saat query
+		if err := tx.Where("id = ?", documentType.DocumentCategoryID).First(&documentCategory).Error; err != nil {
 			return fmt.Errorf("failed to check category document: %w", err)
 		}
 
-		// Step 2: Create entries in role_has_rule table, ensuring no duplicates
-		for _, rule := range payload.RoleHasRules {
-			var existingRoleHasRule models.RoleHasRule
-			if err := tx.Where("role_guard_name = ? AND action = ? AND type = ? AND category = ?", rule.RoleGuardName, rule.Action, payload.Prefix, documentCategory.Prefix).First(&existingRoleHasRule).Error; err == nil {
-				// If a duplicate is found, skip adding this rule
-				continue
-			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
-				return fmt.Errorf("failed to check for existing role has rule: %w", err)
-			}
-
-			// Create new role_has_rule if no duplicate found
-			roleHasRule := models.RoleHasRule{
-				RoleGuardName: rule.RoleGuardName,
-				RulePolicy:    "document",
-				Action:        rule.Action,
-				Type:          payload.Prefix,
-				Category:      documentCategory.Prefix,
-			}
-			if err := tx.Create(&roleHasRule).Error; err != nil {
-				return fmt.Errorf("failed to create role has rule: %w", err)
-			}
-		}
-
-		// Step 3: Create entries in casbin_rule table, ensuring no duplicates
-		for _, rule := range payload.RoleHasRules {
-			var existingCasbinRule models.CasbinRule
-			casbinRule := models.CasbinRule{
-				Ptype: "p",
-				V0:    rule.RoleGuardName,
-				V1:    "document",
-				V2:    rule.Action,
-				V3:    documentCategory.Prefix,
-				V4:    payload.Prefix,
-			}
-
-			// Check for duplicate in casbin_rule
-			if err := tx.Where("ptype = ? AND v0 = ? AND v1 = ? AND v2 = ? AND v3 = ? AND v4 = ?", casbinRule.Ptype, casbinRule.V0, casbinRule.V1, casbinRule.V2, casbinRule.V3, casbinRule.V4).First(&existingCasbinRule).Error; err == nil {
-				// If duplicate found, skip adding this rule
-				continue
-			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
-				return fmt.Errorf("failed to check for existing casbin rule: %w", err)
-			}
-
-			// Create new casbin_rule if no duplicate found
-			if err := tx.Create(&casbinRule).Error; err != nil {
-				return fmt.Errorf("failed to create casbin rule: %w", err)
-			}
-		}
-
-		return nil
+		// Step 2: Grant the rules in role_has_rule and casbin_rule
+		return setDocumentRules(tx, documentCategory.Prefix, payload.Prefix, nil, payload.RoleHasRules)
 	})
 
 	if err != nil {
@@ -222,103 +169,37 @@ func (s *DocumentTypeService) UpdateDocumentTypeByUUID(uuid string, payload *Doc
 	oldPrefix := documentType.Prefix
 
 	// Start transaction to handle updates in the correct order
-	err := config.DB.Transaction(func(tx *gorm.DB) error {
-		// Step 1: Update role_has_rule entries
-
-		// Fetch existing RoleHasRule entries for this document's prefix
-		var existingRules []models.RoleHasRule
-		if err := tx.Where("type = ?", oldPrefix).Find(&existingRules).Error; err != nil {
-			return fmt.Errorf("failed to fetch existing role rules: %w", err)
-		}
-
-		// Prepare maps for quick lookup
-		existingRulesMap := make(map[string]models.RoleHasRule)
-		for _, rule := range existingRules {
-			key := fmt.Sprintf("%s:%s", rule.RoleGuardName, rule.Action)
-			existingRulesMap[key] = rule
-		}
-
+	err := withPolicyTransaction(func(tx *gorm.DB) error {
 		var oldDocumentCategory models.CategoryDocument
 
 		// Query to find the old category document by ID
-		if err := config.DB.Where("id = ?", *oldCategoryID).First(&oldDocumentCategory).Error; err != nil {
+		if err := tx.Where("id = ?", *oldCategoryID).First(&oldDocumentCategory).Error; err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
 				return fmt.Errorf("failed to find old category document: %w", err)
 			}
 			return fmt.Errorf("failed to check old category document: %w", err)
 		}
 
-		// Process role_has_rules from payload and delete old Casbin rules
-		for _, rule := range payload.RoleHasRules {
-			key := fmt.Sprintf("%s:%s", rule.RoleGuardName, rule.Action)
-
-			if existingRule, found := existingRulesMap[key]; found {
-				// Hapus aturan Casbin lama menggunakan kategori dan prefix lama
-				if err := tx.Where("ptype = ? AND v0 = ? AND v1 = ? AND v2 = ? AND v3 = ? AND v4 = ?",
-					"p", existingRule.RoleGuardName, "document", existingRule.Action, oldDocumentCategory.Prefix, oldPrefix).
-					Delete(&models.CasbinRule{}).Error; err != nil {
-					return fmt.Errorf("failed to delete old Casbin rule: %w", err)
-				}
-
-				// Update existing role_has_rule
-				existingRule.Type = payload.Prefix
-				existingRule.RulePolicy = "document"
-				existingRule.Category = oldDocumentCategory.Prefix
-				if err := tx.Save(&existingRule).Error; err != nil {
-					return fmt.Errorf("failed to update role rule: %w", err)
-				}
-				delete(existingRulesMap, key)
-			} else {
-				// Create a new role_has_rule entry if not found
-				newRule := models.RoleHasRule{
-					RoleGuardName: rule.RoleGuardName,
-					RulePolicy:    "document",
-					Action:        rule.Action,
-					Type:          payload.Prefix,
-					Category:      oldDocumentCategory.Prefix,
-				}
-				if err := tx.Create(&newRule).Error; err != nil {
-					return fmt.Errorf("failed to create new role rule: %w", err)
-				}
-			}
+		var newDocumentCategory models.CategoryDocument
+		if err := tx.Where("id = ?", payload.DocumentCategoryID).First(&newDocumentCategory).Error; err != nil {
+			return fmt.Errorf("failed to find new category document: %w", err)
 		}
 
-		// Delete any remaining rules in existingRulesMap that are not in the payload and remove corresponding Casbin rules
-		for _, rule := range existingRulesMap {
-			if err := tx.Where("ptype = ? AND v0 = ? AND v1 = ? AND v2 = ? AND v3 = ? AND v4 = ?",
-				"p", rule.RoleGuardName, "document", rule.Action, oldDocumentCategory.Prefix, oldPrefix).
-				Delete(&models.CasbinRule{}).Error; err != nil {
-				return fmt.Errorf("failed to delete obsolete Casbin rule: %w", err)
-			}
-
-			// Delete the obsolete role rule from the database
-			if err := tx.Delete(&rule).Error; err != nil {
-				return fmt.Errorf("failed to delete obsolete role rule: %w", err)
-			}
+		// Step 1: Move the policies of the type to the new category and prefix, then replace its rules
+		if err := renameDocumentTypeRules(tx, oldDocumentCategory.Prefix, oldPrefix, newDocumentCategory.Prefix, payload.Prefix); err != nil {
+			return err
 		}
 
-		// Step 2: Create new Casbin rules for the updated payload with new category and prefix
-		var newDocumentCategory models.CategoryDocument
-		if err := config.DB.Where("id = ?", payload.DocumentCategoryID).First(&newDocumentCategory).Error; err != nil {
-			return fmt.Errorf("failed to find new category document: %w", err)
+		var existingRules []models.RoleHasRule
+		if err := tx.Where("rule_policy = ? AND category = ? AND type = ?", "document", newDocumentCategory.Prefix, payload.Prefix).
+			Find(&existingRules).Error; err != nil {
+			return fmt.Errorf("failed to fetch existing role rules: %w", err)
 		}
-
-		for _, rule := range payload.RoleHasRules {
-			// Insert a new Casbin rule manually with the updated category and prefix
-			newCasbinRule := models.CasbinRule{
-				Ptype: "p",
-				V0:    rule.RoleGuardName,
-				V1:    "document",
-				V2:    rule.Action,
-				V3:    newDocumentCategory.Prefix,
-				V4:    payload.Prefix,
-			}
-			if err := tx.Create(&newCasbinRule).Error; err != nil {
-				return fmt.Errorf("failed to add new Casbin rule: %w", err)
-			}
+		if err := setDocumentRules(tx, newDocumentCategory.Prefix, payload.Prefix, existingRules, payload.RoleHasRules); err != nil {
+			return err
 		}
 
-		// Step 3: Update document_type record
+		// Step 2: Update document_type record
 
 		// Update the document type fields
 		documentType.Name = payload.Name
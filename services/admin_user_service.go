@@ -5,7 +5,6 @@ import (
 	"backend-school/dto"
 	"backend-school/helpers"
 	"backend-school/models"
-	"backend-school/templates"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
@@ -13,11 +12,9 @@ import (
 	"log"
 	"math"
 	"os"
-	"strings"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
-	"github.com/go-gomail/gomail"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -64,6 +61,32 @@ func Login(username, password string) (string, error) {
 	return tokenString, nil
 }
 
+// StreamTokenPurpose is the "purpose" claim of a stream token, which only opens the notification stream
+const StreamTokenPurpose = "notification-stream"
+
+// streamTokenLifetime is how long a stream token can be used to open the notification stream
+const streamTokenLifetime = time.Minute
+
+// IssueStreamToken returns a short-lived token that only opens the notification stream. EventSource cannot send
+// headers, so the stream takes its token in the URL, where it may end up in logs; the login token never goes there.
+func IssueStreamToken(username string) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(streamTokenLifetime)
+	claims := jwt.MapClaims{
+		"username": username,
+		"purpose":  StreamTokenPurpose,
+		"exp":      expiresAt.Unix(),
+		"iat":      now.Unix(),
+		"nbf":      now.Unix(),
+	}
+
+	tokenString, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecret)
+	if err != nil {
+		return "", time.Time{}, errors.New("failed to generate token")
+	}
+	return tokenString, expiresAt, nil
+}
+
 func HasAccess(username, path, method string) (bool, error) {
 	// Check access using Casbin
 	allowed, err := config.Enforcer.Enforce(username, path, method)
@@ -145,7 +168,8 @@ func GetUserByUsername(username string) (*models.UserGetData, error) {
 }
 
 // ForgotPassword handles the process of generating a password reset token and sending it to the user's email.
-func ForgotPassword(email string) error {
+// locale selects the email template variant; empty uses the default locale.
+func ForgotPassword(email, locale string) error {
 	var user models.UserGetData
 
 	// Check if the user exists in the database by email
@@ -181,31 +205,21 @@ func ForgotPassword(email string) error {
 	// Create the full reset URL
 	resetURL := fmt.Sprintf("%s?token=%s", resetURLBase, resetToken)
 
-	// Send the reset email using Mailtrap
-	err = sendResetEmail(user.Email, resetURL)
+	// Queue the reset email; the outbox worker delivers it
+	err = sendResetEmail(user.Email, resetURL, locale)
 	if err != nil {
-		return errors.New("failed to send reset email")
+		return errors.New("failed to queue reset email")
 	}
 
 	return nil
 }
 
-func sendResetEmail(email, resetLink string) error {
-	// Replace {{RESET_LINK}} with the actual reset link in the template
-	bodyWithLink := strings.Replace(templates.ResetEmailTemplate, "{{RESET_LINK}}", resetLink, -1)
-
-	m := gomail.NewMessage()
-	m.SetHeader("From", "noreply@school.com")
-	m.SetHeader("To", email)
-	m.SetHeader("Subject", "Password Reset Request")
-	m.SetBody("text/html", bodyWithLink)
-
-	// Configure the Mailtrap SMTP settings (ensure credentials are correct)
-	d := gomail.NewDialer("sandbox.smtp.mailtrap.io", 2525, "0e9340db84cbec", "f325d2b3ca4dcc")
-
-	// Attempt to send the email
-	if err := d.DialAndSend(m); err != nil {
-		return fmt.Errorf("failed to send email: %w", err)
+// sendResetEmail queues the password_reset notification in the outbox
+func sendResetEmail(email, resetLink, locale string) error {
+	if err := newDefaultNotificationService().Notify(models.NotificationEventPasswordReset, locale, email, map[string]interface{}{
+		"ResetLink": resetLink,
+	}); err != nil {
+		return fmt.Errorf("failed to queue email: %w", err)
 	}
 
 	return nil
@@ -259,6 +273,9 @@ func ResetPassword(resetToken, newPassword string) error {
 		return errors.New("failed to delete reset token")
 	}
 
+	notifyUserBestEffort(int(user.ID), models.NotificationEventPasswordChanged, "Password reset",
+		"Your password was reset. If this wasn't you, contact an administrator.", "")
+
 	return nil
 }
 
@@ -401,6 +418,9 @@ func AddUserRoleByUUID(userUUID string, role string) error {
 		return errors.New("failed to assign role to user")
 	}
 
+	notifyUserBestEffort(int(user.ID), models.NotificationEventRoleAssigned, "Role assigned",
+		fmt.Sprintf("You were given the %s role.", role), "")
+
 	// Return nil if everything was successful
 	return nil
 }
@@ -430,6 +450,9 @@ func DeleteUserRoleByUUID(userUUID string, roleGuardName string) error {
 		return errors.New("failed to delete role for user")
 	}
 
+	notifyUserBestEffort(int(user.ID), models.NotificationEventRoleRemoved, "Role removed",
+		fmt.Sprintf("The %s role was removed from your account.", roleGuardName), "")
+
 	// Return nil if the role was successfully deleted
 	return nil
 }
@@ -703,6 +726,9 @@ func ActivateUser(userUUID string) error {
 		return errors.New("failed to activate user")
 	}
 
+	notifyUserBestEffort(int(user.ID), models.NotificationEventAccountActivated, "Account activated",
+		"Your account has been activated.", "")
+
 	return nil
 }
 
@@ -768,5 +794,8 @@ func ChangePassword(username string, req ChangePasswordRequest) error {
 		return errors.New("failed to update password")
 	}
 
+	notifyUserBestEffort(int(user.ID), models.NotificationEventPasswordChanged, "Password changed",
+		"Your password was changed. If this wasn't you, contact an administrator.", "")
+
 	return nil
 }
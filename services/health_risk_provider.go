@@ -0,0 +1,104 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// externalHealthRiskEngineName is stored as the risk engine of entries assessed by the external model
+const externalHealthRiskEngineName = "external"
+
+// RiskResponse is one prediction of the external risk model
+type RiskResponse struct {
+	Risk   string `json:"risk"`
+	Sample int    `json:"sample"`
+}
+
+// ExternalHealthRiskProvider asks the risk model behind RISK_API_URL. It sends systolic, diastolic and heart rate
+// only; the model answers with a list of predictions of which the first is used.
+type ExternalHealthRiskProvider struct {
+	url        string
+	version    string
+	httpClient *http.Client
+}
+
+// NewExternalHealthRiskProvider creates a provider for the model at url. version is stored as the rule version of
+// the entries it assesses, and timeout bounds each request.
+func NewExternalHealthRiskProvider(url, version string, timeout time.Duration) (*ExternalHealthRiskProvider, error) {
+	if url == "" {
+		return nil, errors.New("RISK_API_URL is not set")
+	}
+	if version == "" {
+		version = "unversioned"
+	}
+	return &ExternalHealthRiskProvider{
+		url:        url,
+		version:    version,
+		httpClient: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+func (p *ExternalHealthRiskProvider) Name() string {
+	return externalHealthRiskEngineName
+}
+
+func formatMeasurement(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// Assess posts the measurements to the model
+func (p *ExternalHealthRiskProvider) Assess(ctx context.Context, input HealthRiskInput) (*HealthRiskAssessment, error) {
+	payloadBytes, err := json.Marshal(map[string]string{
+		"systol":     formatMeasurement(input.Systolic),
+		"diastol":    formatMeasurement(input.Diastolic),
+		"heart_rate": formatMeasurement(input.HeartRate),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(payloadBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create risk request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request to %s: %w", p.url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("received non-200 response: %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var riskResponse []RiskResponse
+	if err := json.NewDecoder(resp.Body).Decode(&riskResponse); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(riskResponse) == 0 {
+		return nil, errors.New("no risk data found in response")
+	}
+
+	risk := riskResponse[0].Risk
+	switch risk {
+	case HealthRiskLow, HealthRiskMedium, HealthRiskHigh:
+	default:
+		return nil, fmt.Errorf("unknown risk level %q in response", risk)
+	}
+
+	return &HealthRiskAssessment{
+		Risk:        risk,
+		Engine:      externalHealthRiskEngineName,
+		RuleVersion: p.version,
+	}, nil
+}
@@ -5,6 +5,7 @@ import (
 	"backend-school/config"
 	"backend-school/models"
 	"fmt"
+	"log"
 	"time"
 
 	"gorm.io/gorm"
@@ -12,15 +13,17 @@ import (
 
 // PageService handles all page-related logic
 type FormService struct {
-	EmailService    *EmailService
-	SettingsService *SettingsService
+	EmailService        *EmailService
+	SettingsService     *SettingsService
+	NotificationService *NotificationService
 }
 
 // NewPageService creates a new instance of PageService
 func NewFormService(emailService *EmailService, settingsService *SettingsService) *FormService {
 	return &FormService{
-		EmailService:    emailService,
-		SettingsService: settingsService,
+		EmailService:        emailService,
+		SettingsService:     settingsService,
+		NotificationService: NewNotificationService(emailService, settingsService),
 	}
 }
 
@@ -86,21 +89,24 @@ func (s *FormService) SubmitForm(form *models.Form, submittedData map[string]str
 		}
 	}
 
-	subject := fmt.Sprintf("New form submission for %s", form.FormTitle)
-	body := "A new form has been submitted with the following details:\n"
-	for fieldName, fieldValue := range submittedData {
-		body += fmt.Sprintf("%s: %s\n", fieldName, fieldValue)
+	// Let the user managing the form know in-app
+	if form.CreatedBy != nil {
+		notifyUserBestEffort(*form.CreatedBy, models.NotificationEventFormSubmitted,
+			fmt.Sprintf("New submission for %s", form.FormTitle),
+			fmt.Sprintf("%s submitted %s.", email, form.FormTitle),
+			"/forms/"+form.Slug)
 	}
 
-	// Fetch the admin email address from settings (or hard-code it here if preferred)
+	// Queue the form_submitted notification for the admin; delivery is retried by the outbox worker. The submission
+	// is already saved, so a notification that cannot be queued is logged rather than failing it.
 	adminEmail, err := s.SettingsService.GetSetting("admin_email")
 	if err != nil {
-		return nil, fmt.Errorf("could not retrieve admin email: %v", err)
-	}
-
-	// Send email to the admin
-	if err := s.EmailService.SendEmail(adminEmail, subject, body); err != nil {
-		return nil, fmt.Errorf("failed to send email notification: %v", err)
+		log.Printf("Failed to queue form_submitted email for %s: could not retrieve admin email: %v", form.Slug, err)
+	} else if err := s.NotificationService.Notify(models.NotificationEventFormSubmitted, "", adminEmail, map[string]interface{}{
+		"FormTitle": form.FormTitle,
+		"Fields":    submittedData,
+	}); err != nil {
+		log.Printf("Failed to queue form_submitted email for %s: %v", form.Slug, err)
 	}
 
 	// Return the form after successful submission
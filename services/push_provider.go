@@ -0,0 +1,242 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// PushMessage is a notification for a single device
+type PushMessage struct {
+	Token string
+	Title string
+	Body  string
+	Data  map[string]string
+}
+
+// PushResult describes how the provider handled a message
+type PushResult struct {
+	MessageID    string
+	InvalidToken bool // the token is no longer valid and should be removed
+}
+
+// PushProvider delivers push messages to devices
+type PushProvider interface {
+	Name() string
+	Send(ctx context.Context, message PushMessage) (PushResult, error)
+}
+
+const (
+	fcmScope        = "https://www.googleapis.com/auth/firebase.messaging"
+	fcmSendEndpoint = "https://fcm.googleapis.com/v1/projects/%s/messages:send"
+)
+
+// fcmServiceAccount is the subset of a Google service account key file used by FCM
+type fcmServiceAccount struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// FCMProvider sends messages through the Firebase Cloud Messaging HTTP v1 API
+type FCMProvider struct {
+	account      fcmServiceAccount
+	httpClient   *http.Client
+	sendEndpoint string
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewFCMProviderFromFile creates an FCMProvider from a service account key file
+func NewFCMProviderFromFile(path string) (*FCMProvider, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read FCM credentials: %w", err)
+	}
+
+	var account fcmServiceAccount
+	if err := json.Unmarshal(content, &account); err != nil {
+		return nil, fmt.Errorf("invalid FCM credentials: %w", err)
+	}
+	if account.ProjectID == "" || account.ClientEmail == "" || account.PrivateKey == "" {
+		return nil, errors.New("FCM credentials must contain project_id, client_email and private_key")
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	return &FCMProvider{
+		account:      account,
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+		sendEndpoint: fmt.Sprintf(fcmSendEndpoint, account.ProjectID),
+	}, nil
+}
+
+func (p *FCMProvider) Name() string {
+	return "fcm"
+}
+
+// token returns a cached OAuth2 access token, exchanging a signed service account JWT when it expires
+func (p *FCMProvider) token(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.accessToken != "" && time.Now().Before(p.expiresAt.Add(-time.Minute)) {
+		return p.accessToken, nil
+	}
+
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(p.account.PrivateKey))
+	if err != nil {
+		return "", fmt.Errorf("invalid FCM private key: %w", err)
+	}
+
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   p.account.ClientEmail,
+		"scope": fcmScope,
+		"aud":   p.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign FCM assertion: %w", err)
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch FCM access token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var tokenResponse struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("FCM token request failed with status %d: %s", resp.StatusCode, body)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil {
+		return "", fmt.Errorf("invalid FCM token response: %w", err)
+	}
+
+	p.accessToken = tokenResponse.AccessToken
+	p.expiresAt = now.Add(time.Duration(tokenResponse.ExpiresIn) * time.Second)
+	return p.accessToken, nil
+}
+
+// Send delivers a message; UNREGISTERED and NOT_FOUND responses are reported as invalid tokens
+func (p *FCMProvider) Send(ctx context.Context, message PushMessage) (PushResult, error) {
+	accessToken, err := p.token(ctx)
+	if err != nil {
+		return PushResult{}, err
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"message": map[string]interface{}{
+			"token":        message.Token,
+			"notification": map[string]string{"title": message.Title, "body": message.Body},
+			"data":         message.Data,
+		},
+	})
+	if err != nil {
+		return PushResult{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.sendEndpoint, bytes.NewReader(payload))
+	if err != nil {
+		return PushResult{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return PushResult{}, fmt.Errorf("FCM request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		var sent struct {
+			Name string `json:"name"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&sent)
+		return PushResult{MessageID: sent.Name}, nil
+	}
+
+	var failure struct {
+		Error struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+			Details []struct {
+				ErrorCode string `json:"errorCode"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&failure)
+
+	invalid := resp.StatusCode == http.StatusNotFound || failure.Error.Status == "NOT_FOUND"
+	for _, detail := range failure.Error.Details {
+		if detail.ErrorCode == "UNREGISTERED" {
+			invalid = true
+		}
+	}
+	return PushResult{InvalidToken: invalid}, fmt.Errorf("FCM send failed with status %d: %s", resp.StatusCode, failure.Error.Message)
+}
+
+// FakePushProvider records messages in memory instead of sending them. Tokens in InvalidTokens are rejected as invalid.
+type FakePushProvider struct {
+	mu            sync.Mutex
+	Sent          []PushMessage
+	InvalidTokens map[string]bool
+}
+
+func NewFakePushProvider() *FakePushProvider {
+	return &FakePushProvider{InvalidTokens: make(map[string]bool)}
+}
+
+func (p *FakePushProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakePushProvider) Send(ctx context.Context, message PushMessage) (PushResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.InvalidTokens[message.Token] {
+		return PushResult{InvalidToken: true}, errors.New("token is not registered")
+	}
+	p.Sent = append(p.Sent, message)
+	return PushResult{MessageID: fmt.Sprintf("fake-%d", len(p.Sent))}, nil
+}
+
+// Messages returns a copy of the messages recorded so far
+func (p *FakePushProvider) Messages() []PushMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]PushMessage(nil), p.Sent...)
+}
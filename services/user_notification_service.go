@@ -0,0 +1,173 @@
+package services
+
+import (
+	"backend-school/config"
+	"backend-school/models"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrNotificationNotFound is returned when a notification does not exist or belongs to another user
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// notificationSubscriberBuffer is how many notifications a slow stream may fall behind before new ones are dropped
+const notificationSubscriberBuffer = 16
+
+// notificationHub fans out newly created notifications to the live streams of their recipient
+type notificationHub struct {
+	mu          sync.RWMutex
+	subscribers map[int]map[chan models.Notification]struct{}
+}
+
+var inAppNotificationHub = &notificationHub{subscribers: make(map[int]map[chan models.Notification]struct{})}
+
+func (h *notificationHub) subscribe(userID int) chan models.Notification {
+	ch := make(chan models.Notification, notificationSubscriberBuffer)
+	h.mu.Lock()
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = make(map[chan models.Notification]struct{})
+	}
+	h.subscribers[userID][ch] = struct{}{}
+	h.mu.Unlock()
+	return ch
+}
+
+func (h *notificationHub) unsubscribe(userID int, ch chan models.Notification) {
+	h.mu.Lock()
+	if subscribers, ok := h.subscribers[userID]; ok {
+		delete(subscribers, ch)
+		if len(subscribers) == 0 {
+			delete(h.subscribers, userID)
+		}
+	}
+	h.mu.Unlock()
+	close(ch)
+}
+
+func (h *notificationHub) publish(notification models.Notification) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for ch := range h.subscribers[notification.UserID] {
+		select {
+		case ch <- notification:
+		default:
+			// The client can reload the inbox; never block the producer on a slow stream
+		}
+	}
+}
+
+// SubscribeNotifications returns a channel receiving the user's new notifications and a function that ends the subscription
+func SubscribeNotifications(userID int) (<-chan models.Notification, func()) {
+	ch := inAppNotificationHub.subscribe(userID)
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() { inAppNotificationHub.unsubscribe(userID, ch) })
+	}
+}
+
+// InAppNotificationService manages the in-app notification inbox
+type InAppNotificationService struct{}
+
+func NewInAppNotificationService() *InAppNotificationService {
+	return &InAppNotificationService{}
+}
+
+// NotifyUser stores a notification for a user, pushes it to their live streams and sends it to their devices
+func (s *InAppNotificationService) NotifyUser(userID int, event, title, message, link string) (*models.Notification, error) {
+	if userID == 0 {
+		return nil, errors.New("notification recipient is required")
+	}
+
+	notification := models.Notification{
+		UserID:  userID,
+		Event:   event,
+		Title:   title,
+		Message: message,
+		Link:    link,
+	}
+	if err := config.DB.Create(&notification).Error; err != nil {
+		return nil, fmt.Errorf("failed to create notification: %w", err)
+	}
+
+	inAppNotificationHub.publish(notification)
+	pushToUserAsync(userID, event, title, message, map[string]string{
+		"event":             event,
+		"link":              link,
+		"notification_uuid": notification.UUID.String(),
+	})
+	return &notification, nil
+}
+
+// notifyUserBestEffort is NotifyUser for producers that must not fail because of a notification; errors are logged
+func notifyUserBestEffort(userID int, event, title, message, link string) {
+	if _, err := NewInAppNotificationService().NotifyUser(userID, event, title, message, link); err != nil {
+		log.Printf("Failed to create %s notification for user %d: %v", event, userID, err)
+	}
+}
+
+// GetNotificationsPaginated lists a user's notifications, newest first, with the unread count
+func (s *InAppNotificationService) GetNotificationsPaginated(userID, perPage, page int, unreadOnly bool) (map[string]interface{}, error) {
+	var notifications []models.Notification
+	var totalRecords, unreadCount int64
+
+	// Calculate offset for pagination
+	offset := (page - 1) * perPage
+
+	query := config.DB.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	// Get the total number of records
+	query.Count(&totalRecords)
+
+	if err := query.Order("created_at DESC, id DESC").Limit(perPage).Offset(offset).Find(&notifications).Error; err != nil {
+		return nil, errors.New("failed to fetch notifications")
+	}
+
+	config.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&unreadCount)
+
+	// Calculate total pages
+	totalPages := int(math.Ceil(float64(totalRecords) / float64(perPage)))
+
+	return map[string]interface{}{
+		"data":          notifications,
+		"unread_count":  unreadCount,
+		"current_page":  page,
+		"per_page":      perPage,
+		"total_pages":   totalPages,
+		"total_records": totalRecords,
+	}, nil
+}
+
+// MarkRead marks one of the user's notifications as read
+func (s *InAppNotificationService) MarkRead(userID int, uuid string) (*models.Notification, error) {
+	var notification models.Notification
+	if err := config.DB.Where("uuid = ? AND user_id = ?", uuid, userID).First(&notification).Error; err != nil {
+		return nil, ErrNotificationNotFound
+	}
+
+	if notification.ReadAt == nil {
+		now := time.Now()
+		if err := config.DB.Model(&notification).Update("read_at", now).Error; err != nil {
+			return nil, errors.New("failed to mark notification as read")
+		}
+		notification.ReadAt = &now
+	}
+	return &notification, nil
+}
+
+// MarkAllRead marks every unread notification of the user as read and returns how many changed
+func (s *InAppNotificationService) MarkAllRead(userID int) (int64, error) {
+	result := config.DB.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		return 0, errors.New("failed to mark notifications as read")
+	}
+	return result.RowsAffected, nil
+}
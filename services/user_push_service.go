@@ -0,0 +1,184 @@
+package services
+
+import (
+	"backend-school/config"
+	"backend-school/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// pushSendTimeout bounds the time spent delivering one push message
+const pushSendTimeout = 15 * time.Second
+
+var (
+	pushProviderMu sync.RWMutex
+	pushProvider   PushProvider
+)
+
+// SetPushProvider selects the provider used for push delivery; nil disables push
+func SetPushProvider(provider PushProvider) {
+	pushProviderMu.Lock()
+	pushProvider = provider
+	pushProviderMu.Unlock()
+}
+
+func currentPushProvider() PushProvider {
+	pushProviderMu.RLock()
+	defer pushProviderMu.RUnlock()
+	return pushProvider
+}
+
+// PushService manages device tokens and sends push notifications
+type PushService struct{}
+
+func NewPushService() *PushService {
+	return &PushService{}
+}
+
+// RegisterDevice stores a device token for the user; a token moving to another account is reassigned
+func (s *PushService) RegisterDevice(userID int, token, platform string) (*models.DeviceToken, error) {
+	if token == "" {
+		return nil, errors.New("token is required")
+	}
+
+	device := models.DeviceToken{
+		UserID:     userID,
+		Token:      token,
+		Platform:   platform,
+		LastSeenAt: time.Now(),
+	}
+	if err := config.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "token"}},
+		DoUpdates: clause.AssignmentColumns([]string{"user_id", "platform", "last_seen_at", "updated_at"}),
+	}).Create(&device).Error; err != nil {
+		return nil, fmt.Errorf("failed to register device: %w", err)
+	}
+
+	// An upsert of a known token keeps the stored row, so read back its UUID and creation time
+	var stored models.DeviceToken
+	if err := config.DB.Where("token = ?", token).First(&stored).Error; err != nil {
+		return nil, fmt.Errorf("failed to register device: %w", err)
+	}
+	return &stored, nil
+}
+
+// UnregisterDevice removes one of the user's device tokens
+func (s *PushService) UnregisterDevice(userID int, token string) error {
+	result := config.DB.Where("user_id = ? AND token = ?", userID, token).Delete(&models.DeviceToken{})
+	if result.Error != nil {
+		return errors.New("failed to unregister device")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("device not found")
+	}
+	return nil
+}
+
+// GetDevices lists the user's registered devices
+func (s *PushService) GetDevices(userID int) ([]models.DeviceToken, error) {
+	var devices []models.DeviceToken
+	if err := config.DB.Where("user_id = ?", userID).Order("last_seen_at DESC").Find(&devices).Error; err != nil {
+		return nil, errors.New("failed to fetch devices")
+	}
+	return devices, nil
+}
+
+// SendToUser pushes a message to every device of the user, records each delivery and prunes rejected tokens.
+// It returns the number of devices the message was delivered to.
+func (s *PushService) SendToUser(userID int, event, title, body string, data map[string]string) (int, error) {
+	provider := currentPushProvider()
+	if provider == nil {
+		return 0, nil
+	}
+
+	devices, err := s.GetDevices(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, device := range devices {
+		ctx, cancel := context.WithTimeout(context.Background(), pushSendTimeout)
+		result, sendErr := provider.Send(ctx, PushMessage{Token: device.Token, Title: title, Body: body, Data: data})
+		cancel()
+
+		delivery := models.PushDelivery{
+			UserID:            userID,
+			DeviceTokenID:     device.ID,
+			Provider:          provider.Name(),
+			Event:             event,
+			Title:             title,
+			Status:            models.PushStatusSent,
+			ProviderMessageID: result.MessageID,
+		}
+		switch {
+		case sendErr == nil:
+			delivered++
+		case result.InvalidToken:
+			delivery.Status = models.PushStatusInvalidToken
+			delivery.Error = sendErr.Error()
+			if err := config.DB.Delete(&device).Error; err != nil {
+				log.Printf("Failed to prune device token %d: %v", device.ID, err)
+			}
+		default:
+			delivery.Status = models.PushStatusFailed
+			delivery.Error = sendErr.Error()
+		}
+
+		if err := config.DB.Create(&delivery).Error; err != nil {
+			log.Printf("Failed to record push delivery for user %d: %v", userID, err)
+		}
+	}
+	return delivered, nil
+}
+
+// pushToUserAsync sends a push message in the background so producers never wait on the provider
+func pushToUserAsync(userID int, event, title, body string, data map[string]string) {
+	if currentPushProvider() == nil {
+		return
+	}
+	go func() {
+		if _, err := NewPushService().SendToUser(userID, event, title, body, data); err != nil {
+			log.Printf("Failed to push %s to user %d: %v", event, userID, err)
+		}
+	}()
+}
+
+// GetDeliveriesPaginated lists recorded push deliveries, optionally filtered by status
+func (s *PushService) GetDeliveriesPaginated(perPage, page int, status string) (map[string]interface{}, error) {
+	var deliveries []models.PushDelivery
+	var totalRecords int64
+
+	// Calculate offset for pagination
+	offset := (page - 1) * perPage
+
+	query := config.DB.Model(&models.PushDelivery{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	// Get the total number of records
+	query.Count(&totalRecords)
+
+	if err := query.Order("created_at DESC").Limit(perPage).Offset(offset).Find(&deliveries).Error; err != nil {
+		return nil, errors.New("failed to fetch push deliveries")
+	}
+
+	// Calculate total pages
+	totalPages := int(math.Ceil(float64(totalRecords) / float64(perPage)))
+
+	return map[string]interface{}{
+		"data":          deliveries,
+		"current_page":  page,
+		"per_page":      perPage,
+		"total_pages":   totalPages,
+		"total_records": totalRecords,
+	}, nil
+}
@@ -0,0 +1,77 @@
+package templates
+
+import "backend-school/models"
+
+// DefaultNotificationTemplate is the built-in template for an event. It is seeded into
+// notification_template on startup and used when no stored template matches.
+type DefaultNotificationTemplate struct {
+	Subject    string
+	Body       string
+	SampleData map[string]interface{} // example data used by the admin preview
+}
+
+const FormSubmittedEmailTemplate = `
+<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <title>New Form Submission</title>
+</head>
+<body style="font-family: Arial, sans-serif; color: #51545e;">
+    <h1 style="color: #333333; font-size: 20px;">New submission for {{.FormTitle}}</h1>
+    <p>A new form has been submitted with the following details:</p>
+    <table cellpadding="6" style="border-collapse: collapse;">
+        {{range $field, $value := .Fields}}
+        <tr>
+            <td style="font-weight: bold; border-bottom: 1px solid #eeeeee;">{{$field}}</td>
+            <td style="border-bottom: 1px solid #eeeeee;">{{$value}}</td>
+        </tr>
+        {{end}}
+    </table>
+</body>
+</html>
+`
+
+const DocumentStatusEmailTemplate = `
+<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <title>Document {{.Status}}</title>
+</head>
+<body style="font-family: Arial, sans-serif; color: #51545e;">
+    <h1 style="color: #333333; font-size: 20px;">Document {{.Status}}</h1>
+    <p>
+        Your document <strong>{{.DocumentName}}</strong> ({{.DocumentNumber}}, revision {{.RevisionNumber}})
+        has been marked as <strong>{{.Status}}</strong>.
+    </p>
+</body>
+</html>
+`
+
+// DefaultNotificationTemplates holds the built-in template of every known notification event
+var DefaultNotificationTemplates = map[string]DefaultNotificationTemplate{
+	models.NotificationEventPasswordReset: {
+		Subject:    "Password Reset Request",
+		Body:       ResetEmailTemplate,
+		SampleData: map[string]interface{}{"ResetLink": "https://example.com/reset-password?token=sample"},
+	},
+	models.NotificationEventFormSubmitted: {
+		Subject: "New form submission for {{.FormTitle}}",
+		Body:    FormSubmittedEmailTemplate,
+		SampleData: map[string]interface{}{
+			"FormTitle": "Contact Us",
+			"Fields":    map[string]string{"name": "Jane Doe", "email": "jane@example.com", "message": "Hello"},
+		},
+	},
+	models.NotificationEventDocumentApproved: {
+		Subject:    "Document {{.DocumentNumber}} approved",
+		Body:       DocumentStatusEmailTemplate,
+		SampleData: map[string]interface{}{"DocumentName": "Quality Manual", "DocumentNumber": "QM-001", "RevisionNumber": 2, "Status": "Approved"},
+	},
+	models.NotificationEventDocumentRejected: {
+		Subject:    "Document {{.DocumentNumber}} rejected",
+		Body:       DocumentStatusEmailTemplate,
+		SampleData: map[string]interface{}{"DocumentName": "Quality Manual", "DocumentNumber": "QM-001", "RevisionNumber": 2, "Status": "Rejected"},
+	},
+}
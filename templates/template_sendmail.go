@@ -96,7 +96,7 @@ const ResetEmailTemplate = `
                     You are receiving this email because we received a password reset request for your account. Please click the button below to reset your password. If you did not request a password reset, you can safely ignore this email.
                 </p>
                 <div class="email-button">
-                    <a href="{{RESET_LINK}}" target="_blank">Reset Password</a>
+                    <a href="{{.ResetLink}}" target="_blank">Reset Password</a>
                 </div>
                 <p>
                     This password reset link will expire in 1 hour. <br>